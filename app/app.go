@@ -1,51 +1,145 @@
 package app
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"gin-dbo/framework/database"
 	"log"
 	"os"
 
+	"gin-dbo/framework/config"
+	"gin-dbo/framework/event"
+	"gin-dbo/framework/health"
+	"gin-dbo/framework/lifecycle"
 	"gin-dbo/framework/logger"
-
-	"github.com/subosito/gotenv"
+	"gin-dbo/framework/mail"
+	"gin-dbo/framework/metrics"
+	"gin-dbo/framework/middleware"
+	"gin-dbo/framework/server"
+	"gin-dbo/framework/tracing"
+	"gin-dbo/framework/utils"
+	"gin-dbo/framework/webhook"
 
 	controller "gin-dbo/controller"
+	apiKeyController "gin-dbo/controller/apikey"
+	auditController "gin-dbo/controller/audit"
 	customerController "gin-dbo/controller/customer"
+	invoiceController "gin-dbo/controller/invoice"
 	loginController "gin-dbo/controller/login"
 	orderController "gin-dbo/controller/order"
+	segmentController "gin-dbo/controller/segment"
+	webhookController "gin-dbo/controller/webhook"
 
 	_ "gin-dbo/docs"
 )
 
 func Run() {
-	err := gotenv.Load()
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	var baseLogger = logger.Logger()
-	dbConn, err := database.ConnectSQL(baseLogger)
+	var baseLogger = logger.Logger(cfg)
+	baseLogger.Debugf("app.Run : configuration\n%s", cfg)
+	utils.IfMatchRequired = cfg.RequireIfMatch
+	jwtService := middleware.JWTAuthService(cfg.JWT)
+
+	dbConn, err := database.ConnectSQL(cfg.Database, !cfg.IsDevelopment(), baseLogger)
 	if err != nil {
 		baseLogger.Fatal(err)
 	}
 
+	if err = metrics.Instrument(dbConn); err != nil {
+		baseLogger.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Setup(cfg.Tracing, cfg.Environment)
+	if err != nil {
+		baseLogger.Fatal(err)
+	}
+	if err = dbConn.Use(tracing.Plugin{}); err != nil {
+		baseLogger.Fatal(err)
+	}
+
+	app := lifecycle.New(baseLogger, cfg.Server.ShutdownTimeout.Duration())
+	app.Delay = cfg.Server.ShutdownDelay.Duration()
+	app.Append(lifecycle.Hook{
+		Name: "database",
+		OnStop: func(ctx context.Context) error {
+			return database.Close(dbConn)
+		},
+	})
+	app.Append(lifecycle.Hook{
+		Name:   "tracing",
+		OnStop: shutdownTracing,
+	})
+
+	checks := health.New(app.Stopping, cfg.Health.CheckTimeout.Duration())
+	checks.Register("database", 0, health.Database(dbConn))
+
+	eventBus := event.NewBus(dbConn, baseLogger)
+	eventBus.AddTransport(event.NewLogTransport(baseLogger))
+
+	webhookWorker := webhook.NewWorker(dbConn, baseLogger)
+	eventBus.Subscribe(event.All, webhookWorker.Enqueue)
+
+	auditRepository := auditController.NewRepository(dbConn)
+	auditUsecase := auditController.NewUsecase(auditRepository)
+
 	customerRepository := customerController.NewRepository(dbConn)
-	customerUsecase := customerController.NewUsecase(customerRepository)
+	customerUsecase := customerController.NewUsecase(customerRepository, auditUsecase, eventBus)
 
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		baseLogger.Fatal(err)
+	}
 	loginRepository := loginController.NewRepository(dbConn)
-	loginUsecase := loginController.NewUsecase(loginRepository, customerRepository)
+	mailQueue := mail.NewQueue(mailer, baseLogger)
+	loginUsecase := loginController.NewUsecase(loginRepository, customerRepository, auditUsecase, eventBus, mailQueue, jwtService, cfg)
 
 	orderRepository := orderController.NewRepository(dbConn)
-	orderUsecase := orderController.NewUsecase(orderRepository, customerRepository)
+	orderUsecase := orderController.NewUsecase(orderRepository, customerRepository, auditUsecase, eventBus)
+
+	invoiceRepository := invoiceController.NewRepository(dbConn)
+	invoiceUsecase := invoiceController.NewUsecase(invoiceRepository, orderRepository, customerRepository)
+
+	webhookRepository := webhookController.NewRepository(dbConn)
+	webhookUsecase := webhookController.NewUsecase(webhookRepository)
+
+	apiKeyRepository := apiKeyController.NewRepository(dbConn)
+	apiKeyUsecase := apiKeyController.NewUsecase(apiKeyRepository, auditUsecase)
+
+	segmentRepository := segmentController.NewRepository(dbConn)
+	segmentUsecase := segmentController.NewUsecase(segmentRepository, customerRepository, auditUsecase)
 
 	httpRouter := &controller.Controller{
 		Login:    loginUsecase,
 		Customer: customerUsecase,
 		Order:    orderUsecase,
+		Invoice:  invoiceUsecase,
+		Audit:    auditUsecase,
+		Webhook:  webhookUsecase,
+		APIKey:   apiKeyUsecase,
+		Segment:  segmentUsecase,
+
+		JWT:         jwtService,
+		Idempotency: middleware.NewIdempotencyStore(dbConn),
+		RateLimit:   middleware.NewMemoryRateLimitStore(),
+		APIKeys:     middleware.NewAPIKeyStore(dbConn),
+		Config:      cfg,
+		Health:      checks,
 	}
 
+	app.Go("event dispatcher", eventBus.Run)
+	app.Go("webhook worker", webhookWorker.Run)
+	app.Go("mail queue", mailQueue.Run)
+
 	router := controller.Router(httpRouter, baseLogger)
-	if err = router.Run(":" + os.Getenv("PORT")); err != nil {
+	app.Append(server.Hook(server.New(router, cfg.Server), app, baseLogger))
+	if err = app.Run(); err != nil {
 		baseLogger.Fatal(err)
 	}
 }
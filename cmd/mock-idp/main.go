@@ -0,0 +1,48 @@
+// Command mock-idp is a local stand-in for an OpenID Connect provider. It
+// signs in a single configured user without asking anything, which makes it
+// easy to try the OIDC sign in without a real identity provider:
+//
+//	go run ./cmd/mock-idp -addr :30003 -user jane.doe -groups staff-admins
+//
+// then set OIDC_ENABLED=true, OIDC_ISSUER=http://localhost:30003 and open
+// http://localhost:30001/api/auth/oidc/login. Use -deny to see a refused
+// sign in.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"gin-dbo/framework/oidc/oidctest"
+)
+
+func main() {
+	addr := flag.String("addr", ":30003", "address to listen on")
+	issuer := flag.String("issuer", "http://localhost:30003", "issuer, the URL the service reaches this provider at")
+	clientID := flag.String("client", "gin-dbo", "client id of the service")
+	clientSecret := flag.String("secret", "", "client secret of the service, none when empty")
+	user := flag.String("user", "jane.doe", "preferred_username of the signed in user")
+	email := flag.String("email", "jane.doe@example.com", "verified email of the signed in user")
+	groups := flag.String("groups", "staff-admins", "comma separated groups of the signed in user")
+	deny := flag.Bool("deny", false, "refuse every sign in with access_denied")
+	flag.Parse()
+
+	idp, err := oidctest.New(oidctest.Options{
+		Issuer:       *issuer,
+		ClientID:     *clientID,
+		ClientSecret: *clientSecret,
+		User:         *user,
+		Email:        *email,
+		Groups:       strings.Split(*groups, ","),
+		Deny:         *deny,
+		Log:          log.Default(),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("listening on %s as %s", *addr, *issuer)
+	log.Fatal(http.ListenAndServe(*addr, idp))
+}
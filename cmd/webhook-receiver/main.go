@@ -0,0 +1,47 @@
+// Command webhook-receiver is a local stand-in for a partner endpoint. It
+// verifies the signature of every delivery and prints it, which makes it
+// easy to try webhook subscriptions without a real partner:
+//
+//	go run ./cmd/webhook-receiver -addr :30002 -secret whsec_...
+//
+// Use -status 500 to see retries and the automatic disabling of an endpoint
+// that keeps failing.
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"net/http"
+
+	"gin-dbo/framework/webhook"
+)
+
+func main() {
+	addr := flag.String("addr", ":30002", "address to listen on")
+	secret := flag.String("secret", "", "secret of the subscription, signatures are not checked when empty")
+	status := flag.Int("status", http.StatusOK, "status code to answer valid deliveries with")
+	flag.Parse()
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if *secret != "" {
+			if err := webhook.Verify(*secret, r.Header.Get(webhook.HeaderSignature), body, webhook.DefaultTolerance); err != nil {
+				log.Printf("rejected %s : %v", r.Header.Get(webhook.HeaderDelivery), err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		log.Printf("%s %s %s", r.Header.Get(webhook.HeaderEvent), r.Header.Get(webhook.HeaderDelivery), body)
+		w.WriteHeader(*status)
+	})
+
+	log.Printf("listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -0,0 +1,163 @@
+package apikey
+
+import (
+	"fmt"
+	"gin-dbo/framework/logger"
+	"gin-dbo/framework/middleware"
+	"gin-dbo/framework/utils"
+	mdl "gin-dbo/view/apikey"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	Usecase Usecase
+}
+
+// @SecurityDefinitions jwt
+func Router(router *gin.Engine, uc Usecase, auth func(*gin.Context)) {
+	u := Handler{Usecase: uc}
+	authorized := router.Group("/", auth)
+	{
+		authorized.GET("api/api-keys", u.GetHandler)
+		authorized.GET("api/api-keys/:id", u.GetByIdHandler)
+		authorized.POST("api/api-keys", u.CreateHandler)
+		authorized.DELETE("api/api-keys/:id", u.RevokeHandler)
+	}
+}
+
+// @Summary Get All API Keys
+// @Description Get All API Keys, revoked ones included, admin only
+// @param limit query int false "limit"
+// @param page query string false "page"
+// @Produce json
+// @Security jwt
+// @Success 200 {object} mdl.ResponseData
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Router /api/api-keys [get]
+func (u Handler) GetHandler(c *gin.Context) {
+	limit, err := utils.GetLimit(c.Query(utils.Limit))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("apikey.getHandler.BadRequest : %v", err.Message.Error())})
+		return
+	}
+
+	page, err := utils.GetTargetPage(c.Query(utils.Page))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("apikey.getHandler.BadRequest : %v", err.Message.Error())})
+		return
+	}
+
+	param := &mdl.GetRequest{
+		Limit: limit,
+		Page:  page,
+	}
+	result, err := u.Usecase.Get(c, param)
+	if err == nil {
+		result.Success = true
+		result.Message = "success retrieve data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
+// @Summary Get API Key By Id
+// @Description API Key By Id with its last use, admin only
+// @Produce json
+// @Security jwt
+// @Success 200 {object} mdl.ResponseDetail
+// @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.Response400
+// @Failure 500 {object} mdl.Response500
+// @Router /api/api-keys/{id} [get]
+func (u Handler) GetByIdHandler(c *gin.Context) {
+	result, err := u.Usecase.GetById(c, c.Param("id"))
+	if err == nil {
+		result.Success = true
+		result.Message = "success retrieve data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
+// @Summary Create API Key
+// @Description Issue an API key for a machine integration, sent as the Bearer token. Scopes are <resource>:read for GET requests and <resource>:write for the others, resources being customer, order, user, audit and webhook; within its scopes a key sees every customer. The key is only returned once
+// @Accept json
+// @Produce json
+// @Param request body mdl.CreateRequest true "Sample Create request payload"
+// @Security jwt
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Router /api/api-keys [post]
+func (u Handler) CreateHandler(c *gin.Context) {
+	middleware.NoStore(c)
+	param := new(mdl.CreateRequest)
+	if err := c.BindJSON(param); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("apikey.createHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	logger.FromContext(c).Debugf("%+v", param)
+	if err := utils.ValidateCreateAPIKeyRequest(param); err == nil {
+		result, err := u.Usecase.Create(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success create data, store the key safely"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("apikey.createHandler.BadRequest : %v", err.Error())})
+	}
+}
+
+// @Summary Revoke API Key
+// @Description Revoke an API key right away, it stays listed with its revocation time
+// @Produce json
+// @Security jwt
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.Response400
+// @Failure 409 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/api-keys/{id} [delete]
+func (u Handler) RevokeHandler(c *gin.Context) {
+	param := &mdl.RevokeRequest{
+		Id: c.Param("id"),
+	}
+	logger.FromContext(c).Debugf("%+v", param)
+
+	if err := utils.ValidateRevokeAPIKeyRequest(param); err == nil {
+		result, err := u.Usecase.Revoke(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success revoke API key"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("apikey.revokeHandler.BadRequest : %v", err.Error())})
+	}
+}
@@ -0,0 +1,125 @@
+package apikey
+
+import (
+	"errors"
+	"fmt"
+	"gin-dbo/framework/utils"
+	models "gin-dbo/model/apikey"
+	view "gin-dbo/view/apikey"
+
+	"gin-dbo/framework/database"
+	internal "gin-dbo/framework/error"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Repo struct {
+	Dbconn *gorm.DB
+}
+
+type Repository interface {
+	Get(ctx *gin.Context, request *view.GetRequest, page int) (res []*models.Key, err *internal.Error)
+	Count(ctx *gin.Context, request *view.GetRequest) (res int, err *internal.Error)
+	GetById(ctx *gin.Context, id string) (res *models.Key, err *internal.Error)
+	Create(ctx *gin.Context, key *models.Key) (err *internal.Error)
+	Revoke(ctx *gin.Context, request *view.RevokeRequest) (err *internal.Error)
+	Transaction(ctx *gin.Context, fn func(repo Repository) *internal.Error) (err *internal.Error)
+}
+
+func NewRepository(dbconn *gorm.DB) Repository {
+	return tracedRepository{next: &Repo{Dbconn: dbconn}}
+}
+
+// conn joins the transaction carried by ctx, if any.
+func (r Repo) conn(ctx *gin.Context) *gorm.DB {
+	return database.Conn(ctx, r.Dbconn)
+}
+
+func (r Repo) Get(ctx *gin.Context, param *view.GetRequest, page int) ([]*models.Key, *internal.Error) {
+	var (
+		res []*models.Key
+	)
+	query := r.conn(ctx)
+	if param.Page > 0 {
+		query = query.Offset((page - 1) * param.Limit)
+	}
+
+	if param.Limit > 0 {
+		query = query.Limit(param.Limit)
+	}
+
+	if err := query.Order("created_at desc").Find(&res).Error; err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("apikey.repository.Get : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func (r Repo) Count(ctx *gin.Context, param *view.GetRequest) (int, *internal.Error) {
+	var (
+		res int
+	)
+	query := r.conn(ctx).Select("COUNT(1) as total").Model(&models.Key{})
+	if err := query.Pluck("total", &res).Error; err != nil {
+		return 0, internal.NewError(500, fmt.Errorf("apikey.repository.Count : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func (r Repo) GetById(ctx *gin.Context, id string) (*models.Key, *internal.Error) {
+	var (
+		res *models.Key
+		err error
+	)
+	query := r.conn(ctx).Model(&models.Key{}).Where("id = ?", id).Take(&res)
+	if err = query.Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, internal.NewError(404, fmt.Errorf("apikey.repository.GetById : %v", fmt.Errorf("no data found with id %s", id)))
+	}
+	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("apikey.repository.GetById : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func (r Repo) Create(ctx *gin.Context, key *models.Key) *internal.Error {
+	if err := r.conn(ctx).Create(key).Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("apikey.repository.Create : %v", err.Error()))
+	}
+	return nil
+}
+
+// Revoke stops a key from authenticating, keeping it for the record. It
+// fails with 409 when the key is already revoked.
+func (r Repo) Revoke(ctx *gin.Context, param *view.RevokeRequest) *internal.Error {
+	query := r.conn(ctx).Model(&models.Key{}).Where("id = ? and revoked_at = ''", param.Id).Update("revoked_at", utils.FormatTime())
+	if err := query.Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("apikey.repository.Revoke : %v", err.Error()))
+	}
+	if query.RowsAffected == 0 {
+		if _, err := r.GetById(ctx, param.Id); err != nil {
+			return err
+		}
+		return internal.NewError(409, fmt.Errorf("API key %s is already revoked", param.Id))
+	}
+	return nil
+}
+
+// Transaction runs fn with a repository bound to a single database
+// transaction, which other repositories called with the same ctx join,
+// rolling everything back when fn returns an error.
+func (r Repo) Transaction(ctx *gin.Context, fn func(repo Repository) *internal.Error) *internal.Error {
+	var res *internal.Error
+	err := database.Transaction(ctx, r.Dbconn, func(tx *gorm.DB) error {
+		if res = fn(&Repo{Dbconn: tx}); res != nil {
+			return res.Message
+		}
+		return nil
+	})
+	if res != nil {
+		return res
+	}
+	if err != nil {
+		return internal.NewError(500, fmt.Errorf("apikey.repository.Transaction : %v", err.Error()))
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package apikey
+
+import (
+	"testing"
+
+	"gin-dbo/framework/database/dbtest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetByIdNotFound(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `api_keys` WHERE id = \\? LIMIT 1").WithArgs("k1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	res, err := NewRepository(db).GetById(dbtest.Context(), "k1")
+	if err == nil || err.Code != 404 {
+		t.Fatalf("got %+v, %v; want 404", res, err)
+	}
+}
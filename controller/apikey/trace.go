@@ -0,0 +1,72 @@
+package apikey
+
+import (
+	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/tracing"
+	models "gin-dbo/model/apikey"
+	mdl "gin-dbo/view/apikey"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tracedRepository opens a span around every repository method.
+type tracedRepository struct {
+	next Repository
+}
+
+func (t tracedRepository) Get(ctx *gin.Context, request *mdl.GetRequest, page int) (res []*models.Key, err *internal.Error) {
+	defer tracing.Start(ctx, "apikey.repository.Get").End(&err)
+	return t.next.Get(ctx, request, page)
+}
+
+func (t tracedRepository) Count(ctx *gin.Context, request *mdl.GetRequest) (res int, err *internal.Error) {
+	defer tracing.Start(ctx, "apikey.repository.Count").End(&err)
+	return t.next.Count(ctx, request)
+}
+
+func (t tracedRepository) GetById(ctx *gin.Context, id string) (res *models.Key, err *internal.Error) {
+	defer tracing.Start(ctx, "apikey.repository.GetById").End(&err)
+	return t.next.GetById(ctx, id)
+}
+
+func (t tracedRepository) Create(ctx *gin.Context, key *models.Key) (err *internal.Error) {
+	defer tracing.Start(ctx, "apikey.repository.Create").End(&err)
+	return t.next.Create(ctx, key)
+}
+
+func (t tracedRepository) Revoke(ctx *gin.Context, request *mdl.RevokeRequest) (err *internal.Error) {
+	defer tracing.Start(ctx, "apikey.repository.Revoke").End(&err)
+	return t.next.Revoke(ctx, request)
+}
+
+func (t tracedRepository) Transaction(ctx *gin.Context, fn func(repo Repository) *internal.Error) (err *internal.Error) {
+	defer tracing.Start(ctx, "apikey.repository.Transaction").End(&err)
+	return t.next.Transaction(ctx, func(repo Repository) *internal.Error {
+		return fn(tracedRepository{next: repo})
+	})
+}
+
+// tracedUsecase opens a span around every usecase method.
+type tracedUsecase struct {
+	next Usecase
+}
+
+func (t tracedUsecase) Get(ctx *gin.Context, request *mdl.GetRequest) (res mdl.ResponseData, err *internal.Error) {
+	defer tracing.Start(ctx, "apikey.usecase.Get").End(&err)
+	return t.next.Get(ctx, request)
+}
+
+func (t tracedUsecase) GetById(ctx *gin.Context, id string) (res mdl.ResponseDetail, err *internal.Error) {
+	defer tracing.Start(ctx, "apikey.usecase.GetById").End(&err)
+	return t.next.GetById(ctx, id)
+}
+
+func (t tracedUsecase) Create(ctx *gin.Context, request *mdl.CreateRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "apikey.usecase.Create").End(&err)
+	return t.next.Create(ctx, request)
+}
+
+func (t tracedUsecase) Revoke(ctx *gin.Context, request *mdl.RevokeRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "apikey.usecase.Revoke").End(&err)
+	return t.next.Revoke(ctx, request)
+}
@@ -0,0 +1,151 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	models "gin-dbo/model/apikey"
+	auditModel "gin-dbo/model/audit"
+	mdl "gin-dbo/view/apikey"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"gin-dbo/controller/audit"
+	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/logger"
+	"gin-dbo/framework/middleware"
+	"gin-dbo/framework/utils"
+)
+
+const (
+	resource  = "api_key"
+	keyBytes  = 32
+	hintChars = 8
+)
+
+type UsecaseModul struct {
+	Repo  Repository
+	Audit audit.Recorder
+}
+
+type Usecase interface {
+	Get(ctx *gin.Context, request *mdl.GetRequest) (res mdl.ResponseData, err *internal.Error)
+	GetById(ctx *gin.Context, id string) (res mdl.ResponseDetail, err *internal.Error)
+	Create(ctx *gin.Context, request *mdl.CreateRequest) (res mdl.GeneralResponse, err *internal.Error)
+	Revoke(ctx *gin.Context, request *mdl.RevokeRequest) (res mdl.GeneralResponse, err *internal.Error)
+}
+
+func NewUsecase(u Repository, a audit.Recorder) Usecase {
+	return tracedUsecase{next: &UsecaseModul{Repo: u, Audit: a}}
+}
+
+func (u *UsecaseModul) Get(ctx *gin.Context, param *mdl.GetRequest) (mdl.ResponseData, *internal.Error) {
+	var res mdl.ResponseData
+	count, err := u.Repo.Count(ctx, param)
+	if err != nil {
+		return mdl.ResponseData{}, err
+	}
+	page := utils.GetPage(param.Page)
+	totalPage := utils.GetTotalPage(param.Limit, count)
+
+	if page > totalPage {
+		return mdl.ResponseData{}, internal.NewError(400, fmt.Errorf("page greater than totalPage"))
+	}
+
+	data, err := u.Repo.Get(ctx, param, page)
+	if err != nil {
+		return mdl.ResponseData{}, err
+	}
+
+	res.Data = data
+	res.Limit = param.Limit
+	res.Page = page
+	res.TotalPage = totalPage
+	return res, nil
+}
+
+func (u *UsecaseModul) GetById(ctx *gin.Context, id string) (mdl.ResponseDetail, *internal.Error) {
+	var res mdl.ResponseDetail
+	data, err := u.Repo.GetById(ctx, id)
+	if err != nil {
+		return mdl.ResponseDetail{}, err
+	}
+	res.Data = data
+	return res, nil
+}
+
+// Create issues a key and returns it, only its hash is stored so it cannot
+// be read back later.
+func (u *UsecaseModul) Create(ctx *gin.Context, param *mdl.CreateRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	if param.ExpiresAt != "" && param.ExpiresAt <= utils.FormatTime() {
+		return res, internal.NewError(400, fmt.Errorf("apikey.usecase.Create : expiresAt must be in the future"))
+	}
+	token, errn := newKey()
+	if errn != nil {
+		return res, internal.NewError(500, fmt.Errorf("apikey.usecase.Create : %v", errn))
+	}
+
+	var createdBy string
+	var JWT, _ = ctx.Get(middleware.JwtClaims)
+	if jwtClaims, ok := JWT.(*middleware.AuthCustomClaims); ok {
+		createdBy = jwtClaims.Username
+	}
+	key := &models.Key{
+		Id:         uuid.New().String(),
+		Name:       param.Name,
+		Hint:       token[:len(models.Prefix)+hintChars],
+		KeyHash:    utils.Encrypt(token),
+		Scopes:     param.Scopes,
+		AllowedIps: param.AllowedIps,
+		ExpiresAt:  param.ExpiresAt,
+		CreatedBy:  createdBy,
+		CreatedAt:  utils.FormatTime(),
+	}
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		if err := repo.Create(ctx, key); err != nil {
+			return err
+		}
+		return u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionCreate, resource, key.Id, nil, key))
+	})
+	if err != nil {
+		return res, err
+	}
+	logger.FromContext(ctx).Infof("apikey.usecase.Create : issued %s with scopes %v", key.Name, key.Scopes)
+	res.Id = key.Id
+	res.Key = token
+	return res, nil
+}
+
+// Revoke stops a key from authenticating right away.
+func (u *UsecaseModul) Revoke(ctx *gin.Context, param *mdl.RevokeRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		before, err := repo.GetById(ctx, param.Id)
+		if err != nil {
+			return err
+		}
+		if err = repo.Revoke(ctx, param); err != nil {
+			return err
+		}
+		after, err := repo.GetById(ctx, param.Id)
+		if err != nil {
+			return err
+		}
+		return u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionUpdate, resource, param.Id, before, after))
+	})
+	if err != nil {
+		return res, err
+	}
+	res.Id = param.Id
+	return res, nil
+}
+
+func newKey() (string, error) {
+	b := make([]byte, keyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return models.Prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
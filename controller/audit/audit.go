@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"fmt"
+	"gin-dbo/framework/logger"
+	"gin-dbo/framework/utils"
+	mdl "gin-dbo/view/audit"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const dateLayout = "2006-01-02"
+
+type Handler struct {
+	Usecase Usecase
+}
+
+// @SecurityDefinitions jwt
+func Router(router *gin.Engine, uc Usecase, auth func(*gin.Context)) {
+	u := Handler{Usecase: uc}
+	authorized := router.Group("/", auth)
+	{
+		authorized.GET("api/audit", u.GetHandler)
+	}
+}
+
+// @Summary Get Audit Log
+// @Description Get the audit trail of every create, update and delete, admin only
+// @param limit query int false "limit"
+// @param page query string false "page"
+// @param actor query string false "username that did the change"
+// @param resource query string false "customer, order or user"
+// @param resourceId query string false "id of the changed resource"
+// @param from query string false "start of the time range, 2006-01-02 or 2006-01-02 15:04:05"
+// @param to query string false "end of the time range, 2006-01-02 or 2006-01-02 15:04:05"
+// @Produce json
+// @Security jwt
+// @Success 200 {object} mdl.ResponseData
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Router /api/audit [get]
+func (u Handler) GetHandler(c *gin.Context) {
+	limit, err := utils.GetLimit(c.Query(utils.Limit))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("audit.getHandler.BadRequest : %v", err.Message.Error())})
+		return
+	}
+
+	page, err := utils.GetTargetPage(c.Query(utils.Page))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("audit.getHandler.BadRequest : %v", err.Message.Error())})
+		return
+	}
+
+	from, errn := parseTime(c.Query("from"), "00:00:00")
+	if errn != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("audit.getHandler.BadRequest : %v", errn.Error())})
+		return
+	}
+
+	to, errn := parseTime(c.Query("to"), "23:59:59")
+	if errn != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("audit.getHandler.BadRequest : %v", errn.Error())})
+		return
+	}
+
+	param := &mdl.GetRequest{
+		Actor:      c.Query("actor"),
+		Resource:   c.Query("resource"),
+		ResourceId: c.Query("resourceId"),
+		From:       from,
+		To:         to,
+		Limit:      limit,
+		Page:       page,
+	}
+	result, err := u.Usecase.Get(c, param)
+	if err == nil {
+		result.Success = true
+		result.Message = "success retrieve data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
+// parseTime accepts a full timestamp or a plain date, which is completed
+// with clock so a date range covers whole days.
+func parseTime(v string, clock string) (string, error) {
+	if v == "" {
+		return "", nil
+	}
+	if _, err := time.Parse(dateLayout, v); err == nil {
+		return v + " " + clock, nil
+	}
+	if _, err := time.Parse(utils.TimeLayout, v); err != nil {
+		return "", fmt.Errorf("%s is not a valid time", v)
+	}
+	return v, nil
+}
@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"fmt"
+	models "gin-dbo/model/audit"
+	view "gin-dbo/view/audit"
+
+	"gin-dbo/framework/database"
+	internal "gin-dbo/framework/error"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const batchSize = 500
+
+type Repo struct {
+	Dbconn *gorm.DB
+}
+
+// Repository only appends entries, the audit trail is never changed.
+type Repository interface {
+	Create(ctx *gin.Context, entries []*models.Audit) (err *internal.Error)
+	Get(ctx *gin.Context, request *view.GetRequest, page int) (res []*models.Audit, err *internal.Error)
+	Count(ctx *gin.Context, request *view.GetRequest) (res int, err *internal.Error)
+}
+
+func NewRepository(dbconn *gorm.DB) Repository {
+	return tracedRepository{next: &Repo{Dbconn: dbconn}}
+}
+
+// conn joins the transaction carried by ctx, if any.
+func (r Repo) conn(ctx *gin.Context) *gorm.DB {
+	return database.Conn(ctx, r.Dbconn)
+}
+
+func (r Repo) Create(ctx *gin.Context, entries []*models.Audit) *internal.Error {
+	if err := r.conn(ctx).CreateInBatches(entries, batchSize).Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("audit.repository.Create : %v", err.Error()))
+	}
+	return nil
+}
+
+func (r Repo) Get(ctx *gin.Context, param *view.GetRequest, page int) ([]*models.Audit, *internal.Error) {
+	var (
+		res []*models.Audit
+	)
+	query := filter(r.conn(ctx), param)
+
+	if param.Page > 0 {
+		query = query.Offset((page - 1) * param.Limit)
+	}
+
+	if param.Limit > 0 {
+		query = query.Limit(param.Limit)
+	}
+
+	if err := query.Order("created_at desc").Find(&res).Error; err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("audit.repository.Get : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func (r Repo) Count(ctx *gin.Context, param *view.GetRequest) (int, *internal.Error) {
+	var (
+		res int
+	)
+	query := filter(r.conn(ctx).Select("COUNT(1) as total").Model(&models.Audit{}), param)
+
+	if err := query.Pluck("total", &res).Error; err != nil {
+		return 0, internal.NewError(500, fmt.Errorf("audit.repository.Count : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func filter(query *gorm.DB, param *view.GetRequest) *gorm.DB {
+	if param.Actor != "" {
+		query = query.Where("actor = ?", param.Actor)
+	}
+	if param.Resource != "" {
+		query = query.Where("resource = ?", param.Resource)
+	}
+	if param.ResourceId != "" {
+		query = query.Where("resource_id = ?", param.ResourceId)
+	}
+	if param.From != "" {
+		query = query.Where("created_at >= ?", param.From)
+	}
+	if param.To != "" {
+		query = query.Where("created_at <= ?", param.To)
+	}
+	return query
+}
@@ -0,0 +1,45 @@
+package audit
+
+import (
+	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/tracing"
+	models "gin-dbo/model/audit"
+	mdl "gin-dbo/view/audit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tracedRepository opens a span around every repository method.
+type tracedRepository struct {
+	next Repository
+}
+
+func (t tracedRepository) Create(ctx *gin.Context, entries []*models.Audit) (err *internal.Error) {
+	defer tracing.Start(ctx, "audit.repository.Create").End(&err)
+	return t.next.Create(ctx, entries)
+}
+
+func (t tracedRepository) Get(ctx *gin.Context, request *mdl.GetRequest, page int) (res []*models.Audit, err *internal.Error) {
+	defer tracing.Start(ctx, "audit.repository.Get").End(&err)
+	return t.next.Get(ctx, request, page)
+}
+
+func (t tracedRepository) Count(ctx *gin.Context, request *mdl.GetRequest) (res int, err *internal.Error) {
+	defer tracing.Start(ctx, "audit.repository.Count").End(&err)
+	return t.next.Count(ctx, request)
+}
+
+// tracedUsecase opens a span around every usecase method.
+type tracedUsecase struct {
+	next Usecase
+}
+
+func (t tracedUsecase) Record(ctx *gin.Context, entries ...*models.Audit) (err *internal.Error) {
+	defer tracing.Start(ctx, "audit.usecase.Record").End(&err)
+	return t.next.Record(ctx, entries...)
+}
+
+func (t tracedUsecase) Get(ctx *gin.Context, request *mdl.GetRequest) (res mdl.ResponseData, err *internal.Error) {
+	defer tracing.Start(ctx, "audit.usecase.Get").End(&err)
+	return t.next.Get(ctx, request)
+}
@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	models "gin-dbo/model/audit"
+	mdl "gin-dbo/view/audit"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/logger"
+	"gin-dbo/framework/middleware"
+	"gin-dbo/framework/utils"
+)
+
+// Recorder is what the other usecases need to leave an audit trail.
+type Recorder interface {
+	Record(ctx *gin.Context, entries ...*models.Audit) (err *internal.Error)
+}
+
+type UsecaseModul struct {
+	Repo Repository
+}
+
+type Usecase interface {
+	Recorder
+	Get(ctx *gin.Context, request *mdl.GetRequest) (res mdl.ResponseData, err *internal.Error)
+}
+
+func NewUsecase(u Repository) Usecase {
+	return tracedUsecase{next: &UsecaseModul{Repo: u}}
+}
+
+// NewEntry describes a single mutation of a resource done by the caller of
+// ctx. Before is nil for creations and after is nil for deletions.
+func NewEntry(ctx *gin.Context, action string, resource string, id string, before interface{}, after interface{}) *models.Audit {
+	entry := &models.Audit{
+		Id:         uuid.New().String(),
+		Action:     action,
+		Resource:   resource,
+		ResourceId: id,
+		ClientIp:   ctx.ClientIP(),
+		RequestId:  logger.RequestID(ctx),
+		CreatedAt:  utils.FormatTime(),
+	}
+	if JWT, ok := ctx.Get(middleware.JwtClaims); ok {
+		entry.Actor = JWT.(*middleware.AuthCustomClaims).Username
+	}
+
+	changes, err := diff(before, after)
+	if err != nil {
+		changes, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	entry.Changes = changes
+	return entry
+}
+
+func (u *UsecaseModul) Record(ctx *gin.Context, entries ...*models.Audit) *internal.Error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return u.Repo.Create(ctx, entries)
+}
+
+func (u *UsecaseModul) Get(ctx *gin.Context, param *mdl.GetRequest) (mdl.ResponseData, *internal.Error) {
+	var res mdl.ResponseData
+	count, err := u.Repo.Count(ctx, param)
+	if err != nil {
+		return mdl.ResponseData{}, err
+	}
+	page := utils.GetPage(param.Page)
+	totalPage := utils.GetTotalPage(param.Limit, count)
+
+	if page > totalPage {
+		return mdl.ResponseData{}, internal.NewError(400, fmt.Errorf("page greater than totalPage"))
+	}
+
+	data, err := u.Repo.Get(ctx, param, page)
+	if err != nil {
+		return mdl.ResponseData{}, err
+	}
+
+	res.Data = data
+	res.Limit = param.Limit
+	res.Page = page
+	res.TotalPage = totalPage
+	return res, nil
+}
+
+// diff keeps only the fields that changed, as {"field": {"before": .., "after": ..}}.
+func diff(before interface{}, after interface{}) (json.RawMessage, error) {
+	b, err := toMap(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := toMap(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]map[string]interface{}{}
+	for name, value := range a {
+		if !reflect.DeepEqual(b[name], value) {
+			changes[name] = map[string]interface{}{"before": b[name], "after": value}
+		}
+	}
+	for name, value := range b {
+		if _, ok := a[name]; !ok {
+			changes[name] = map[string]interface{}{"before": value, "after": nil}
+		}
+	}
+	return json.Marshal(changes)
+}
+
+// toMap flattens v to its json members; nil, including a nil pointer, has none.
+func toMap(v interface{}) (map[string]interface{}, error) {
+	res := map[string]interface{}{}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
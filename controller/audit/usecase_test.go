@@ -0,0 +1,32 @@
+package audit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gin-dbo/framework/logger"
+	models "gin-dbo/model/audit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewEntryCarriesTheGeneratedRequestId(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	var entry *models.Audit
+	router := gin.New()
+	router.Use(logger.Middleware(log))
+	router.POST("/api/customer", func(c *gin.Context) {
+		entry = NewEntry(c, models.ActionCreate, "customer", "c1", nil, map[string]string{"name": "jane"})
+	})
+
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest(http.MethodPost, "/api/customer", nil))
+	if entry.RequestId == "" || entry.RequestId != res.Header().Get(logger.RequestIDHeader) {
+		t.Fatalf("got request id %q, want the one answered %q", entry.RequestId, res.Header().Get(logger.RequestIDHeader))
+	}
+}
@@ -1,9 +1,21 @@
 package controller
 
 import (
+	apikey "gin-dbo/controller/apikey"
+	audit "gin-dbo/controller/audit"
 	customer "gin-dbo/controller/customer"
+	healthController "gin-dbo/controller/health"
+	invoice "gin-dbo/controller/invoice"
 	login "gin-dbo/controller/login"
 	order "gin-dbo/controller/order"
+	segment "gin-dbo/controller/segment"
+	webhook "gin-dbo/controller/webhook"
+	"gin-dbo/framework/config"
+	"gin-dbo/framework/health"
+	"gin-dbo/framework/logger"
+	"gin-dbo/framework/metrics"
+	"gin-dbo/framework/middleware"
+	"gin-dbo/framework/tracing"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -17,21 +29,63 @@ type Controller struct {
 	Login    login.Usecase
 	Customer customer.Usecase
 	Order    order.Usecase
+	Invoice  invoice.Usecase
+	Audit    audit.Usecase
+	Webhook  webhook.Usecase
+	APIKey   apikey.Usecase
+	Segment  segment.Usecase
+
+	JWT         middleware.JWTService
+	Idempotency middleware.IdempotencyStore
+	RateLimit   middleware.RateLimitStore
+	APIKeys     middleware.APIKeyStore
+	Config      *config.Config
+	Health      *health.Health
 }
 
-func Router(usecase *Controller, logger *logrus.Logger) *gin.Engine {
+func Router(usecase *Controller, log *logrus.Logger) *gin.Engine {
 	router := gin.New()
+	// rate limits, lockouts and API key allowlists rely on the client IP,
+	// which only the configured proxies may forward
+	if err := router.SetTrustedProxies(usecase.Config.Server.TrustedProxies); err != nil {
+		log.Errorf("controller.Router : %v", err)
+	}
+	router.Use(tracing.Middleware(usecase.Config.Tracing.ServiceName))
+	router.Use(logger.Middleware(log))
+	router.Use(metrics.Middleware())
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"*"},
-		AllowHeaders:     []string{"Origin, X-Requested-With, Content-Type, Accept, Authorization, Access-Control-Allow-Headers, Accept-Encoding, X-CSRF-Token"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin, X-Requested-With, Content-Type, Accept, Authorization, Access-Control-Allow-Headers, Accept-Encoding, X-CSRF-Token, Idempotency-Key, If-Match, If-None-Match, X-Request-ID, traceparent, tracestate"},
+		ExposeHeaders:    []string{"Content-Length, Idempotency-Replayed, ETag, X-Request-ID, X-Trace-ID, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After"},
 		AllowCredentials: true,
 	}))
+	if limits := usecase.Config.RateLimit; limits.Enabled {
+		router.Use(middleware.RateLimit(usecase.RateLimit, []middleware.RateLimitRule{
+			{Name: "login", Route: "/api/login", Rate: limits.Login},
+			{Name: "login", Route: "/api/auth/", Rate: limits.Login},
+			{Name: "register", Route: "/api/register", Rate: limits.Register},
+			{Name: "password", Route: "/api/password/", Rate: limits.Password},
+			{Name: "password", Route: "/api/verify-email", Rate: limits.Password},
+			{Name: "password", Route: "/api/me/password", Rate: limits.Password},
+			{Name: "api", Route: "/api/", Rate: limits.API},
+		}, usecase.JWT))
+	}
+	router.Use(middleware.Idempotency(usecase.Idempotency, usecase.Config.Idempotency, usecase.JWT, []string{
+		"/api/login", "/api/auth/", "/api/register", "/api/password/", "/api/verify-email",
+	}))
+	auth := middleware.AuthorizeJWT(usecase.JWT, usecase.APIKeys)
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-	login.Router(router, usecase.Login, logger)
-	customer.Router(router, usecase.Customer, logger)
-	order.Router(router, usecase.Order, logger)
+	healthController.Router(router, usecase.Health)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	login.Router(router, usecase.Login, auth)
+	customer.Router(router, usecase.Customer, auth)
+	order.Router(router, usecase.Order, auth)
+	invoice.Router(router, usecase.Invoice, auth)
+	audit.Router(router, usecase.Audit, auth)
+	webhook.Router(router, usecase.Webhook, auth)
+	apikey.Router(router, usecase.APIKey, auth)
+	segment.Router(router, usecase.Segment, auth)
 	return router
 }
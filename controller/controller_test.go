@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gin-dbo/framework/config"
+	"gin-dbo/framework/health"
+	"gin-dbo/framework/middleware"
+	"gin-dbo/model/apikey"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// countingKeys knows a single key granted every scope and counts the
+// lookups.
+type countingKeys struct {
+	finds, touches int
+}
+
+func (k *countingKeys) Find(keyHash string) (*apikey.Key, error) {
+	k.finds++
+	return &apikey.Key{Id: "k1", Name: "billing", Scopes: apikey.Scopes}, nil
+}
+
+func (k *countingKeys) Touch(id string, ip string) error {
+	k.touches++
+	return nil
+}
+
+func testRouter(keys middleware.APIKeyStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	cfg := config.Default()
+	cfg.RateLimit.Enabled = false
+	return Router(&Controller{
+		JWT:     middleware.JWTAuthService(config.JWT{SecretKey: "secret"}),
+		APIKeys: keys,
+		Config:  cfg,
+		Health:  health.New(func() bool { return false }, time.Second),
+	}, log)
+}
+
+func TestRouterRefusesABearerWithoutToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/segments", nil)
+	req.Header.Set(middleware.Authorization, middleware.BEARER_SCHEMA)
+	res := httptest.NewRecorder()
+	testRouter(&countingKeys{}).ServeHTTP(res, req)
+	if res.Code != http.StatusUnauthorized {
+		t.Fatalf("got %d, want 401", res.Code)
+	}
+}
+
+func TestRouterAuthorizesOncePerRequest(t *testing.T) {
+	keys := &countingKeys{}
+	req := httptest.NewRequest(http.MethodPost, "/api/segments", strings.NewReader("not json"))
+	req.Header.Set(middleware.Authorization, middleware.BEARER_SCHEMA+" "+apikey.Prefix+"secret")
+	res := httptest.NewRecorder()
+	testRouter(keys).ServeHTTP(res, req)
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want the 400 of the handler", res.Code)
+	}
+	if keys.finds != 1 || keys.touches != 1 {
+		t.Fatalf("got %d lookups and %d touches, want one each", keys.finds, keys.touches)
+	}
+}
@@ -2,31 +2,42 @@ package customer
 
 import (
 	"fmt"
+	"gin-dbo/framework/export"
+	"gin-dbo/framework/importer"
+	"gin-dbo/framework/logger"
 	"gin-dbo/framework/middleware"
+	"gin-dbo/framework/patch"
 	"gin-dbo/framework/utils"
+	models "gin-dbo/model/customer"
 	mdl "gin-dbo/view/customer"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
 )
 
 type Handler struct {
 	Usecase Usecase
-	logger  *logrus.Logger
 }
 
 // @SecurityDefinitions jwt
-func Router(router *gin.Engine, uc Usecase, logger *logrus.Logger) {
-	u := Handler{Usecase: uc, logger: logger}
+func Router(router *gin.Engine, uc Usecase, auth func(*gin.Context)) {
+	u := Handler{Usecase: uc}
 
-	router.Use(middleware.AuthorizeJWT())
+	authorized := router.Group("/", auth)
 	{
-		router.GET("api/customer", u.GetHandler)
-		router.GET("api/customer/:id", u.GetByIdHandler)
-		router.POST("api/customer", u.CreateHandler)
-		router.PUT("api/customer/:id", u.UpdateHandler)
-		router.DELETE("api/customer/:id", u.DeleteHandler)
+		authorized.GET("api/customer", u.GetHandler)
+		authorized.GET("api/customer/export", u.ExportHandler)
+		authorized.GET("api/customer/duplicates", u.GetDuplicatesHandler)
+		authorized.GET("api/customer/:id", u.GetByIdHandler)
+		authorized.GET("api/me/customer", u.GetMineHandler)
+		authorized.POST("api/customer", u.CreateHandler)
+		authorized.POST("api/customer/import", u.ImportHandler)
+		authorized.PUT("api/customer/:id", u.UpdateHandler)
+		authorized.PATCH("api/customer/:id", u.PatchHandler)
+		authorized.DELETE("api/customer/:id", u.DeleteHandler)
+		authorized.POST("api/customer/:id/merge", u.MergeHandler)
+		authorized.GET("api/customer/:id/merges", u.GetMergesHandler)
 	}
 }
 
@@ -35,10 +46,13 @@ func Router(router *gin.Engine, uc Usecase, logger *logrus.Logger) {
 // @param limit query int false "limit"
 // @param page query string false "page"
 // @param keyword query string false "name of some customer"
+// @param tag query string false "tag of the customers"
+// @param segment query string false "id of a segment the customers belong to"
 // @Produce json
 // @Success 200 {object} mdl.ResponseData
 // @Failure 400 {object} mdl.Response400
 // @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.GeneralResponse
 // @Failure 500 {object} mdl.Response500
 // @Router /api/customer [get]
 func (u Handler) GetHandler(c *gin.Context) {
@@ -55,9 +69,11 @@ func (u Handler) GetHandler(c *gin.Context) {
 	}
 
 	param := &mdl.GetRequest{
-		Keyword: c.Query(utils.Keyword),
-		Limit:   limit,
-		Page:    page,
+		Keyword:   c.Query(utils.Keyword),
+		Tag:       strings.ToLower(c.Query(utils.Tag)),
+		SegmentId: c.Query(utils.Segment),
+		Limit:     limit,
+		Page:      page,
 	}
 	result, err := u.Usecase.Get(c, param)
 	if err == nil {
@@ -65,17 +81,103 @@ func (u Handler) GetHandler(c *gin.Context) {
 		result.Message = "success retrieve data"
 		c.JSON(http.StatusOK, result)
 	} else {
-		u.logger.Error(err)
+		logger.FromContext(c).Error(err)
 		result.Success = false
 		result.Message = err.Message.Error()
 		c.JSON(err.Code, result)
 	}
 }
 
+// @Summary Export Customers
+// @Description Export Customers as CSV or XLSX
+// @param format query string false "csv or xlsx, default csv"
+// @param columns query string false "comma separated columns to export, default all"
+// @param keyword query string false "name of some customer"
+// @param tag query string false "tag of the customers"
+// @param segment query string false "id of a segment the customers belong to"
+// @Produce text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security jwt
+// @Success 200 {string} string
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/customer/export [get]
+func (u Handler) ExportHandler(c *gin.Context) {
+	format := c.Query(export.Format)
+	if err := export.CheckFormat(format); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("customer.exportHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	columns, errn := export.SelectColumns(mdl.ExportColumns, c.Query(export.Columns))
+	if errn != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("customer.exportHandler.BadRequest : %v", errn.Error())})
+		return
+	}
+
+	param := &mdl.GetRequest{
+		Keyword:   c.Query(utils.Keyword),
+		Tag:       strings.ToLower(c.Query(utils.Tag)),
+		SegmentId: c.Query(utils.Segment),
+	}
+	logger.FromContext(c).Debugf("%+v", param)
+
+	// the response starts with the first rows, so a failure before them,
+	// such as an unknown segment, is still answered with its status
+	var writer export.Writer
+	start := func() error {
+		c.Header("Content-Type", export.ContentType(format))
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename("customers", format)))
+		c.Status(http.StatusOK)
+		w, err := export.NewWriter(format, c.Writer)
+		if err != nil {
+			return err
+		}
+		writer = w
+		return writer.Write(export.Header(columns))
+	}
+
+	err := u.Usecase.Export(c, param, func(rows []*models.Customer) error {
+		if writer == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		for _, row := range rows {
+			if err := writer.Write(export.Record(columns, row)); err != nil {
+				return err
+			}
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		logger.FromContext(c).Error(err)
+		if writer == nil {
+			c.JSON(err.Code, mdl.GeneralResponse{Success: false, Message: err.Message.Error()})
+		}
+		return
+	}
+	if writer == nil {
+		errn = start()
+	}
+	if errn == nil {
+		errn = writer.Close()
+	}
+	if errn != nil {
+		logger.FromContext(c).Error(errn)
+	}
+}
+
 // @Summary Get Customer By Id
-// @Description Customer By Id
+// @Description Customer By Id, the id of a merged customer resolving to the customer it was merged into
 // @Produce json
+// @Param If-None-Match header string false "etag of a cached copy"
 // @Success 200 {object} mdl.ResponseDetail
+// @Header 200 {string} ETag "version of the data"
+// @Header 200 {string} Content-Location "where the customer lives now, when it was merged into another"
+// @Success 304 "cached copy is still current"
 // @Failure 400 {object} mdl.Response400
 // @Failure 401 {object} middleware.Response
 // @Failure 500 {object} mdl.Response500
@@ -84,11 +186,59 @@ func (u Handler) GetByIdHandler(c *gin.Context) {
 	id := c.Param("id")
 	result, err := u.Usecase.GetById(c, id)
 	if err == nil {
+		if result.Data.Id != id {
+			c.Header("Content-Location", "/api/customer/"+result.Data.Id)
+		}
+		etag := utils.FormatETag(result.Data.Version)
+		c.Header(utils.ETag, etag)
+		if utils.MatchETag(c.GetHeader(utils.IfNoneMatch), etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+		result.Success = true
+		result.Message = "success retrieve data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
+// @Summary Get My Customer
+// @Description Get the customer of the caller
+// @Produce json
+// @Param If-None-Match header string false "etag of a cached copy"
+// @Security jwt
+// @Success 200 {object} mdl.ResponseDetail
+// @Header 200 {string} ETag "version of the data"
+// @Success 304 "cached copy is still current"
+// @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/me/customer [get]
+func (u Handler) GetMineHandler(c *gin.Context) {
+	var JWT, _ = c.Get(middleware.JwtClaims)
+	jwtClaims := JWT.(*middleware.AuthCustomClaims)
+	if jwtClaims.CustomerId == "" {
+		c.JSON(http.StatusNotFound, mdl.GeneralResponse{Success: false, Message: "customer.getMineHandler.NotFound : this user has no customer"})
+		return
+	}
+
+	result, err := u.Usecase.GetById(c, jwtClaims.CustomerId)
+	if err == nil {
+		etag := utils.FormatETag(result.Data.Version)
+		c.Header(utils.ETag, etag)
+		if utils.MatchETag(c.GetHeader(utils.IfNoneMatch), etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
 		result.Success = true
 		result.Message = "success retrieve data"
 		c.JSON(http.StatusOK, result)
 	} else {
-		u.logger.Error(err)
+		logger.FromContext(c).Error(err)
 		result.Success = false
 		result.Message = err.Message.Error()
 		c.JSON(err.Code, result)
@@ -113,7 +263,7 @@ func (u Handler) CreateHandler(c *gin.Context) {
 		return
 	}
 
-	u.logger.Debugf("%+v", param)
+	logger.FromContext(c).Debugf("%+v", param)
 	if err := utils.ValidateCreateCustomerRequest(param); err == nil {
 		result, err := u.Usecase.Create(c, param)
 		if err == nil {
@@ -121,7 +271,7 @@ func (u Handler) CreateHandler(c *gin.Context) {
 			result.Message = "success create data"
 			c.JSON(http.StatusOK, result)
 		} else {
-			u.logger.Error(err)
+			logger.FromContext(c).Error(err)
 			result.Success = false
 			result.Message = err.Message.Error()
 			c.JSON(err.Code, result)
@@ -131,15 +281,75 @@ func (u Handler) CreateHandler(c *gin.Context) {
 	}
 }
 
+// @Summary Import Customers
+// @Description Import Customers from a CSV whose header names the columns name and optionally email, phone and tags, the tags separated by commas
+// @Accept text/csv,multipart/form-data
+// @Produce json,text/csv
+// @Param file formData file false "CSV file when sent as multipart form"
+// @param dryRun query bool false "validate rows without creating them"
+// @param report query string false "csv to download the report as CSV"
+// @Security jwt
+// @Success 200 {object} mdl.ImportResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Router /api/customer/import [post]
+func (u Handler) ImportHandler(c *gin.Context) {
+	file, errn := importer.Open(c)
+	if errn != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("customer.importHandler.BadRequest : %v", errn.Error())})
+		return
+	}
+	defer file.Close()
+
+	param := &mdl.ImportRequest{DryRun: c.Query(importer.DryRun) == "true"}
+	errn = importer.ReadCSV(file, func(row int, record map[string]string) error {
+		request, err := mdl.ParseImportRecord(record)
+		param.Rows = append(param.Rows, &mdl.ImportRow{Row: row, Request: request, Error: err})
+		return nil
+	})
+	if errn != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("customer.importHandler.BadRequest : %v", errn.Error())})
+		return
+	}
+	logger.FromContext(c).Debugf("importing %d rows, dry run %v", len(param.Rows), param.DryRun)
+
+	result, err := u.Usecase.Import(c, param)
+	if err != nil {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+		return
+	}
+
+	if c.Query(importer.Report) == importer.ReportCSV {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "customer-import-report.csv"))
+		c.Status(http.StatusOK)
+		if errn = importer.WriteReport(c.Writer, result.Data); errn != nil {
+			logger.FromContext(c).Error(errn)
+		}
+		return
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("%d created, %d skipped, %d failed", result.Created, result.Skipped, result.Failed)
+	c.JSON(http.StatusOK, result)
+}
+
 // @Summary Update Customer
-// @Description Update Some Customer
+// @Description Update Some Customer, replacing every field with the request payload but the tags when left out; only admins can change tags
 // @Accept json
 // @Produce json
+// @Param If-Match header string false "etag returned by get by id, required when REQUIRE_IF_MATCH is enabled"
 // @Param request body mdl.UpdateRequest true "Sample Update request payload"
 // @Security jwt
 // @Success 200 {object} mdl.GeneralResponse
 // @Failure 400 {object} mdl.Response400
 // @Failure 401 {object} middleware.Response
+// @Failure 412 {object} mdl.GeneralResponse
+// @Failure 428 {object} mdl.GeneralResponse
 // @Failure 500 {object} mdl.Response500
 // @Router /api/customer/{id} [put]
 func (u Handler) UpdateHandler(c *gin.Context) {
@@ -156,7 +366,17 @@ func (u Handler) UpdateHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("customer.updateHandler.BadRequest : %v", fmt.Errorf("this user can't update this id %s", param.Id))})
 		return
 	}
-	u.logger.Debugf("%+v", param)
+	if jwtClaims.Role != "admin" && param.Tags != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("customer.updateHandler.BadRequest : %v", fmt.Errorf("this user can't change tags"))})
+		return
+	}
+	version, err := utils.GetIfMatch(c.GetHeader(utils.IfMatch))
+	if err != nil {
+		c.JSON(err.Code, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("customer.updateHandler.Precondition : %v", err.Message.Error())})
+		return
+	}
+	param.Version = version
+	logger.FromContext(c).Debugf("%+v", param)
 
 	if err := utils.ValidateUpdateCustomerRequest(param); err == nil {
 		result, err := u.Usecase.Update(c, param)
@@ -165,7 +385,7 @@ func (u Handler) UpdateHandler(c *gin.Context) {
 			result.Message = "success update data"
 			c.JSON(http.StatusOK, result)
 		} else {
-			u.logger.Error(err)
+			logger.FromContext(c).Error(err)
 			result.Success = false
 			result.Message = err.Message.Error()
 			c.JSON(err.Code, result)
@@ -175,21 +395,94 @@ func (u Handler) UpdateHandler(c *gin.Context) {
 	}
 }
 
+// @Summary Patch Customer
+// @Description Partially Update Some Customer with a JSON Merge Patch (RFC 7396)
+// @Accept application/merge-patch+json,json
+// @Produce json
+// @Param If-Match header string false "etag returned by get by id, required when REQUIRE_IF_MATCH is enabled"
+// @Param request body mdl.CreateRequest true "Sample Patch request payload, only the fields to change"
+// @Security jwt
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 412 {object} mdl.GeneralResponse
+// @Failure 415 {object} mdl.GeneralResponse
+// @Failure 428 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/customer/{id} [patch]
+func (u Handler) PatchHandler(c *gin.Context) {
+	var JWT, _ = c.Get(middleware.JwtClaims)
+	jwtClaims := JWT.(*middleware.AuthCustomClaims)
+	if contentType := c.ContentType(); contentType != patch.ContentType && contentType != gin.MIMEJSON {
+		c.JSON(http.StatusUnsupportedMediaType, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("customer.patchHandler.UnsupportedMediaType : expected %s", patch.ContentType)})
+		return
+	}
+
+	if jwtClaims.Role != "admin" && c.Param("id") != jwtClaims.CustomerId {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("customer.patchHandler.BadRequest : %v", fmt.Errorf("this user can't update this id %s", c.Param("id")))})
+		return
+	}
+
+	document, errn := c.GetRawData()
+	if errn == nil {
+		errn = utils.ValidatePatchCustomerFields(jwtClaims.Role, document)
+	}
+	if errn != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("customer.patchHandler.BadRequest : %v", errn.Error())})
+		return
+	}
+
+	version, err := utils.GetIfMatch(c.GetHeader(utils.IfMatch))
+	if err != nil {
+		c.JSON(err.Code, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("customer.patchHandler.Precondition : %v", err.Message.Error())})
+		return
+	}
+
+	param := &mdl.PatchRequest{
+		Id:      c.Param("id"),
+		Patch:   document,
+		Version: version,
+	}
+	logger.FromContext(c).Debugf("%s %s", param.Id, param.Patch)
+
+	result, err := u.Usecase.Patch(c, param)
+	if err == nil {
+		result.Success = true
+		result.Message = "success update data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
 // @Summary Delete Customer
 // @Description Delete Some Customer
 // @Accept json
 // @Produce json
+// @Param If-Match header string false "etag returned by get by id, required when REQUIRE_IF_MATCH is enabled"
 // @Security jwt
 // @Success 200 {object} mdl.GeneralResponse
 // @Failure 400 {object} mdl.Response400
 // @Failure 401 {object} middleware.Response
+// @Failure 412 {object} mdl.GeneralResponse
+// @Failure 428 {object} mdl.GeneralResponse
 // @Failure 500 {object} mdl.Response500
 // @Router /api/customer/{id} [delete]
 func (u Handler) DeleteHandler(c *gin.Context) {
+	version, err := utils.GetIfMatch(c.GetHeader(utils.IfMatch))
+	if err != nil {
+		c.JSON(err.Code, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("customer.deleteHandler.Precondition : %v", err.Message.Error())})
+		return
+	}
+
 	param := &mdl.DeleteRequest{
-		Id: c.Param("id"),
+		Id:      c.Param("id"),
+		Version: version,
 	}
-	u.logger.Debugf("%+v", param)
+	logger.FromContext(c).Debugf("%+v", param)
 
 	if err := utils.ValidateDeleteCustomerRequest(param); err == nil {
 		result, err := u.Usecase.Delete(c, param)
@@ -198,7 +491,7 @@ func (u Handler) DeleteHandler(c *gin.Context) {
 			result.Message = "success delete data"
 			c.JSON(http.StatusOK, result)
 		} else {
-			u.logger.Error(err)
+			logger.FromContext(c).Error(err)
 			result.Success = false
 			result.Message = err.Message.Error()
 			c.JSON(err.Code, result)
@@ -207,3 +500,132 @@ func (u Handler) DeleteHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("customer.deleteHandler.BadRequest : %v", err.Error())})
 	}
 }
+
+// @Summary Get Duplicate Customers
+// @Description Groups of customers likely to be the same, by names reading alike or the same email or phone, only admins can
+// @param limit query int false "limit"
+// @param page query string false "page"
+// @Produce json
+// @Security jwt
+// @Success 200 {object} mdl.ResponseDuplicates
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 403 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/customer/duplicates [get]
+func (u Handler) GetDuplicatesHandler(c *gin.Context) {
+	var JWT, _ = c.Get(middleware.JwtClaims)
+	jwtClaims := JWT.(*middleware.AuthCustomClaims)
+	if jwtClaims.Role != "admin" {
+		c.JSON(http.StatusForbidden, mdl.GeneralResponse{Success: false, Message: "customer.getDuplicatesHandler.Forbidden : only admins can look for duplicates"})
+		return
+	}
+
+	limit, err := utils.GetLimit(c.Query(utils.Limit))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("customer.getDuplicatesHandler.BadRequest : %v", err.Message.Error())})
+		return
+	}
+
+	page, err := utils.GetTargetPage(c.Query(utils.Page))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("customer.getDuplicatesHandler.BadRequest : %v", err.Message.Error())})
+		return
+	}
+
+	param := &mdl.GetRequest{
+		Limit: limit,
+		Page:  page,
+	}
+	result, err := u.Usecase.GetDuplicates(c, param)
+	if err == nil {
+		result.Success = true
+		result.Message = "success retrieve data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
+// @Summary Merge Customers
+// @Description Merge the source customers into this one in one transaction: their orders and users move here, blank email and phone are filled from them, their tags are added, and their ids keep resolving to this customer
+// @Accept json
+// @Produce json
+// @Param If-Match header string false "etag of this customer returned by get by id, required when REQUIRE_IF_MATCH is enabled"
+// @Param request body mdl.MergeRequest true "Sample Merge request payload"
+// @Security jwt
+// @Success 200 {object} mdl.ResponseMerge
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.GeneralResponse
+// @Failure 409 {object} mdl.GeneralResponse
+// @Failure 412 {object} mdl.GeneralResponse
+// @Failure 428 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/customer/{id}/merge [post]
+func (u Handler) MergeHandler(c *gin.Context) {
+	param := new(mdl.MergeRequest)
+	if err := c.BindJSON(param); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("customer.mergeHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	param.TargetId = c.Param("id")
+	version, err := utils.GetIfMatch(c.GetHeader(utils.IfMatch))
+	if err != nil {
+		c.JSON(err.Code, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("customer.mergeHandler.Precondition : %v", err.Message.Error())})
+		return
+	}
+	param.Version = version
+	logger.FromContext(c).Debugf("%+v", param)
+
+	if err := utils.ValidateMergeCustomerRequest(param); err == nil {
+		result, err := u.Usecase.Merge(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success merge data"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("customer.mergeHandler.BadRequest : %v", err.Error())})
+	}
+}
+
+// @Summary Get Customer Merges
+// @Description The history of the customers merged into this one, latest first, only admins can
+// @Produce json
+// @Security jwt
+// @Success 200 {object} mdl.ResponseMerges
+// @Failure 401 {object} middleware.Response
+// @Failure 403 {object} mdl.GeneralResponse
+// @Failure 404 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/customer/{id}/merges [get]
+func (u Handler) GetMergesHandler(c *gin.Context) {
+	var JWT, _ = c.Get(middleware.JwtClaims)
+	jwtClaims := JWT.(*middleware.AuthCustomClaims)
+	if jwtClaims.Role != "admin" {
+		c.JSON(http.StatusForbidden, mdl.GeneralResponse{Success: false, Message: "customer.getMergesHandler.Forbidden : only admins can read the merges"})
+		return
+	}
+
+	result, err := u.Usecase.GetMerges(c, c.Param("id"))
+	if err == nil {
+		result.Success = true
+		result.Message = "success retrieve data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
@@ -1,11 +1,20 @@
 package customer
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"gin-dbo/framework/export"
 	"gin-dbo/framework/utils"
 	models "gin-dbo/model/customer"
+	loginModel "gin-dbo/model/login"
+	orderModel "gin-dbo/model/order"
+	segmentModel "gin-dbo/model/segment"
 	view "gin-dbo/view/customer"
+	"strings"
+	"time"
 
+	"gin-dbo/framework/database"
 	internal "gin-dbo/framework/error"
 
 	"github.com/gin-gonic/gin"
@@ -20,25 +29,86 @@ type Repo struct {
 type Repository interface {
 	Get(ctx *gin.Context, request *view.GetRequest, page int) (res []*models.Customer, err *internal.Error)
 	Count(ctx *gin.Context, request *view.GetRequest) (res int, err *internal.Error)
+	Export(ctx *gin.Context, request *view.GetRequest, fn func([]*models.Customer) error) (err *internal.Error)
 	GetById(ctx *gin.Context, id string) (res *models.Customer, err *internal.Error)
 	Create(ctx *gin.Context, request *view.CreateRequest) (res string, err *internal.Error)
+	CreateBatch(ctx *gin.Context, request []*view.CreateRequest) (res []string, err *internal.Error)
 	Update(ctx *gin.Context, request *view.UpdateRequest) (err *internal.Error)
 	Delete(ctx *gin.Context, request *view.DeleteRequest) (err *internal.Error)
+	Resolve(ctx *gin.Context, id string) (res string, err *internal.Error)
+	GetCandidates(ctx *gin.Context) (res []*models.Customer, err *internal.Error)
+	MoveOrders(ctx *gin.Context, from []string, to string) (res int64, err *internal.Error)
+	MoveUsers(ctx *gin.Context, from []string, to string) (res int64, err *internal.Error)
+	Redirect(ctx *gin.Context, from []string, to string, mergeId string) (err *internal.Error)
+	CreateMerge(ctx *gin.Context, merge *models.Merge) (err *internal.Error)
+	GetMerges(ctx *gin.Context, targetId string) (res []*models.Merge, err *internal.Error)
+	GetSegment(ctx *gin.Context, id string) (res *segmentModel.Segment, err *internal.Error)
+	Transaction(ctx *gin.Context, fn func(repo Repository) *internal.Error) (err *internal.Error)
 }
 
 func NewRepository(dbconn *gorm.DB) Repository {
-	return &Repo{Dbconn: dbconn}
+	return tracedRepository{next: &Repo{Dbconn: dbconn}}
 }
 
-func (r Repo) Get(ctx *gin.Context, param *view.GetRequest, page int) ([]*models.Customer, *internal.Error) {
-	var (
-		res []*models.Customer
-	)
-	query := r.Dbconn
+// conn joins the transaction carried by ctx, if any.
+func (r Repo) conn(ctx *gin.Context) *gorm.DB {
+	return database.Conn(ctx, r.Dbconn)
+}
+
+// filter narrows customers down to the keyword, tag and segment of param.
+func filter(query *gorm.DB, param *view.GetRequest) *gorm.DB {
 	if param.Keyword != "" {
 		query = query.Where("name LIKE ?", "%"+param.Keyword+"%")
 	}
+	if param.Tag != "" {
+		query = query.Where("JSON_CONTAINS(customers.tags, JSON_QUOTE(?))", param.Tag)
+	}
+	if param.Segment != nil {
+		condition, args := segmentCondition(param.Segment)
+		query = query.Where(condition, args...)
+	}
+	return query
+}
+
+// segmentCondition turns the rules of a segment into a condition on
+// customers, the orders of each customer being counted by a subquery.
+func segmentCondition(segment *segmentModel.Segment) (string, []interface{}) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	for _, rule := range segment.Rules {
+		if rule.Field == segmentModel.FieldTag {
+			conditions = append(conditions, "JSON_CONTAINS(customers.tags, JSON_QUOTE(?))")
+			args = append(args, rule.Tag)
+			continue
+		}
 
+		aggregate := "COUNT(1)"
+		if rule.Field == segmentModel.FieldItems {
+			aggregate = "COALESCE(SUM(orders.qty), 0)"
+		}
+		subquery := "SELECT " + aggregate + " FROM orders WHERE orders.customer_id = customers.id"
+		if rule.Days > 0 {
+			subquery += " AND orders.created_at >= ?"
+			args = append(args, utils.FormatTimeAfter(-time.Duration(rule.Days)*24*time.Hour))
+		}
+		conditions = append(conditions, "("+subquery+") "+segmentModel.Operators[rule.Operator]+" ?")
+		args = append(args, rule.Value)
+	}
+
+	separator := " AND "
+	if segment.Match == segmentModel.MatchAny {
+		separator = " OR "
+	}
+	return "(" + strings.Join(conditions, separator) + ")", args
+}
+
+func (r Repo) Get(ctx *gin.Context, param *view.GetRequest, page int) ([]*models.Customer, *internal.Error) {
+	var (
+		res []*models.Customer
+	)
+	query := filter(r.conn(ctx), param)
 	if param.Page > 0 {
 		query = query.Offset((page - 1) * param.Limit)
 	}
@@ -57,10 +127,7 @@ func (r Repo) Count(ctx *gin.Context, param *view.GetRequest) (int, *internal.Er
 	var (
 		res int
 	)
-	query := r.Dbconn.Select("COUNT(1) as total").Model(&models.Customer{})
-	if param.Keyword != "" {
-		query = query.Where("name LIKE ?", "%"+param.Keyword+"%")
-	}
+	query := filter(r.conn(ctx).Select("COUNT(1) as total").Model(&models.Customer{}), param)
 
 	if err := query.Pluck("total", &res).Error; err != nil {
 		return 0, internal.NewError(500, fmt.Errorf("customer.repository.Count : %v", err.Error()))
@@ -68,18 +135,32 @@ func (r Repo) Count(ctx *gin.Context, param *view.GetRequest) (int, *internal.Er
 	return res, nil
 }
 
+func (r Repo) Export(ctx *gin.Context, param *view.GetRequest, fn func([]*models.Customer) error) *internal.Error {
+	var (
+		res []*models.Customer
+	)
+	query := filter(r.conn(ctx), param)
+	err := query.FindInBatches(&res, export.BatchSize, func(tx *gorm.DB, batch int) error {
+		return fn(res)
+	}).Error
+	if err != nil {
+		return internal.NewError(500, fmt.Errorf("customer.repository.Export : %v", err.Error()))
+	}
+	return nil
+}
+
 func (r Repo) GetById(ctx *gin.Context, id string) (*models.Customer, *internal.Error) {
 	var (
 		res *models.Customer
 		err error
 	)
-	query := r.Dbconn.Model(&models.Customer{}).Where("id = ?", id).Find(&res)
-	if err = query.Error; err != nil {
-		return nil, internal.NewError(500, fmt.Errorf("customer.repository.GetById : %v", err.Error()))
-	}
-	if res == nil {
+	query := r.conn(ctx).Model(&models.Customer{}).Where("id = ?", id).Take(&res)
+	if err = query.Error; errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, internal.NewError(404, fmt.Errorf("customer.repository.GetById : %v", fmt.Errorf("no data found with id %s", id)))
 	}
+	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("customer.repository.GetById : %v", err.Error()))
+	}
 	return res, nil
 }
 
@@ -88,25 +169,186 @@ func (r Repo) Create(ctx *gin.Context, param *view.CreateRequest) (string, *inte
 
 	uid := uuid.New().String()
 	now := utils.FormatTime()
-	query := r.Dbconn.Create(models.Customer{Id: uid, Name: param.Name, CreatedAt: now, UpdatedAt: now})
+	query := r.conn(ctx).Create(models.Customer{Id: uid, Name: param.Name, Email: param.Email, Phone: param.Phone, Tags: param.Tags, Version: 1, CreatedAt: now, UpdatedAt: now})
 	if err = query.Error; err != nil {
 		return "", internal.NewError(500, fmt.Errorf("customer.repository.Create : %v", err.Error()))
 	}
 	return uid, nil
 }
 
-func (r Repo) Update(ctx *gin.Context, param *view.UpdateRequest) *internal.Error {
-	err := r.Dbconn.Updates(models.Customer{Id: param.Id, Name: param.Name, UpdatedAt: utils.FormatTime()}).Error
+func (r Repo) CreateBatch(ctx *gin.Context, param []*view.CreateRequest) ([]string, *internal.Error) {
+	now := utils.FormatTime()
+	ids := make([]string, len(param))
+	data := make([]models.Customer, len(param))
+	for i, p := range param {
+		ids[i] = uuid.New().String()
+		data[i] = models.Customer{Id: ids[i], Name: p.Name, Email: p.Email, Phone: p.Phone, Tags: p.Tags, Version: 1, CreatedAt: now, UpdatedAt: now}
+	}
+
+	err := database.Transaction(ctx, r.Dbconn, func(tx *gorm.DB) error {
+		return tx.Create(&data).Error
+	})
 	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("customer.repository.CreateBatch : %v", err.Error()))
+	}
+	return ids, nil
+}
+
+func (r Repo) Update(ctx *gin.Context, param *view.UpdateRequest) *internal.Error {
+	query := r.conn(ctx).Model(&models.Customer{}).Where("id = ?", param.Id)
+	if param.Version > 0 {
+		query = query.Where("version = ?", param.Version)
+	}
+	values := map[string]interface{}{"name": param.Name, "email": param.Email, "phone": param.Phone, "version": gorm.Expr("version + 1"), "updated_at": utils.FormatTime()}
+	if param.Tags != nil {
+		// a map update bypasses the serializer of the column
+		tags, err := json.Marshal(*param.Tags)
+		if err != nil {
+			return internal.NewError(500, fmt.Errorf("customer.repository.Update : %v", err.Error()))
+		}
+		values["tags"] = string(tags)
+	}
+	query = query.Updates(values)
+	if err := query.Error; err != nil {
 		return internal.NewError(500, fmt.Errorf("customer.repository.Update : %v", err.Error()))
 	}
+	if query.RowsAffected == 0 {
+		return r.conflict(ctx, "customer.repository.Update", param.Id, param.Version)
+	}
 	return nil
 }
 
 func (r Repo) Delete(ctx *gin.Context, param *view.DeleteRequest) *internal.Error {
-	err := r.Dbconn.Delete(models.Customer{Id: param.Id}).Error
-	if err != nil {
+	query := r.conn(ctx).Where("id = ?", param.Id)
+	if param.Version > 0 {
+		query = query.Where("version = ?", param.Version)
+	}
+	query = query.Delete(&models.Customer{})
+	if err := query.Error; err != nil {
 		return internal.NewError(500, fmt.Errorf("customer.repository.Delete : %v", err.Error()))
 	}
+	if query.RowsAffected == 0 {
+		return r.conflict(ctx, "customer.repository.Delete", param.Id, param.Version)
+	}
+	return nil
+}
+
+// conflict explains why a write touched no row: either the customer is gone
+// or it was changed since the version the client read.
+func (r Repo) conflict(ctx *gin.Context, method string, id string, version int64) *internal.Error {
+	if _, err := r.GetById(ctx, id); err != nil {
+		return err
+	}
+	return internal.NewError(412, fmt.Errorf("%s : %v", method, fmt.Errorf("version %d of id %s is outdated", version, id)))
+}
+
+// Resolve follows the redirect left by a merge, returning the id of the
+// customer id was merged into, or id itself when it was not merged.
+func (r Repo) Resolve(ctx *gin.Context, id string) (string, *internal.Error) {
+	var res *models.Redirect
+	err := r.conn(ctx).Where("from_id = ?", id).Take(&res).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return id, nil
+	}
+	if err != nil {
+		return "", internal.NewError(500, fmt.Errorf("customer.repository.Resolve : %v", err.Error()))
+	}
+	return res.ToId, nil
+}
+
+// GetCandidates reads every customer, oldest first, to look for duplicates
+// among them.
+func (r Repo) GetCandidates(ctx *gin.Context) ([]*models.Customer, *internal.Error) {
+	var res []*models.Customer
+	query := r.conn(ctx).Order("created_at, id").Find(&res)
+	if err := query.Error; err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("customer.repository.GetCandidates : %v", err.Error()))
+	}
+	return res, nil
+}
+
+// MoveOrders hands the orders of the customers from over to the customer
+// to, returning how many moved.
+func (r Repo) MoveOrders(ctx *gin.Context, from []string, to string) (int64, *internal.Error) {
+	query := r.conn(ctx).Model(&orderModel.Order{}).Where("customer_id IN ?", from).Updates(map[string]interface{}{"customer_id": to, "version": gorm.Expr("version + 1"), "updated_at": utils.FormatTime()})
+	if err := query.Error; err != nil {
+		return 0, internal.NewError(500, fmt.Errorf("customer.repository.MoveOrders : %v", err.Error()))
+	}
+	return query.RowsAffected, nil
+}
+
+// MoveUsers links the users of the customers from to the customer to,
+// returning how many moved.
+func (r Repo) MoveUsers(ctx *gin.Context, from []string, to string) (int64, *internal.Error) {
+	query := r.conn(ctx).Model(&loginModel.User{}).Where("customer_id IN ?", from).Updates(map[string]interface{}{"customer_id": to, "version": gorm.Expr("version + 1"), "updated_at": utils.FormatTime()})
+	if err := query.Error; err != nil {
+		return 0, internal.NewError(500, fmt.Errorf("customer.repository.MoveUsers : %v", err.Error()))
+	}
+	return query.RowsAffected, nil
+}
+
+// Redirect points the ids from, and the ids already redirected to them, to
+// the customer to, so a redirect is always a single hop.
+func (r Repo) Redirect(ctx *gin.Context, from []string, to string, mergeId string) *internal.Error {
+	now := utils.FormatTime()
+	redirects := make([]*models.Redirect, len(from))
+	for i, id := range from {
+		redirects[i] = &models.Redirect{FromId: id, ToId: to, MergeId: mergeId, CreatedAt: now}
+	}
+	err := r.conn(ctx).Model(&models.Redirect{}).Where("to_id IN ?", from).Update("to_id", to).Error
+	if err == nil {
+		err = r.conn(ctx).Create(&redirects).Error
+	}
+	if err != nil {
+		return internal.NewError(500, fmt.Errorf("customer.repository.Redirect : %v", err.Error()))
+	}
+	return nil
+}
+
+func (r Repo) CreateMerge(ctx *gin.Context, merge *models.Merge) *internal.Error {
+	if err := r.conn(ctx).Create(merge).Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("customer.repository.CreateMerge : %v", err.Error()))
+	}
+	return nil
+}
+
+// GetMerges reads the merges into targetId, latest first.
+func (r Repo) GetMerges(ctx *gin.Context, targetId string) ([]*models.Merge, *internal.Error) {
+	res := []*models.Merge{}
+	if err := r.conn(ctx).Where("target_id = ?", targetId).Order("created_at desc").Find(&res).Error; err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("customer.repository.GetMerges : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func (r Repo) GetSegment(ctx *gin.Context, id string) (*segmentModel.Segment, *internal.Error) {
+	var res *segmentModel.Segment
+	err := r.conn(ctx).Where("id = ?", id).Take(&res).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, internal.NewError(404, fmt.Errorf("customer.repository.GetSegment : %v", fmt.Errorf("no segment found with id %s", id)))
+	}
+	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("customer.repository.GetSegment : %v", err.Error()))
+	}
+	return res, nil
+}
+
+// Transaction runs fn with a repository bound to a single database
+// transaction, which other repositories called with the same ctx join,
+// rolling everything back when fn returns an error.
+func (r Repo) Transaction(ctx *gin.Context, fn func(repo Repository) *internal.Error) *internal.Error {
+	var res *internal.Error
+	err := database.Transaction(ctx, r.Dbconn, func(tx *gorm.DB) error {
+		if res = fn(&Repo{Dbconn: tx}); res != nil {
+			return res.Message
+		}
+		return nil
+	})
+	if res != nil {
+		return res
+	}
+	if err != nil {
+		return internal.NewError(500, fmt.Errorf("customer.repository.Transaction : %v", err.Error()))
+	}
 	return nil
 }
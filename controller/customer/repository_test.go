@@ -0,0 +1,61 @@
+package customer
+
+import (
+	"testing"
+
+	"gin-dbo/framework/database/dbtest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var redirectColumns = []string{"from_id", "to_id", "merge_id"}
+
+func TestResolveNeverMergedCustomer(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `customer_redirects` WHERE from_id = \\? LIMIT 1").WithArgs("c1").
+		WillReturnRows(sqlmock.NewRows(redirectColumns))
+
+	id, err := NewRepository(db).Resolve(dbtest.Context(), "c1")
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	if id != "c1" {
+		t.Fatalf("got %q, want c1 itself", id)
+	}
+}
+
+func TestResolveMergedCustomer(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `customer_redirects` WHERE from_id = \\? LIMIT 1").WithArgs("c1").
+		WillReturnRows(sqlmock.NewRows(redirectColumns).AddRow("c1", "c2", "m1"))
+
+	id, err := NewRepository(db).Resolve(dbtest.Context(), "c1")
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	if id != "c2" {
+		t.Fatalf("got %q, want c2 it was merged into", id)
+	}
+}
+
+func TestGetByIdNotFound(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `customers` WHERE id = \\? LIMIT 1").WithArgs("c1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	res, err := NewRepository(db).GetById(dbtest.Context(), "c1")
+	if err == nil || err.Code != 404 {
+		t.Fatalf("got %+v, %v; want 404", res, err)
+	}
+}
+
+func TestGetSegmentNotFound(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `segments` WHERE id = \\? LIMIT 1").WithArgs("s1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	res, err := NewRepository(db).GetSegment(dbtest.Context(), "s1")
+	if err == nil || err.Code != 404 {
+		t.Fatalf("got %+v, %v; want 404", res, err)
+	}
+}
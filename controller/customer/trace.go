@@ -0,0 +1,163 @@
+package customer
+
+import (
+	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/tracing"
+	models "gin-dbo/model/customer"
+	segmentModel "gin-dbo/model/segment"
+	mdl "gin-dbo/view/customer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tracedRepository opens a span around every repository method.
+type tracedRepository struct {
+	next Repository
+}
+
+func (t tracedRepository) Get(ctx *gin.Context, request *mdl.GetRequest, page int) (res []*models.Customer, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.repository.Get").End(&err)
+	return t.next.Get(ctx, request, page)
+}
+
+func (t tracedRepository) Count(ctx *gin.Context, request *mdl.GetRequest) (res int, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.repository.Count").End(&err)
+	return t.next.Count(ctx, request)
+}
+
+func (t tracedRepository) Export(ctx *gin.Context, request *mdl.GetRequest, fn func([]*models.Customer) error) (err *internal.Error) {
+	defer tracing.Start(ctx, "customer.repository.Export").End(&err)
+	return t.next.Export(ctx, request, fn)
+}
+
+func (t tracedRepository) GetById(ctx *gin.Context, id string) (res *models.Customer, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.repository.GetById").End(&err)
+	return t.next.GetById(ctx, id)
+}
+
+func (t tracedRepository) Create(ctx *gin.Context, request *mdl.CreateRequest) (res string, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.repository.Create").End(&err)
+	return t.next.Create(ctx, request)
+}
+
+func (t tracedRepository) CreateBatch(ctx *gin.Context, request []*mdl.CreateRequest) (res []string, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.repository.CreateBatch").End(&err)
+	return t.next.CreateBatch(ctx, request)
+}
+
+func (t tracedRepository) Update(ctx *gin.Context, request *mdl.UpdateRequest) (err *internal.Error) {
+	defer tracing.Start(ctx, "customer.repository.Update").End(&err)
+	return t.next.Update(ctx, request)
+}
+
+func (t tracedRepository) Delete(ctx *gin.Context, request *mdl.DeleteRequest) (err *internal.Error) {
+	defer tracing.Start(ctx, "customer.repository.Delete").End(&err)
+	return t.next.Delete(ctx, request)
+}
+
+func (t tracedRepository) Resolve(ctx *gin.Context, id string) (res string, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.repository.Resolve").End(&err)
+	return t.next.Resolve(ctx, id)
+}
+
+func (t tracedRepository) GetCandidates(ctx *gin.Context) (res []*models.Customer, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.repository.GetCandidates").End(&err)
+	return t.next.GetCandidates(ctx)
+}
+
+func (t tracedRepository) MoveOrders(ctx *gin.Context, from []string, to string) (res int64, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.repository.MoveOrders").End(&err)
+	return t.next.MoveOrders(ctx, from, to)
+}
+
+func (t tracedRepository) MoveUsers(ctx *gin.Context, from []string, to string) (res int64, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.repository.MoveUsers").End(&err)
+	return t.next.MoveUsers(ctx, from, to)
+}
+
+func (t tracedRepository) Redirect(ctx *gin.Context, from []string, to string, mergeId string) (err *internal.Error) {
+	defer tracing.Start(ctx, "customer.repository.Redirect").End(&err)
+	return t.next.Redirect(ctx, from, to, mergeId)
+}
+
+func (t tracedRepository) CreateMerge(ctx *gin.Context, merge *models.Merge) (err *internal.Error) {
+	defer tracing.Start(ctx, "customer.repository.CreateMerge").End(&err)
+	return t.next.CreateMerge(ctx, merge)
+}
+
+func (t tracedRepository) GetMerges(ctx *gin.Context, targetId string) (res []*models.Merge, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.repository.GetMerges").End(&err)
+	return t.next.GetMerges(ctx, targetId)
+}
+
+func (t tracedRepository) GetSegment(ctx *gin.Context, id string) (res *segmentModel.Segment, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.repository.GetSegment").End(&err)
+	return t.next.GetSegment(ctx, id)
+}
+
+func (t tracedRepository) Transaction(ctx *gin.Context, fn func(repo Repository) *internal.Error) (err *internal.Error) {
+	defer tracing.Start(ctx, "customer.repository.Transaction").End(&err)
+	return t.next.Transaction(ctx, func(repo Repository) *internal.Error {
+		return fn(tracedRepository{next: repo})
+	})
+}
+
+// tracedUsecase opens a span around every usecase method.
+type tracedUsecase struct {
+	next Usecase
+}
+
+func (t tracedUsecase) Get(ctx *gin.Context, request *mdl.GetRequest) (res mdl.ResponseData, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.usecase.Get").End(&err)
+	return t.next.Get(ctx, request)
+}
+
+func (t tracedUsecase) Export(ctx *gin.Context, request *mdl.GetRequest, fn func([]*models.Customer) error) (err *internal.Error) {
+	defer tracing.Start(ctx, "customer.usecase.Export").End(&err)
+	return t.next.Export(ctx, request, fn)
+}
+
+func (t tracedUsecase) GetById(ctx *gin.Context, id string) (res mdl.ResponseDetail, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.usecase.GetById").End(&err)
+	return t.next.GetById(ctx, id)
+}
+
+func (t tracedUsecase) Create(ctx *gin.Context, request *mdl.CreateRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.usecase.Create").End(&err)
+	return t.next.Create(ctx, request)
+}
+
+func (t tracedUsecase) Import(ctx *gin.Context, request *mdl.ImportRequest) (res mdl.ImportResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.usecase.Import").End(&err)
+	return t.next.Import(ctx, request)
+}
+
+func (t tracedUsecase) Update(ctx *gin.Context, request *mdl.UpdateRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.usecase.Update").End(&err)
+	return t.next.Update(ctx, request)
+}
+
+func (t tracedUsecase) Patch(ctx *gin.Context, request *mdl.PatchRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.usecase.Patch").End(&err)
+	return t.next.Patch(ctx, request)
+}
+
+func (t tracedUsecase) Delete(ctx *gin.Context, request *mdl.DeleteRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.usecase.Delete").End(&err)
+	return t.next.Delete(ctx, request)
+}
+
+func (t tracedUsecase) GetDuplicates(ctx *gin.Context, request *mdl.GetRequest) (res mdl.ResponseDuplicates, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.usecase.GetDuplicates").End(&err)
+	return t.next.GetDuplicates(ctx, request)
+}
+
+func (t tracedUsecase) Merge(ctx *gin.Context, request *mdl.MergeRequest) (res mdl.ResponseMerge, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.usecase.Merge").End(&err)
+	return t.next.Merge(ctx, request)
+}
+
+func (t tracedUsecase) GetMerges(ctx *gin.Context, id string) (res mdl.ResponseMerges, err *internal.Error) {
+	defer tracing.Start(ctx, "customer.usecase.GetMerges").End(&err)
+	return t.next.GetMerges(ctx, id)
+}
@@ -1,33 +1,58 @@
 package customer
 
 import (
+	"encoding/json"
 	"fmt"
+	"gin-dbo/controller/audit"
+	"gin-dbo/framework/dedupe"
+	"gin-dbo/framework/event"
+	"gin-dbo/framework/importer"
+	"gin-dbo/framework/logger"
+	"gin-dbo/framework/middleware"
 	"gin-dbo/framework/utils"
+	auditModel "gin-dbo/model/audit"
+	models "gin-dbo/model/customer"
+	eventModel "gin-dbo/model/event"
 	mdl "gin-dbo/view/customer"
 
 	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/patch"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
+const resource = "customer"
+
 type UsecaseModul struct {
-	Repo Repository
+	Repo   Repository
+	Audit  audit.Recorder
+	Events event.Publisher
 }
 
 type Usecase interface {
 	Get(ctx *gin.Context, request *mdl.GetRequest) (res mdl.ResponseData, err *internal.Error)
+	Export(ctx *gin.Context, request *mdl.GetRequest, fn func([]*models.Customer) error) (err *internal.Error)
 	GetById(ctx *gin.Context, id string) (res mdl.ResponseDetail, err *internal.Error)
 	Create(ctx *gin.Context, request *mdl.CreateRequest) (res mdl.GeneralResponse, err *internal.Error)
+	Import(ctx *gin.Context, request *mdl.ImportRequest) (res mdl.ImportResponse, err *internal.Error)
 	Update(ctx *gin.Context, request *mdl.UpdateRequest) (res mdl.GeneralResponse, err *internal.Error)
+	Patch(ctx *gin.Context, request *mdl.PatchRequest) (res mdl.GeneralResponse, err *internal.Error)
 	Delete(ctx *gin.Context, request *mdl.DeleteRequest) (res mdl.GeneralResponse, err *internal.Error)
+	GetDuplicates(ctx *gin.Context, request *mdl.GetRequest) (res mdl.ResponseDuplicates, err *internal.Error)
+	Merge(ctx *gin.Context, request *mdl.MergeRequest) (res mdl.ResponseMerge, err *internal.Error)
+	GetMerges(ctx *gin.Context, id string) (res mdl.ResponseMerges, err *internal.Error)
 }
 
-func NewUsecase(u Repository) Usecase {
-	return &UsecaseModul{Repo: u}
+func NewUsecase(u Repository, a audit.Recorder, e event.Publisher) Usecase {
+	return tracedUsecase{next: &UsecaseModul{Repo: u, Audit: a, Events: e}}
 }
 
 func (u *UsecaseModul) Get(ctx *gin.Context, param *mdl.GetRequest) (mdl.ResponseData, *internal.Error) {
 	var res mdl.ResponseData
+	if err := u.segment(ctx, param); err != nil {
+		return res, err
+	}
 
 	count, err := u.Repo.Count(ctx, param)
 	if err != nil {
@@ -52,8 +77,33 @@ func (u *UsecaseModul) Get(ctx *gin.Context, param *mdl.GetRequest) (mdl.Respons
 	return res, nil
 }
 
+func (u *UsecaseModul) Export(ctx *gin.Context, param *mdl.GetRequest, fn func([]*models.Customer) error) *internal.Error {
+	if err := u.segment(ctx, param); err != nil {
+		return err
+	}
+	return u.Repo.Export(ctx, param, fn)
+}
+
+// segment reads the segment the customers are filtered by, if any.
+func (u *UsecaseModul) segment(ctx *gin.Context, param *mdl.GetRequest) *internal.Error {
+	if param.SegmentId == "" {
+		return nil
+	}
+	segment, err := u.Repo.GetSegment(ctx, param.SegmentId)
+	if err != nil {
+		return err
+	}
+	param.Segment = segment
+	return nil
+}
+
+// GetById reads a customer, or the customer it was merged into.
 func (u *UsecaseModul) GetById(ctx *gin.Context, id string) (mdl.ResponseDetail, *internal.Error) {
 	var res mdl.ResponseDetail
+	id, err := u.Repo.Resolve(ctx, id)
+	if err != nil {
+		return mdl.ResponseDetail{}, err
+	}
 	data, err := u.Repo.GetById(ctx, id)
 	if err != nil {
 		return mdl.ResponseDetail{}, err
@@ -64,28 +114,347 @@ func (u *UsecaseModul) GetById(ctx *gin.Context, id string) (mdl.ResponseDetail,
 
 func (u *UsecaseModul) Create(ctx *gin.Context, param *mdl.CreateRequest) (mdl.GeneralResponse, *internal.Error) {
 	var res mdl.GeneralResponse
-	id, err := u.Repo.Create(ctx, param)
-	if err != nil {
-		return res, err
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		id, err := repo.Create(ctx, param)
+		if err != nil {
+			return err
+		}
+		res.Id = id
+
+		after, err := repo.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err = u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionCreate, resource, id, nil, after)); err != nil {
+			return err
+		}
+		return u.Events.Publish(ctx, event.New(ctx, eventModel.CustomerCreated, resource, id, after))
+	})
+	return res, err
+}
+
+func (u *UsecaseModul) Import(ctx *gin.Context, param *mdl.ImportRequest) (mdl.ImportResponse, *internal.Error) {
+	var (
+		res     = mdl.ImportResponse{DryRun: param.DryRun}
+		valid   []*mdl.CreateRequest
+		results []*importer.Result
+	)
+
+	for _, row := range param.Rows {
+		result := &importer.Result{Row: row.Row, Status: importer.StatusError}
+		res.Data = append(res.Data, result)
+		if row.Error != nil {
+			result.Reason = row.Error.Error()
+		} else if row.Request == nil {
+			result.Status, result.Reason = importer.StatusSkipped, "empty row"
+		} else if err := utils.ValidateCreateCustomerRequest(row.Request); err != nil {
+			result.Reason = err.Error()
+		} else if param.DryRun {
+			result.Status, result.Reason = importer.StatusSkipped, "dry run"
+		} else {
+			valid = append(valid, row.Request)
+			results = append(results, result)
+		}
 	}
-	res.Id = id
+
+	for start := 0; start < len(valid); start += importer.BatchSize {
+		end := start + importer.BatchSize
+		if end > len(valid) {
+			end = len(valid)
+		}
+		var ids []string
+		err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+			var (
+				entries []*auditModel.Audit
+				events  []*eventModel.Event
+				err     *internal.Error
+			)
+			if ids, err = repo.CreateBatch(ctx, valid[start:end]); err != nil {
+				return err
+			}
+			for i, id := range ids {
+				request := valid[start+i]
+				after := &models.Customer{Id: id, Name: request.Name, Email: request.Email, Phone: request.Phone, Tags: request.Tags}
+				entries = append(entries, audit.NewEntry(ctx, auditModel.ActionCreate, resource, id, nil, after))
+				events = append(events, event.New(ctx, eventModel.CustomerCreated, resource, id, after))
+			}
+			if err = u.Audit.Record(ctx, entries...); err != nil {
+				return err
+			}
+			return u.Events.Publish(ctx, events...)
+		})
+		for i, result := range results[start:end] {
+			if err != nil {
+				result.Reason = err.Message.Error()
+			} else {
+				result.Status, result.Id = importer.StatusCreated, ids[i]
+			}
+		}
+	}
+
+	for _, result := range res.Data {
+		switch result.Status {
+		case importer.StatusCreated:
+			res.Created++
+		case importer.StatusSkipped:
+			res.Skipped++
+		default:
+			res.Failed++
+		}
+	}
+	logger.FromContext(ctx).Infof("customer.usecase.Import : created %d, skipped %d, failed %d", res.Created, res.Skipped, res.Failed)
 	return res, nil
 }
 
 func (u *UsecaseModul) Update(ctx *gin.Context, param *mdl.UpdateRequest) (mdl.GeneralResponse, *internal.Error) {
 	var res mdl.GeneralResponse
-	err := u.Repo.Update(ctx, param)
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		id, err := repo.Resolve(ctx, param.Id)
+		if err != nil {
+			return err
+		}
+		param.Id = id
+		before, err := repo.GetById(ctx, param.Id)
+		if err != nil {
+			return err
+		}
+		if err = repo.Update(ctx, param); err != nil {
+			return err
+		}
+
+		after, err := repo.GetById(ctx, param.Id)
+		if err != nil {
+			return err
+		}
+		if err = u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionUpdate, resource, param.Id, before, after)); err != nil {
+			return err
+		}
+		return u.Events.Publish(ctx, event.New(ctx, eventModel.CustomerUpdated, resource, param.Id, after))
+	})
+	return res, err
+}
+
+func (u *UsecaseModul) Patch(ctx *gin.Context, param *mdl.PatchRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	id, err := u.Repo.Resolve(ctx, param.Id)
 	if err != nil {
 		return res, err
 	}
-	return res, nil
+	data, err := u.Repo.GetById(ctx, id)
+	if err != nil {
+		return res, err
+	}
+
+	current, errn := json.Marshal(&mdl.UpdateRequest{Id: data.Id, Name: data.Name, Email: data.Email, Phone: data.Phone, Tags: &data.Tags})
+	if errn == nil {
+		current, errn = patch.Merge(current, param.Patch)
+	}
+	request := new(mdl.UpdateRequest)
+	if errn == nil {
+		errn = json.Unmarshal(current, request)
+	}
+	if errn != nil {
+		return res, internal.NewError(400, fmt.Errorf("customer.usecase.Patch : %v", errn))
+	}
+
+	request.Id = data.Id
+	request.Version = param.Version
+	if request.Version == 0 {
+		request.Version = data.Version
+	}
+	if errn = utils.ValidateUpdateCustomerRequest(request); errn != nil {
+		return res, internal.NewError(400, fmt.Errorf("customer.usecase.Patch : %v", errn))
+	}
+	return u.Update(ctx, request)
 }
 
 func (u *UsecaseModul) Delete(ctx *gin.Context, param *mdl.DeleteRequest) (mdl.GeneralResponse, *internal.Error) {
 	var res mdl.GeneralResponse
-	err := u.Repo.Delete(ctx, param)
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		id, err := repo.Resolve(ctx, param.Id)
+		if err != nil {
+			return err
+		}
+		param.Id = id
+		before, err := repo.GetById(ctx, param.Id)
+		if err != nil {
+			return err
+		}
+		if err = repo.Delete(ctx, param); err != nil {
+			return err
+		}
+		if err = u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionDelete, resource, param.Id, before, nil)); err != nil {
+			return err
+		}
+		return u.Events.Publish(ctx, event.New(ctx, eventModel.CustomerDeleted, resource, param.Id, before))
+	})
+	return res, err
+}
+
+// GetDuplicates groups the customers likely to be the same: names reading
+// alike, or the same email or phone. Every customer is compared, so it is
+// meant for an occasional clean up rather than every request.
+func (u *UsecaseModul) GetDuplicates(ctx *gin.Context, param *mdl.GetRequest) (mdl.ResponseDuplicates, *internal.Error) {
+	var res mdl.ResponseDuplicates
+	customers, err := u.Repo.GetCandidates(ctx)
 	if err != nil {
 		return res, err
 	}
+	records := make([]dedupe.Record, len(customers))
+	byId := make(map[string]*models.Customer, len(customers))
+	for i, c := range customers {
+		records[i] = dedupe.Record{Id: c.Id, Name: c.Name, Email: c.Email, Phone: c.Phone}
+		byId[c.Id] = c
+	}
+	groups := dedupe.Find(records)
+
+	page := utils.GetPage(param.Page)
+	totalPage := utils.GetTotalPage(param.Limit, len(groups))
+	if page > totalPage {
+		return res, internal.NewError(400, fmt.Errorf("page greater than totalPage"))
+	}
+	if param.Limit > 0 {
+		start := (page - 1) * param.Limit
+		end := start + param.Limit
+		if start > len(groups) {
+			start = len(groups)
+		}
+		if end > len(groups) {
+			end = len(groups)
+		}
+		groups = groups[start:end]
+	}
+
+	res.Data = make([]*mdl.DuplicateGroup, len(groups))
+	for i, group := range groups {
+		res.Data[i] = &mdl.DuplicateGroup{Reasons: group.Reasons}
+		for _, id := range group.Ids {
+			res.Data[i].Customers = append(res.Data[i].Customers, byId[id])
+		}
+	}
+	res.Limit = param.Limit
+	res.Page = page
+	res.TotalPage = totalPage
 	return res, nil
 }
+
+// Merge folds the source customers into the target in one transaction:
+// their orders and users move to the target, blank contacts of the target
+// are filled from them, and their ids keep resolving to the target. The
+// merge is kept in the history of the target.
+func (u *UsecaseModul) Merge(ctx *gin.Context, param *mdl.MergeRequest) (mdl.ResponseMerge, *internal.Error) {
+	var res mdl.ResponseMerge
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		targetId, err := repo.Resolve(ctx, param.TargetId)
+		if err != nil {
+			return err
+		}
+		before, err := repo.GetById(ctx, targetId)
+		if err != nil {
+			return err
+		}
+
+		tags := append([]string{}, before.Tags...)
+		update := &mdl.UpdateRequest{Id: targetId, Name: before.Name, Email: before.Email, Phone: before.Phone, Tags: &tags, Version: param.Version}
+		sources := make([]*models.Customer, len(param.SourceIds))
+		for i, id := range param.SourceIds {
+			if sources[i], err = repo.GetById(ctx, id); err != nil {
+				if err.Code != 404 {
+					return err
+				}
+				if into, errr := repo.Resolve(ctx, id); errr == nil && into != id {
+					return internal.NewError(409, fmt.Errorf("customer.usecase.Merge : customer %s was already merged into %s", id, into))
+				}
+				return err
+			}
+			if id == targetId {
+				return internal.NewError(400, fmt.Errorf("customer.usecase.Merge : customer %s cannot be merged into itself", id))
+			}
+			if update.Email == "" {
+				update.Email = sources[i].Email
+			}
+			if update.Phone == "" {
+				update.Phone = sources[i].Phone
+			}
+			for _, tag := range sources[i].Tags {
+				if !contains(tags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+		}
+
+		// the target changes even without contacts or tags to take over, so
+		// cached copies and concurrent merges into it see the merge
+		if err = repo.Update(ctx, update); err != nil {
+			return err
+		}
+		merge := &models.Merge{
+			Id:        uuid.New().String(),
+			TargetId:  targetId,
+			SourceIds: param.SourceIds,
+			Sources:   sources,
+			CreatedAt: utils.FormatTime(),
+		}
+		if JWT, ok := ctx.Get(middleware.JwtClaims); ok {
+			merge.MergedBy = JWT.(*middleware.AuthCustomClaims).Username
+		}
+		if merge.Orders, err = repo.MoveOrders(ctx, param.SourceIds, targetId); err != nil {
+			return err
+		}
+		if merge.Users, err = repo.MoveUsers(ctx, param.SourceIds, targetId); err != nil {
+			return err
+		}
+		for _, id := range param.SourceIds {
+			if err = repo.Delete(ctx, &mdl.DeleteRequest{Id: id}); err != nil {
+				return err
+			}
+		}
+		if err = repo.Redirect(ctx, param.SourceIds, targetId, merge.Id); err != nil {
+			return err
+		}
+		if err = repo.CreateMerge(ctx, merge); err != nil {
+			return err
+		}
+
+		after, err := repo.GetById(ctx, targetId)
+		if err != nil {
+			return err
+		}
+		entries := []*auditModel.Audit{audit.NewEntry(ctx, auditModel.ActionMerge, resource, targetId, before, after)}
+		for _, source := range sources {
+			entries = append(entries, audit.NewEntry(ctx, auditModel.ActionMerge, resource, source.Id, source, after))
+		}
+		if err = u.Audit.Record(ctx, entries...); err != nil {
+			return err
+		}
+		res.Data = merge
+		return u.Events.Publish(ctx, event.New(ctx, eventModel.CustomerMerged, resource, targetId, merge))
+	})
+	if err == nil {
+		logger.FromContext(ctx).Infof("customer.usecase.Merge : merged %v into %s, moving %d orders and %d users", param.SourceIds, res.Data.TargetId, res.Data.Orders, res.Data.Users)
+	}
+	return res, err
+}
+
+// GetMerges reads the history of the merges into a customer.
+func (u *UsecaseModul) GetMerges(ctx *gin.Context, id string) (mdl.ResponseMerges, *internal.Error) {
+	var res mdl.ResponseMerges
+	id, err := u.Repo.Resolve(ctx, id)
+	if err != nil {
+		return res, err
+	}
+	if _, err = u.Repo.GetById(ctx, id); err != nil {
+		return res, err
+	}
+	res.Data, err = u.Repo.GetMerges(ctx, id)
+	return res, err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package customer
+
+import (
+	"testing"
+
+	"gin-dbo/framework/database/dbtest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetByIdOfNeverMergedCustomer(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `customer_redirects` WHERE from_id = \\?").WithArgs("c1").
+		WillReturnRows(sqlmock.NewRows(redirectColumns))
+	mock.ExpectQuery("SELECT \\* FROM `customers` WHERE id = \\?").WithArgs("c1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "version"}).AddRow("c1", "jane", 1))
+
+	res, err := NewUsecase(NewRepository(db), nil, nil).GetById(dbtest.Context(), "c1")
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	if res.Data.Id != "c1" || res.Data.Name != "jane" {
+		t.Fatalf("got customer %+v, want c1", res.Data)
+	}
+}
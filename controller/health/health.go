@@ -0,0 +1,63 @@
+package health
+
+import (
+	"gin-dbo/framework/health"
+	"gin-dbo/framework/logger"
+	mdl "gin-dbo/view/health"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	Health *health.Health
+}
+
+// Router registers the probes before any router installs the JWT
+// middleware, so orchestrators can call them without a token.
+func Router(router *gin.Engine, h *health.Health) {
+	u := Handler{Health: h}
+	router.GET("/healthz", u.HealthHandler)
+	router.GET("/readyz", u.ReadyHandler)
+	router.GET("/version", u.VersionHandler)
+}
+
+// @Summary Liveness
+// @Description Liveness probe, succeeds as long as the process serves requests
+// @Produce json
+// @Success 200 {object} mdl.ResponseHealth
+// @Router /healthz [get]
+func (u Handler) HealthHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, mdl.ResponseHealth{Status: health.StatusUp})
+}
+
+// @Summary Readiness
+// @Description Readiness probe, pings the database and every registered dependency and fails while the service shuts down
+// @Produce json
+// @Success 200 {object} mdl.ResponseReady
+// @Failure 503 {object} mdl.ResponseReady
+// @Router /readyz [get]
+func (u Handler) ReadyHandler(c *gin.Context) {
+	status, checks := u.Health.Ready(c.Request.Context())
+	result := mdl.ResponseReady{Status: status, Checks: checks}
+	if status != health.StatusUp {
+		logger.FromContext(c).Warnf("health.readyHandler : %s", status)
+		for _, check := range checks {
+			if check.Status != health.StatusUp {
+				logger.FromContext(c).Warnf("health.readyHandler : %s is %s : %s", check.Name, check.Status, check.Error)
+			}
+		}
+		c.JSON(http.StatusServiceUnavailable, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary Version
+// @Description Build version, commit and start time of the running instance
+// @Produce json
+// @Success 200 {object} mdl.ResponseVersion
+// @Router /version [get]
+func (u Handler) VersionHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, mdl.ResponseVersion{Success: true, Message: "success retrieve data", Data: u.Health.Build()})
+}
@@ -0,0 +1,75 @@
+package invoice
+
+import (
+	"fmt"
+	"gin-dbo/framework/logger"
+	"gin-dbo/framework/pdf"
+	mdl "gin-dbo/view/invoice"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	MimeHTML = "text/html"
+	MimePDF  = "application/pdf"
+)
+
+type Handler struct {
+	Usecase Usecase
+}
+
+// @SecurityDefinitions jwt
+func Router(router *gin.Engine, uc Usecase, auth func(*gin.Context)) {
+	u := Handler{Usecase: uc}
+	authorized := router.Group("/", auth)
+	{
+		authorized.GET("api/order/:id/invoice", u.GetHandler)
+	}
+}
+
+// @Summary Get Order Invoice
+// @Description Get Invoice of Some Order as HTML or PDF depending on the Accept header
+// @Produce html,application/pdf
+// @Security jwt
+// @Success 200 {string} string
+// @Failure 401 {object} middleware.Response
+// @Failure 403 {object} mdl.GeneralResponse
+// @Failure 404 {object} mdl.GeneralResponse
+// @Failure 406 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/order/{id}/invoice [get]
+func (u Handler) GetHandler(c *gin.Context) {
+	format := c.NegotiateFormat(MimeHTML, MimePDF)
+	if format == "" {
+		c.JSON(http.StatusNotAcceptable, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("invoice.getHandler.NotAcceptable : supported formats are %s and %s", MimeHTML, MimePDF)})
+		return
+	}
+
+	result, err := u.Usecase.GetByOrderId(c, c.Param("id"))
+	if err != nil {
+		logger.FromContext(c).Error(err)
+		c.JSON(err.Code, mdl.GeneralResponse{Success: false, Message: err.Message.Error()})
+		return
+	}
+
+	if format == MimePDF {
+		text, errn := mdl.RenderText(result)
+		if errn != nil {
+			logger.FromContext(c).Error(errn)
+			c.JSON(http.StatusInternalServerError, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("invoice.getHandler.InternalServerError : %v", errn)})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", result.Number+".pdf"))
+		c.Data(http.StatusOK, MimePDF, pdf.FromText(text))
+		return
+	}
+
+	html, errn := mdl.RenderHTML(result)
+	if errn != nil {
+		logger.FromContext(c).Error(errn)
+		c.JSON(http.StatusInternalServerError, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("invoice.getHandler.InternalServerError : %v", errn)})
+		return
+	}
+	c.Data(http.StatusOK, MimeHTML+"; charset=utf-8", html)
+}
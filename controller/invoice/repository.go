@@ -0,0 +1,87 @@
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"gin-dbo/framework/utils"
+	models "gin-dbo/model/invoice"
+
+	"gin-dbo/framework/database"
+	internal "gin-dbo/framework/error"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const sequenceName = "invoice"
+
+type Repo struct {
+	Dbconn *gorm.DB
+}
+
+type Repository interface {
+	GetOrCreate(ctx *gin.Context, orderId string) (res *models.Invoice, err *internal.Error)
+}
+
+func NewRepository(dbconn *gorm.DB) Repository {
+	return tracedRepository{next: &Repo{Dbconn: dbconn}}
+}
+
+// GetOrCreate returns the invoice of an order, issuing it with the next
+// number when it does not exist yet. The sequence row is locked for the
+// lifetime of the transaction so numbers stay gap-free.
+func (r Repo) GetOrCreate(ctx *gin.Context, orderId string) (*models.Invoice, *internal.Error) {
+	var res *models.Invoice
+	err := database.Transaction(ctx, r.Dbconn, func(tx *gorm.DB) error {
+		var err error
+		if res, err = issued(tx, orderId); err != nil {
+			return err
+		}
+		if res != nil {
+			return nil
+		}
+
+		if err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Sequence{Name: sequenceName}).Error; err != nil {
+			return err
+		}
+		var seq models.Sequence
+		if err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", sequenceName).First(&seq).Error; err != nil {
+			return err
+		}
+
+		// another request may have issued the invoice while we waited for the lock
+		if res, err = issued(tx, orderId); err != nil {
+			return err
+		}
+		if res != nil {
+			return nil
+		}
+
+		seq.Value++
+		if err = tx.Model(&seq).Update("value", seq.Value).Error; err != nil {
+			return err
+		}
+		res = &models.Invoice{Id: uuid.New().String(), Number: seq.Value, OrderId: orderId, CreatedAt: utils.FormatTime()}
+		return tx.Create(res).Error
+	})
+	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("invoice.repository.GetOrCreate : %v", err.Error()))
+	}
+	return res, nil
+}
+
+// issued returns the invoice already issued for an order, nil when there is
+// none yet.
+func issued(tx *gorm.DB, orderId string) (*models.Invoice, error) {
+	var res models.Invoice
+	err := tx.Where("order_id = ?", orderId).Take(&res).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
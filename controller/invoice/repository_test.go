@@ -0,0 +1,49 @@
+package invoice
+
+import (
+	"testing"
+
+	"gin-dbo/framework/database/dbtest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetOrCreateReturnsIssuedInvoice(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `invoices` WHERE order_id = \\?").WithArgs("o1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "number", "order_id"}).AddRow("i1", 7, "o1"))
+	mock.ExpectCommit()
+
+	res, err := NewRepository(db).GetOrCreate(dbtest.Context(), "o1")
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	if res.Id != "i1" || res.Number != 7 {
+		t.Fatalf("got invoice %+v, want i1 numbered 7", res)
+	}
+}
+
+func TestGetOrCreateIssuesNextNumber(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `invoices` WHERE order_id = \\?").WithArgs("o1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "number", "order_id"}))
+	mock.ExpectExec("INSERT INTO `invoice_sequences`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT \\* FROM `invoice_sequences` WHERE name = \\? .* FOR UPDATE").WithArgs(sequenceName).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "value"}).AddRow(sequenceName, 41))
+	mock.ExpectQuery("SELECT \\* FROM `invoices` WHERE order_id = \\?").WithArgs("o1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "number", "order_id"}))
+	mock.ExpectExec("UPDATE `invoice_sequences` SET `value`=\\?").WithArgs(42, sequenceName).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `invoices`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	res, err := NewRepository(db).GetOrCreate(dbtest.Context(), "o1")
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	if res.Number != 42 || res.OrderId != "o1" || res.Id == "" {
+		t.Fatalf("got invoice %+v, want a new one numbered 42", res)
+	}
+}
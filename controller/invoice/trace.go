@@ -0,0 +1,30 @@
+package invoice
+
+import (
+	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/tracing"
+	models "gin-dbo/model/invoice"
+	mdl "gin-dbo/view/invoice"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tracedRepository opens a span around every repository method.
+type tracedRepository struct {
+	next Repository
+}
+
+func (t tracedRepository) GetOrCreate(ctx *gin.Context, orderId string) (res *models.Invoice, err *internal.Error) {
+	defer tracing.Start(ctx, "invoice.repository.GetOrCreate").End(&err)
+	return t.next.GetOrCreate(ctx, orderId)
+}
+
+// tracedUsecase opens a span around every usecase method.
+type tracedUsecase struct {
+	next Usecase
+}
+
+func (t tracedUsecase) GetByOrderId(ctx *gin.Context, orderId string) (res *mdl.Invoice, err *internal.Error) {
+	defer tracing.Start(ctx, "invoice.usecase.GetByOrderId").End(&err)
+	return t.next.GetByOrderId(ctx, orderId)
+}
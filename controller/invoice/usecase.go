@@ -0,0 +1,63 @@
+package invoice
+
+import (
+	"fmt"
+	mdl "gin-dbo/view/invoice"
+
+	"github.com/gin-gonic/gin"
+
+	"gin-dbo/controller/customer"
+	"gin-dbo/controller/order"
+	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/middleware"
+)
+
+type UsecaseModul struct {
+	Repo         Repository
+	OrderRepo    order.Repository
+	CustomerRepo customer.Repository
+}
+
+type Usecase interface {
+	GetByOrderId(ctx *gin.Context, orderId string) (res *mdl.Invoice, err *internal.Error)
+}
+
+func NewUsecase(u Repository, o order.Repository, c customer.Repository) Usecase {
+	return tracedUsecase{next: &UsecaseModul{Repo: u, OrderRepo: o, CustomerRepo: c}}
+}
+
+func (u *UsecaseModul) GetByOrderId(ctx *gin.Context, orderId string) (*mdl.Invoice, *internal.Error) {
+	orderData, err := u.OrderRepo.GetById(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	var JWT, _ = ctx.Get(middleware.JwtClaims)
+	if jwtClaims, ok := JWT.(*middleware.AuthCustomClaims); ok && jwtClaims.Role != "admin" {
+		// tokens issued before a merge still carry the merged customer
+		customerId, err := u.CustomerRepo.Resolve(ctx, jwtClaims.CustomerId)
+		if err != nil {
+			return nil, err
+		}
+		if orderData.CustomerId != customerId {
+			return nil, internal.NewError(403, fmt.Errorf("this user can't access invoice of order %s", orderId))
+		}
+	}
+
+	customerData, err := u.CustomerRepo.GetById(ctx, orderData.CustomerId)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := u.Repo.GetOrCreate(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mdl.Invoice{
+		Number:   fmt.Sprintf("INV-%06d", data.Number),
+		Invoice:  data,
+		Customer: customerData,
+		Order:    orderData,
+	}, nil
+}
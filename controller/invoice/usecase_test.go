@@ -0,0 +1,63 @@
+package invoice
+
+import (
+	"testing"
+
+	"gin-dbo/controller/customer"
+	"gin-dbo/controller/order"
+	"gin-dbo/framework/database/dbtest"
+	"gin-dbo/framework/middleware"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func newUsecase(db *gorm.DB) *UsecaseModul {
+	return &UsecaseModul{Repo: NewRepository(db), OrderRepo: order.NewRepository(db), CustomerRepo: customer.NewRepository(db)}
+}
+
+func customerContext(customerId string) *gin.Context {
+	ctx := dbtest.Context()
+	ctx.Set(middleware.JwtClaims, &middleware.AuthCustomClaims{Username: "jane", Role: "customer", CustomerId: customerId})
+	return ctx
+}
+
+// expectOrder expects order o1 of customerId to be read.
+func expectOrder(mock sqlmock.Sqlmock, customerId string) {
+	mock.ExpectQuery("SELECT \\* FROM `orders` WHERE id = \\?").WithArgs("o1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "customer_id", "name", "qty", "version"}).AddRow("o1", customerId, "book", 2, 1))
+}
+
+func TestGetByOrderIdOfAMergedCustomer(t *testing.T) {
+	db, mock := dbtest.New(t)
+	expectOrder(mock, "c2")
+	mock.ExpectQuery("SELECT \\* FROM `customer_redirects` WHERE from_id = \\?").WithArgs("c1").
+		WillReturnRows(sqlmock.NewRows([]string{"from_id", "to_id", "merge_id"}).AddRow("c1", "c2", "m1"))
+	mock.ExpectQuery("SELECT \\* FROM `customers` WHERE id = \\?").WithArgs("c2").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "version"}).AddRow("c2", "Jane", 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `invoices` WHERE order_id = \\?").WithArgs("o1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "number", "order_id"}).AddRow("i1", 7, "o1"))
+	mock.ExpectCommit()
+
+	res, err := newUsecase(db).GetByOrderId(customerContext("c1"), "o1")
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	if res.Number != "INV-000007" || res.Customer.Id != "c2" {
+		t.Fatalf("got invoice %s of %+v, want INV-000007 of c2", res.Number, res.Customer)
+	}
+}
+
+func TestGetByOrderIdOfAnotherCustomer(t *testing.T) {
+	db, mock := dbtest.New(t)
+	expectOrder(mock, "c2")
+	mock.ExpectQuery("SELECT \\* FROM `customer_redirects` WHERE from_id = \\?").WithArgs("c1").
+		WillReturnRows(sqlmock.NewRows([]string{"from_id", "to_id", "merge_id"}))
+
+	_, err := newUsecase(db).GetByOrderId(customerContext("c1"), "o1")
+	if err == nil || err.Code != 403 {
+		t.Fatalf("got %v, want 403", err)
+	}
+}
@@ -3,97 +3,234 @@ package login
 import (
 	"errors"
 	"fmt"
+	"gin-dbo/framework/export"
+	"gin-dbo/framework/logger"
 	"gin-dbo/framework/middleware"
+	"gin-dbo/framework/patch"
 	"gin-dbo/framework/utils"
+	models "gin-dbo/model/login"
 	mdl "gin-dbo/view/login"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
 )
 
 type Handler struct {
 	Usecase Usecase
-	logger  *logrus.Logger
 }
 
 // @SecurityDefinitions jwt
-func Router(router *gin.Engine, uc Usecase, logger *logrus.Logger) {
-	u := Handler{Usecase: uc, logger: logger}
+func Router(router *gin.Engine, uc Usecase, auth func(*gin.Context)) {
+	u := Handler{Usecase: uc}
 
-	router.POST("api/register", u.CreateHandler)
+	router.POST("api/register", u.RegisterHandler)
 	router.POST("api/login", u.LoginHandler)
-	router.Use(middleware.AuthorizeJWT())
+	router.POST("api/login/2fa", u.LoginTwoFactorHandler)
+	router.GET("api/auth/oidc/login", u.OIDCLoginHandler)
+	router.GET("api/auth/oidc/callback", u.OIDCCallbackHandler)
+	router.GET("api/verify-email", u.VerifyEmailHandler)
+	router.POST("api/verify-email", u.VerifyEmailHandler)
+	router.POST("api/password/forgot", u.ForgotPasswordHandler)
+	router.POST("api/password/reset", u.ResetPasswordHandler)
+	authorized := router.Group("/", auth)
 	{
-		router.GET("api/user", u.GetHandler)
-		router.GET("api/user/:id", u.GetByIdHandler)
-		router.POST("api/user", u.CreateHandler)
-		router.PUT("api/user/:id", u.UpdateHandler)
-		router.DELETE("api/user/:id", u.DeleteHandler)
+		authorized.GET("api/user", u.GetHandler)
+		authorized.GET("api/user/export", u.ExportHandler)
+		authorized.GET("api/user/invitations", u.GetInvitationsHandler)
+		authorized.POST("api/user/invitations", u.CreateInvitationHandler)
+		authorized.DELETE("api/user/invitations/:id", u.DeleteInvitationHandler)
+		authorized.POST("api/user/me/2fa/setup", u.SetupTwoFactorHandler)
+		authorized.POST("api/user/me/2fa/confirm", u.ConfirmTwoFactorHandler)
+		authorized.POST("api/user/me/2fa/disable", u.DisableTwoFactorHandler)
+		authorized.POST("api/user/me/2fa/recovery-codes", u.RegenerateRecoveryCodesHandler)
+		authorized.GET("api/me", u.GetMeHandler)
+		authorized.PATCH("api/me", u.PatchMeHandler)
+		authorized.POST("api/me/password", u.ChangePasswordHandler)
+		authorized.GET("api/user/:id", u.GetByIdHandler)
+		authorized.POST("api/user", u.CreateHandler)
+		authorized.PUT("api/user/:id", u.UpdateHandler)
+		authorized.PATCH("api/user/:id", u.PatchHandler)
+		authorized.DELETE("api/user/:id", u.DeleteHandler)
 	}
 }
 
 // @Summary Register
-// @Description Create Some New Users
+// @Description Sign up as a customer, or with the role of an invitation when its token is given. Usernames are 3 to 32 letters, digits, dots, dashes or underscores; passwords are 8 to 72 characters mixing upper and lower case letters and digits and must not contain the username.
 // @Accept json
 // @Produce json
-// @Param request body mdl.CreateRequest true "Sample Create request payload"
-// @Security jwt
+// @Param request body mdl.RegisterRequest true "Sample Register request payload"
 // @Success 200 {object} mdl.GeneralResponse
 // @Failure 400 {object} mdl.Response400
-// @Failure 401 {object} middleware.Response
+// @Failure 409 {object} mdl.GeneralResponse
+// @Failure 429 {object} middleware.Response
 // @Failure 500 {object} mdl.Response500
 // @Router /api/register [post]
 func (u Handler) RegisterHandler(c *gin.Context) {
-	param := new(mdl.CreateRequest)
+	param := new(mdl.RegisterRequest)
 	if err := c.BindJSON(param); err != nil {
 		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.registerHandler.BadRequest : %v", err.Error())})
 		return
 	}
 
-	u.logger.Debugf("%+v", param)
-	result, err := u.Usecase.Create(c, param)
-	if err == nil {
-		result.Success = true
-		result.Message = "success create data"
-		c.JSON(http.StatusOK, result)
+	logger.FromContext(c).Debugf("%+v", mdl.RegisterRequest{Username: param.Username, Email: param.Email})
+	if err := utils.ValidateRegisterRequest(param); err == nil {
+		result, err := u.Usecase.Register(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success register"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
 	} else {
-		u.logger.Error(err)
-		result.Success = false
-		result.Message = err.Message.Error()
-		c.JSON(http.StatusInternalServerError, result)
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.registerHandler.BadRequest : %v", err.Error())})
+	}
+}
+
+// @Summary Verify Email
+// @Description Confirm the email of a user with the token mailed to it, given as the token query parameter of the link or in the body
+// @Accept json
+// @Produce json
+// @Param token query string false "verification token"
+// @Param request body mdl.VerifyEmailRequest false "Sample Verify Email request payload"
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 409 {object} mdl.GeneralResponse
+// @Failure 429 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Router /api/verify-email [get]
+// @Router /api/verify-email [post]
+func (u Handler) VerifyEmailHandler(c *gin.Context) {
+	param := &mdl.VerifyEmailRequest{Token: c.Query("token")}
+	if param.Token == "" && c.Request.Method == http.MethodPost {
+		if err := c.BindJSON(param); err != nil {
+			c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.verifyEmailHandler.BadRequest : %v", err.Error())})
+			return
+		}
+	}
+
+	if err := utils.ValidateVerifyEmailRequest(param); err == nil {
+		result, err := u.Usecase.VerifyEmail(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success verify email"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.verifyEmailHandler.BadRequest : %v", err.Error())})
+	}
+}
+
+// @Summary Forgot Password
+// @Description Mail a single use password reset token to the user owning the email. The answer is the same whether or not the email is registered.
+// @Accept json
+// @Produce json
+// @Param request body mdl.ForgotPasswordRequest true "Sample Forgot Password request payload"
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 429 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Router /api/password/forgot [post]
+func (u Handler) ForgotPasswordHandler(c *gin.Context) {
+	param := new(mdl.ForgotPasswordRequest)
+	if err := c.BindJSON(param); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.forgotPasswordHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	if err := utils.ValidateForgotPasswordRequest(param); err == nil {
+		result, err := u.Usecase.ForgotPassword(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "if the email is registered, a reset token was sent to it"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.forgotPasswordHandler.BadRequest : %v", err.Error())})
+	}
+}
+
+// @Summary Reset Password
+// @Description Set a new password with a token from forgot password, the password follows the same rules as register
+// @Accept json
+// @Produce json
+// @Param request body mdl.ResetPasswordRequest true "Sample Reset Password request payload"
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 429 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Router /api/password/reset [post]
+func (u Handler) ResetPasswordHandler(c *gin.Context) {
+	param := new(mdl.ResetPasswordRequest)
+	if err := c.BindJSON(param); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.resetPasswordHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	if err := utils.ValidateResetPasswordRequest(param); err == nil {
+		result, err := u.Usecase.ResetPassword(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success reset password"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.resetPasswordHandler.BadRequest : %v", err.Error())})
 	}
 }
 
 // @Summary Login
-// @Description Handle Login of Some Users
+// @Description Handle Login of Some Users. Users with two-factor authentication get a challenge token instead of a token, to send with their code to login 2fa. When two-factor authentication is required of admins, admins without it get a token only good to set it up.
 // @Accept json
 // @Produce json
 // @Param request body mdl.LoginRequest true "Sample Login request payload"
 // @Success 200 {object} mdl.ResponseLogin
 // @Failure 400 {object} mdl.GeneralResponse
 // @Failure 401 {object} middleware.Response
+// @Failure 429 {object} middleware.Response
 // @Failure 500 {object} mdl.GeneralResponse
 // @Router /api/login [post]
 func (u Handler) LoginHandler(c *gin.Context) {
+	middleware.NoStore(c)
 	param := new(mdl.LoginRequest)
 	if err := c.BindJSON(param); err != nil {
 		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.loginHandler.BadRequest : %v", err.Error())})
 		return
 	}
-	u.logger.Debug(param)
+	logger.FromContext(c).Debug(param)
 
 	if utils.ValidateLoginRequest(param) == nil {
 		result, err := u.Usecase.Login(c, param)
 		if err == nil {
 			result.Success = true
 			result.Message = "success login"
+			if result.Data.TwoFactorRequired {
+				result.Message = "two-factor code required"
+			}
 			c.JSON(http.StatusOK, result)
 		} else {
-			u.logger.Error(err)
+			logger.FromContext(c).Error(err)
 			result.Success = false
 			result.Message = err.Message.Error()
-			c.JSON(http.StatusInternalServerError, result)
+			c.JSON(err.Code, result)
 		}
 	} else {
 		errn := errors.New("mandatory field is missing")
@@ -101,6 +238,350 @@ func (u Handler) LoginHandler(c *gin.Context) {
 	}
 }
 
+// @Summary Login Two-Factor
+// @Description Complete a login with the challenge token of login and a code of the authenticator app or a recovery code. Wrong codes count towards the lockout like wrong passwords.
+// @Accept json
+// @Produce json
+// @Param request body mdl.LoginTwoFactorRequest true "Sample Login Two-Factor request payload"
+// @Success 200 {object} mdl.ResponseLogin
+// @Failure 400 {object} mdl.GeneralResponse
+// @Failure 401 {object} mdl.GeneralResponse
+// @Failure 429 {object} middleware.Response
+// @Failure 500 {object} mdl.GeneralResponse
+// @Router /api/login/2fa [post]
+func (u Handler) LoginTwoFactorHandler(c *gin.Context) {
+	middleware.NoStore(c)
+	param := new(mdl.LoginTwoFactorRequest)
+	if err := c.BindJSON(param); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.loginTwoFactorHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	if err := utils.ValidateLoginTwoFactorRequest(param); err == nil {
+		result, err := u.Usecase.LoginTwoFactor(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success login"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.loginTwoFactorHandler.BadRequest : %v", err.Error())})
+	}
+}
+
+// @Summary OIDC Login
+// @Description Redirect to the OIDC provider to sign in, which comes back to the callback
+// @Produce json
+// @Success 302
+// @Failure 404 {object} mdl.GeneralResponse
+// @Failure 429 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Failure 502 {object} mdl.GeneralResponse
+// @Router /api/auth/oidc/login [get]
+func (u Handler) OIDCLoginHandler(c *gin.Context) {
+	location, err := u.Usecase.OIDCLogin(c)
+	if err == nil {
+		c.Redirect(http.StatusFound, location)
+	} else {
+		logger.FromContext(c).Error(err)
+		c.JSON(err.Code, mdl.GeneralResponse{Success: false, Message: err.Message.Error()})
+	}
+}
+
+// @Summary OIDC Callback
+// @Description Finish a sign in at the OIDC provider and get a token. Users are created on their first sign in, or linked to the user owning their verified email, and their role follows their groups at the provider.
+// @Produce json
+// @Param code query string false "authorization code"
+// @Param state query string true "state of the sign in"
+// @Param error query string false "error from the provider"
+// @Success 200 {object} mdl.ResponseLogin
+// @Failure 400 {object} mdl.GeneralResponse
+// @Failure 401 {object} mdl.GeneralResponse
+// @Failure 403 {object} mdl.GeneralResponse
+// @Failure 409 {object} mdl.GeneralResponse
+// @Failure 429 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Router /api/auth/oidc/callback [get]
+func (u Handler) OIDCCallbackHandler(c *gin.Context) {
+	middleware.NoStore(c)
+	param := new(mdl.OIDCCallbackRequest)
+	if err := c.BindQuery(param); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.oidcCallbackHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	if err := utils.ValidateOIDCCallbackRequest(param); err == nil {
+		result, err := u.Usecase.OIDCCallback(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success login"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.oidcCallbackHandler.BadRequest : %v", err.Error())})
+	}
+}
+
+// @Summary Set Up Two-Factor
+// @Description Generate a TOTP secret for the logged in user, as an otpauth URI and its QR code to scan with an authenticator app. It stays pending until confirmed, setting up again replaces it.
+// @Produce json
+// @Success 200 {object} mdl.ResponseTwoFactorSetup
+// @Failure 401 {object} middleware.Response
+// @Failure 409 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/user/me/2fa/setup [post]
+func (u Handler) SetupTwoFactorHandler(c *gin.Context) {
+	middleware.NoStore(c)
+	result, err := u.Usecase.SetupTwoFactor(c)
+	if err == nil {
+		result.Success = true
+		result.Message = "scan the QR code and confirm with a code"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
+// @Summary Confirm Two-Factor
+// @Description Enable two-factor authentication with a code of the secret from set up. The recovery codes are returned once, along with a full token when logged in with a set up only token.
+// @Accept json
+// @Produce json
+// @Param request body mdl.TwoFactorCodeRequest true "Sample Two-Factor Code request payload"
+// @Success 200 {object} mdl.ResponseRecoveryCodes
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 409 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/user/me/2fa/confirm [post]
+func (u Handler) ConfirmTwoFactorHandler(c *gin.Context) {
+	middleware.NoStore(c)
+	param := new(mdl.TwoFactorCodeRequest)
+	if err := c.BindJSON(param); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.confirmTwoFactorHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	if err := utils.ValidateTwoFactorCodeRequest(param); err == nil {
+		result, err := u.Usecase.ConfirmTwoFactor(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success enable two-factor authentication, store the recovery codes safely"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.confirmTwoFactorHandler.BadRequest : %v", err.Error())})
+	}
+}
+
+// @Summary Disable Two-Factor
+// @Description Disable two-factor authentication with a code of the authenticator app or a recovery code
+// @Accept json
+// @Produce json
+// @Param request body mdl.TwoFactorCodeRequest true "Sample Two-Factor Code request payload"
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 409 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/user/me/2fa/disable [post]
+func (u Handler) DisableTwoFactorHandler(c *gin.Context) {
+	param := new(mdl.TwoFactorCodeRequest)
+	if err := c.BindJSON(param); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.disableTwoFactorHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	if err := utils.ValidateTwoFactorCodeRequest(param); err == nil {
+		result, err := u.Usecase.DisableTwoFactor(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success disable two-factor authentication"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.disableTwoFactorHandler.BadRequest : %v", err.Error())})
+	}
+}
+
+// @Summary Regenerate Recovery Codes
+// @Description Replace all recovery codes, used or not, given a code of the authenticator app or a recovery code. The new codes are returned once.
+// @Accept json
+// @Produce json
+// @Param request body mdl.TwoFactorCodeRequest true "Sample Two-Factor Code request payload"
+// @Success 200 {object} mdl.ResponseRecoveryCodes
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 409 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/user/me/2fa/recovery-codes [post]
+func (u Handler) RegenerateRecoveryCodesHandler(c *gin.Context) {
+	middleware.NoStore(c)
+	param := new(mdl.TwoFactorCodeRequest)
+	if err := c.BindJSON(param); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.regenerateRecoveryCodesHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	if err := utils.ValidateTwoFactorCodeRequest(param); err == nil {
+		result, err := u.Usecase.RegenerateRecoveryCodes(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success regenerate recovery codes, store them safely"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.regenerateRecoveryCodesHandler.BadRequest : %v", err.Error())})
+	}
+}
+
+// @Summary Get Me
+// @Description Get the account of the caller
+// @Produce json
+// @Param If-None-Match header string false "etag of a cached copy"
+// @Success 200 {object} mdl.ResponseDetail
+// @Header 200 {string} ETag "version of the data"
+// @Success 304 "cached copy is still current"
+// @Failure 401 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Router /api/me [get]
+func (u Handler) GetMeHandler(c *gin.Context) {
+	result, err := u.Usecase.GetMe(c)
+	if err == nil {
+		etag := utils.FormatETag(result.Data.Version)
+		c.Header(utils.ETag, etag)
+		if utils.MatchETag(c.GetHeader(utils.IfNoneMatch), etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+		result.Success = true
+		result.Message = "success retrieve data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
+// @Summary Patch Me
+// @Description Partially Update the account of the caller with a JSON Merge Patch (RFC 7396), only the email can be changed and is verified again
+// @Accept application/merge-patch+json,json
+// @Produce json
+// @Param If-Match header string false "etag returned by get me, required when REQUIRE_IF_MATCH is enabled"
+// @Param request body mdl.UpdateMeRequest true "Sample Patch Me request payload"
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 409 {object} mdl.GeneralResponse
+// @Failure 412 {object} mdl.GeneralResponse
+// @Failure 415 {object} mdl.GeneralResponse
+// @Failure 428 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/me [patch]
+func (u Handler) PatchMeHandler(c *gin.Context) {
+	if contentType := c.ContentType(); contentType != patch.ContentType && contentType != gin.MIMEJSON {
+		c.JSON(http.StatusUnsupportedMediaType, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.patchMeHandler.UnsupportedMediaType : expected %s", patch.ContentType)})
+		return
+	}
+
+	document, errn := c.GetRawData()
+	if errn == nil {
+		errn = utils.ValidatePatchMeFields(document)
+	}
+	if errn != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.patchMeHandler.BadRequest : %v", errn.Error())})
+		return
+	}
+
+	version, err := utils.GetIfMatch(c.GetHeader(utils.IfMatch))
+	if err != nil {
+		c.JSON(err.Code, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.patchMeHandler.Precondition : %v", err.Message.Error())})
+		return
+	}
+
+	param := &mdl.PatchRequest{
+		Patch:   document,
+		Version: version,
+	}
+	logger.FromContext(c).Debugf("%s", param.Patch)
+
+	result, err := u.Usecase.PatchMe(c, param)
+	if err == nil {
+		result.Success = true
+		result.Message = "success update data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
+// @Summary Change Password
+// @Description Change the password of the caller given the current one; the new password follows the same rules as register. Wrong current passwords count towards the lockout.
+// @Accept json
+// @Produce json
+// @Param request body mdl.ChangePasswordRequest true "Sample Change Password request payload"
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 429 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/me/password [post]
+func (u Handler) ChangePasswordHandler(c *gin.Context) {
+	param := new(mdl.ChangePasswordRequest)
+	if err := c.BindJSON(param); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.changePasswordHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	if err := utils.ValidateChangePasswordRequest(param); err == nil {
+		result, err := u.Usecase.ChangePassword(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success change password"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.changePasswordHandler.BadRequest : %v", err.Error())})
+	}
+}
+
 // @Summary Get All Users
 // @Description Get All Users
 // @Produce json
@@ -134,17 +615,79 @@ func (u Handler) GetHandler(c *gin.Context) {
 		result.Message = "success retrieve data"
 		c.JSON(http.StatusOK, result)
 	} else {
-		u.logger.Error(err)
+		logger.FromContext(c).Error(err)
 		result.Success = false
 		result.Message = err.Message.Error()
 		c.JSON(http.StatusInternalServerError, result)
 	}
 }
 
+// @Summary Export Users
+// @Description Export Users as CSV or XLSX
+// @param format query string false "csv or xlsx, default csv"
+// @param columns query string false "comma separated columns to export, default all"
+// @param keyword query string false "username of some user"
+// @Produce text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Success 200 {string} string
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Router /api/user/export [get]
+func (u Handler) ExportHandler(c *gin.Context) {
+	format := c.Query(export.Format)
+	if err := export.CheckFormat(format); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.exportHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	columns, errn := export.SelectColumns(mdl.ExportColumns, c.Query(export.Columns))
+	if errn != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.exportHandler.BadRequest : %v", errn.Error())})
+		return
+	}
+
+	param := &mdl.GetRequest{
+		Keyword: c.Query(utils.Keyword),
+	}
+	logger.FromContext(c).Debugf("%+v", param)
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename("users", format)))
+	c.Status(http.StatusOK)
+	writer, errn := export.NewWriter(format, c.Writer)
+	if errn == nil {
+		errn = writer.Write(export.Header(columns))
+	}
+	if errn != nil {
+		logger.FromContext(c).Error(errn)
+		return
+	}
+
+	err := u.Usecase.Export(c, param, func(rows []*models.User) error {
+		for _, row := range rows {
+			if err := writer.Write(export.Record(columns, row)); err != nil {
+				return err
+			}
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		logger.FromContext(c).Error(err)
+		return
+	}
+	if errn = writer.Close(); errn != nil {
+		logger.FromContext(c).Error(errn)
+	}
+}
+
 // @Summary Get User By Id
 // @Description Get User By Id
 // @Produce json
+// @Param If-None-Match header string false "etag of a cached copy"
 // @Success 200 {object} mdl.ResponseDetail
+// @Header 200 {string} ETag "version of the data"
+// @Success 304 "cached copy is still current"
 // @Failure 400 {object} mdl.Response400
 // @Failure 401 {object} middleware.Response
 // @Failure 500 {object} mdl.Response500
@@ -153,11 +696,17 @@ func (u Handler) GetByIdHandler(c *gin.Context) {
 	id := c.Param("id")
 	result, err := u.Usecase.GetById(c, id)
 	if err == nil {
+		etag := utils.FormatETag(result.Data.Version)
+		c.Header(utils.ETag, etag)
+		if utils.MatchETag(c.GetHeader(utils.IfNoneMatch), etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
 		result.Success = true
 		result.Message = "success retrieve data"
 		c.JSON(http.StatusOK, result)
 	} else {
-		u.logger.Error(err)
+		logger.FromContext(c).Error(err)
 		result.Success = false
 		result.Message = err.Message.Error()
 		c.JSON(err.Code, result)
@@ -165,11 +714,10 @@ func (u Handler) GetByIdHandler(c *gin.Context) {
 }
 
 // @Summary Create User
-// @Description Create Some New Users
+// @Description Create users of any role, only admins can; the username and password follow the same rules as register
 // @Accept json
 // @Produce json
 // @Param request body mdl.CreateRequest true "Sample Create request payload"
-// @Security jwt
 // @Success 200 {object} mdl.GeneralResponse
 // @Failure 400 {object} mdl.Response400
 // @Failure 401 {object} middleware.Response
@@ -182,7 +730,7 @@ func (u Handler) CreateHandler(c *gin.Context) {
 		return
 	}
 
-	u.logger.Debugf("%+v", param)
+	logger.FromContext(c).Debugf("%+v", param)
 	if err := utils.ValidateCreateRequest(param); err == nil {
 		result, err := u.Usecase.Create(c, param)
 		if err == nil {
@@ -190,10 +738,10 @@ func (u Handler) CreateHandler(c *gin.Context) {
 			result.Message = "success create data"
 			c.JSON(http.StatusOK, result)
 		} else {
-			u.logger.Error(err)
+			logger.FromContext(c).Error(err)
 			result.Success = false
 			result.Message = err.Message.Error()
-			c.JSON(http.StatusInternalServerError, result)
+			c.JSON(err.Code, result)
 		}
 	} else {
 		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("users.delivery.createHandler.BadRequest : %v", err.Error())})
@@ -201,14 +749,16 @@ func (u Handler) CreateHandler(c *gin.Context) {
 }
 
 // @Summary Update User
-// @Description Update Some Users
+// @Description Update Some Users, replacing every field with the request payload
 // @Accept json
 // @Produce json
+// @Param If-Match header string false "etag returned by get by id, required when REQUIRE_IF_MATCH is enabled"
 // @Param request body mdl.UpdateRequest true "Sample Update request payload"
-// @Security jwt
 // @Success 200 {object} mdl.GeneralResponse
 // @Failure 400 {object} mdl.Response400
 // @Failure 401 {object} middleware.Response
+// @Failure 412 {object} mdl.GeneralResponse
+// @Failure 428 {object} mdl.GeneralResponse
 // @Failure 500 {object} mdl.Response500
 // @Router /api/user/{id} [put]
 func (u Handler) UpdateHandler(c *gin.Context) {
@@ -218,7 +768,13 @@ func (u Handler) UpdateHandler(c *gin.Context) {
 		return
 	}
 	param.Username = c.Param("id")
-	u.logger.Debugf("%+v", param)
+	version, err := utils.GetIfMatch(c.GetHeader(utils.IfMatch))
+	if err != nil {
+		c.JSON(err.Code, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.updateHandler.Precondition : %v", err.Message.Error())})
+		return
+	}
+	param.Version = version
+	logger.FromContext(c).Debugf("%+v", param)
 
 	if err := utils.ValidateUpdateRequest(param); err == nil {
 		result, err := u.Usecase.Update(c, param)
@@ -227,31 +783,97 @@ func (u Handler) UpdateHandler(c *gin.Context) {
 			result.Message = "success update data"
 			c.JSON(http.StatusOK, result)
 		} else {
-			u.logger.Error(err)
+			logger.FromContext(c).Error(err)
 			result.Success = false
 			result.Message = err.Message.Error()
-			c.JSON(http.StatusInternalServerError, result)
+			c.JSON(err.Code, result)
 		}
 	} else {
 		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("users.delivery.updateHandler.BadRequest : %v", err.Error())})
 	}
 }
 
+// @Summary Patch Users
+// @Description Partially Update Some Users with a JSON Merge Patch (RFC 7396), the password is only changed when it is part of the patch
+// @Accept application/merge-patch+json,json
+// @Produce json
+// @Param If-Match header string false "etag returned by get by id, required when REQUIRE_IF_MATCH is enabled"
+// @Param request body mdl.UpdateRequest true "Sample Patch request payload, only the fields to change"
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 412 {object} mdl.GeneralResponse
+// @Failure 415 {object} mdl.GeneralResponse
+// @Failure 428 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/user/{id} [patch]
+func (u Handler) PatchHandler(c *gin.Context) {
+	var JWT, _ = c.Get(middleware.JwtClaims)
+	jwtClaims := JWT.(*middleware.AuthCustomClaims)
+	if contentType := c.ContentType(); contentType != patch.ContentType && contentType != gin.MIMEJSON {
+		c.JSON(http.StatusUnsupportedMediaType, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.patchHandler.UnsupportedMediaType : expected %s", patch.ContentType)})
+		return
+	}
+
+	document, errn := c.GetRawData()
+	if errn == nil {
+		errn = utils.ValidatePatchFields(jwtClaims.Role, document)
+	}
+	if errn != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.patchHandler.BadRequest : %v", errn.Error())})
+		return
+	}
+
+	version, err := utils.GetIfMatch(c.GetHeader(utils.IfMatch))
+	if err != nil {
+		c.JSON(err.Code, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.patchHandler.Precondition : %v", err.Message.Error())})
+		return
+	}
+
+	param := &mdl.PatchRequest{
+		Username: c.Param("id"),
+		Patch:    document,
+		Version:  version,
+	}
+	logger.FromContext(c).Debugf("%s %s", param.Username, param.Patch)
+
+	result, err := u.Usecase.Patch(c, param)
+	if err == nil {
+		result.Success = true
+		result.Message = "success update data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
 // @Summary Delete User
 // @Description Delete Some Users
 // @Accept json
 // @Produce json
-// @Security jwt
+// @Param If-Match header string false "etag returned by get by id, required when REQUIRE_IF_MATCH is enabled"
 // @Success 200 {object} mdl.GeneralResponse
 // @Failure 400 {object} mdl.Response400
 // @Failure 401 {object} middleware.Response
+// @Failure 412 {object} mdl.GeneralResponse
+// @Failure 428 {object} mdl.GeneralResponse
 // @Failure 500 {object} mdl.Response500
 // @Router /api/user/{id} [delete]
 func (u Handler) DeleteHandler(c *gin.Context) {
+	version, err := utils.GetIfMatch(c.GetHeader(utils.IfMatch))
+	if err != nil {
+		c.JSON(err.Code, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.deleteHandler.Precondition : %v", err.Message.Error())})
+		return
+	}
+
 	param := &mdl.DeleteRequest{
 		Username: c.Param("id"),
+		Version:  version,
 	}
-	u.logger.Debugf("%+v", param)
+	logger.FromContext(c).Debugf("%+v", param)
 
 	if err := utils.ValidateDeleteRequest(param); err == nil {
 		result, err := u.Usecase.Delete(c, param)
@@ -260,12 +882,100 @@ func (u Handler) DeleteHandler(c *gin.Context) {
 			result.Message = "success delete data"
 			c.JSON(http.StatusOK, result)
 		} else {
-			u.logger.Error(err)
+			logger.FromContext(c).Error(err)
 			result.Success = false
 			result.Message = err.Message.Error()
-			c.JSON(http.StatusInternalServerError, result)
+			c.JSON(err.Code, result)
 		}
 	} else {
 		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("users.delivery.deleteHandler.BadRequest : %v", err.Error())})
 	}
 }
+
+// @Summary Get Invitations
+// @Description Get the invitations that can still be used to register
+// @Produce json
+// @Success 200 {object} mdl.ResponseInvitations
+// @Failure 401 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Router /api/user/invitations [get]
+func (u Handler) GetInvitationsHandler(c *gin.Context) {
+	result, err := u.Usecase.GetInvitations(c)
+	if err == nil {
+		result.Success = true
+		result.Message = "success retrieve data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
+// @Summary Create Invitation
+// @Description Create a single use token to register with the given role, e.g. to onboard staff. The token is only returned here.
+// @Accept json
+// @Produce json
+// @Param request body mdl.CreateInvitationRequest true "Sample Create Invitation request payload"
+// @Success 200 {object} mdl.ResponseInvitation
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Router /api/user/invitations [post]
+func (u Handler) CreateInvitationHandler(c *gin.Context) {
+	middleware.NoStore(c)
+	param := new(mdl.CreateInvitationRequest)
+	if err := c.BindJSON(param); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.createInvitationHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	logger.FromContext(c).Debugf("%+v", param)
+	if err := utils.ValidateCreateInvitationRequest(param); err == nil {
+		result, err := u.Usecase.CreateInvitation(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success create data"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.createInvitationHandler.BadRequest : %v", err.Error())})
+	}
+}
+
+// @Summary Delete Invitation
+// @Description Revoke an invitation
+// @Produce json
+// @Param id path string true "invitation id"
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/user/invitations/{id} [delete]
+func (u Handler) DeleteInvitationHandler(c *gin.Context) {
+	param := &mdl.DeleteInvitationRequest{Id: c.Param("id")}
+	logger.FromContext(c).Debugf("%+v", param)
+
+	if err := utils.ValidateDeleteInvitationRequest(param); err == nil {
+		result, err := u.Usecase.DeleteInvitation(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success delete data"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("user.deleteInvitationHandler.BadRequest : %v", err.Error())})
+	}
+}
@@ -1,62 +1,97 @@
 package login
 
 import (
+	"errors"
 	"fmt"
-	"gin-dbo/framework/middleware"
+	"gin-dbo/framework/export"
 	"gin-dbo/framework/utils"
 	models "gin-dbo/model/login"
 	view "gin-dbo/view/login"
 
+	"gin-dbo/framework/database"
 	internal "gin-dbo/framework/error"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// userColumns leaves out the password and two-factor secrets.
+const userColumns = "username, role, customer_id, email, email_verified_at, two_factor_enabled_at, oidc_subject, version, created_at, updated_at"
+
 type Repo struct {
 	Dbconn *gorm.DB
 }
 
 type Repository interface {
-	Login(ctx *gin.Context, request *view.LoginRequest) (res view.ResponseLogin, err *internal.Error)
+	Login(ctx *gin.Context, request *view.LoginRequest) (res *models.User, err *internal.Error)
 	Get(ctx *gin.Context, request *view.GetRequest, page int) (res []*models.User, err *internal.Error)
 	Count(ctx *gin.Context, request *view.GetRequest) (res int, err *internal.Error)
+	Export(ctx *gin.Context, request *view.GetRequest, fn func([]*models.User) error) (err *internal.Error)
 	GetById(ctx *gin.Context, id string) (res *models.User, err *internal.Error)
 	Create(ctx *gin.Context, request *view.CreateRequest) (res view.GeneralResponse, err *internal.Error)
 	Update(ctx *gin.Context, request *view.UpdateRequest) (res view.GeneralResponse, err *internal.Error)
 	Delete(ctx *gin.Context, request *view.DeleteRequest) (res view.GeneralResponse, err *internal.Error)
+	GetAttempt(ctx *gin.Context, username string) (res *models.Attempt, err *internal.Error)
+	SaveAttempt(ctx *gin.Context, attempt *models.Attempt) (err *internal.Error)
+	DeleteAttempt(ctx *gin.Context, username string) (err *internal.Error)
+	GetByEmail(ctx *gin.Context, email string) (res *models.User, err *internal.Error)
+	SetPassword(ctx *gin.Context, username string, password string) (err *internal.Error)
+	VerifyEmail(ctx *gin.Context, username string, email string) (err *internal.Error)
+	SetEmail(ctx *gin.Context, username string, email string, version int64) (err *internal.Error)
+	CreateToken(ctx *gin.Context, token *models.Token) (err *internal.Error)
+	GetCredentials(ctx *gin.Context, username string) (res *models.User, err *internal.Error)
+	GetByOidcSubject(ctx *gin.Context, subject string) (res *models.User, err *internal.Error)
+	LinkOidc(ctx *gin.Context, username string, subject string) (err *internal.Error)
+	CreateOIDCState(ctx *gin.Context, state *models.OIDCState) (err *internal.Error)
+	TakeOIDCState(ctx *gin.Context, stateHash string) (res *models.OIDCState, err *internal.Error)
+	SetTwoFactor(ctx *gin.Context, username string, secret string, enabledAt string, lastStep int64) (err *internal.Error)
+	SetTotpLastStep(ctx *gin.Context, username string, step int64) (err *internal.Error)
+	ReplaceRecoveryCodes(ctx *gin.Context, username string, codes []*models.RecoveryCode) (err *internal.Error)
+	UseRecoveryCode(ctx *gin.Context, username string, codeHash string) (err *internal.Error)
+	GetTokenByHash(ctx *gin.Context, tokenHash string) (res *models.Token, err *internal.Error)
+	UseToken(ctx *gin.Context, tokenHash string) (err *internal.Error)
+	CreateInvitation(ctx *gin.Context, invitation *models.Invitation) (err *internal.Error)
+	GetInvitations(ctx *gin.Context) (res []*models.Invitation, err *internal.Error)
+	GetInvitationByToken(ctx *gin.Context, tokenHash string) (res *models.Invitation, err *internal.Error)
+	UseInvitation(ctx *gin.Context, id string, username string) (err *internal.Error)
+	DeleteInvitation(ctx *gin.Context, id string) (res *models.Invitation, err *internal.Error)
+	Transaction(ctx *gin.Context, fn func(repo Repository) *internal.Error) (err *internal.Error)
 }
 
 func NewRepository(dbconn *gorm.DB) Repository {
-	return &Repo{Dbconn: dbconn}
+	return tracedRepository{next: &Repo{Dbconn: dbconn}}
+}
+
+// conn joins the transaction carried by ctx, if any.
+func (r Repo) conn(ctx *gin.Context) *gorm.DB {
+	return database.Conn(ctx, r.Dbconn)
 }
 
-func (r Repo) Login(ctx *gin.Context, param *view.LoginRequest) (view.ResponseLogin, *internal.Error) {
+// Login finds the user matching the credentials, with its two-factor
+// settings.
+func (r Repo) Login(ctx *gin.Context, param *view.LoginRequest) (*models.User, *internal.Error) {
 	var (
 		result *models.User
-		res    view.ResponseLogin
 		err    error
 	)
-	query := r.Dbconn.Model(&models.User{}).Where("username = ? and password = ?", param.Username, utils.Encrypt(param.Password))
+	query := r.conn(ctx).Model(&models.User{}).Where("username = ? and password = ?", param.Username, utils.Encrypt(param.Password))
 	err = query.Scan(&result).Error
 	if err != nil {
-		return res, internal.NewError(500, fmt.Errorf("login.repository.GetDetail : %v", err.Error()))
+		return nil, internal.NewError(500, fmt.Errorf("login.repository.GetDetail : %v", err.Error()))
 	}
 
 	if result == nil {
-		return res, internal.NewError(400, fmt.Errorf("username or password invalid"))
+		return nil, internal.NewError(400, fmt.Errorf("username or password invalid"))
 	}
-
-	tokenString := middleware.JWTAuthService().GenerateToken(result)
-	res.Data.Token = tokenString
-	return res, nil
+	return result, nil
 }
 
 func (r Repo) Get(ctx *gin.Context, param *view.GetRequest, page int) ([]*models.User, *internal.Error) {
 	var (
 		res []*models.User
 	)
-	query := r.Dbconn.Select("username, role, customer_id, created_at, updated_at")
+	query := r.conn(ctx).Select(userColumns)
 	if param.Keyword != "" {
 		query = query.Where("username LIKE ?", "%"+param.Keyword+"%")
 	}
@@ -79,7 +114,7 @@ func (r Repo) Count(ctx *gin.Context, param *view.GetRequest) (int, *internal.Er
 	var (
 		res int
 	)
-	query := r.Dbconn.Select("COUNT(1) as total").Model(&models.User{})
+	query := r.conn(ctx).Select("COUNT(1) as total").Model(&models.User{})
 	if param.Keyword != "" {
 		query = query.Where("username LIKE ?", "%"+param.Keyword+"%")
 	}
@@ -90,14 +125,35 @@ func (r Repo) Count(ctx *gin.Context, param *view.GetRequest) (int, *internal.Er
 	return res, nil
 }
 
+func (r Repo) Export(ctx *gin.Context, param *view.GetRequest, fn func([]*models.User) error) *internal.Error {
+	var (
+		res []*models.User
+	)
+	query := r.conn(ctx).Select(userColumns)
+	if param.Keyword != "" {
+		query = query.Where("username LIKE ?", "%"+param.Keyword+"%")
+	}
+
+	err := query.FindInBatches(&res, export.BatchSize, func(tx *gorm.DB, batch int) error {
+		return fn(res)
+	}).Error
+	if err != nil {
+		return internal.NewError(500, fmt.Errorf("user.repository.Export : %v", err.Error()))
+	}
+	return nil
+}
+
 func (r Repo) GetById(ctx *gin.Context, id string) (*models.User, *internal.Error) {
 	var (
 		res *models.User
 		err error
 	)
-	query := r.Dbconn.Find(&res).Where("username = ?", id)
-	if err = query.Error; err != nil {
-		return res, internal.NewError(500, fmt.Errorf("login.repository.GetById : %v", err.Error()))
+	query := r.conn(ctx).Model(&models.User{}).Select(userColumns).Where("username = ?", id).Take(&res)
+	if err = query.Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, internal.NewError(404, fmt.Errorf("login.repository.GetById : %v", fmt.Errorf("no data found with id %s", id)))
+	}
+	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("login.repository.GetById : %v", err.Error()))
 	}
 	return res, nil
 }
@@ -108,7 +164,7 @@ func (r Repo) Create(ctx *gin.Context, param *view.CreateRequest) (view.GeneralR
 		err error
 	)
 	now := utils.FormatTime()
-	query := r.Dbconn.Create(models.User{Username: param.Username, Password: utils.Encrypt(param.Password), Role: param.Role, CustomerId: param.CustomerId, CreatedAt: now, UpdatedAt: now})
+	query := r.conn(ctx).Create(models.User{Username: param.Username, Password: utils.Encrypt(param.Password), Role: param.Role, CustomerId: param.CustomerId, Email: param.Email, Version: 1, CreatedAt: now, UpdatedAt: now})
 	if err = query.Error; err != nil {
 		return res, internal.NewError(500, fmt.Errorf("login.repository.Create : %v", err.Error()))
 	}
@@ -117,18 +173,378 @@ func (r Repo) Create(ctx *gin.Context, param *view.CreateRequest) (view.GeneralR
 
 func (r Repo) Update(ctx *gin.Context, param *view.UpdateRequest) (view.GeneralResponse, *internal.Error) {
 	var res view.GeneralResponse
-	err := r.Dbconn.Updates(models.User{Username: param.Username, Password: utils.Encrypt(param.Password), Role: param.Role, UpdatedAt: utils.FormatTime()}).Error
-	if err != nil {
+	query := r.conn(ctx).Model(&models.User{}).Where("username = ?", param.Username)
+	if param.Version > 0 {
+		query = query.Where("version = ?", param.Version)
+	}
+	data := map[string]interface{}{"role": param.Role, "customer_id": param.CustomerId, "version": gorm.Expr("version + 1"), "updated_at": utils.FormatTime()}
+	if param.Password != "" {
+		// an empty password comes from a patch that leaves the password as is
+		data["password"] = utils.Encrypt(param.Password)
+	}
+	query = query.Updates(data)
+	if err := query.Error; err != nil {
 		return res, internal.NewError(500, fmt.Errorf("login.repository.Update : %v", err.Error()))
 	}
+	if query.RowsAffected == 0 {
+		return res, r.conflict(ctx, "login.repository.Update", param.Username, param.Version)
+	}
 	return res, nil
 }
 
 func (r Repo) Delete(ctx *gin.Context, param *view.DeleteRequest) (view.GeneralResponse, *internal.Error) {
 	var res view.GeneralResponse
-	err := r.Dbconn.Delete(models.User{Username: param.Username}).Error
-	if err != nil {
+	query := r.conn(ctx).Where("username = ?", param.Username)
+	if param.Version > 0 {
+		query = query.Where("version = ?", param.Version)
+	}
+	query = query.Delete(&models.User{})
+	if err := query.Error; err != nil {
 		return res, internal.NewError(500, fmt.Errorf("login.repository.Delete : %v", err.Error()))
 	}
+	if query.RowsAffected == 0 {
+		return res, r.conflict(ctx, "login.repository.Delete", param.Username, param.Version)
+	}
+	return res, nil
+}
+
+// conflict explains why a write touched no row: either the user is gone or
+// it was changed since the version the client read.
+func (r Repo) conflict(ctx *gin.Context, method string, id string, version int64) *internal.Error {
+	if _, err := r.GetById(ctx, id); err != nil {
+		return err
+	}
+	return internal.NewError(412, fmt.Errorf("%s : %v", method, fmt.Errorf("version %d of id %s is outdated", version, id)))
+}
+
+// GetAttempt reads the failed logins of username, locking the row until the
+// transaction of ctx ends. A username without failures gets an empty one.
+func (r Repo) GetAttempt(ctx *gin.Context, username string) (*models.Attempt, *internal.Error) {
+	var res *models.Attempt
+	query := r.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("username = ?", username).Take(&res)
+	err := query.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.Attempt{Username: username}, nil
+	}
+	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("login.repository.GetAttempt : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func (r Repo) SaveAttempt(ctx *gin.Context, attempt *models.Attempt) *internal.Error {
+	query := r.conn(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(attempt)
+	if err := query.Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("login.repository.SaveAttempt : %v", err.Error()))
+	}
+	return nil
+}
+
+func (r Repo) DeleteAttempt(ctx *gin.Context, username string) *internal.Error {
+	query := r.conn(ctx).Where("username = ?", username).Delete(&models.Attempt{})
+	if err := query.Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("login.repository.DeleteAttempt : %v", err.Error()))
+	}
+	return nil
+}
+
+func (r Repo) GetByEmail(ctx *gin.Context, email string) (*models.User, *internal.Error) {
+	var res *models.User
+	query := r.conn(ctx).Model(&models.User{}).Select(userColumns).Where("email = ?", email).Take(&res)
+	err := query.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, internal.NewError(404, fmt.Errorf("login.repository.GetByEmail : no user found with this email"))
+	}
+	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("login.repository.GetByEmail : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func (r Repo) SetPassword(ctx *gin.Context, username string, password string) *internal.Error {
+	query := r.conn(ctx).Model(&models.User{}).Where("username = ?", username).Updates(map[string]interface{}{"password": utils.Encrypt(password), "version": gorm.Expr("version + 1"), "updated_at": utils.FormatTime()})
+	if err := query.Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("login.repository.SetPassword : %v", err.Error()))
+	}
+	if query.RowsAffected == 0 {
+		return internal.NewError(404, fmt.Errorf("login.repository.SetPassword : %v", fmt.Errorf("no data found with id %s", username)))
+	}
+	return nil
+}
+
+// VerifyEmail marks email of username as verified, failing with 409 when the
+// user changed its email since the token was sent.
+func (r Repo) VerifyEmail(ctx *gin.Context, username string, email string) *internal.Error {
+	query := r.conn(ctx).Model(&models.User{}).Where("username = ? and email = ?", username, email).Update("email_verified_at", utils.FormatTime())
+	if err := query.Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("login.repository.VerifyEmail : %v", err.Error()))
+	}
+	if query.RowsAffected == 0 {
+		return internal.NewError(409, fmt.Errorf("login.repository.VerifyEmail : the email of %s changed since the token was sent", username))
+	}
+	return nil
+}
+
+// SetEmail changes the email of username, which is unverified until the
+// new owner follows the link sent to it.
+func (r Repo) SetEmail(ctx *gin.Context, username string, email string, version int64) *internal.Error {
+	query := r.conn(ctx).Model(&models.User{}).Where("username = ?", username)
+	if version > 0 {
+		query = query.Where("version = ?", version)
+	}
+	query = query.Updates(map[string]interface{}{"email": email, "email_verified_at": "", "version": gorm.Expr("version + 1"), "updated_at": utils.FormatTime()})
+	if err := query.Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("login.repository.SetEmail : %v", err.Error()))
+	}
+	if query.RowsAffected == 0 {
+		return r.conflict(ctx, "login.repository.SetEmail", username, version)
+	}
+	return nil
+}
+
+// CreateToken stores token, dropping the unused tokens of the same purpose
+// of the user so only the last one sent works.
+func (r Repo) CreateToken(ctx *gin.Context, token *models.Token) *internal.Error {
+	err := r.conn(ctx).Where("username = ? and purpose = ? and used_at = ''", token.Username, token.Purpose).Delete(&models.Token{}).Error
+	if err == nil {
+		err = r.conn(ctx).Create(token).Error
+	}
+	if err != nil {
+		return internal.NewError(500, fmt.Errorf("login.repository.CreateToken : %v", err.Error()))
+	}
+	return nil
+}
+
+// GetCredentials reads the whole user, secrets included, for the
+// authentication code only.
+func (r Repo) GetCredentials(ctx *gin.Context, username string) (*models.User, *internal.Error) {
+	var res *models.User
+	err := r.conn(ctx).Where("username = ?", username).Take(&res).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, internal.NewError(404, fmt.Errorf("login.repository.GetCredentials : %v", fmt.Errorf("no data found with id %s", username)))
+	}
+	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("login.repository.GetCredentials : %v", err.Error()))
+	}
+	return res, nil
+}
+
+// SetTwoFactor stores the TOTP secret of username; an empty enabledAt leaves
+// it pending and an empty secret turns two-factor authentication off.
+func (r Repo) SetTwoFactor(ctx *gin.Context, username string, secret string, enabledAt string, lastStep int64) *internal.Error {
+	query := r.conn(ctx).Model(&models.User{}).Where("username = ?", username).Updates(map[string]interface{}{
+		"totp_secret":           secret,
+		"totp_last_step":        lastStep,
+		"two_factor_enabled_at": enabledAt,
+		"updated_at":            utils.FormatTime(),
+	})
+	if err := query.Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("login.repository.SetTwoFactor : %v", err.Error()))
+	}
+	return nil
+}
+
+// SetTotpLastStep records the time step of an accepted code. It fails with
+// 400 when a code of that step or a later one was already accepted, so two
+// requests cannot both use the same code.
+func (r Repo) SetTotpLastStep(ctx *gin.Context, username string, step int64) *internal.Error {
+	query := r.conn(ctx).Model(&models.User{}).Where("username = ? and totp_last_step < ?", username, step).Update("totp_last_step", step)
+	if err := query.Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("login.repository.SetTotpLastStep : %v", err.Error()))
+	}
+	if query.RowsAffected == 0 {
+		return internal.NewError(400, fmt.Errorf("two-factor code invalid"))
+	}
+	return nil
+}
+
+func (r Repo) ReplaceRecoveryCodes(ctx *gin.Context, username string, codes []*models.RecoveryCode) *internal.Error {
+	err := r.conn(ctx).Where("username = ?", username).Delete(&models.RecoveryCode{}).Error
+	if err == nil && len(codes) > 0 {
+		err = r.conn(ctx).Create(codes).Error
+	}
+	if err != nil {
+		return internal.NewError(500, fmt.Errorf("login.repository.ReplaceRecoveryCodes : %v", err.Error()))
+	}
+	return nil
+}
+
+// UseRecoveryCode spends a recovery code of username, failing with 400 when
+// it is unknown or was used.
+func (r Repo) UseRecoveryCode(ctx *gin.Context, username string, codeHash string) *internal.Error {
+	query := r.conn(ctx).Model(&models.RecoveryCode{}).Where("code_hash = ? and username = ? and used_at = ''", codeHash, username).Update("used_at", utils.FormatTime())
+	if err := query.Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("login.repository.UseRecoveryCode : %v", err.Error()))
+	}
+	if query.RowsAffected == 0 {
+		return internal.NewError(400, fmt.Errorf("two-factor code invalid"))
+	}
+	return nil
+}
+
+func (r Repo) GetByOidcSubject(ctx *gin.Context, subject string) (*models.User, *internal.Error) {
+	var res *models.User
+	query := r.conn(ctx).Model(&models.User{}).Select(userColumns).Where("oidc_subject = ?", subject).Take(&res)
+	err := query.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, internal.NewError(404, fmt.Errorf("login.repository.GetByOidcSubject : no user linked to %s", subject))
+	}
+	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("login.repository.GetByOidcSubject : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func (r Repo) LinkOidc(ctx *gin.Context, username string, subject string) *internal.Error {
+	query := r.conn(ctx).Model(&models.User{}).Where("username = ?", username).Updates(map[string]interface{}{
+		"oidc_subject": subject,
+		"version":      gorm.Expr("version + 1"),
+		"updated_at":   utils.FormatTime(),
+	})
+	if err := query.Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("login.repository.LinkOidc : %v", err.Error()))
+	}
+	return nil
+}
+
+// CreateOIDCState stores a sign in started at the OIDC provider, dropping
+// the ones never finished.
+func (r Repo) CreateOIDCState(ctx *gin.Context, state *models.OIDCState) *internal.Error {
+	err := r.conn(ctx).Where("expires_at < ?", utils.FormatTime()).Delete(&models.OIDCState{}).Error
+	if err == nil {
+		err = r.conn(ctx).Create(state).Error
+	}
+	if err != nil {
+		return internal.NewError(500, fmt.Errorf("login.repository.CreateOIDCState : %v", err.Error()))
+	}
+	return nil
+}
+
+// TakeOIDCState finds a sign in and deletes it, so its state works once.
+func (r Repo) TakeOIDCState(ctx *gin.Context, stateHash string) (*models.OIDCState, *internal.Error) {
+	var res *models.OIDCState
+	err := r.conn(ctx).Where("state_hash = ?", stateHash).Take(&res).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, internal.NewError(404, fmt.Errorf("login.repository.TakeOIDCState : no state found"))
+	}
+	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("login.repository.TakeOIDCState : %v", err.Error()))
+	}
+	query := r.conn(ctx).Where("state_hash = ?", stateHash).Delete(&models.OIDCState{})
+	if err := query.Error; err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("login.repository.TakeOIDCState : %v", err.Error()))
+	}
+	if query.RowsAffected == 0 {
+		// another callback took it first
+		return nil, internal.NewError(404, fmt.Errorf("login.repository.TakeOIDCState : no state found"))
+	}
+	return res, nil
+}
+
+// GetTokenByHash finds a token, used or not, locking it until the
+// transaction of ctx ends.
+func (r Repo) GetTokenByHash(ctx *gin.Context, tokenHash string) (*models.Token, *internal.Error) {
+	var res *models.Token
+	query := r.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", tokenHash).Take(&res)
+	err := query.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, internal.NewError(404, fmt.Errorf("login.repository.GetTokenByHash : no token found"))
+	}
+	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("login.repository.GetTokenByHash : %v", err.Error()))
+	}
 	return res, nil
 }
+
+// UseToken marks a token as used, which fails with 409 when it was used
+// meanwhile.
+func (r Repo) UseToken(ctx *gin.Context, tokenHash string) *internal.Error {
+	query := r.conn(ctx).Model(&models.Token{}).Where("token_hash = ? and used_at = ''", tokenHash).Update("used_at", utils.FormatTime())
+	if err := query.Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("login.repository.UseToken : %v", err.Error()))
+	}
+	if query.RowsAffected == 0 {
+		return internal.NewError(409, fmt.Errorf("login.repository.UseToken : token was already used"))
+	}
+	return nil
+}
+
+func (r Repo) CreateInvitation(ctx *gin.Context, invitation *models.Invitation) *internal.Error {
+	if err := r.conn(ctx).Create(invitation).Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("login.repository.CreateInvitation : %v", err.Error()))
+	}
+	return nil
+}
+
+// GetInvitations lists the invitations that can still be used.
+func (r Repo) GetInvitations(ctx *gin.Context) ([]*models.Invitation, *internal.Error) {
+	var res []*models.Invitation
+	query := r.conn(ctx).Where("used_at = '' and expires_at > ?", utils.FormatTime()).Order("created_at desc").Find(&res)
+	if err := query.Error; err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("login.repository.GetInvitations : %v", err.Error()))
+	}
+	return res, nil
+}
+
+// GetInvitationByToken finds the invitation of a token, used or not, locking
+// it until the transaction of ctx ends.
+func (r Repo) GetInvitationByToken(ctx *gin.Context, tokenHash string) (*models.Invitation, *internal.Error) {
+	var res *models.Invitation
+	query := r.conn(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", tokenHash).Take(&res)
+	err := query.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, internal.NewError(404, fmt.Errorf("login.repository.GetInvitationByToken : no invitation found with this token"))
+	}
+	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("login.repository.GetInvitationByToken : %v", err.Error()))
+	}
+	return res, nil
+}
+
+// UseInvitation marks the invitation as used by username, which fails with
+// 409 when it was used meanwhile.
+func (r Repo) UseInvitation(ctx *gin.Context, id string, username string) *internal.Error {
+	query := r.conn(ctx).Model(&models.Invitation{}).Where("id = ? and used_at = ''", id).Updates(map[string]interface{}{"used_at": utils.FormatTime(), "used_by": username})
+	if err := query.Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("login.repository.UseInvitation : %v", err.Error()))
+	}
+	if query.RowsAffected == 0 {
+		return internal.NewError(409, fmt.Errorf("login.repository.UseInvitation : invitation %s was already used", id))
+	}
+	return nil
+}
+
+func (r Repo) DeleteInvitation(ctx *gin.Context, id string) (*models.Invitation, *internal.Error) {
+	var res *models.Invitation
+	err := r.conn(ctx).Where("id = ?", id).Take(&res).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, internal.NewError(404, fmt.Errorf("login.repository.DeleteInvitation : %v", fmt.Errorf("no data found with id %s", id)))
+	}
+	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("login.repository.DeleteInvitation : %v", err.Error()))
+	}
+	if err := r.conn(ctx).Where("id = ?", id).Delete(&models.Invitation{}).Error; err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("login.repository.DeleteInvitation : %v", err.Error()))
+	}
+	return res, nil
+}
+
+// Transaction runs fn with a repository bound to a single database
+// transaction, which other repositories called with the same ctx join,
+// rolling everything back when fn returns an error.
+func (r Repo) Transaction(ctx *gin.Context, fn func(repo Repository) *internal.Error) *internal.Error {
+	var res *internal.Error
+	err := database.Transaction(ctx, r.Dbconn, func(tx *gorm.DB) error {
+		if res = fn(&Repo{Dbconn: tx}); res != nil {
+			return res.Message
+		}
+		return nil
+	})
+	if res != nil {
+		return res
+	}
+	if err != nil {
+		return internal.NewError(500, fmt.Errorf("login.repository.Transaction : %v", err.Error()))
+	}
+	return nil
+}
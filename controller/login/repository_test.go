@@ -0,0 +1,94 @@
+package login
+
+import (
+	"testing"
+
+	"gin-dbo/framework/database/dbtest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var attemptColumns = []string{"username", "failures", "last_failure_at", "locked_until"}
+
+func TestGetAttemptFound(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `login_attempts` WHERE username = \\? LIMIT 1 FOR UPDATE").WithArgs("jane").
+		WillReturnRows(sqlmock.NewRows(attemptColumns).AddRow("jane", 2, "2026-10-19 10:00:00", ""))
+
+	res, err := NewRepository(db).GetAttempt(dbtest.Context(), "jane")
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	if res.Username != "jane" || res.Failures != 2 {
+		t.Fatalf("got attempt %+v", res)
+	}
+}
+
+func TestGetAttemptWithoutFailures(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `login_attempts` WHERE username = \\? LIMIT 1 FOR UPDATE").WithArgs("jane").
+		WillReturnRows(sqlmock.NewRows(attemptColumns))
+
+	res, err := NewRepository(db).GetAttempt(dbtest.Context(), "jane")
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	if res.Username != "jane" || res.Failures != 0 {
+		t.Fatalf("got attempt %+v, want an empty one of jane", res)
+	}
+}
+
+func TestGetByIdNotFound(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE username = \\? LIMIT 1").WithArgs("jane").
+		WillReturnRows(sqlmock.NewRows([]string{"username"}))
+
+	res, err := NewRepository(db).GetById(dbtest.Context(), "jane")
+	if err == nil || err.Code != 404 {
+		t.Fatalf("got %+v, %v; want 404", res, err)
+	}
+}
+
+func TestGetInvitationByUnknownToken(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `invitations` WHERE token_hash = \\?").WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	res, err := NewRepository(db).GetInvitationByToken(dbtest.Context(), "hash")
+	if err == nil || err.Code != 404 {
+		t.Fatalf("got %+v, %v; want 404", res, err)
+	}
+}
+
+func TestDeleteMissingInvitation(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `invitations` WHERE id = \\?").WithArgs("i1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	res, err := NewRepository(db).DeleteInvitation(dbtest.Context(), "i1")
+	if err == nil || err.Code != 404 {
+		t.Fatalf("got %+v, %v; want 404", res, err)
+	}
+}
+
+func TestGetCredentialsNotFound(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE username = \\? LIMIT 1").WithArgs("jane").
+		WillReturnRows(sqlmock.NewRows([]string{"username"}))
+
+	res, err := NewRepository(db).GetCredentials(dbtest.Context(), "jane")
+	if err == nil || err.Code != 404 {
+		t.Fatalf("got %+v, %v; want 404", res, err)
+	}
+}
+
+func TestTakeUnknownOIDCState(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `oidc_states` WHERE state_hash = \\? LIMIT 1").WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"state_hash"}))
+
+	res, err := NewRepository(db).TakeOIDCState(dbtest.Context(), "hash")
+	if err == nil || err.Code != 404 {
+		t.Fatalf("got %+v, %v; want 404", res, err)
+	}
+}
@@ -0,0 +1,312 @@
+package login
+
+import (
+	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/tracing"
+	models "gin-dbo/model/login"
+	mdl "gin-dbo/view/login"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tracedRepository opens a span around every repository method.
+type tracedRepository struct {
+	next Repository
+}
+
+func (t tracedRepository) Login(ctx *gin.Context, request *mdl.LoginRequest) (res *models.User, err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.Login").End(&err)
+	return t.next.Login(ctx, request)
+}
+
+func (t tracedRepository) Get(ctx *gin.Context, request *mdl.GetRequest, page int) (res []*models.User, err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.Get").End(&err)
+	return t.next.Get(ctx, request, page)
+}
+
+func (t tracedRepository) Count(ctx *gin.Context, request *mdl.GetRequest) (res int, err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.Count").End(&err)
+	return t.next.Count(ctx, request)
+}
+
+func (t tracedRepository) Export(ctx *gin.Context, request *mdl.GetRequest, fn func([]*models.User) error) (err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.Export").End(&err)
+	return t.next.Export(ctx, request, fn)
+}
+
+func (t tracedRepository) GetById(ctx *gin.Context, id string) (res *models.User, err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.GetById").End(&err)
+	return t.next.GetById(ctx, id)
+}
+
+func (t tracedRepository) Create(ctx *gin.Context, request *mdl.CreateRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.Create").End(&err)
+	return t.next.Create(ctx, request)
+}
+
+func (t tracedRepository) Update(ctx *gin.Context, request *mdl.UpdateRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.Update").End(&err)
+	return t.next.Update(ctx, request)
+}
+
+func (t tracedRepository) Delete(ctx *gin.Context, request *mdl.DeleteRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.Delete").End(&err)
+	return t.next.Delete(ctx, request)
+}
+
+func (t tracedRepository) GetAttempt(ctx *gin.Context, username string) (res *models.Attempt, err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.GetAttempt").End(&err)
+	return t.next.GetAttempt(ctx, username)
+}
+
+func (t tracedRepository) SaveAttempt(ctx *gin.Context, attempt *models.Attempt) (err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.SaveAttempt").End(&err)
+	return t.next.SaveAttempt(ctx, attempt)
+}
+
+func (t tracedRepository) DeleteAttempt(ctx *gin.Context, username string) (err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.DeleteAttempt").End(&err)
+	return t.next.DeleteAttempt(ctx, username)
+}
+
+func (t tracedRepository) GetByEmail(ctx *gin.Context, email string) (res *models.User, err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.GetByEmail").End(&err)
+	return t.next.GetByEmail(ctx, email)
+}
+
+func (t tracedRepository) SetPassword(ctx *gin.Context, username string, password string) (err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.SetPassword").End(&err)
+	return t.next.SetPassword(ctx, username, password)
+}
+
+func (t tracedRepository) VerifyEmail(ctx *gin.Context, username string, email string) (err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.VerifyEmail").End(&err)
+	return t.next.VerifyEmail(ctx, username, email)
+}
+
+func (t tracedRepository) SetEmail(ctx *gin.Context, username string, email string, version int64) (err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.SetEmail").End(&err)
+	return t.next.SetEmail(ctx, username, email, version)
+}
+
+func (t tracedRepository) CreateToken(ctx *gin.Context, token *models.Token) (err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.CreateToken").End(&err)
+	return t.next.CreateToken(ctx, token)
+}
+
+func (t tracedRepository) GetCredentials(ctx *gin.Context, username string) (res *models.User, err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.GetCredentials").End(&err)
+	return t.next.GetCredentials(ctx, username)
+}
+
+func (t tracedRepository) GetByOidcSubject(ctx *gin.Context, subject string) (res *models.User, err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.GetByOidcSubject").End(&err)
+	return t.next.GetByOidcSubject(ctx, subject)
+}
+
+func (t tracedRepository) LinkOidc(ctx *gin.Context, username string, subject string) (err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.LinkOidc").End(&err)
+	return t.next.LinkOidc(ctx, username, subject)
+}
+
+func (t tracedRepository) CreateOIDCState(ctx *gin.Context, state *models.OIDCState) (err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.CreateOIDCState").End(&err)
+	return t.next.CreateOIDCState(ctx, state)
+}
+
+func (t tracedRepository) TakeOIDCState(ctx *gin.Context, stateHash string) (res *models.OIDCState, err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.TakeOIDCState").End(&err)
+	return t.next.TakeOIDCState(ctx, stateHash)
+}
+
+func (t tracedRepository) SetTwoFactor(ctx *gin.Context, username string, secret string, enabledAt string, lastStep int64) (err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.SetTwoFactor").End(&err)
+	return t.next.SetTwoFactor(ctx, username, secret, enabledAt, lastStep)
+}
+
+func (t tracedRepository) SetTotpLastStep(ctx *gin.Context, username string, step int64) (err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.SetTotpLastStep").End(&err)
+	return t.next.SetTotpLastStep(ctx, username, step)
+}
+
+func (t tracedRepository) ReplaceRecoveryCodes(ctx *gin.Context, username string, codes []*models.RecoveryCode) (err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.ReplaceRecoveryCodes").End(&err)
+	return t.next.ReplaceRecoveryCodes(ctx, username, codes)
+}
+
+func (t tracedRepository) UseRecoveryCode(ctx *gin.Context, username string, codeHash string) (err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.UseRecoveryCode").End(&err)
+	return t.next.UseRecoveryCode(ctx, username, codeHash)
+}
+
+func (t tracedRepository) GetTokenByHash(ctx *gin.Context, tokenHash string) (res *models.Token, err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.GetTokenByHash").End(&err)
+	return t.next.GetTokenByHash(ctx, tokenHash)
+}
+
+func (t tracedRepository) UseToken(ctx *gin.Context, tokenHash string) (err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.UseToken").End(&err)
+	return t.next.UseToken(ctx, tokenHash)
+}
+
+func (t tracedRepository) CreateInvitation(ctx *gin.Context, invitation *models.Invitation) (err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.CreateInvitation").End(&err)
+	return t.next.CreateInvitation(ctx, invitation)
+}
+
+func (t tracedRepository) GetInvitations(ctx *gin.Context) (res []*models.Invitation, err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.GetInvitations").End(&err)
+	return t.next.GetInvitations(ctx)
+}
+
+func (t tracedRepository) GetInvitationByToken(ctx *gin.Context, tokenHash string) (res *models.Invitation, err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.GetInvitationByToken").End(&err)
+	return t.next.GetInvitationByToken(ctx, tokenHash)
+}
+
+func (t tracedRepository) UseInvitation(ctx *gin.Context, id string, username string) (err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.UseInvitation").End(&err)
+	return t.next.UseInvitation(ctx, id, username)
+}
+
+func (t tracedRepository) DeleteInvitation(ctx *gin.Context, id string) (res *models.Invitation, err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.DeleteInvitation").End(&err)
+	return t.next.DeleteInvitation(ctx, id)
+}
+
+func (t tracedRepository) Transaction(ctx *gin.Context, fn func(repo Repository) *internal.Error) (err *internal.Error) {
+	defer tracing.Start(ctx, "login.repository.Transaction").End(&err)
+	return t.next.Transaction(ctx, func(repo Repository) *internal.Error {
+		return fn(tracedRepository{next: repo})
+	})
+}
+
+// tracedUsecase opens a span around every usecase method.
+type tracedUsecase struct {
+	next Usecase
+}
+
+func (t tracedUsecase) Login(ctx *gin.Context, request *mdl.LoginRequest) (res mdl.ResponseLogin, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.Login").End(&err)
+	return t.next.Login(ctx, request)
+}
+
+func (t tracedUsecase) GetMe(ctx *gin.Context) (res mdl.ResponseDetail, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.GetMe").End(&err)
+	return t.next.GetMe(ctx)
+}
+
+func (t tracedUsecase) PatchMe(ctx *gin.Context, request *mdl.PatchRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.PatchMe").End(&err)
+	return t.next.PatchMe(ctx, request)
+}
+
+func (t tracedUsecase) ChangePassword(ctx *gin.Context, request *mdl.ChangePasswordRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.ChangePassword").End(&err)
+	return t.next.ChangePassword(ctx, request)
+}
+
+func (t tracedUsecase) Get(ctx *gin.Context, request *mdl.GetRequest) (res mdl.ResponseData, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.Get").End(&err)
+	return t.next.Get(ctx, request)
+}
+
+func (t tracedUsecase) Export(ctx *gin.Context, request *mdl.GetRequest, fn func([]*models.User) error) (err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.Export").End(&err)
+	return t.next.Export(ctx, request, fn)
+}
+
+func (t tracedUsecase) GetById(ctx *gin.Context, id string) (res mdl.ResponseDetail, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.GetById").End(&err)
+	return t.next.GetById(ctx, id)
+}
+
+func (t tracedUsecase) Create(ctx *gin.Context, request *mdl.CreateRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.Create").End(&err)
+	return t.next.Create(ctx, request)
+}
+
+func (t tracedUsecase) Register(ctx *gin.Context, request *mdl.RegisterRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.Register").End(&err)
+	return t.next.Register(ctx, request)
+}
+
+func (t tracedUsecase) LoginTwoFactor(ctx *gin.Context, request *mdl.LoginTwoFactorRequest) (res mdl.ResponseLogin, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.LoginTwoFactor").End(&err)
+	return t.next.LoginTwoFactor(ctx, request)
+}
+
+func (t tracedUsecase) OIDCLogin(ctx *gin.Context) (res string, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.OIDCLogin").End(&err)
+	return t.next.OIDCLogin(ctx)
+}
+
+func (t tracedUsecase) OIDCCallback(ctx *gin.Context, request *mdl.OIDCCallbackRequest) (res mdl.ResponseLogin, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.OIDCCallback").End(&err)
+	return t.next.OIDCCallback(ctx, request)
+}
+
+func (t tracedUsecase) SetupTwoFactor(ctx *gin.Context) (res mdl.ResponseTwoFactorSetup, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.SetupTwoFactor").End(&err)
+	return t.next.SetupTwoFactor(ctx)
+}
+
+func (t tracedUsecase) ConfirmTwoFactor(ctx *gin.Context, request *mdl.TwoFactorCodeRequest) (res mdl.ResponseRecoveryCodes, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.ConfirmTwoFactor").End(&err)
+	return t.next.ConfirmTwoFactor(ctx, request)
+}
+
+func (t tracedUsecase) DisableTwoFactor(ctx *gin.Context, request *mdl.TwoFactorCodeRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.DisableTwoFactor").End(&err)
+	return t.next.DisableTwoFactor(ctx, request)
+}
+
+func (t tracedUsecase) RegenerateRecoveryCodes(ctx *gin.Context, request *mdl.TwoFactorCodeRequest) (res mdl.ResponseRecoveryCodes, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.RegenerateRecoveryCodes").End(&err)
+	return t.next.RegenerateRecoveryCodes(ctx, request)
+}
+
+func (t tracedUsecase) VerifyEmail(ctx *gin.Context, request *mdl.VerifyEmailRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.VerifyEmail").End(&err)
+	return t.next.VerifyEmail(ctx, request)
+}
+
+func (t tracedUsecase) ForgotPassword(ctx *gin.Context, request *mdl.ForgotPasswordRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.ForgotPassword").End(&err)
+	return t.next.ForgotPassword(ctx, request)
+}
+
+func (t tracedUsecase) ResetPassword(ctx *gin.Context, request *mdl.ResetPasswordRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.ResetPassword").End(&err)
+	return t.next.ResetPassword(ctx, request)
+}
+
+func (t tracedUsecase) CreateInvitation(ctx *gin.Context, request *mdl.CreateInvitationRequest) (res mdl.ResponseInvitation, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.CreateInvitation").End(&err)
+	return t.next.CreateInvitation(ctx, request)
+}
+
+func (t tracedUsecase) GetInvitations(ctx *gin.Context) (res mdl.ResponseInvitations, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.GetInvitations").End(&err)
+	return t.next.GetInvitations(ctx)
+}
+
+func (t tracedUsecase) DeleteInvitation(ctx *gin.Context, request *mdl.DeleteInvitationRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.DeleteInvitation").End(&err)
+	return t.next.DeleteInvitation(ctx, request)
+}
+
+func (t tracedUsecase) Update(ctx *gin.Context, request *mdl.UpdateRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.Update").End(&err)
+	return t.next.Update(ctx, request)
+}
+
+func (t tracedUsecase) Patch(ctx *gin.Context, request *mdl.PatchRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.Patch").End(&err)
+	return t.next.Patch(ctx, request)
+}
+
+func (t tracedUsecase) Delete(ctx *gin.Context, request *mdl.DeleteRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "login.usecase.Delete").End(&err)
+	return t.next.Delete(ctx, request)
+}
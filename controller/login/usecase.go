@@ -1,44 +1,804 @@
 package login
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	auditModel "gin-dbo/model/audit"
+	eventModel "gin-dbo/model/event"
+	models "gin-dbo/model/login"
 	mdl "gin-dbo/view/login"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
+	"gin-dbo/controller/audit"
 	"gin-dbo/controller/customer"
+	"gin-dbo/framework/config"
 	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/event"
+	"gin-dbo/framework/logger"
+	"gin-dbo/framework/mail"
+	"gin-dbo/framework/metrics"
+	"gin-dbo/framework/middleware"
+	"gin-dbo/framework/oidc"
+	"gin-dbo/framework/patch"
+	"gin-dbo/framework/twofactor"
 	"gin-dbo/framework/utils"
 	customerView "gin-dbo/view/customer"
 )
 
+const (
+	resource         = "user"
+	customerResource = "customer"
+	maskedPassword   = "********"
+
+	invitationResource = "invitation"
+	tokenBytes         = 32
+)
+
 type UsecaseModul struct {
 	Repo         Repository
 	CustomerRepo customer.Repository
+	Audit        audit.Recorder
+	Events       event.Publisher
+	Mailer       mail.Sender
+	Lockout      config.Lockout
+	Invitation   config.Invitation
+	Account      config.Account
+	Mail         config.Mail
+	JWT          middleware.JWTService
+	TwoFactor    config.TwoFactor
+	// OIDC is nil unless OIDC sign in is enabled.
+	OIDC       *oidc.Provider
+	OIDCConfig config.OIDC
 }
 
 type Usecase interface {
 	Login(ctx *gin.Context, request *mdl.LoginRequest) (res mdl.ResponseLogin, err *internal.Error)
+	LoginTwoFactor(ctx *gin.Context, request *mdl.LoginTwoFactorRequest) (res mdl.ResponseLogin, err *internal.Error)
+	OIDCLogin(ctx *gin.Context) (res string, err *internal.Error)
+	OIDCCallback(ctx *gin.Context, request *mdl.OIDCCallbackRequest) (res mdl.ResponseLogin, err *internal.Error)
+	SetupTwoFactor(ctx *gin.Context) (res mdl.ResponseTwoFactorSetup, err *internal.Error)
+	ConfirmTwoFactor(ctx *gin.Context, request *mdl.TwoFactorCodeRequest) (res mdl.ResponseRecoveryCodes, err *internal.Error)
+	DisableTwoFactor(ctx *gin.Context, request *mdl.TwoFactorCodeRequest) (res mdl.GeneralResponse, err *internal.Error)
+	RegenerateRecoveryCodes(ctx *gin.Context, request *mdl.TwoFactorCodeRequest) (res mdl.ResponseRecoveryCodes, err *internal.Error)
+	GetMe(ctx *gin.Context) (res mdl.ResponseDetail, err *internal.Error)
+	PatchMe(ctx *gin.Context, request *mdl.PatchRequest) (res mdl.GeneralResponse, err *internal.Error)
+	ChangePassword(ctx *gin.Context, request *mdl.ChangePasswordRequest) (res mdl.GeneralResponse, err *internal.Error)
 	Get(ctx *gin.Context, request *mdl.GetRequest) (res mdl.ResponseData, err *internal.Error)
+	Export(ctx *gin.Context, request *mdl.GetRequest, fn func([]*models.User) error) (err *internal.Error)
 	GetById(ctx *gin.Context, id string) (res mdl.ResponseDetail, err *internal.Error)
 	Create(ctx *gin.Context, request *mdl.CreateRequest) (res mdl.GeneralResponse, err *internal.Error)
+	Register(ctx *gin.Context, request *mdl.RegisterRequest) (res mdl.GeneralResponse, err *internal.Error)
+	VerifyEmail(ctx *gin.Context, request *mdl.VerifyEmailRequest) (res mdl.GeneralResponse, err *internal.Error)
+	ForgotPassword(ctx *gin.Context, request *mdl.ForgotPasswordRequest) (res mdl.GeneralResponse, err *internal.Error)
+	ResetPassword(ctx *gin.Context, request *mdl.ResetPasswordRequest) (res mdl.GeneralResponse, err *internal.Error)
+	CreateInvitation(ctx *gin.Context, request *mdl.CreateInvitationRequest) (res mdl.ResponseInvitation, err *internal.Error)
+	GetInvitations(ctx *gin.Context) (res mdl.ResponseInvitations, err *internal.Error)
+	DeleteInvitation(ctx *gin.Context, request *mdl.DeleteInvitationRequest) (res mdl.GeneralResponse, err *internal.Error)
 	Update(ctx *gin.Context, request *mdl.UpdateRequest) (res mdl.GeneralResponse, err *internal.Error)
+	Patch(ctx *gin.Context, request *mdl.PatchRequest) (res mdl.GeneralResponse, err *internal.Error)
 	Delete(ctx *gin.Context, request *mdl.DeleteRequest) (res mdl.GeneralResponse, err *internal.Error)
 }
 
-func NewUsecase(u Repository, c customer.Repository) Usecase {
-	return &UsecaseModul{Repo: u, CustomerRepo: c}
+func NewUsecase(u Repository, c customer.Repository, a audit.Recorder, e event.Publisher, m mail.Sender, jwt middleware.JWTService, cfg *config.Config) Usecase {
+	modul := &UsecaseModul{
+		Repo:         u,
+		CustomerRepo: c,
+		Audit:        a,
+		Events:       e,
+		Mailer:       m,
+		Lockout:      cfg.Lockout,
+		Invitation:   cfg.Invitation,
+		Account:      cfg.Account,
+		Mail:         cfg.Mail,
+		JWT:          jwt,
+		TwoFactor:    cfg.TwoFactor,
+		OIDCConfig:   cfg.OIDC,
+	}
+	if cfg.OIDC.Enabled {
+		modul.OIDC = oidc.New(cfg.OIDC)
+	}
+	return tracedUsecase{next: modul}
 }
 
+// Login issues a token for valid credentials. Once Lockout.Threshold logins
+// of a username failed in a row it is locked out, longer on every further
+// failure, and refused with 429 until the lock expires, even with the right
+// password. Users with two-factor authentication get a challenge token
+// instead, to send with their code to LoginTwoFactor.
 func (u *UsecaseModul) Login(ctx *gin.Context, param *mdl.LoginRequest) (mdl.ResponseLogin, *internal.Error) {
 	var res mdl.ResponseLogin
-	res, err := u.Repo.Login(ctx, param)
+	attempt, err := u.checkLockout(ctx, param.Username)
+	if err != nil {
+		return res, err
+	}
+
+	user, err := u.Repo.Login(ctx, param)
+	if err != nil {
+		return res, u.loginFailed(ctx, param.Username, err)
+	}
+
+	if user.TwoFactorEnabledAt != "" {
+		token, err := u.issueToken(ctx, models.TokenLoginChallenge, user.Username, "", u.TwoFactor.ChallengeTTL.Duration())
+		if err != nil {
+			metrics.Logins.WithLabelValues(metrics.LoginError).Inc()
+			return res, err
+		}
+		res.Data.TwoFactorRequired = true
+		res.Data.ChallengeToken = token
+		logger.FromContext(ctx).Infof("login.usecase.Login : %s waits for the two-factor code", param.Username)
+		metrics.Logins.WithLabelValues(metrics.LoginChallenged).Inc()
+		return res, nil
+	}
+	return u.loginSucceeded(ctx, user, attempt)
+}
+
+// LoginTwoFactor completes a login with the challenge token of Login and a
+// TOTP or recovery code. Wrong codes count towards the lockout like wrong
+// passwords, and the challenge stays usable until it expires.
+func (u *UsecaseModul) LoginTwoFactor(ctx *gin.Context, param *mdl.LoginTwoFactorRequest) (mdl.ResponseLogin, *internal.Error) {
+	var res mdl.ResponseLogin
+	token, err := u.Repo.GetTokenByHash(ctx, utils.Encrypt(param.ChallengeToken))
+	if err != nil && err.Code != 404 {
+		return res, err
+	}
+	if err != nil || token.Purpose != models.TokenLoginChallenge || token.UsedAt != "" || token.ExpiresAt < utils.FormatTime() {
+		return res, internal.NewError(401, fmt.Errorf("challenge token is invalid, used or expired, log in again"))
+	}
+
+	attempt, err := u.checkLockout(ctx, token.Username)
+	if err != nil {
+		return res, err
+	}
+	user, err := u.Repo.GetCredentials(ctx, token.Username)
+	if err != nil {
+		return res, err
+	}
+	err = u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		if err := u.verifyCode(ctx, repo, user, param.Code); err != nil {
+			return err
+		}
+		return repo.UseToken(ctx, token.TokenHash)
+	})
+	if err != nil {
+		return res, u.loginFailed(ctx, token.Username, err)
+	}
+	return u.loginSucceeded(ctx, user, attempt)
+}
+
+// OIDCLogin starts a sign in at the OIDC provider and returns where to send
+// the user, PKCE binding the code it comes back with to this sign in.
+func (u *UsecaseModul) OIDCLogin(ctx *gin.Context) (string, *internal.Error) {
+	if u.OIDC == nil {
+		return "", internal.NewError(404, fmt.Errorf("OIDC sign in is not enabled"))
+	}
+	var values [3]string
+	for i := range values {
+		value, errn := oidc.NewVerifier()
+		if errn != nil {
+			return "", internal.NewError(500, fmt.Errorf("login.usecase.OIDCLogin : %v", errn))
+		}
+		values[i] = value
+	}
+	state, verifier, nonce := values[0], values[1], values[2]
+
+	err := u.Repo.CreateOIDCState(ctx, &models.OIDCState{
+		StateHash: utils.Encrypt(state),
+		Verifier:  verifier,
+		Nonce:     nonce,
+		ExpiresAt: utils.FormatTimeAfter(u.OIDCConfig.StateTTL.Duration()),
+		CreatedAt: utils.FormatTime(),
+	})
+	if err != nil {
+		return "", err
+	}
+	location, errn := u.OIDC.AuthCodeURL(ctx.Request.Context(), state, nonce, verifier)
+	if errn != nil {
+		return "", internal.NewError(502, fmt.Errorf("login.usecase.OIDCLogin : %v", errn))
+	}
+	return location, nil
+}
+
+// OIDCCallback finishes a sign in at the OIDC provider and issues the token
+// of the user, provisioning it on its first sign in. The provider is trusted
+// with the second factor, so no two-factor code is asked.
+func (u *UsecaseModul) OIDCCallback(ctx *gin.Context, param *mdl.OIDCCallbackRequest) (mdl.ResponseLogin, *internal.Error) {
+	var res mdl.ResponseLogin
+	if u.OIDC == nil {
+		return res, internal.NewError(404, fmt.Errorf("OIDC sign in is not enabled"))
+	}
+	state, err := u.Repo.TakeOIDCState(ctx, utils.Encrypt(param.State))
+	if err != nil && err.Code != 404 {
+		return res, err
+	}
+	if err != nil || state.ExpiresAt < utils.FormatTime() {
+		return res, internal.NewError(400, fmt.Errorf("sign in state is invalid or expired, start again"))
+	}
+	if param.Error != "" {
+		metrics.Logins.WithLabelValues(metrics.LoginFailed).Inc()
+		return res, internal.NewError(401, fmt.Errorf("identity provider refused the sign in : %s %s", param.Error, param.ErrorDescription))
+	}
+
+	identity, errn := u.OIDC.Exchange(ctx.Request.Context(), param.Code, state.Verifier, state.Nonce)
+	if errn != nil {
+		metrics.Logins.WithLabelValues(metrics.LoginFailed).Inc()
+		return res, internal.NewError(401, fmt.Errorf("login.usecase.OIDCCallback : %v", errn))
+	}
+	role := u.oidcRole(identity.Groups)
+	if role == "" {
+		logger.FromContext(ctx).Warnf("login.usecase.OIDCCallback : %s is in none of the groups allowed to sign in : %v", identity.Subject, identity.Groups)
+		metrics.Logins.WithLabelValues(metrics.LoginFailed).Inc()
+		return res, internal.NewError(403, fmt.Errorf("your account is in none of the groups allowed to sign in"))
+	}
+
+	user, err := u.provision(ctx, identity, role)
+	if err != nil {
+		metrics.Logins.WithLabelValues(metrics.LoginError).Inc()
+		return res, err
+	}
+	res.Data.Token = u.JWT.GenerateToken(user)
+	logger.FromContext(ctx).Infof("login.usecase.OIDCCallback : %s signed in through OIDC as %s", user.Username, user.Role)
+	metrics.Logins.WithLabelValues(metrics.LoginSucceeded).Inc()
+	return res, nil
+}
+
+// oidcRole maps the groups of an OIDC identity to a role, admin winning over
+// customer, empty when the identity may not sign in.
+func (u *UsecaseModul) oidcRole(groups []string) string {
+	inAny := func(allowed []string) bool {
+		for _, group := range groups {
+			for _, a := range allowed {
+				if group == a {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if inAny(u.OIDCConfig.AdminGroups) {
+		return models.RoleAdmin
+	}
+	if inAny(u.OIDCConfig.CustomerGroups) {
+		return models.RoleCustomer
+	}
+	return u.OIDCConfig.DefaultRole
+}
+
+// provision finds the user of an OIDC identity and keeps its role in line
+// with the provider. An unknown identity is linked to the user owning its
+// email when the provider verified it, or else gets a new user named after
+// its username claim, with a random password it never learns.
+func (u *UsecaseModul) provision(ctx *gin.Context, identity *oidc.Identity, role string) (*models.User, *internal.Error) {
+	var user *models.User
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		var err *internal.Error
+		user, err = repo.GetByOidcSubject(ctx, identity.Subject)
+		if err != nil && err.Code == 404 && identity.Email != "" && identity.EmailVerified {
+			user, err = repo.GetByEmail(ctx, identity.Email)
+		}
+		if err != nil && err.Code != 404 {
+			return err
+		}
+		if err != nil {
+			user, err = u.provisionUser(ctx, repo, identity, role)
+			return err
+		}
+		if user.OidcSubject == identity.Subject && user.Role == role {
+			return nil
+		}
+		if user.OidcSubject != "" && user.OidcSubject != identity.Subject {
+			return internal.NewError(409, fmt.Errorf("the user owning %s is linked to another identity", identity.Email))
+		}
+
+		if user.OidcSubject != identity.Subject {
+			if err = repo.LinkOidc(ctx, user.Username, identity.Subject); err != nil {
+				return err
+			}
+			logger.FromContext(ctx).Infof("login.usecase.OIDCCallback : linked %s to its OIDC identity", user.Username)
+		}
+		var (
+			entries []*auditModel.Audit
+			events  []*eventModel.Event
+		)
+		if user.Role != role {
+			customerId := user.CustomerId
+			if role == models.RoleCustomer && customerId == "" {
+				id, entry, customerEvent, err := u.newCustomer(ctx, user.Username, user.Email)
+				if err != nil {
+					return err
+				}
+				customerId = id
+				entries = append(entries, entry)
+				events = append(events, customerEvent)
+			}
+			if _, err = repo.Update(ctx, &mdl.UpdateRequest{Username: user.Username, Role: role, CustomerId: customerId}); err != nil {
+				return err
+			}
+			logger.FromContext(ctx).Infof("login.usecase.OIDCCallback : %s is now %s as its groups say", user.Username, role)
+		}
+		after, err := repo.GetById(ctx, user.Username)
+		if err != nil {
+			return err
+		}
+		events = append(events, event.New(ctx, eventModel.UserUpdated, resource, user.Username, after))
+		entries = append(entries, audit.NewEntry(ctx, auditModel.ActionUpdate, resource, user.Username, user, after))
+		if err = u.Audit.Record(ctx, entries...); err != nil {
+			return err
+		}
+		user = after
+		return u.Events.Publish(ctx, events...)
+	})
+	return user, err
+}
+
+func (u *UsecaseModul) provisionUser(ctx *gin.Context, repo Repository, identity *oidc.Identity, role string) (*models.User, *internal.Error) {
+	username := identity.Username
+	if utils.Validate.Var(username, "username") != nil {
+		return nil, internal.NewError(400, fmt.Errorf("the %s claim %q is not a valid username", u.OIDCConfig.UsernameClaim, username))
+	}
+	if _, err := repo.GetById(ctx, username); err == nil {
+		return nil, internal.NewError(409, fmt.Errorf("username %s is already taken by a user not linked to this identity", username))
+	} else if err.Code != 404 {
+		return nil, err
+	}
+	password, errn := newToken()
+	if errn != nil {
+		return nil, internal.NewError(500, fmt.Errorf("login.usecase.OIDCCallback : %v", errn))
+	}
+	var email string
+	if identity.EmailVerified {
+		email = identity.Email
+	}
+
+	if _, err := u.create(ctx, &mdl.CreateRequest{Username: username, Password: password, Role: role, Email: email}); err != nil {
+		return nil, err
+	}
+	if email != "" {
+		if err := repo.VerifyEmail(ctx, username, email); err != nil {
+			return nil, err
+		}
+	}
+	if err := repo.LinkOidc(ctx, username, identity.Subject); err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Infof("login.usecase.OIDCCallback : provisioned %s as %s", username, role)
+	return repo.GetById(ctx, username)
+}
+
+// checkLockout refuses a locked out username and returns its failed
+// attempts.
+func (u *UsecaseModul) checkLockout(ctx *gin.Context, username string) (*models.Attempt, *internal.Error) {
+	if u.Lockout.Threshold == 0 {
+		return &models.Attempt{Username: username}, nil
+	}
+	attempt, err := u.Repo.GetAttempt(ctx, username)
+	if err != nil {
+		metrics.Logins.WithLabelValues(metrics.LoginError).Inc()
+		return nil, err
+	}
+	if err = locked(ctx, attempt); err != nil {
+		logger.FromContext(ctx).Warnf("login.usecase.Login : %s is locked out until %s", username, attempt.LockedUntil)
+		metrics.Logins.WithLabelValues(metrics.LoginLocked).Inc()
+		return nil, err
+	}
+	return attempt, nil
+}
+
+// loginFailed counts a failed login of username when err means bad
+// credentials, and returns the error to answer with.
+func (u *UsecaseModul) loginFailed(ctx *gin.Context, username string, err *internal.Error) *internal.Error {
+	logger.FromContext(ctx).Warnf("login.usecase.Login : %s failed to log in : %v", username, err.Message)
+	if err.Code != 400 {
+		metrics.Logins.WithLabelValues(metrics.LoginError).Inc()
+		return err
+	}
+	metrics.Logins.WithLabelValues(metrics.LoginFailed).Inc()
+	if u.Lockout.Threshold > 0 {
+		if failure := u.recordFailure(ctx, username); failure != nil {
+			return failure
+		}
+	}
+	return err
+}
+
+// loginSucceeded clears the failed attempts of user and issues its token.
+// Admins without two-factor authentication only get an enrollment token when
+// TwoFactor.RequireAdmin is set.
+func (u *UsecaseModul) loginSucceeded(ctx *gin.Context, user *models.User, attempt *models.Attempt) (mdl.ResponseLogin, *internal.Error) {
+	var res mdl.ResponseLogin
+	if attempt.Failures > 0 {
+		if err := u.Repo.DeleteAttempt(ctx, user.Username); err != nil {
+			return res, err
+		}
+	}
+	if u.TwoFactor.RequireAdmin && user.Role == models.RoleAdmin && user.TwoFactorEnabledAt == "" {
+		res.Data.Token = u.JWT.GenerateEnrollmentToken(user)
+		res.Data.TwoFactorSetupRequired = true
+		logger.FromContext(ctx).Infof("login.usecase.Login : %s logged in and must set up two-factor authentication", user.Username)
+	} else {
+		res.Data.Token = u.JWT.GenerateToken(user)
+		logger.FromContext(ctx).Infof("login.usecase.Login : %s logged in", user.Username)
+	}
+	metrics.Logins.WithLabelValues(metrics.LoginSucceeded).Inc()
+	return res, nil
+}
+
+// recordFailure counts a failed login of username, forgetting failures older
+// than Lockout.Window, and locks it from the threshold on for a duration
+// doubling with every failure.
+func (u *UsecaseModul) recordFailure(ctx *gin.Context, username string) *internal.Error {
+	return u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		attempt, err := repo.GetAttempt(ctx, username)
+		if err != nil {
+			return err
+		}
+		if attempt.LastFailureAt < utils.FormatTimeAfter(-u.Lockout.Window.Duration()) {
+			attempt.Failures = 0
+		}
+		attempt.Failures++
+		attempt.LastFailureAt = utils.FormatTime()
+		if excess := attempt.Failures - u.Lockout.Threshold; excess >= 0 {
+			duration := utils.Backoff(excess+1, u.Lockout.Duration.Duration(), u.Lockout.MaxDuration.Duration())
+			attempt.LockedUntil = utils.FormatTimeAfter(duration)
+			logger.FromContext(ctx).Warnf("login.usecase.Login : %s locked out for %s after %d failed logins", username, duration, attempt.Failures)
+		}
+		return repo.SaveAttempt(ctx, attempt)
+	})
+}
+
+// locked refuses a login while the lock of attempt holds and tells the
+// client when to retry.
+func locked(ctx *gin.Context, attempt *models.Attempt) *internal.Error {
+	if attempt.LockedUntil == "" {
+		return nil
+	}
+	until, err := utils.ParseTime(attempt.LockedUntil)
+	if err != nil {
+		return internal.NewError(500, fmt.Errorf("login.usecase.Login : %v", err))
+	}
+	wait := time.Until(until)
+	if wait <= 0 {
+		return nil
+	}
+	retry := int(math.Ceil(wait.Seconds()))
+	ctx.Header(middleware.RetryAfter, strconv.Itoa(retry))
+	return internal.NewError(429, fmt.Errorf("account is locked after too many failed logins, retry in %d seconds", retry))
+}
+
+// SetupTwoFactor generates a TOTP secret for the caller, pending until
+// ConfirmTwoFactor proves the authenticator app enrolled it. Calling it again
+// replaces the pending secret.
+func (u *UsecaseModul) SetupTwoFactor(ctx *gin.Context) (mdl.ResponseTwoFactorSetup, *internal.Error) {
+	var res mdl.ResponseTwoFactorSetup
+	user, err := u.currentUser(ctx)
+	if err != nil {
+		return res, err
+	}
+	if user.TwoFactorEnabledAt != "" {
+		return res, internal.NewError(409, fmt.Errorf("two-factor authentication is already enabled, disable it first"))
+	}
+	key, errn := twofactor.Generate(u.TwoFactor.Issuer, user.Username)
+	if errn != nil {
+		return res, internal.NewError(500, fmt.Errorf("login.usecase.SetupTwoFactor : %v", errn))
+	}
+	if err = u.Repo.SetTwoFactor(ctx, user.Username, key.Secret, "", 0); err != nil {
+		return res, err
+	}
+	res.Data.Secret = key.Secret
+	res.Data.Uri = key.URI
+	res.Data.QrCode = key.QRCode
+	return res, nil
+}
+
+// ConfirmTwoFactor enables the secret of SetupTwoFactor with a code of it
+// and returns the recovery codes. Callers holding an enrollment token get a
+// full token along.
+func (u *UsecaseModul) ConfirmTwoFactor(ctx *gin.Context, param *mdl.TwoFactorCodeRequest) (mdl.ResponseRecoveryCodes, *internal.Error) {
+	var res mdl.ResponseRecoveryCodes
+	user, err := u.currentUser(ctx)
+	if err != nil {
+		return res, err
+	}
+	if user.TwoFactorEnabledAt != "" {
+		return res, internal.NewError(409, fmt.Errorf("two-factor authentication is already enabled"))
+	}
+	if user.TotpSecret == "" {
+		return res, internal.NewError(400, fmt.Errorf("set up two-factor authentication first"))
+	}
+	step, ok := twofactor.Validate(user.TotpSecret, param.Code, time.Now(), 0)
+	if !ok {
+		return res, internal.NewError(400, fmt.Errorf("two-factor code invalid"))
+	}
+
+	err = u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		before, err := repo.GetById(ctx, user.Username)
+		if err != nil {
+			return err
+		}
+		if err = repo.SetTwoFactor(ctx, user.Username, user.TotpSecret, utils.FormatTime(), step); err != nil {
+			return err
+		}
+		if res.Data.RecoveryCodes, err = u.replaceRecoveryCodes(ctx, repo, user.Username); err != nil {
+			return err
+		}
+		after, err := repo.GetById(ctx, user.Username)
+		if err != nil {
+			return err
+		}
+		return u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionUpdate, resource, user.Username, before, after))
+	})
+	if err != nil {
+		return res, err
+	}
+	if claims := requestClaims(ctx); claims != nil && claims.MustEnroll {
+		res.Data.Token = u.JWT.GenerateToken(user)
+	}
+	logger.FromContext(ctx).Infof("login.usecase.ConfirmTwoFactor : %s enabled two-factor authentication", user.Username)
+	return res, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off after checking a
+// TOTP or recovery code, and drops the secret and the recovery codes.
+func (u *UsecaseModul) DisableTwoFactor(ctx *gin.Context, param *mdl.TwoFactorCodeRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	user, err := u.currentUser(ctx)
+	if err != nil {
+		return res, err
+	}
+	if user.TwoFactorEnabledAt == "" {
+		return res, internal.NewError(409, fmt.Errorf("two-factor authentication is not enabled"))
+	}
+	err = u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		if err := u.verifyCode(ctx, repo, user, param.Code); err != nil {
+			return err
+		}
+		before, err := repo.GetById(ctx, user.Username)
+		if err != nil {
+			return err
+		}
+		if err = repo.SetTwoFactor(ctx, user.Username, "", "", 0); err != nil {
+			return err
+		}
+		if err = repo.ReplaceRecoveryCodes(ctx, user.Username, nil); err != nil {
+			return err
+		}
+		after, err := repo.GetById(ctx, user.Username)
+		if err != nil {
+			return err
+		}
+		return u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionUpdate, resource, user.Username, before, after))
+	})
+	if err == nil {
+		logger.FromContext(ctx).Infof("login.usecase.DisableTwoFactor : %s disabled two-factor authentication", user.Username)
+	}
+	return res, err
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of the caller, used or
+// not, after checking a TOTP or recovery code.
+func (u *UsecaseModul) RegenerateRecoveryCodes(ctx *gin.Context, param *mdl.TwoFactorCodeRequest) (mdl.ResponseRecoveryCodes, *internal.Error) {
+	var res mdl.ResponseRecoveryCodes
+	user, err := u.currentUser(ctx)
+	if err != nil {
+		return res, err
+	}
+	if user.TwoFactorEnabledAt == "" {
+		return res, internal.NewError(409, fmt.Errorf("two-factor authentication is not enabled"))
+	}
+	err = u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		if err := u.verifyCode(ctx, repo, user, param.Code); err != nil {
+			return err
+		}
+		codes, err := u.replaceRecoveryCodes(ctx, repo, user.Username)
+		res.Data.RecoveryCodes = codes
+		return err
+	})
+	return res, err
+}
+
+// GetMe reads the account of the caller.
+func (u *UsecaseModul) GetMe(ctx *gin.Context) (mdl.ResponseDetail, *internal.Error) {
+	claims := requestClaims(ctx)
+	if claims == nil {
+		return mdl.ResponseDetail{}, internal.NewError(401, fmt.Errorf("login.usecase.GetMe : no authenticated user"))
+	}
+	return u.GetById(ctx, claims.Username)
+}
+
+// PatchMe applies a merge patch to the account of the caller. A new email
+// stays unverified until the link mailed to it is followed.
+func (u *UsecaseModul) PatchMe(ctx *gin.Context, param *mdl.PatchRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	claims := requestClaims(ctx)
+	if claims == nil {
+		return res, internal.NewError(401, fmt.Errorf("login.usecase.PatchMe : no authenticated user"))
+	}
+	data, err := u.Repo.GetById(ctx, claims.Username)
 	if err != nil {
 		return res, err
 	}
+
+	current, errn := json.Marshal(&mdl.UpdateMeRequest{Email: data.Email})
+	if errn == nil {
+		current, errn = patch.Merge(current, param.Patch)
+	}
+	request := new(mdl.UpdateMeRequest)
+	if errn == nil {
+		errn = json.Unmarshal(current, request)
+	}
+	if errn == nil {
+		errn = utils.ValidateUpdateMeRequest(request)
+	}
+	if errn != nil {
+		return res, internal.NewError(400, fmt.Errorf("user.usecase.PatchMe : %v", errn))
+	}
+	if request.Email == data.Email {
+		return res, nil
+	}
+
+	version := param.Version
+	if version == 0 {
+		version = data.Version
+	}
+	err = u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		if _, err := repo.GetByEmail(ctx, request.Email); err == nil {
+			return internal.NewError(409, fmt.Errorf("login.usecase.PatchMe : email %s is already used", request.Email))
+		} else if err.Code != 404 {
+			return err
+		}
+		if err := repo.SetEmail(ctx, data.Username, request.Email, version); err != nil {
+			return err
+		}
+		after, err := repo.GetById(ctx, data.Username)
+		if err != nil {
+			return err
+		}
+		if err = u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionUpdate, resource, data.Username, data, after)); err != nil {
+			return err
+		}
+		return u.Events.Publish(ctx, event.New(ctx, eventModel.UserUpdated, resource, data.Username, after))
+	})
+	if err == nil {
+		u.sendVerification(ctx, data.Username, request.Email)
+	}
+	return res, err
+}
+
+// ChangePassword replaces the password of the caller once it proved knowing
+// the current one. Wrong current passwords count towards the lockout like
+// failed logins, and a verified email is told of the change.
+func (u *UsecaseModul) ChangePassword(ctx *gin.Context, param *mdl.ChangePasswordRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	claims := requestClaims(ctx)
+	if claims == nil {
+		return res, internal.NewError(401, fmt.Errorf("login.usecase.ChangePassword : no authenticated user"))
+	}
+	username := claims.Username
+	attempt, err := u.checkLockout(ctx, username)
+	if err != nil {
+		return res, err
+	}
+	if _, err = u.Repo.Login(ctx, &mdl.LoginRequest{Username: username, Password: param.CurrentPassword}); err != nil {
+		if err.Code != 400 {
+			return res, err
+		}
+		logger.FromContext(ctx).Warnf("login.usecase.ChangePassword : %s gave a wrong current password", username)
+		if u.Lockout.Threshold > 0 {
+			if failure := u.recordFailure(ctx, username); failure != nil {
+				return res, failure
+			}
+		}
+		return res, internal.NewError(400, fmt.Errorf("current password is wrong"))
+	}
+	if param.Password == param.CurrentPassword {
+		return res, internal.NewError(400, fmt.Errorf("login.usecase.ChangePassword : the new password must differ from the current one"))
+	}
+	if strings.Contains(strings.ToLower(param.Password), strings.ToLower(username)) {
+		return res, internal.NewError(400, fmt.Errorf("login.usecase.ChangePassword : the password must not contain the username"))
+	}
+
+	var email string
+	err = u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		before, err := repo.GetById(ctx, username)
+		if err != nil {
+			return err
+		}
+		if err = repo.SetPassword(ctx, username, param.Password); err != nil {
+			return err
+		}
+		if attempt.Failures > 0 {
+			if err = repo.DeleteAttempt(ctx, username); err != nil {
+				return err
+			}
+		}
+		after, err := repo.GetById(ctx, username)
+		if err != nil {
+			return err
+		}
+		if after.EmailVerifiedAt != "" {
+			email = after.Email
+		}
+		userEvent := event.New(ctx, eventModel.UserUpdated, resource, username, after)
+		after.Password = maskedPassword
+		if err = u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionUpdate, resource, username, before, after)); err != nil {
+			return err
+		}
+		return u.Events.Publish(ctx, userEvent)
+	})
+	if err != nil {
+		return res, err
+	}
+	logger.FromContext(ctx).Infof("login.usecase.ChangePassword : %s changed its password", username)
+	if email != "" {
+		u.send(ctx, mail.Message{
+			To:      email,
+			Subject: "Your password was changed",
+			Body: fmt.Sprintf("Hello %s,\n\nthe password of your account was just changed. If it was not you, reset it right away with POST %s/api/password/forgot.\n",
+				username, u.Mail.LinkBaseURL),
+		})
+	}
 	return res, nil
 }
 
+// currentUser reads the caller with its two-factor secret.
+func (u *UsecaseModul) currentUser(ctx *gin.Context) (*models.User, *internal.Error) {
+	claims := requestClaims(ctx)
+	if claims == nil {
+		return nil, internal.NewError(401, fmt.Errorf("login.usecase.currentUser : no authenticated user"))
+	}
+	return u.Repo.GetCredentials(ctx, claims.Username)
+}
+
+func requestClaims(ctx *gin.Context) *middleware.AuthCustomClaims {
+	var JWT, _ = ctx.Get(middleware.JwtClaims)
+	claims, _ := JWT.(*middleware.AuthCustomClaims)
+	return claims
+}
+
+// verifyCode accepts a TOTP code of user not used yet, or else spends one of
+// its recovery codes, and fails with 400 otherwise.
+func (u *UsecaseModul) verifyCode(ctx *gin.Context, repo Repository, user *models.User, code string) *internal.Error {
+	if step, ok := twofactor.Validate(user.TotpSecret, code, time.Now(), user.TotpLastStep); ok {
+		return repo.SetTotpLastStep(ctx, user.Username, step)
+	}
+	if err := repo.UseRecoveryCode(ctx, user.Username, recoveryCodeHash(user.Username, code)); err != nil {
+		return err
+	}
+	logger.FromContext(ctx).Warnf("login.usecase.verifyCode : %s used a recovery code", user.Username)
+	return nil
+}
+
+// replaceRecoveryCodes stores the hashes of TwoFactor.RecoveryCodes new
+// recovery codes of username and returns the codes.
+func (u *UsecaseModul) replaceRecoveryCodes(ctx *gin.Context, repo Repository, username string) ([]string, *internal.Error) {
+	codes, errn := twofactor.RecoveryCodes(u.TwoFactor.RecoveryCodes)
+	if errn != nil {
+		return nil, internal.NewError(500, fmt.Errorf("login.usecase.replaceRecoveryCodes : %v", errn))
+	}
+	rows := make([]*models.RecoveryCode, len(codes))
+	for i, code := range codes {
+		rows[i] = &models.RecoveryCode{
+			CodeHash:  recoveryCodeHash(username, code),
+			Username:  username,
+			CreatedAt: utils.FormatTime(),
+		}
+	}
+	return codes, repo.ReplaceRecoveryCodes(ctx, username, rows)
+}
+
+// recoveryCodeHash salts the hash with the username, the codes being short.
+func recoveryCodeHash(username string, code string) string {
+	return utils.Encrypt(username + ":" + twofactor.NormalizeRecoveryCode(code))
+}
+
 func (u *UsecaseModul) Get(ctx *gin.Context, param *mdl.GetRequest) (mdl.ResponseData, *internal.Error) {
 	var res mdl.ResponseData
 	count, err := u.Repo.Count(ctx, param)
@@ -64,6 +824,10 @@ func (u *UsecaseModul) Get(ctx *gin.Context, param *mdl.GetRequest) (mdl.Respons
 	return res, nil
 }
 
+func (u *UsecaseModul) Export(ctx *gin.Context, param *mdl.GetRequest, fn func([]*models.User) error) *internal.Error {
+	return u.Repo.Export(ctx, param, fn)
+}
+
 func (u *UsecaseModul) GetById(ctx *gin.Context, id string) (mdl.ResponseDetail, *internal.Error) {
 	var res mdl.ResponseDetail
 	data, err := u.Repo.GetById(ctx, id)
@@ -74,42 +838,404 @@ func (u *UsecaseModul) GetById(ctx *gin.Context, id string) (mdl.ResponseDetail,
 	return res, nil
 }
 
+// Create adds a user of any role and mails a verification link to its
+// email, if any, once it is committed.
 func (u *UsecaseModul) Create(ctx *gin.Context, param *mdl.CreateRequest) (mdl.GeneralResponse, *internal.Error) {
-	var (
-		res mdl.GeneralResponse
-	)
+	res, err := u.create(ctx, param)
+	if err == nil && param.Email != "" {
+		u.sendVerification(ctx, param.Username, param.Email)
+	}
+	return res, err
+}
 
-	if param.Role == "customer" {
-		customerReq := &customerView.CreateRequest{
-			Name: param.Username,
+func (u *UsecaseModul) create(ctx *gin.Context, param *mdl.CreateRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		var (
+			entries []*auditModel.Audit
+			events  []*eventModel.Event
+			err     *internal.Error
+		)
+		if param.Email != "" {
+			if _, err = repo.GetByEmail(ctx, param.Email); err == nil {
+				return internal.NewError(409, fmt.Errorf("login.usecase.Create : email %s is already used", param.Email))
+			} else if err.Code != 404 {
+				return err
+			}
 		}
-		id, err := u.CustomerRepo.Create(ctx, customerReq)
+		if param.Role == models.RoleCustomer {
+			id, entry, customerEvent, err := u.newCustomer(ctx, param.Username, param.Email)
+			if err != nil {
+				return err
+			}
+			param.CustomerId = id
+			entries = append(entries, entry)
+			events = append(events, customerEvent)
+		}
+		if res, err = repo.Create(ctx, param); err != nil {
+			return err
+		}
+
+		after, err := repo.GetById(ctx, param.Username)
 		if err != nil {
-			return res, err
+			return err
+		}
+		events = append(events, event.New(ctx, eventModel.UserCreated, resource, param.Username, after))
+		after.Password = maskedPassword
+		entries = append(entries, audit.NewEntry(ctx, auditModel.ActionCreate, resource, param.Username, nil, after))
+		if err = u.Audit.Record(ctx, entries...); err != nil {
+			return err
 		}
-		param.CustomerId = id
+		return u.Events.Publish(ctx, events...)
+	})
+	if err == nil {
+		metrics.UsersRegistered.WithLabelValues(param.Role).Inc()
 	}
-	res, err := u.Repo.Create(ctx, param)
+	return res, err
+}
+
+// newCustomer creates the customer of a customer user named name, returning
+// its id with the audit entry and event to record along. The email of the
+// user is kept on the customer so duplicates can be told by it.
+func (u *UsecaseModul) newCustomer(ctx *gin.Context, name string, email string) (string, *auditModel.Audit, *eventModel.Event, *internal.Error) {
+	id, err := u.CustomerRepo.Create(ctx, &customerView.CreateRequest{Name: name, Email: email})
+	if err != nil {
+		return "", nil, nil, err
+	}
+	customerData, err := u.CustomerRepo.GetById(ctx, id)
 	if err != nil {
+		return "", nil, nil, err
+	}
+	return id, audit.NewEntry(ctx, auditModel.ActionCreate, customerResource, id, nil, customerData), event.New(ctx, eventModel.CustomerCreated, customerResource, id, customerData), nil
+}
+
+// Register signs up a user on its own. It always gets the customer role
+// unless an invitation token is given, which is then used up and grants the
+// role it was created with.
+func (u *UsecaseModul) Register(ctx *gin.Context, param *mdl.RegisterRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	request := &mdl.CreateRequest{Username: param.Username, Password: param.Password, Email: param.Email, Role: models.RoleCustomer}
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		if _, err := repo.GetById(ctx, param.Username); err == nil {
+			return internal.NewError(409, fmt.Errorf("login.usecase.Register : username %s is already taken", param.Username))
+		} else if err.Code != 404 {
+			return err
+		}
+
+		if param.InvitationToken != "" {
+			invitation, err := repo.GetInvitationByToken(ctx, utils.Encrypt(param.InvitationToken))
+			if err != nil && err.Code != 404 {
+				return err
+			}
+			if err != nil || invitation.UsedAt != "" || invitation.ExpiresAt < utils.FormatTime() {
+				return internal.NewError(400, fmt.Errorf("login.usecase.Register : invitation token is invalid, used or expired"))
+			}
+			if invitation.Username != "" && !strings.EqualFold(invitation.Username, param.Username) {
+				return internal.NewError(400, fmt.Errorf("login.usecase.Register : invitation token is for another username"))
+			}
+			if err = repo.UseInvitation(ctx, invitation.Id, param.Username); err != nil {
+				return err
+			}
+			request.Role = invitation.Role
+		}
+
+		var err *internal.Error
+		res, err = u.create(ctx, request)
+		return err
+	})
+	if err == nil {
+		logger.FromContext(ctx).Infof("login.usecase.Register : %s registered as %s", param.Username, request.Role)
+		u.sendVerification(ctx, param.Username, param.Email)
+	}
+	return res, err
+}
+
+// VerifyEmail marks the email a verification token was sent to as verified.
+func (u *UsecaseModul) VerifyEmail(ctx *gin.Context, param *mdl.VerifyEmailRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		token, err := useToken(ctx, repo, models.TokenVerifyEmail, param.Token)
+		if err != nil {
+			return err
+		}
+		before, err := repo.GetById(ctx, token.Username)
+		if err != nil {
+			return err
+		}
+		if err = repo.VerifyEmail(ctx, token.Username, token.Email); err != nil {
+			return err
+		}
+		after, err := repo.GetById(ctx, token.Username)
+		if err != nil {
+			return err
+		}
+		return u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionUpdate, resource, token.Username, before, after))
+	})
+	return res, err
+}
+
+// ForgotPassword mails a password reset token to the user owning the
+// email. It succeeds whether or not there is one, so it cannot be used to
+// find out which emails are registered; the mail is only queued, so the
+// answer does not take longer for them either.
+func (u *UsecaseModul) ForgotPassword(ctx *gin.Context, param *mdl.ForgotPasswordRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	user, err := u.Repo.GetByEmail(ctx, param.Email)
+	if err != nil {
+		if err.Code == 404 {
+			logger.FromContext(ctx).Infof("login.usecase.ForgotPassword : no user with the requested email")
+			return res, nil
+		}
 		return res, err
 	}
+
+	token, err := u.issueToken(ctx, models.TokenResetPassword, user.Username, user.Email, u.Account.PasswordResetTTL.Duration())
+	if err != nil {
+		return res, err
+	}
+	u.send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\nsomeone asked to reset your password. To choose a new one, send this token with your new password to POST %s/api/password/reset within %s:\n\n%s\n\nIf it was not you, ignore this email, your password stays the same.\n",
+			user.Username, u.Mail.LinkBaseURL, u.Account.PasswordResetTTL, token),
+	})
 	return res, nil
 }
 
-func (u *UsecaseModul) Update(ctx *gin.Context, param *mdl.UpdateRequest) (mdl.GeneralResponse, *internal.Error) {
+// ResetPassword sets a new password with a token from ForgotPassword and
+// lifts any lockout of the account.
+func (u *UsecaseModul) ResetPassword(ctx *gin.Context, param *mdl.ResetPasswordRequest) (mdl.GeneralResponse, *internal.Error) {
 	var res mdl.GeneralResponse
-	res, err := u.Repo.Update(ctx, param)
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		token, err := useToken(ctx, repo, models.TokenResetPassword, param.Token)
+		if err != nil {
+			return err
+		}
+		if strings.Contains(strings.ToLower(param.Password), strings.ToLower(token.Username)) {
+			return internal.NewError(400, fmt.Errorf("login.usecase.ResetPassword : the password must not contain the username"))
+		}
+		before, err := repo.GetById(ctx, token.Username)
+		if err != nil {
+			return err
+		}
+		if err = repo.SetPassword(ctx, token.Username, param.Password); err != nil {
+			return err
+		}
+		if err = repo.DeleteAttempt(ctx, token.Username); err != nil {
+			return err
+		}
+		after, err := repo.GetById(ctx, token.Username)
+		if err != nil {
+			return err
+		}
+		userEvent := event.New(ctx, eventModel.UserUpdated, resource, token.Username, after)
+		after.Password = maskedPassword
+		if err = u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionUpdate, resource, token.Username, before, after)); err != nil {
+			return err
+		}
+		return u.Events.Publish(ctx, userEvent)
+	})
+	if err == nil {
+		logger.FromContext(ctx).Infof("login.usecase.ResetPassword : password reset")
+	}
+	return res, err
+}
+
+// sendVerification mails a link verifying email. A failure is only logged,
+// the user is created anyway.
+func (u *UsecaseModul) sendVerification(ctx *gin.Context, username string, email string) {
+	token, err := u.issueToken(ctx, models.TokenVerifyEmail, username, email, u.Account.VerificationTTL.Duration())
+	if err != nil {
+		logger.FromContext(ctx).Errorf("login.usecase.sendVerification : %v", err.Message)
+		return
+	}
+	u.send(ctx, mail.Message{
+		To:      email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hello %s,\n\nplease confirm this is your email by opening this link within %s:\n\n%s/api/verify-email?token=%s\n",
+			username, u.Account.VerificationTTL, u.Mail.LinkBaseURL, url.QueryEscape(token)),
+	})
+}
+
+// issueToken stores the hash of a new single use token and returns the
+// token itself, which only goes out by email.
+func (u *UsecaseModul) issueToken(ctx *gin.Context, purpose string, username string, email string, ttl time.Duration) (string, *internal.Error) {
+	token, errn := newToken()
+	if errn != nil {
+		return "", internal.NewError(500, fmt.Errorf("login.usecase.issueToken : %v", errn))
+	}
+	err := u.Repo.CreateToken(ctx, &models.Token{
+		TokenHash: utils.Encrypt(token),
+		Purpose:   purpose,
+		Username:  username,
+		Email:     email,
+		ExpiresAt: utils.FormatTimeAfter(ttl),
+		CreatedAt: utils.FormatTime(),
+	})
+	return token, err
+}
+
+func (u *UsecaseModul) send(ctx *gin.Context, message mail.Message) {
+	if err := u.Mailer.Send(ctx, message); err != nil {
+		logger.FromContext(ctx).Errorf("login.usecase.send : %q to %s : %v", message.Subject, message.To, err)
+	}
+}
+
+// useToken spends a token of purpose, telling apart neither unknown, used
+// nor expired tokens.
+func useToken(ctx *gin.Context, repo Repository, purpose string, value string) (*models.Token, *internal.Error) {
+	token, err := repo.GetTokenByHash(ctx, utils.Encrypt(value))
+	if err != nil && err.Code != 404 {
+		return nil, err
+	}
+	if err != nil || token.Purpose != purpose || token.UsedAt != "" || token.ExpiresAt < utils.FormatTime() {
+		return nil, internal.NewError(400, fmt.Errorf("token is invalid, used or expired"))
+	}
+	if err = repo.UseToken(ctx, token.TokenHash); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// CreateInvitation issues a single use token to register with the given
+// role before Invitation.TTL runs out. Only its hash is stored, so the token
+// is returned here and never again.
+func (u *UsecaseModul) CreateInvitation(ctx *gin.Context, param *mdl.CreateInvitationRequest) (mdl.ResponseInvitation, *internal.Error) {
+	var res mdl.ResponseInvitation
+	token, errn := newToken()
+	if errn != nil {
+		return res, internal.NewError(500, fmt.Errorf("login.usecase.CreateInvitation : %v", errn))
+	}
+
+	var createdBy string
+	if claims := requestClaims(ctx); claims != nil {
+		createdBy = claims.Username
+	}
+	invitation := &models.Invitation{
+		Id:        uuid.New().String(),
+		TokenHash: utils.Encrypt(token),
+		Role:      param.Role,
+		Username:  param.Username,
+		CreatedBy: createdBy,
+		ExpiresAt: utils.FormatTimeAfter(u.Invitation.TTL.Duration()),
+		CreatedAt: utils.FormatTime(),
+	}
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		if err := repo.CreateInvitation(ctx, invitation); err != nil {
+			return err
+		}
+		return u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionCreate, invitationResource, invitation.Id, nil, invitation))
+	})
 	if err != nil {
 		return res, err
 	}
+	res.Data = invitation
+	res.Token = token
 	return res, nil
 }
 
-func (u *UsecaseModul) Delete(ctx *gin.Context, param *mdl.DeleteRequest) (mdl.GeneralResponse, *internal.Error) {
-	var res mdl.GeneralResponse
-	res, err := u.Repo.Delete(ctx, param)
+func (u *UsecaseModul) GetInvitations(ctx *gin.Context) (mdl.ResponseInvitations, *internal.Error) {
+	var res mdl.ResponseInvitations
+	data, err := u.Repo.GetInvitations(ctx)
 	if err != nil {
 		return res, err
 	}
+	res.Data = data
 	return res, nil
 }
+
+func (u *UsecaseModul) DeleteInvitation(ctx *gin.Context, param *mdl.DeleteInvitationRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		before, err := repo.DeleteInvitation(ctx, param.Id)
+		if err != nil {
+			return err
+		}
+		return u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionDelete, invitationResource, param.Id, before, nil))
+	})
+	return res, err
+}
+
+func newToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (u *UsecaseModul) Update(ctx *gin.Context, param *mdl.UpdateRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		before, err := repo.GetById(ctx, param.Username)
+		if err != nil {
+			return err
+		}
+		if res, err = repo.Update(ctx, param); err != nil {
+			return err
+		}
+
+		after, err := repo.GetById(ctx, param.Username)
+		if err != nil {
+			return err
+		}
+		userEvent := event.New(ctx, eventModel.UserUpdated, resource, param.Username, after)
+		if param.Password != "" {
+			// passwords are never stored in the trail, only the fact they changed
+			after.Password = maskedPassword
+		}
+		if err = u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionUpdate, resource, param.Username, before, after)); err != nil {
+			return err
+		}
+		return u.Events.Publish(ctx, userEvent)
+	})
+	return res, err
+}
+
+func (u *UsecaseModul) Patch(ctx *gin.Context, param *mdl.PatchRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	data, err := u.Repo.GetById(ctx, param.Username)
+	if err != nil {
+		return res, err
+	}
+
+	current, errn := json.Marshal(&mdl.UpdateRequest{Username: data.Username, CustomerId: data.CustomerId, Role: data.Role})
+	if errn == nil {
+		current, errn = patch.Merge(current, param.Patch)
+	}
+	request := new(mdl.UpdateRequest)
+	if errn == nil {
+		errn = json.Unmarshal(current, request)
+	}
+	if errn != nil {
+		return res, internal.NewError(400, fmt.Errorf("user.usecase.Patch : %v", errn))
+	}
+
+	request.Username = param.Username
+	request.Version = param.Version
+	if request.Version == 0 {
+		request.Version = data.Version
+	}
+	if errn = utils.ValidatePatchedRequest(request); errn != nil {
+		return res, internal.NewError(400, fmt.Errorf("user.usecase.Patch : %v", errn))
+	}
+	return u.Update(ctx, request)
+}
+
+func (u *UsecaseModul) Delete(ctx *gin.Context, param *mdl.DeleteRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		before, err := repo.GetById(ctx, param.Username)
+		if err != nil {
+			return err
+		}
+		if res, err = repo.Delete(ctx, param); err != nil {
+			return err
+		}
+		if err = u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionDelete, resource, param.Username, before, nil)); err != nil {
+			return err
+		}
+		return u.Events.Publish(ctx, event.New(ctx, eventModel.UserDeleted, resource, param.Username, before))
+	})
+	return res, err
+}
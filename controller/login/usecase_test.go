@@ -0,0 +1,484 @@
+package login
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gin-dbo/framework/config"
+	"gin-dbo/framework/database/dbtest"
+	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/mail"
+	"gin-dbo/framework/middleware"
+	"gin-dbo/framework/oidc"
+	"gin-dbo/framework/oidc/oidctest"
+	"gin-dbo/framework/twofactor"
+	"gin-dbo/framework/utils"
+	auditModel "gin-dbo/model/audit"
+	eventModel "gin-dbo/model/event"
+	models "gin-dbo/model/login"
+	mdl "gin-dbo/view/login"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp/totp"
+	"gorm.io/gorm"
+)
+
+var lockout = config.Lockout{
+	Threshold:   1,
+	Duration:    config.Duration(time.Minute),
+	MaxDuration: config.Duration(time.Hour),
+	Window:      config.Duration(time.Hour),
+}
+
+var userRows = []string{"username", "role", "customer_id", "email", "version"}
+
+// trail keeps the audit entries and events of a usecase instead of storing
+// them.
+type trail struct {
+	entries []*auditModel.Audit
+	events  []*eventModel.Event
+}
+
+func (t *trail) Record(ctx *gin.Context, entries ...*auditModel.Audit) *internal.Error {
+	t.entries = append(t.entries, entries...)
+	return nil
+}
+
+func (t *trail) Publish(ctx *gin.Context, events ...*eventModel.Event) *internal.Error {
+	t.events = append(t.events, events...)
+	return nil
+}
+
+// expectToken expects a token of purpose to be issued to username.
+func expectToken(mock sqlmock.Sqlmock, username string, purpose string) {
+	mock.ExpectExec("DELETE FROM `user_tokens` WHERE username = \\? and purpose = \\?").WithArgs(username, purpose).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO `user_tokens`").WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func newUsecase(db *gorm.DB) *UsecaseModul {
+	t := &trail{}
+	return &UsecaseModul{
+		Repo:    NewRepository(db),
+		Audit:   t,
+		Events:  t,
+		Mailer:  mail.NewMemorySender(),
+		Lockout: lockout,
+		Account: config.Account{VerificationTTL: config.Duration(time.Hour), PasswordResetTTL: config.Duration(time.Hour)},
+	}
+}
+
+func TestLoginFailureLocksTheUsername(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `login_attempts` WHERE username = \\?").WithArgs("jane").
+		WillReturnRows(sqlmock.NewRows(attemptColumns))
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE username = \\? and password = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"username"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `login_attempts` WHERE username = \\?").WithArgs("jane").
+		WillReturnRows(sqlmock.NewRows(attemptColumns))
+	mock.ExpectExec("INSERT INTO `login_attempts`").
+		WithArgs("jane", 1, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, err := newUsecase(db).Login(dbtest.Context(), &mdl.LoginRequest{Username: "jane", Password: "wrong"})
+	if err == nil || err.Code != 400 {
+		t.Fatalf("got %v, want 400", err)
+	}
+}
+
+func TestLoginRefusesALockedUsername(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `login_attempts` WHERE username = \\?").WithArgs("jane").
+		WillReturnRows(sqlmock.NewRows(attemptColumns).AddRow("jane", 1, utils.FormatTime(), utils.FormatTimeAfter(time.Minute)))
+
+	_, err := newUsecase(db).Login(dbtest.Context(), &mdl.LoginRequest{Username: "jane", Password: "secret"})
+	if err == nil || err.Code != 429 {
+		t.Fatalf("got %v, want 429", err)
+	}
+}
+
+func TestRegisterWithInvitation(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE username = \\?").WithArgs("jane").
+		WillReturnRows(sqlmock.NewRows(userRows))
+	mock.ExpectQuery("SELECT \\* FROM `invitations` WHERE token_hash = \\? LIMIT 1 FOR UPDATE").WithArgs(utils.Encrypt("invite")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role", "expires_at"}).AddRow("i1", models.RoleAdmin, utils.FormatTimeAfter(time.Hour)))
+	mock.ExpectExec("UPDATE `invitations` SET .* WHERE id = \\? and used_at = ''").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `users`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE username = \\?").WithArgs("jane").
+		WillReturnRows(sqlmock.NewRows(userRows).AddRow("jane", models.RoleAdmin, "", "", 1))
+	mock.ExpectCommit()
+	expectToken(mock, "jane", models.TokenVerifyEmail)
+
+	u := newUsecase(db)
+	_, err := u.Register(dbtest.Context(), &mdl.RegisterRequest{Username: "jane", Password: "Correct-Horse-1", InvitationToken: "invite"})
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	if entries := u.Audit.(*trail).entries; len(entries) != 1 || entries[0].ResourceId != "jane" {
+		t.Fatalf("got audit entries %+v, want the creation of jane", entries)
+	}
+}
+
+func TestRegisterTakenUsername(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE username = \\?").WithArgs("jane").
+		WillReturnRows(sqlmock.NewRows(userRows).AddRow("jane", models.RoleCustomer, "c1", "", 1))
+	mock.ExpectRollback()
+
+	_, err := newUsecase(db).Register(dbtest.Context(), &mdl.RegisterRequest{Username: "jane", Password: "Correct-Horse-1"})
+	if err == nil || err.Code != 409 {
+		t.Fatalf("got %v, want 409", err)
+	}
+}
+
+func TestCreateWithEmail(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE email = \\?").WithArgs("jane@example.com").
+		WillReturnRows(sqlmock.NewRows(userRows))
+	mock.ExpectExec("INSERT INTO `users`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE username = \\?").WithArgs("jane").
+		WillReturnRows(sqlmock.NewRows(userRows).AddRow("jane", models.RoleAdmin, "", "jane@example.com", 1))
+	mock.ExpectCommit()
+	expectToken(mock, "jane", models.TokenVerifyEmail)
+
+	u := newUsecase(db)
+	_, err := u.Create(dbtest.Context(), &mdl.CreateRequest{Username: "jane", Password: "Correct-Horse-1", Role: models.RoleAdmin, Email: "jane@example.com"})
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	if sent := u.Mailer.(*mail.MemorySender).Messages(); len(sent) != 1 || sent[0].To != "jane@example.com" {
+		t.Fatalf("got mails %+v, want the verification of jane@example.com", sent)
+	}
+}
+
+func TestCreateWithUsedEmail(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE email = \\?").WithArgs("jane@example.com").
+		WillReturnRows(sqlmock.NewRows(userRows).AddRow("jane", models.RoleAdmin, "", "jane@example.com", 1))
+	mock.ExpectRollback()
+
+	_, err := newUsecase(db).Create(dbtest.Context(), &mdl.CreateRequest{Username: "joe", Password: "Correct-Horse-1", Role: models.RoleAdmin, Email: "jane@example.com"})
+	if err == nil || err.Code != 409 {
+		t.Fatalf("got %v, want 409", err)
+	}
+}
+
+func TestForgotPasswordOfUnknownEmail(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE email = \\?").WithArgs("jane@example.com").
+		WillReturnRows(sqlmock.NewRows(userRows))
+
+	u := newUsecase(db)
+	if _, err := u.ForgotPassword(dbtest.Context(), &mdl.ForgotPasswordRequest{Email: "jane@example.com"}); err != nil {
+		t.Fatal(err.Message)
+	}
+	if sent := u.Mailer.(*mail.MemorySender).Messages(); len(sent) != 0 {
+		t.Fatalf("got mails %+v, want none", sent)
+	}
+}
+
+func TestResetPasswordWithTheMailedToken(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE email = \\?").WithArgs("jane@example.com").
+		WillReturnRows(sqlmock.NewRows(userRows).AddRow("jane", models.RoleCustomer, "c1", "jane@example.com", 1))
+	expectToken(mock, "jane", models.TokenResetPassword)
+
+	u := newUsecase(db)
+	if _, err := u.ForgotPassword(dbtest.Context(), &mdl.ForgotPasswordRequest{Email: "jane@example.com"}); err != nil {
+		t.Fatal(err.Message)
+	}
+	sent := u.Mailer.(*mail.MemorySender).Messages()
+	if len(sent) != 1 || sent[0].To != "jane@example.com" {
+		t.Fatalf("got mails %+v, want the reset of jane@example.com", sent)
+	}
+	token := strings.Split(sent[0].Body, "\n\n")[2]
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `user_tokens` WHERE token_hash = \\? LIMIT 1 FOR UPDATE").WithArgs(utils.Encrypt(token)).
+		WillReturnRows(sqlmock.NewRows([]string{"token_hash", "purpose", "username", "email", "expires_at", "used_at"}).
+			AddRow(utils.Encrypt(token), models.TokenResetPassword, "jane", "jane@example.com", utils.FormatTimeAfter(time.Hour), ""))
+	mock.ExpectExec("UPDATE `user_tokens` SET `used_at`=\\? WHERE token_hash = \\? and used_at = ''").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE username = \\?").WithArgs("jane").
+		WillReturnRows(sqlmock.NewRows(userRows).AddRow("jane", models.RoleCustomer, "c1", "jane@example.com", 1))
+	mock.ExpectExec("UPDATE `users` SET .* WHERE username = \\?").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM `login_attempts` WHERE username = \\?").WithArgs("jane").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE username = \\?").WithArgs("jane").
+		WillReturnRows(sqlmock.NewRows(userRows).AddRow("jane", models.RoleCustomer, "c1", "jane@example.com", 2))
+	mock.ExpectCommit()
+
+	if _, err := u.ResetPassword(dbtest.Context(), &mdl.ResetPasswordRequest{Token: token, Password: "Correct-Horse-2"}); err != nil {
+		t.Fatal(err.Message)
+	}
+}
+
+func TestResetPasswordWithUnknownToken(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `user_tokens` WHERE token_hash = \\?").WithArgs(utils.Encrypt("forged")).
+		WillReturnRows(sqlmock.NewRows([]string{"token_hash"}))
+	mock.ExpectRollback()
+
+	_, err := newUsecase(db).ResetPassword(dbtest.Context(), &mdl.ResetPasswordRequest{Token: "forged", Password: "Correct-Horse-2"})
+	if err == nil || err.Code != 400 {
+		t.Fatalf("got %v, want 400", err)
+	}
+}
+
+func TestPatchMeEmail(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE username = \\?").WithArgs("jane").
+		WillReturnRows(sqlmock.NewRows(userRows).AddRow("jane", models.RoleCustomer, "c1", "jane@example.com", 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE email = \\?").WithArgs("jane.doe@example.com").
+		WillReturnRows(sqlmock.NewRows(userRows))
+	mock.ExpectExec("UPDATE `users` SET .* WHERE username = \\? AND version = \\?").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE username = \\?").WithArgs("jane").
+		WillReturnRows(sqlmock.NewRows(userRows).AddRow("jane", models.RoleCustomer, "c1", "jane.doe@example.com", 2))
+	mock.ExpectCommit()
+	expectToken(mock, "jane", models.TokenVerifyEmail)
+
+	u := newUsecase(db)
+	ctx := dbtest.Context()
+	ctx.Set(middleware.JwtClaims, &middleware.AuthCustomClaims{Username: "jane", Role: models.RoleCustomer})
+	if _, err := u.PatchMe(ctx, &mdl.PatchRequest{Patch: []byte(`{"email":"jane.doe@example.com"}`)}); err != nil {
+		t.Fatal(err.Message)
+	}
+	if sent := u.Mailer.(*mail.MemorySender).Messages(); len(sent) != 1 || sent[0].To != "jane.doe@example.com" {
+		t.Fatalf("got mails %+v, want the verification of jane.doe@example.com", sent)
+	}
+}
+
+// captured matches any argument and keeps it.
+type captured struct {
+	value driver.Value
+}
+
+func (c *captured) Match(v driver.Value) bool {
+	c.value = v
+	return true
+}
+
+func TestOIDCSignInProvisionsTheUser(t *testing.T) {
+	idp, errn := oidctest.New(oidctest.Options{ClientID: "gin-dbo", User: "jane.doe", Email: "jane.doe@example.com", Groups: []string{"staff-admins"}})
+	if errn != nil {
+		t.Fatal(errn)
+	}
+	server := httptest.NewServer(idp)
+	defer server.Close()
+	idp.Issuer = server.URL
+
+	db, mock := dbtest.New(t)
+	u := newUsecase(db)
+	u.JWT = middleware.JWTAuthService(config.JWT{SecretKey: "secret", Expiry: config.Duration(time.Hour)})
+	u.OIDCConfig = config.OIDC{
+		Issuer:        server.URL,
+		ClientID:      "gin-dbo",
+		RedirectURL:   "http://localhost:30001/api/auth/oidc/callback",
+		Scopes:        []string{"openid", "email", "profile"},
+		UsernameClaim: "preferred_username",
+		GroupsClaim:   "groups",
+		AdminGroups:   []string{"staff-admins"},
+		StateTTL:      config.Duration(time.Minute),
+	}
+	u.OIDC = oidc.New(u.OIDCConfig)
+
+	verifier, nonce := &captured{}, &captured{}
+	mock.ExpectExec("DELETE FROM `oidc_states` WHERE expires_at < \\?").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO `oidc_states`").
+		WithArgs(sqlmock.AnyArg(), verifier, nonce, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	location, err := u.OIDCLogin(dbtest.Context())
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	answer, errn := client.Get(location)
+	if errn != nil {
+		t.Fatal(errn)
+	}
+	answer.Body.Close()
+	back, errn := url.Parse(answer.Header.Get("Location"))
+	if errn != nil {
+		t.Fatal(errn)
+	}
+	callback := &mdl.OIDCCallbackRequest{Code: back.Query().Get("code"), State: back.Query().Get("state")}
+
+	user := func(subject string, version int64) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"username", "role", "email", "oidc_subject", "version"}).
+			AddRow("jane.doe", models.RoleAdmin, "jane.doe@example.com", subject, version)
+	}
+	mock.ExpectQuery("SELECT \\* FROM `oidc_states` WHERE state_hash = \\? LIMIT 1").WithArgs(utils.Encrypt(callback.State)).
+		WillReturnRows(sqlmock.NewRows([]string{"state_hash", "verifier", "nonce", "expires_at"}).
+			AddRow(utils.Encrypt(callback.State), verifier.value, nonce.value, utils.FormatTimeAfter(time.Minute)))
+	mock.ExpectExec("DELETE FROM `oidc_states` WHERE state_hash = \\?").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE oidc_subject = \\?").WithArgs("mock|jane.doe").
+		WillReturnRows(sqlmock.NewRows(userRows))
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE email = \\?").WithArgs("jane.doe@example.com").
+		WillReturnRows(sqlmock.NewRows(userRows))
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE username = \\?").WithArgs("jane.doe").
+		WillReturnRows(sqlmock.NewRows(userRows))
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE email = \\?").WithArgs("jane.doe@example.com").
+		WillReturnRows(sqlmock.NewRows(userRows))
+	mock.ExpectExec("INSERT INTO `users`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE username = \\?").WithArgs("jane.doe").WillReturnRows(user("", 1))
+	mock.ExpectExec("UPDATE `users` SET `email_verified_at`=\\? WHERE username = \\? and email = \\?").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `users` SET .*`oidc_subject`=\\?").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE username = \\?").WithArgs("jane.doe").WillReturnRows(user("mock|jane.doe", 2))
+	mock.ExpectCommit()
+
+	res, err := u.OIDCCallback(dbtest.Context(), callback)
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	claims, errn := u.JWT.ValidateToken(res.Data.Token)
+	if errn != nil {
+		t.Fatal(errn)
+	}
+	if claims.Username != "jane.doe" || claims.Role != models.RoleAdmin {
+		t.Fatalf("got claims %+v, want jane.doe as admin", claims)
+	}
+}
+
+const totpSecret = "JBSWY3DPEHPK3PXP"
+
+var tokenColumns = []string{"token_hash", "purpose", "username", "email", "expires_at", "used_at"}
+
+// expectChallenge expects the challenge token of jane to be read, and jane
+// with two-factor authentication enabled, the last code being of lastStep.
+func expectChallenge(mock sqlmock.Sqlmock, lastStep int64) {
+	mock.ExpectQuery("SELECT \\* FROM `user_tokens` WHERE token_hash = \\? LIMIT 1 FOR UPDATE").WithArgs(utils.Encrypt("challenge")).
+		WillReturnRows(sqlmock.NewRows(tokenColumns).
+			AddRow(utils.Encrypt("challenge"), models.TokenLoginChallenge, "jane", "", utils.FormatTimeAfter(time.Minute), ""))
+	mock.ExpectQuery("SELECT \\* FROM `login_attempts` WHERE username = \\?").WithArgs("jane").
+		WillReturnRows(sqlmock.NewRows(attemptColumns))
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE username = \\?").WithArgs("jane").
+		WillReturnRows(sqlmock.NewRows([]string{"username", "role", "totp_secret", "totp_last_step", "two_factor_enabled_at"}).
+			AddRow("jane", models.RoleCustomer, totpSecret, lastStep, utils.FormatTime()))
+}
+
+func twoFactorUsecase(db *gorm.DB) *UsecaseModul {
+	u := newUsecase(db)
+	u.JWT = middleware.JWTAuthService(config.JWT{SecretKey: "secret", Expiry: config.Duration(time.Hour)})
+	return u
+}
+
+func TestLoginTwoFactorWithTOTPCode(t *testing.T) {
+	code, errn := totp.GenerateCode(totpSecret, time.Now())
+	if errn != nil {
+		t.Fatal(errn)
+	}
+	db, mock := dbtest.New(t)
+	expectChallenge(mock, 0)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `totp_last_step`=\\? WHERE username = \\? and totp_last_step < \\?").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `user_tokens` SET `used_at`=\\? WHERE token_hash = \\? and used_at = ''").
+		WithArgs(sqlmock.AnyArg(), utils.Encrypt("challenge")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	u := twoFactorUsecase(db)
+	res, err := u.LoginTwoFactor(dbtest.Context(), &mdl.LoginTwoFactorRequest{ChallengeToken: "challenge", Code: code})
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	if claims, errn := u.JWT.ValidateToken(res.Data.Token); errn != nil || claims.Username != "jane" {
+		t.Fatalf("got claims %+v and %v, want jane", claims, errn)
+	}
+}
+
+func TestLoginTwoFactorSpendsARecoveryCode(t *testing.T) {
+	db, mock := dbtest.New(t)
+	expectChallenge(mock, 0)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `recovery_codes` SET `used_at`=\\? WHERE code_hash = \\? and username = \\? and used_at = ''").
+		WithArgs(sqlmock.AnyArg(), recoveryCodeHash("jane", "ABCDE-FGHIJ"), "jane").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `user_tokens` SET `used_at`=\\? WHERE token_hash = \\? and used_at = ''").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, err := twoFactorUsecase(db).LoginTwoFactor(dbtest.Context(), &mdl.LoginTwoFactorRequest{ChallengeToken: "challenge", Code: "abcde fghij"})
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+}
+
+func TestLoginTwoFactorFailureLocksTheUsername(t *testing.T) {
+	code, errn := totp.GenerateCode(totpSecret, time.Now())
+	if errn != nil {
+		t.Fatal(errn)
+	}
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+	}{
+		{"wrong code", "000000", 0},
+		{"replayed code", code, time.Now().Unix()/twofactor.Period + twofactor.Skew},
+		{"spent recovery code", "ABCDE-FGHIJ", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := dbtest.New(t)
+			expectChallenge(mock, tt.lastStep)
+			mock.ExpectBegin()
+			mock.ExpectExec("UPDATE `recovery_codes` SET `used_at`=\\?").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectRollback()
+			mock.ExpectBegin()
+			mock.ExpectQuery("SELECT \\* FROM `login_attempts` WHERE username = \\?").WithArgs("jane").
+				WillReturnRows(sqlmock.NewRows(attemptColumns))
+			mock.ExpectExec("INSERT INTO `login_attempts`").
+				WithArgs("jane", 1, sqlmock.AnyArg(), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			_, err := twoFactorUsecase(db).LoginTwoFactor(dbtest.Context(), &mdl.LoginTwoFactorRequest{ChallengeToken: "challenge", Code: tt.code})
+			if err == nil || err.Code != 400 {
+				t.Fatalf("got %v, want 400", err)
+			}
+		})
+	}
+}
+
+func TestLoginTwoFactorRefusesTheChallenge(t *testing.T) {
+	tests := []struct {
+		name string
+		rows *sqlmock.Rows
+	}{
+		{"unknown", sqlmock.NewRows(tokenColumns)},
+		{"used", sqlmock.NewRows(tokenColumns).
+			AddRow(utils.Encrypt("challenge"), models.TokenLoginChallenge, "jane", "", utils.FormatTimeAfter(time.Minute), utils.FormatTime())},
+		{"expired", sqlmock.NewRows(tokenColumns).
+			AddRow(utils.Encrypt("challenge"), models.TokenLoginChallenge, "jane", "", utils.FormatTimeAfter(-time.Minute), "")},
+		{"of another purpose", sqlmock.NewRows(tokenColumns).
+			AddRow(utils.Encrypt("challenge"), models.TokenResetPassword, "jane", "", utils.FormatTimeAfter(time.Minute), "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := dbtest.New(t)
+			mock.ExpectQuery("SELECT \\* FROM `user_tokens` WHERE token_hash = \\?").WithArgs(utils.Encrypt("challenge")).
+				WillReturnRows(tt.rows)
+
+			_, err := twoFactorUsecase(db).LoginTwoFactor(dbtest.Context(), &mdl.LoginTwoFactorRequest{ChallengeToken: "challenge", Code: "123456"})
+			if err == nil || err.Code != 401 {
+				t.Fatalf("got %v, want 401", err)
+			}
+		})
+	}
+}
@@ -2,30 +2,38 @@ package order
 
 import (
 	"fmt"
+	"gin-dbo/framework/export"
+	"gin-dbo/framework/importer"
+	"gin-dbo/framework/logger"
 	"gin-dbo/framework/middleware"
+	"gin-dbo/framework/patch"
 	"gin-dbo/framework/utils"
+	models "gin-dbo/model/order"
 	mdl "gin-dbo/view/order"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
 )
 
 type Handler struct {
 	Usecase Usecase
-	logger  *logrus.Logger
 }
 
 // @SecurityDefinitions jwt
-func Router(router *gin.Engine, uc Usecase, logger *logrus.Logger) {
-	u := Handler{Usecase: uc, logger: logger}
-	router.Use(middleware.AuthorizeJWT())
+func Router(router *gin.Engine, uc Usecase, auth func(*gin.Context)) {
+	u := Handler{Usecase: uc}
+	authorized := router.Group("/", auth)
 	{
-		router.GET("api/order", u.GetHandler)
-		router.GET("api/order/:id", u.GetByIdHandler)
-		router.POST("api/order", u.CreateHandler)
-		router.PUT("api/order/:id", u.UpdateHandler)
-		router.DELETE("api/order/:id", u.DeleteHandler)
+		authorized.GET("api/order", u.GetHandler)
+		authorized.GET("api/me/orders", u.GetMineHandler)
+		authorized.GET("api/order/export", u.ExportHandler)
+		authorized.GET("api/order/:id", u.GetByIdHandler)
+		authorized.POST("api/order", u.CreateHandler)
+		authorized.POST("api/order/import", u.ImportHandler)
+		authorized.POST("api/order/batch", u.BatchHandler)
+		authorized.PUT("api/order/:id", u.UpdateHandler)
+		authorized.PATCH("api/order/:id", u.PatchHandler)
+		authorized.DELETE("api/order/:id", u.DeleteHandler)
 	}
 }
 
@@ -61,17 +69,132 @@ func (u Handler) GetHandler(c *gin.Context) {
 		result.Message = "success retrieve data"
 		c.JSON(http.StatusOK, result)
 	} else {
-		u.logger.Error(err)
+		logger.FromContext(c).Error(err)
 		result.Success = false
 		result.Message = err.Message.Error()
 		c.JSON(http.StatusInternalServerError, result)
 	}
 }
 
+// @Summary Get My Orders
+// @Description Get the orders of the customer of the caller
+// @param limit query int false "limit"
+// @param page query string false "page"
+// @param keyword query string false "name of some order"
+// @Produce json
+// @Security jwt
+// @Success 200 {object} mdl.ResponseData
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/me/orders [get]
+func (u Handler) GetMineHandler(c *gin.Context) {
+	var JWT, _ = c.Get(middleware.JwtClaims)
+	jwtClaims := JWT.(*middleware.AuthCustomClaims)
+	if jwtClaims.CustomerId == "" {
+		c.JSON(http.StatusNotFound, mdl.GeneralResponse{Success: false, Message: "order.getMineHandler.NotFound : this user has no customer"})
+		return
+	}
+
+	limit, err := utils.GetLimit(c.Query(utils.Limit))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("order.getMineHandler.BadRequest : %v", err.Message.Error())})
+		return
+	}
+
+	page, err := utils.GetTargetPage(c.Query(utils.Page))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("order.getMineHandler.BadRequest : %v", err.Message.Error())})
+		return
+	}
+
+	param := &mdl.GetRequest{
+		Keyword:    c.Query(utils.Keyword),
+		CustomerId: jwtClaims.CustomerId,
+		Limit:      limit,
+		Page:       page,
+	}
+	result, err := u.Usecase.Get(c, param)
+	if err == nil {
+		result.Success = true
+		result.Message = "success retrieve data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
+// @Summary Export Orders
+// @Description Export Orders as CSV or XLSX
+// @param format query string false "csv or xlsx, default csv"
+// @param columns query string false "comma separated columns to export, default all"
+// @param keyword query string false "name of some order"
+// @Produce text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security jwt
+// @Success 200 {string} string
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Router /api/order/export [get]
+func (u Handler) ExportHandler(c *gin.Context) {
+	format := c.Query(export.Format)
+	if err := export.CheckFormat(format); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("order.exportHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	columns, errn := export.SelectColumns(mdl.ExportColumns, c.Query(export.Columns))
+	if errn != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("order.exportHandler.BadRequest : %v", errn.Error())})
+		return
+	}
+
+	param := &mdl.GetRequest{
+		Keyword: c.Query(utils.Keyword),
+	}
+	logger.FromContext(c).Debugf("%+v", param)
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename("orders", format)))
+	c.Status(http.StatusOK)
+	writer, errn := export.NewWriter(format, c.Writer)
+	if errn == nil {
+		errn = writer.Write(export.Header(columns))
+	}
+	if errn != nil {
+		logger.FromContext(c).Error(errn)
+		return
+	}
+
+	err := u.Usecase.Export(c, param, func(rows []*models.Order) error {
+		for _, row := range rows {
+			if err := writer.Write(export.Record(columns, row)); err != nil {
+				return err
+			}
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		logger.FromContext(c).Error(err)
+		return
+	}
+	if errn = writer.Close(); errn != nil {
+		logger.FromContext(c).Error(errn)
+	}
+}
+
 // @Summary Get Order By Id
 // @Description Get Order By Id
 // @Produce json
+// @Param If-None-Match header string false "etag of a cached copy"
 // @Success 200 {object} mdl.ResponseData
+// @Header 200 {string} ETag "version of the data"
+// @Success 304 "cached copy is still current"
 // @Failure 400 {object} mdl.Response400
 // @Failure 401 {object} middleware.Response
 // @Failure 500 {object} mdl.Response500
@@ -80,11 +203,17 @@ func (u Handler) GetByIdHandler(c *gin.Context) {
 	id := c.Param("id")
 	result, err := u.Usecase.GetById(c, id)
 	if err == nil {
+		etag := utils.FormatETag(result.Data.Version)
+		c.Header(utils.ETag, etag)
+		if utils.MatchETag(c.GetHeader(utils.IfNoneMatch), etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
 		result.Success = true
 		result.Message = "success retrieve data"
 		c.JSON(http.StatusOK, result)
 	} else {
-		u.logger.Error(err)
+		logger.FromContext(c).Error(err)
 		result.Success = false
 		result.Message = err.Message.Error()
 		c.JSON(err.Code, result)
@@ -109,7 +238,7 @@ func (u Handler) CreateHandler(c *gin.Context) {
 		return
 	}
 
-	u.logger.Debugf("%+v", param)
+	logger.FromContext(c).Debugf("%+v", param)
 	if err := utils.ValidateCreateOrderRequest(param); err == nil {
 		result, err := u.Usecase.Create(c, param)
 		if err == nil {
@@ -117,7 +246,7 @@ func (u Handler) CreateHandler(c *gin.Context) {
 			result.Message = "success create data"
 			c.JSON(http.StatusOK, result)
 		} else {
-			u.logger.Error(err)
+			logger.FromContext(c).Error(err)
 			result.Success = false
 			result.Message = err.Message.Error()
 			c.JSON(err.Code, result)
@@ -127,15 +256,111 @@ func (u Handler) CreateHandler(c *gin.Context) {
 	}
 }
 
+// @Summary Import Orders
+// @Description Import Orders from a CSV whose header names the columns customer_id, name and qty, as exported
+// @Accept text/csv,multipart/form-data
+// @Produce json,text/csv
+// @Param file formData file false "CSV file when sent as multipart form"
+// @param dryRun query bool false "validate rows without creating them"
+// @param report query string false "csv to download the report as CSV"
+// @Security jwt
+// @Success 200 {object} mdl.ImportResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Router /api/order/import [post]
+func (u Handler) ImportHandler(c *gin.Context) {
+	file, errn := importer.Open(c)
+	if errn != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("order.importHandler.BadRequest : %v", errn.Error())})
+		return
+	}
+	defer file.Close()
+
+	param := &mdl.ImportRequest{DryRun: c.Query(importer.DryRun) == "true"}
+	errn = importer.ReadCSV(file, func(row int, record map[string]string) error {
+		request, err := mdl.ParseImportRecord(record)
+		param.Rows = append(param.Rows, &mdl.ImportRow{Row: row, Request: request, Error: err})
+		return nil
+	})
+	if errn != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("order.importHandler.BadRequest : %v", errn.Error())})
+		return
+	}
+	logger.FromContext(c).Debugf("importing %d rows, dry run %v", len(param.Rows), param.DryRun)
+
+	result, err := u.Usecase.Import(c, param)
+	if err != nil {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+		return
+	}
+
+	if c.Query(importer.Report) == importer.ReportCSV {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "order-import-report.csv"))
+		c.Status(http.StatusOK)
+		if errn = importer.WriteReport(c.Writer, result.Data); errn != nil {
+			logger.FromContext(c).Error(errn)
+		}
+		return
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("%d created, %d skipped, %d failed", result.Created, result.Skipped, result.Failed)
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary Batch Orders
+// @Description Create, Update and Delete Orders in one request, either atomically or best-effort
+// @Accept json
+// @Produce json
+// @Param request body mdl.BatchRequest true "Sample Batch request payload"
+// @Security jwt
+// @Success 200 {object} mdl.BatchResponse
+// @Failure 400 {object} mdl.BatchResponse
+// @Failure 401 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Router /api/order/batch [post]
+func (u Handler) BatchHandler(c *gin.Context) {
+	param := new(mdl.BatchRequest)
+	if err := c.BindJSON(param); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("order.batchHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	logger.FromContext(c).Debugf("%+v", param)
+	if err := utils.ValidateBatchOrderRequest(param); err == nil {
+		result, err := u.Usecase.Batch(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success process batch"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("order.batchHandler.BadRequest : %v", err.Error())})
+	}
+}
+
 // @Summary Update Order
-// @Description Update Some Orders
+// @Description Update Some Orders, replacing every field with the request payload
 // @Accept json
 // @Produce json
+// @Param If-Match header string false "etag returned by get by id, required when REQUIRE_IF_MATCH is enabled"
 // @Param request body mdl.UpdateRequest true "Sample Update request payload"
 // @Security jwt
 // @Success 200 {object} mdl.GeneralResponse
 // @Failure 400 {object} mdl.Response400
 // @Failure 401 {object} middleware.Response
+// @Failure 412 {object} mdl.GeneralResponse
+// @Failure 428 {object} mdl.GeneralResponse
 // @Failure 500 {object} mdl.Response500
 // @Router /api/order/{id} [put]
 func (u Handler) UpdateHandler(c *gin.Context) {
@@ -145,7 +370,13 @@ func (u Handler) UpdateHandler(c *gin.Context) {
 		return
 	}
 	param.Id = c.Param("id")
-	u.logger.Debugf("%+v", param)
+	version, err := utils.GetIfMatch(c.GetHeader(utils.IfMatch))
+	if err != nil {
+		c.JSON(err.Code, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("order.updateHandler.Precondition : %v", err.Message.Error())})
+		return
+	}
+	param.Version = version
+	logger.FromContext(c).Debugf("%+v", param)
 
 	if err := utils.ValidateUpdateOrderRequest(param); err == nil {
 		result, err := u.Usecase.Update(c, param)
@@ -154,7 +385,7 @@ func (u Handler) UpdateHandler(c *gin.Context) {
 			result.Message = "success update data"
 			c.JSON(http.StatusOK, result)
 		} else {
-			u.logger.Error(err)
+			logger.FromContext(c).Error(err)
 			result.Success = false
 			result.Message = err.Message.Error()
 			c.JSON(err.Code, result)
@@ -164,21 +395,89 @@ func (u Handler) UpdateHandler(c *gin.Context) {
 	}
 }
 
+// @Summary Patch Orders
+// @Description Partially Update Some Orders with a JSON Merge Patch (RFC 7396), customer users can not move an order to another customer
+// @Accept application/merge-patch+json,json
+// @Produce json
+// @Param If-Match header string false "etag returned by get by id, required when REQUIRE_IF_MATCH is enabled"
+// @Param request body mdl.CreateRequest true "Sample Patch request payload, only the fields to change"
+// @Security jwt
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 412 {object} mdl.GeneralResponse
+// @Failure 415 {object} mdl.GeneralResponse
+// @Failure 428 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/order/{id} [patch]
+func (u Handler) PatchHandler(c *gin.Context) {
+	var JWT, _ = c.Get(middleware.JwtClaims)
+	jwtClaims := JWT.(*middleware.AuthCustomClaims)
+	if contentType := c.ContentType(); contentType != patch.ContentType && contentType != gin.MIMEJSON {
+		c.JSON(http.StatusUnsupportedMediaType, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("order.patchHandler.UnsupportedMediaType : expected %s", patch.ContentType)})
+		return
+	}
+
+	document, errn := c.GetRawData()
+	if errn == nil {
+		errn = utils.ValidatePatchOrderFields(jwtClaims.Role, document)
+	}
+	if errn != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("order.patchHandler.BadRequest : %v", errn.Error())})
+		return
+	}
+
+	version, err := utils.GetIfMatch(c.GetHeader(utils.IfMatch))
+	if err != nil {
+		c.JSON(err.Code, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("order.patchHandler.Precondition : %v", err.Message.Error())})
+		return
+	}
+
+	param := &mdl.PatchRequest{
+		Id:      c.Param("id"),
+		Patch:   document,
+		Version: version,
+	}
+	logger.FromContext(c).Debugf("%s %s", param.Id, param.Patch)
+
+	result, err := u.Usecase.Patch(c, param)
+	if err == nil {
+		result.Success = true
+		result.Message = "success update data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
 // @Summary Delete Order
 // @Description Delete Some Orders
 // @Accept json
 // @Produce json
+// @Param If-Match header string false "etag returned by get by id, required when REQUIRE_IF_MATCH is enabled"
 // @Security jwt
 // @Success 200 {object} mdl.GeneralResponse
 // @Failure 400 {object} mdl.Response400
 // @Failure 401 {object} middleware.Response
+// @Failure 412 {object} mdl.GeneralResponse
+// @Failure 428 {object} mdl.GeneralResponse
 // @Failure 500 {object} mdl.Response500
 // @Router /api/order/{id} [delete]
 func (u Handler) DeleteHandler(c *gin.Context) {
+	version, err := utils.GetIfMatch(c.GetHeader(utils.IfMatch))
+	if err != nil {
+		c.JSON(err.Code, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("order.deleteHandler.Precondition : %v", err.Message.Error())})
+		return
+	}
+
 	param := &mdl.DeleteRequest{
-		Id: c.Param("id"),
+		Id:      c.Param("id"),
+		Version: version,
 	}
-	u.logger.Debugf("%+v", param)
+	logger.FromContext(c).Debugf("%+v", param)
 
 	if err := utils.ValidateDeleteOrderRequest(param); err == nil {
 		result, err := u.Usecase.Delete(c, param)
@@ -187,7 +486,7 @@ func (u Handler) DeleteHandler(c *gin.Context) {
 			result.Message = "success delete data"
 			c.JSON(http.StatusOK, result)
 		} else {
-			u.logger.Error(err)
+			logger.FromContext(c).Error(err)
 			result.Success = false
 			result.Message = err.Message.Error()
 			c.JSON(err.Code, result)
@@ -1,7 +1,9 @@
 package order
 
 import (
+	"errors"
 	"fmt"
+	"gin-dbo/framework/export"
 	"gin-dbo/framework/utils"
 	models "gin-dbo/model/order"
 	view "gin-dbo/view/order"
@@ -10,6 +12,7 @@ import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"gin-dbo/framework/database"
 	internal "gin-dbo/framework/error"
 )
 
@@ -20,24 +23,35 @@ type Repo struct {
 type Repository interface {
 	Get(ctx *gin.Context, param *view.GetRequest, page int) (res []*models.Order, err *internal.Error)
 	Count(ctx *gin.Context, param *view.GetRequest) (res int, err *internal.Error)
+	Export(ctx *gin.Context, param *view.GetRequest, fn func([]*models.Order) error) (err *internal.Error)
 	GetById(ctx *gin.Context, id string) (res *models.Order, err *internal.Error)
 	Create(ctx *gin.Context, request *view.CreateRequest) (res string, err *internal.Error)
+	CreateBatch(ctx *gin.Context, request []*view.CreateRequest) (res []string, err *internal.Error)
 	Update(ctx *gin.Context, request *view.UpdateRequest) (err *internal.Error)
 	Delete(ctx *gin.Context, request *view.DeleteRequest) (err *internal.Error)
+	Transaction(ctx *gin.Context, fn func(repo Repository) *internal.Error) (err *internal.Error)
 }
 
 func NewRepository(dbconn *gorm.DB) Repository {
-	return &Repo{Dbconn: dbconn}
+	return tracedRepository{next: &Repo{Dbconn: dbconn}}
+}
+
+// conn joins the transaction carried by ctx, if any.
+func (r Repo) conn(ctx *gin.Context) *gorm.DB {
+	return database.Conn(ctx, r.Dbconn)
 }
 
 func (r Repo) Get(ctx *gin.Context, param *view.GetRequest, page int) ([]*models.Order, *internal.Error) {
 	var (
 		res []*models.Order
 	)
-	query := r.Dbconn
+	query := r.conn(ctx)
 	if param.Keyword != "" {
 		query = query.Where("name LIKE ?", "%"+param.Keyword+"%")
 	}
+	if param.CustomerId != "" {
+		query = query.Where("customer_id = ?", param.CustomerId)
+	}
 
 	if param.Page > 0 {
 		query = query.Offset((page - 1) * param.Limit)
@@ -57,10 +71,13 @@ func (r Repo) Count(ctx *gin.Context, param *view.GetRequest) (int, *internal.Er
 	var (
 		res int
 	)
-	query := r.Dbconn.Select("COUNT(1) as total").Model(&models.Order{})
+	query := r.conn(ctx).Select("COUNT(1) as total").Model(&models.Order{})
 	if param.Keyword != "" {
 		query = query.Where("name LIKE ?", "%"+param.Keyword+"%")
 	}
+	if param.CustomerId != "" {
+		query = query.Where("customer_id = ?", param.CustomerId)
+	}
 
 	if err := query.Pluck("total", &res).Error; err != nil {
 		return 0, internal.NewError(500, fmt.Errorf("customer.repository.Count : %v", err.Error()))
@@ -68,13 +85,37 @@ func (r Repo) Count(ctx *gin.Context, param *view.GetRequest) (int, *internal.Er
 	return res, nil
 }
 
+func (r Repo) Export(ctx *gin.Context, param *view.GetRequest, fn func([]*models.Order) error) *internal.Error {
+	var (
+		res []*models.Order
+	)
+	query := r.conn(ctx)
+	if param.Keyword != "" {
+		query = query.Where("name LIKE ?", "%"+param.Keyword+"%")
+	}
+	if param.CustomerId != "" {
+		query = query.Where("customer_id = ?", param.CustomerId)
+	}
+
+	err := query.FindInBatches(&res, export.BatchSize, func(tx *gorm.DB, batch int) error {
+		return fn(res)
+	}).Error
+	if err != nil {
+		return internal.NewError(500, fmt.Errorf("order.repository.Export : %v", err.Error()))
+	}
+	return nil
+}
+
 func (r Repo) GetById(ctx *gin.Context, id string) (*models.Order, *internal.Error) {
 	var (
 		res *models.Order
 		err error
 	)
-	query := r.Dbconn.Find(&res).Where("id = ?", id)
-	if err = query.Error; err != nil {
+	query := r.conn(ctx).Model(&models.Order{}).Where("id = ?", id).Take(&res)
+	if err = query.Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, internal.NewError(404, fmt.Errorf("order.repository.GetById : %v", fmt.Errorf("no data found with id %s", id)))
+	}
+	if err != nil {
 		return nil, internal.NewError(500, fmt.Errorf("order.repository.GetById : %v", err.Error()))
 	}
 	return res, nil
@@ -85,7 +126,7 @@ func (r Repo) Create(ctx *gin.Context, param *view.CreateRequest) (string, *inte
 
 	uid := uuid.New().String()
 	now := utils.FormatTime()
-	query := r.Dbconn.Create(models.Order{Id: uid, CustomerId: param.CustomerId, Name: param.Name, Qty: param.Qty, CreatedAt: now, UpdatedAt: now})
+	query := r.conn(ctx).Create(models.Order{Id: uid, CustomerId: param.CustomerId, Name: param.Name, Qty: param.Qty, Version: 1, CreatedAt: now, UpdatedAt: now})
 	if err = query.Error; err != nil {
 		return "", internal.NewError(500, fmt.Errorf("order.repository.Create : %v", err.Error()))
 	}
@@ -93,18 +134,79 @@ func (r Repo) Create(ctx *gin.Context, param *view.CreateRequest) (string, *inte
 	return uid, nil
 }
 
-func (r Repo) Update(ctx *gin.Context, param *view.UpdateRequest) *internal.Error {
-	err := r.Dbconn.Updates(models.Order{Id: param.Id, CustomerId: param.CustomerId, Name: param.Name, Qty: param.Qty, UpdatedAt: utils.FormatTime()}).Error
+func (r Repo) CreateBatch(ctx *gin.Context, param []*view.CreateRequest) ([]string, *internal.Error) {
+	now := utils.FormatTime()
+	ids := make([]string, len(param))
+	data := make([]models.Order, len(param))
+	for i, p := range param {
+		ids[i] = uuid.New().String()
+		data[i] = models.Order{Id: ids[i], CustomerId: p.CustomerId, Name: p.Name, Qty: p.Qty, Version: 1, CreatedAt: now, UpdatedAt: now}
+	}
+
+	err := database.Transaction(ctx, r.Dbconn, func(tx *gorm.DB) error {
+		return tx.Create(&data).Error
+	})
 	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("order.repository.CreateBatch : %v", err.Error()))
+	}
+	return ids, nil
+}
+
+func (r Repo) Update(ctx *gin.Context, param *view.UpdateRequest) *internal.Error {
+	query := r.conn(ctx).Model(&models.Order{}).Where("id = ?", param.Id)
+	if param.Version > 0 {
+		query = query.Where("version = ?", param.Version)
+	}
+	query = query.Updates(map[string]interface{}{"customer_id": param.CustomerId, "name": param.Name, "qty": param.Qty, "version": gorm.Expr("version + 1"), "updated_at": utils.FormatTime()})
+	if err := query.Error; err != nil {
 		return internal.NewError(500, fmt.Errorf("order.repository.Update : %v", err.Error()))
 	}
+	if query.RowsAffected == 0 {
+		return r.conflict(ctx, "order.repository.Update", param.Id, param.Version)
+	}
 	return nil
 }
 
 func (r Repo) Delete(ctx *gin.Context, param *view.DeleteRequest) *internal.Error {
-	err := r.Dbconn.Delete(models.Order{Id: param.Id}).Error
-	if err != nil {
+	query := r.conn(ctx).Where("id = ?", param.Id)
+	if param.Version > 0 {
+		query = query.Where("version = ?", param.Version)
+	}
+	query = query.Delete(&models.Order{})
+	if err := query.Error; err != nil {
 		return internal.NewError(500, fmt.Errorf("order.repository.Delete : %v", err.Error()))
 	}
+	if query.RowsAffected == 0 {
+		return r.conflict(ctx, "order.repository.Delete", param.Id, param.Version)
+	}
+	return nil
+}
+
+// conflict explains why a write touched no row: either the order is gone or
+// it was changed since the version the client read.
+func (r Repo) conflict(ctx *gin.Context, method string, id string, version int64) *internal.Error {
+	if _, err := r.GetById(ctx, id); err != nil {
+		return err
+	}
+	return internal.NewError(412, fmt.Errorf("%s : %v", method, fmt.Errorf("version %d of id %s is outdated", version, id)))
+}
+
+// Transaction runs fn with a repository bound to a single database
+// transaction, which other repositories called with the same ctx join,
+// rolling everything back when fn returns an error.
+func (r Repo) Transaction(ctx *gin.Context, fn func(repo Repository) *internal.Error) *internal.Error {
+	var res *internal.Error
+	err := database.Transaction(ctx, r.Dbconn, func(tx *gorm.DB) error {
+		if res = fn(&Repo{Dbconn: tx}); res != nil {
+			return res.Message
+		}
+		return nil
+	})
+	if res != nil {
+		return res
+	}
+	if err != nil {
+		return internal.NewError(500, fmt.Errorf("order.repository.Transaction : %v", err.Error()))
+	}
 	return nil
 }
@@ -0,0 +1,73 @@
+package order
+
+import (
+	"testing"
+
+	"gin-dbo/framework/database/dbtest"
+	view "gin-dbo/view/order"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var orderColumns = []string{"id", "customer_id", "name", "qty", "version"}
+
+func TestGetByIdFound(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `orders` WHERE id = \\?").WithArgs("o1").
+		WillReturnRows(sqlmock.NewRows(orderColumns).AddRow("o1", "c1", "book", 2, 3))
+
+	res, err := NewRepository(db).GetById(dbtest.Context(), "o1")
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	if res.Id != "o1" || res.CustomerId != "c1" || res.Version != 3 {
+		t.Fatalf("got order %+v", res)
+	}
+}
+
+func TestGetByIdNotFound(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `orders` WHERE id = \\?").WithArgs("o1").
+		WillReturnRows(sqlmock.NewRows(orderColumns))
+
+	res, err := NewRepository(db).GetById(dbtest.Context(), "o1")
+	if err == nil || err.Code != 404 {
+		t.Fatalf("got %+v, %v; want 404", res, err)
+	}
+}
+
+func TestUpdateOutdatedVersion(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectExec("UPDATE `orders` SET .* WHERE id = \\? AND version = \\?").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT \\* FROM `orders` WHERE id = \\?").WithArgs("o1").
+		WillReturnRows(sqlmock.NewRows(orderColumns).AddRow("o1", "c1", "book", 2, 3))
+
+	err := NewRepository(db).Update(dbtest.Context(), &view.UpdateRequest{Id: "o1", CustomerId: "c1", Name: "book", Version: 2})
+	if err == nil || err.Code != 412 {
+		t.Fatalf("got %v, want 412", err)
+	}
+}
+
+func TestUpdateMissingOrder(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectExec("UPDATE `orders` SET .* WHERE id = \\? AND version = \\?").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT \\* FROM `orders` WHERE id = \\?").WithArgs("o1").
+		WillReturnRows(sqlmock.NewRows(orderColumns))
+
+	err := NewRepository(db).Update(dbtest.Context(), &view.UpdateRequest{Id: "o1", CustomerId: "c1", Name: "book", Version: 2})
+	if err == nil || err.Code != 404 {
+		t.Fatalf("got %v, want 404", err)
+	}
+}
+
+func TestDeleteMissingOrder(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectExec("DELETE FROM `orders` WHERE id = \\?").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT \\* FROM `orders` WHERE id = \\?").WithArgs("o1").
+		WillReturnRows(sqlmock.NewRows(orderColumns))
+
+	err := NewRepository(db).Delete(dbtest.Context(), &view.DeleteRequest{Id: "o1"})
+	if err == nil || err.Code != 404 {
+		t.Fatalf("got %v, want 404", err)
+	}
+}
@@ -0,0 +1,112 @@
+package order
+
+import (
+	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/tracing"
+	models "gin-dbo/model/order"
+	mdl "gin-dbo/view/order"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tracedRepository opens a span around every repository method.
+type tracedRepository struct {
+	next Repository
+}
+
+func (t tracedRepository) Get(ctx *gin.Context, param *mdl.GetRequest, page int) (res []*models.Order, err *internal.Error) {
+	defer tracing.Start(ctx, "order.repository.Get").End(&err)
+	return t.next.Get(ctx, param, page)
+}
+
+func (t tracedRepository) Count(ctx *gin.Context, param *mdl.GetRequest) (res int, err *internal.Error) {
+	defer tracing.Start(ctx, "order.repository.Count").End(&err)
+	return t.next.Count(ctx, param)
+}
+
+func (t tracedRepository) Export(ctx *gin.Context, param *mdl.GetRequest, fn func([]*models.Order) error) (err *internal.Error) {
+	defer tracing.Start(ctx, "order.repository.Export").End(&err)
+	return t.next.Export(ctx, param, fn)
+}
+
+func (t tracedRepository) GetById(ctx *gin.Context, id string) (res *models.Order, err *internal.Error) {
+	defer tracing.Start(ctx, "order.repository.GetById").End(&err)
+	return t.next.GetById(ctx, id)
+}
+
+func (t tracedRepository) Create(ctx *gin.Context, request *mdl.CreateRequest) (res string, err *internal.Error) {
+	defer tracing.Start(ctx, "order.repository.Create").End(&err)
+	return t.next.Create(ctx, request)
+}
+
+func (t tracedRepository) CreateBatch(ctx *gin.Context, request []*mdl.CreateRequest) (res []string, err *internal.Error) {
+	defer tracing.Start(ctx, "order.repository.CreateBatch").End(&err)
+	return t.next.CreateBatch(ctx, request)
+}
+
+func (t tracedRepository) Update(ctx *gin.Context, request *mdl.UpdateRequest) (err *internal.Error) {
+	defer tracing.Start(ctx, "order.repository.Update").End(&err)
+	return t.next.Update(ctx, request)
+}
+
+func (t tracedRepository) Delete(ctx *gin.Context, request *mdl.DeleteRequest) (err *internal.Error) {
+	defer tracing.Start(ctx, "order.repository.Delete").End(&err)
+	return t.next.Delete(ctx, request)
+}
+
+func (t tracedRepository) Transaction(ctx *gin.Context, fn func(repo Repository) *internal.Error) (err *internal.Error) {
+	defer tracing.Start(ctx, "order.repository.Transaction").End(&err)
+	return t.next.Transaction(ctx, func(repo Repository) *internal.Error {
+		return fn(tracedRepository{next: repo})
+	})
+}
+
+// tracedUsecase opens a span around every usecase method.
+type tracedUsecase struct {
+	next Usecase
+}
+
+func (t tracedUsecase) Get(ctx *gin.Context, param *mdl.GetRequest) (res mdl.ResponseData, err *internal.Error) {
+	defer tracing.Start(ctx, "order.usecase.Get").End(&err)
+	return t.next.Get(ctx, param)
+}
+
+func (t tracedUsecase) Export(ctx *gin.Context, request *mdl.GetRequest, fn func([]*models.Order) error) (err *internal.Error) {
+	defer tracing.Start(ctx, "order.usecase.Export").End(&err)
+	return t.next.Export(ctx, request, fn)
+}
+
+func (t tracedUsecase) GetById(ctx *gin.Context, id string) (res mdl.ResponseDetail, err *internal.Error) {
+	defer tracing.Start(ctx, "order.usecase.GetById").End(&err)
+	return t.next.GetById(ctx, id)
+}
+
+func (t tracedUsecase) Create(ctx *gin.Context, request *mdl.CreateRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "order.usecase.Create").End(&err)
+	return t.next.Create(ctx, request)
+}
+
+func (t tracedUsecase) Import(ctx *gin.Context, request *mdl.ImportRequest) (res mdl.ImportResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "order.usecase.Import").End(&err)
+	return t.next.Import(ctx, request)
+}
+
+func (t tracedUsecase) Update(ctx *gin.Context, request *mdl.UpdateRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "order.usecase.Update").End(&err)
+	return t.next.Update(ctx, request)
+}
+
+func (t tracedUsecase) Patch(ctx *gin.Context, request *mdl.PatchRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "order.usecase.Patch").End(&err)
+	return t.next.Patch(ctx, request)
+}
+
+func (t tracedUsecase) Delete(ctx *gin.Context, request *mdl.DeleteRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "order.usecase.Delete").End(&err)
+	return t.next.Delete(ctx, request)
+}
+
+func (t tracedUsecase) Batch(ctx *gin.Context, request *mdl.BatchRequest) (res mdl.BatchResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "order.usecase.Batch").End(&err)
+	return t.next.Batch(ctx, request)
+}
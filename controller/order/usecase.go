@@ -1,35 +1,66 @@
 package order
 
 import (
+	"encoding/json"
 	"fmt"
+	auditModel "gin-dbo/model/audit"
+	eventModel "gin-dbo/model/event"
+	models "gin-dbo/model/order"
 	mdl "gin-dbo/view/order"
 
 	"github.com/gin-gonic/gin"
 
+	"gin-dbo/controller/audit"
 	"gin-dbo/controller/customer"
 	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/event"
+	"gin-dbo/framework/importer"
+	"gin-dbo/framework/logger"
+	"gin-dbo/framework/metrics"
+	"gin-dbo/framework/patch"
 	"gin-dbo/framework/utils"
 )
 
+const resource = "order"
+
+var batchEvents = map[string]string{
+	mdl.OpCreate: eventModel.OrderCreated,
+	mdl.OpUpdate: eventModel.OrderUpdated,
+	mdl.OpDelete: eventModel.OrderDeleted,
+}
+
 type UsecaseModul struct {
 	Repo         Repository
 	CustomerRepo customer.Repository
+	Audit        audit.Recorder
+	Events       event.Publisher
 }
 
 type Usecase interface {
 	Get(ctx *gin.Context, param *mdl.GetRequest) (res mdl.ResponseData, err *internal.Error)
+	Export(ctx *gin.Context, request *mdl.GetRequest, fn func([]*models.Order) error) (err *internal.Error)
 	GetById(ctx *gin.Context, id string) (res mdl.ResponseDetail, err *internal.Error)
 	Create(ctx *gin.Context, request *mdl.CreateRequest) (res mdl.GeneralResponse, err *internal.Error)
+	Import(ctx *gin.Context, request *mdl.ImportRequest) (res mdl.ImportResponse, err *internal.Error)
 	Update(ctx *gin.Context, request *mdl.UpdateRequest) (res mdl.GeneralResponse, err *internal.Error)
+	Patch(ctx *gin.Context, request *mdl.PatchRequest) (res mdl.GeneralResponse, err *internal.Error)
 	Delete(ctx *gin.Context, request *mdl.DeleteRequest) (res mdl.GeneralResponse, err *internal.Error)
+	Batch(ctx *gin.Context, request *mdl.BatchRequest) (res mdl.BatchResponse, err *internal.Error)
 }
 
-func NewUsecase(u Repository, c customer.Repository) Usecase {
-	return &UsecaseModul{Repo: u, CustomerRepo: c}
+func NewUsecase(u Repository, c customer.Repository, a audit.Recorder, e event.Publisher) Usecase {
+	return tracedUsecase{next: &UsecaseModul{Repo: u, CustomerRepo: c, Audit: a, Events: e}}
 }
 
 func (u *UsecaseModul) Get(ctx *gin.Context, param *mdl.GetRequest) (mdl.ResponseData, *internal.Error) {
 	var res mdl.ResponseData
+	if param.CustomerId != "" {
+		customerId, err := u.CustomerRepo.Resolve(ctx, param.CustomerId)
+		if err != nil {
+			return mdl.ResponseData{}, err
+		}
+		param.CustomerId = customerId
+	}
 	count, err := u.Repo.Count(ctx, param)
 	if err != nil {
 		return mdl.ResponseData{}, err
@@ -54,6 +85,10 @@ func (u *UsecaseModul) Get(ctx *gin.Context, param *mdl.GetRequest) (mdl.Respons
 	return res, nil
 }
 
+func (u *UsecaseModul) Export(ctx *gin.Context, param *mdl.GetRequest, fn func([]*models.Order) error) *internal.Error {
+	return u.Repo.Export(ctx, param, fn)
+}
+
 func (u *UsecaseModul) GetById(ctx *gin.Context, id string) (mdl.ResponseDetail, *internal.Error) {
 	var res mdl.ResponseDetail
 	data, err := u.Repo.GetById(ctx, id)
@@ -66,37 +101,320 @@ func (u *UsecaseModul) GetById(ctx *gin.Context, id string) (mdl.ResponseDetail,
 
 func (u *UsecaseModul) Create(ctx *gin.Context, param *mdl.CreateRequest) (mdl.GeneralResponse, *internal.Error) {
 	var res mdl.GeneralResponse
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		customerId, err := u.customer(ctx, param.CustomerId)
+		if err != nil {
+			return err
+		}
+		param.CustomerId = customerId
+		id, err := repo.Create(ctx, param)
+		if err != nil {
+			return err
+		}
+		res.Id = id
 
-	_, err := u.CustomerRepo.GetById(ctx, param.CustomerId)
-	if err != nil {
-		return res, err
+		after, err := repo.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err = u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionCreate, resource, id, nil, after)); err != nil {
+			return err
+		}
+		return u.Events.Publish(ctx, event.New(ctx, eventModel.OrderCreated, resource, id, after))
+	})
+	if err == nil {
+		metrics.OrdersCreated.Inc()
 	}
-	id, err := u.Repo.Create(ctx, param)
-	if err != nil {
-		return res, err
+	return res, err
+}
+
+func (u *UsecaseModul) Import(ctx *gin.Context, param *mdl.ImportRequest) (mdl.ImportResponse, *internal.Error) {
+	var (
+		res       = mdl.ImportResponse{DryRun: param.DryRun}
+		valid     []*mdl.CreateRequest
+		results   []*importer.Result
+		customers = map[string]struct {
+			id  string
+			err *internal.Error
+		}{}
+	)
+
+	for _, row := range param.Rows {
+		result := &importer.Result{Row: row.Row, Status: importer.StatusError}
+		res.Data = append(res.Data, result)
+		if row.Error != nil {
+			result.Reason = row.Error.Error()
+			continue
+		} else if row.Request == nil {
+			result.Status, result.Reason = importer.StatusSkipped, "empty row"
+			continue
+		} else if err := utils.ValidateCreateOrderRequest(row.Request); err != nil {
+			result.Reason = err.Error()
+			continue
+		}
+
+		owner, checked := customers[row.Request.CustomerId]
+		if !checked {
+			owner.id, owner.err = u.customer(ctx, row.Request.CustomerId)
+			customers[row.Request.CustomerId] = owner
+		}
+		if owner.err != nil {
+			result.Reason = owner.err.Message.Error()
+		} else if param.DryRun {
+			result.Status, result.Reason = importer.StatusSkipped, "dry run"
+		} else {
+			row.Request.CustomerId = owner.id
+			valid = append(valid, row.Request)
+			results = append(results, result)
+		}
 	}
-	res.Id = id
+
+	for start := 0; start < len(valid); start += importer.BatchSize {
+		end := start + importer.BatchSize
+		if end > len(valid) {
+			end = len(valid)
+		}
+		var ids []string
+		err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+			var (
+				entries []*auditModel.Audit
+				events  []*eventModel.Event
+				err     *internal.Error
+			)
+			if ids, err = repo.CreateBatch(ctx, valid[start:end]); err != nil {
+				return err
+			}
+			for i, id := range ids {
+				request := valid[start+i]
+				after := &models.Order{Id: id, CustomerId: request.CustomerId, Name: request.Name, Qty: request.Qty}
+				entries = append(entries, audit.NewEntry(ctx, auditModel.ActionCreate, resource, id, nil, after))
+				events = append(events, event.New(ctx, eventModel.OrderCreated, resource, id, after))
+			}
+			if err = u.Audit.Record(ctx, entries...); err != nil {
+				return err
+			}
+			return u.Events.Publish(ctx, events...)
+		})
+		for i, result := range results[start:end] {
+			if err != nil {
+				result.Reason = err.Message.Error()
+			} else {
+				result.Status, result.Id = importer.StatusCreated, ids[i]
+			}
+		}
+		if err == nil {
+			metrics.OrdersCreated.Add(float64(len(ids)))
+		}
+	}
+
+	for _, result := range res.Data {
+		switch result.Status {
+		case importer.StatusCreated:
+			res.Created++
+		case importer.StatusSkipped:
+			res.Skipped++
+		default:
+			res.Failed++
+		}
+	}
+	logger.FromContext(ctx).Infof("order.usecase.Import : created %d, skipped %d, failed %d", res.Created, res.Skipped, res.Failed)
 	return res, nil
 }
 
 func (u *UsecaseModul) Update(ctx *gin.Context, param *mdl.UpdateRequest) (mdl.GeneralResponse, *internal.Error) {
 	var res mdl.GeneralResponse
-	_, err := u.CustomerRepo.GetById(ctx, param.CustomerId)
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		before, err := repo.GetById(ctx, param.Id)
+		if err != nil {
+			return err
+		}
+		if param.CustomerId, err = u.customer(ctx, param.CustomerId); err != nil {
+			return err
+		}
+		if err = repo.Update(ctx, param); err != nil {
+			return err
+		}
+
+		after, err := repo.GetById(ctx, param.Id)
+		if err != nil {
+			return err
+		}
+		if err = u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionUpdate, resource, param.Id, before, after)); err != nil {
+			return err
+		}
+		return u.Events.Publish(ctx, event.New(ctx, eventModel.OrderUpdated, resource, param.Id, after))
+	})
+	return res, err
+}
+
+func (u *UsecaseModul) Patch(ctx *gin.Context, param *mdl.PatchRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	data, err := u.Repo.GetById(ctx, param.Id)
 	if err != nil {
 		return res, err
 	}
-	err = u.Repo.Update(ctx, param)
-	if err != nil {
-		return res, err
+
+	current, errn := json.Marshal(&mdl.UpdateRequest{Id: data.Id, CustomerId: data.CustomerId, Name: data.Name, Qty: &data.Qty})
+	if errn == nil {
+		current, errn = patch.Merge(current, param.Patch)
 	}
-	return res, nil
+	request := new(mdl.UpdateRequest)
+	if errn == nil {
+		errn = json.Unmarshal(current, request)
+	}
+	if errn != nil {
+		return res, internal.NewError(400, fmt.Errorf("order.usecase.Patch : %v", errn))
+	}
+
+	request.Id = param.Id
+	request.Version = param.Version
+	if request.Version == 0 {
+		request.Version = data.Version
+	}
+	if errn = utils.ValidateUpdateOrderRequest(request); errn != nil {
+		return res, internal.NewError(400, fmt.Errorf("order.usecase.Patch : %v", errn))
+	}
+	return u.Update(ctx, request)
 }
 
 func (u *UsecaseModul) Delete(ctx *gin.Context, param *mdl.DeleteRequest) (mdl.GeneralResponse, *internal.Error) {
 	var res mdl.GeneralResponse
-	err := u.Repo.Delete(ctx, param)
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		before, err := repo.GetById(ctx, param.Id)
+		if err != nil {
+			return err
+		}
+		if err = repo.Delete(ctx, param); err != nil {
+			return err
+		}
+		if err = u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionDelete, resource, param.Id, before, nil)); err != nil {
+			return err
+		}
+		return u.Events.Publish(ctx, event.New(ctx, eventModel.OrderDeleted, resource, param.Id, before))
+	})
+	return res, err
+}
+
+func (u *UsecaseModul) Batch(ctx *gin.Context, param *mdl.BatchRequest) (mdl.BatchResponse, *internal.Error) {
+	var (
+		res     = mdl.BatchResponse{Atomic: param.Atomic}
+		invalid *internal.Error
+	)
+
+	for i, op := range param.Operations {
+		result := &mdl.BatchResult{Index: i, Op: op.Op, Id: op.Id}
+		res.Data = append(res.Data, result)
+		if err := utils.ValidateBatchOrderOperation(op); err != nil {
+			result.Message = err.Error()
+			if invalid == nil {
+				invalid = internal.NewError(400, fmt.Errorf("operation %d is invalid : %v", i, err))
+			}
+		}
+	}
+
+	if !param.Atomic {
+		for i, op := range param.Operations {
+			if res.Data[i].Message == "" {
+				// every operation commits on its own together with its trail
+				err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+					return u.applyBatchOperation(ctx, repo, op, res.Data[i])
+				})
+				if err == nil && op.Op == mdl.OpCreate {
+					metrics.OrdersCreated.Inc()
+				} else if err != nil {
+					logger.FromContext(ctx).Warnf("order.usecase.Batch : operation %d failed : %v", i, err.Message)
+				}
+			}
+		}
+		return res, nil
+	}
+
+	if invalid != nil {
+		return res, invalid
+	}
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		for i, op := range param.Operations {
+			if err := u.applyBatchOperation(ctx, repo, op, res.Data[i]); err != nil {
+				return internal.NewError(err.Code, fmt.Errorf("operation %d failed : %v", i, err.Message))
+			}
+		}
+		return nil
+	})
 	if err != nil {
+		logger.FromContext(ctx).Warnf("order.usecase.Batch : rolled back %d operations : %v", len(param.Operations), err.Message)
+		for _, result := range res.Data {
+			if result.Success {
+				result.Success = false
+				result.Message = "rolled back"
+			}
+		}
 		return res, err
 	}
+	for _, op := range param.Operations {
+		if op.Op == mdl.OpCreate {
+			metrics.OrdersCreated.Inc()
+		}
+	}
 	return res, nil
 }
+
+// applyBatchOperation runs a single batch operation through repo and records
+// its audit entry and event in the same transaction.
+func (u *UsecaseModul) applyBatchOperation(ctx *gin.Context, repo Repository, op *mdl.BatchOperation, result *mdl.BatchResult) *internal.Error {
+	var (
+		before *models.Order
+		after  *models.Order
+		err    *internal.Error
+	)
+	switch op.Op {
+	case mdl.OpCreate:
+		if op.CustomerId, err = u.customer(ctx, op.CustomerId); err == nil {
+			if result.Id, err = repo.Create(ctx, op.CreateRequest()); err == nil {
+				after, err = repo.GetById(ctx, result.Id)
+			}
+		}
+	case mdl.OpUpdate:
+		if before, err = repo.GetById(ctx, op.Id); err == nil {
+			if op.CustomerId, err = u.customer(ctx, op.CustomerId); err == nil {
+				if err = repo.Update(ctx, op.UpdateRequest()); err == nil {
+					after, err = repo.GetById(ctx, op.Id)
+				}
+			}
+		}
+	case mdl.OpDelete:
+		if before, err = repo.GetById(ctx, op.Id); err == nil {
+			err = repo.Delete(ctx, op.DeleteRequest())
+		}
+	}
+
+	if err == nil {
+		err = u.Audit.Record(ctx, audit.NewEntry(ctx, op.Op, resource, result.Id, before, after))
+	}
+	if err == nil {
+		payload := after
+		if payload == nil {
+			payload = before
+		}
+		err = u.Events.Publish(ctx, event.New(ctx, batchEvents[op.Op], resource, result.Id, payload))
+	}
+	if err != nil {
+		result.Message = err.Message.Error()
+		return err
+	}
+	result.Success = true
+	result.Message = "success " + op.Op + " data"
+	return nil
+}
+
+// customer checks that the customer id exists, returning the id of the
+// customer it was merged into when it was.
+func (u *UsecaseModul) customer(ctx *gin.Context, id string) (string, *internal.Error) {
+	resolved, err := u.CustomerRepo.Resolve(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if _, err = u.CustomerRepo.GetById(ctx, resolved); err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
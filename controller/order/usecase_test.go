@@ -0,0 +1,124 @@
+package order
+
+import (
+	"testing"
+
+	"gin-dbo/framework/database/dbtest"
+	internal "gin-dbo/framework/error"
+	auditModel "gin-dbo/model/audit"
+	eventModel "gin-dbo/model/event"
+	mdl "gin-dbo/view/order"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// trail keeps the audit entries and events of a usecase instead of storing
+// them.
+type trail struct {
+	entries []*auditModel.Audit
+	events  []*eventModel.Event
+}
+
+func (t *trail) Record(ctx *gin.Context, entries ...*auditModel.Audit) *internal.Error {
+	t.entries = append(t.entries, entries...)
+	return nil
+}
+
+func (t *trail) Publish(ctx *gin.Context, events ...*eventModel.Event) *internal.Error {
+	t.events = append(t.events, events...)
+	return nil
+}
+
+func newUsecase(db *gorm.DB) *UsecaseModul {
+	t := &trail{}
+	return &UsecaseModul{Repo: NewRepository(db), Audit: t, Events: t}
+}
+
+// expectDelete expects order id to be read and deleted, affecting rows.
+func expectDelete(mock sqlmock.Sqlmock, id string, rows int64) {
+	mock.ExpectQuery("SELECT \\* FROM `orders` WHERE id = \\?").WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(id, "c1", "book", 2, 3))
+	mock.ExpectExec("DELETE FROM `orders` WHERE id = \\? AND version = \\?").WithArgs(id, 3).
+		WillReturnResult(sqlmock.NewResult(0, rows))
+}
+
+func TestBatchAtomicRollsBackEverything(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectBegin()
+	expectDelete(mock, "o1", 1)
+	expectDelete(mock, "o2", 0)
+	mock.ExpectQuery("SELECT \\* FROM `orders` WHERE id = \\?").WithArgs("o2").
+		WillReturnRows(sqlmock.NewRows(orderColumns).AddRow("o2", "c1", "book", 2, 4))
+	mock.ExpectRollback()
+
+	res, err := newUsecase(db).Batch(dbtest.Context(), &mdl.BatchRequest{Atomic: true, Operations: []*mdl.BatchOperation{
+		{Op: mdl.OpDelete, Id: "o1", Version: 3},
+		{Op: mdl.OpDelete, Id: "o2", Version: 3},
+	}})
+	if err == nil || err.Code != 412 {
+		t.Fatalf("got %v, want 412", err)
+	}
+	if !res.Atomic || len(res.Data) != 2 {
+		t.Fatalf("got %+v, want the results of 2 atomic operations", res)
+	}
+	if res.Data[0].Success || res.Data[0].Message != "rolled back" {
+		t.Fatalf("got first result %+v, want it rolled back", res.Data[0])
+	}
+	if res.Data[1].Success || res.Data[1].Message == "" {
+		t.Fatalf("got second result %+v, want its failure", res.Data[1])
+	}
+}
+
+func TestBatchAtomicRefusesInvalidOperations(t *testing.T) {
+	db, _ := dbtest.New(t)
+
+	res, err := newUsecase(db).Batch(dbtest.Context(), &mdl.BatchRequest{Atomic: true, Operations: []*mdl.BatchOperation{
+		{Op: mdl.OpDelete, Id: "o1"},
+		{Op: mdl.OpDelete},
+	}})
+	if err == nil || err.Code != 400 {
+		t.Fatalf("got %v, want 400", err)
+	}
+	if res.Data[0].Success || res.Data[0].Message != "" || res.Data[1].Message == "" {
+		t.Fatalf("got results %+v %+v, want only the second one invalid and nothing applied", res.Data[0], res.Data[1])
+	}
+}
+
+func TestBatchBestEffortKeepsWhatSucceeded(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectBegin()
+	expectDelete(mock, "o1", 1)
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `orders` WHERE id = \\?").WithArgs("o2").
+		WillReturnRows(sqlmock.NewRows(orderColumns))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	expectDelete(mock, "o3", 1)
+	mock.ExpectCommit()
+
+	u := newUsecase(db)
+	res, err := u.Batch(dbtest.Context(), &mdl.BatchRequest{Operations: []*mdl.BatchOperation{
+		{Op: mdl.OpDelete, Id: "o1", Version: 3},
+		{Op: mdl.OpDelete, Id: "o2", Version: 3},
+		{Op: "archive", Id: "o4"},
+		{Op: mdl.OpDelete, Id: "o3", Version: 3},
+	}})
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	want := []bool{true, false, false, true}
+	for i, result := range res.Data {
+		if result.Index != i || result.Success != want[i] || result.Message == "" {
+			t.Fatalf("got result %d %+v, want success %t with a message", i, result, want[i])
+		}
+	}
+	if entries := u.Audit.(*trail).entries; len(entries) != 2 || entries[0].ResourceId != "o1" || entries[1].ResourceId != "o3" {
+		t.Fatalf("got audit entries %+v, want the deletions of o1 and o3", entries)
+	}
+	if events := u.Events.(*trail).events; len(events) != 2 || events[0].Type != eventModel.OrderDeleted {
+		t.Fatalf("got events %+v, want 2 deletions", events)
+	}
+}
@@ -0,0 +1,148 @@
+package segment
+
+import (
+	"errors"
+	"fmt"
+	"gin-dbo/framework/utils"
+	models "gin-dbo/model/segment"
+	view "gin-dbo/view/segment"
+
+	"gin-dbo/framework/database"
+	internal "gin-dbo/framework/error"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Repo struct {
+	Dbconn *gorm.DB
+}
+
+type Repository interface {
+	Get(ctx *gin.Context, request *view.GetRequest, page int) (res []*models.Segment, err *internal.Error)
+	Count(ctx *gin.Context, request *view.GetRequest) (res int, err *internal.Error)
+	GetById(ctx *gin.Context, id string) (res *models.Segment, err *internal.Error)
+	GetByName(ctx *gin.Context, name string) (res *models.Segment, err *internal.Error)
+	Create(ctx *gin.Context, segment *models.Segment) (err *internal.Error)
+	Update(ctx *gin.Context, request *view.UpdateRequest) (err *internal.Error)
+	Delete(ctx *gin.Context, request *view.DeleteRequest) (err *internal.Error)
+	Transaction(ctx *gin.Context, fn func(repo Repository) *internal.Error) (err *internal.Error)
+}
+
+func NewRepository(dbconn *gorm.DB) Repository {
+	return tracedRepository{next: &Repo{Dbconn: dbconn}}
+}
+
+// conn joins the transaction carried by ctx, if any.
+func (r Repo) conn(ctx *gin.Context) *gorm.DB {
+	return database.Conn(ctx, r.Dbconn)
+}
+
+func (r Repo) Get(ctx *gin.Context, param *view.GetRequest, page int) ([]*models.Segment, *internal.Error) {
+	var (
+		res []*models.Segment
+	)
+	query := r.conn(ctx)
+	if param.Page > 0 {
+		query = query.Offset((page - 1) * param.Limit)
+	}
+
+	if param.Limit > 0 {
+		query = query.Limit(param.Limit)
+	}
+
+	if err := query.Order("name").Find(&res).Error; err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("segment.repository.Get : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func (r Repo) Count(ctx *gin.Context, param *view.GetRequest) (int, *internal.Error) {
+	var (
+		res int
+	)
+	query := r.conn(ctx).Select("COUNT(1) as total").Model(&models.Segment{})
+	if err := query.Pluck("total", &res).Error; err != nil {
+		return 0, internal.NewError(500, fmt.Errorf("segment.repository.Count : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func (r Repo) GetById(ctx *gin.Context, id string) (*models.Segment, *internal.Error) {
+	var (
+		res *models.Segment
+		err error
+	)
+	query := r.conn(ctx).Model(&models.Segment{}).Where("id = ?", id).Take(&res)
+	if err = query.Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, internal.NewError(404, fmt.Errorf("segment.repository.GetById : %v", fmt.Errorf("no data found with id %s", id)))
+	}
+	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("segment.repository.GetById : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func (r Repo) GetByName(ctx *gin.Context, name string) (*models.Segment, *internal.Error) {
+	var res *models.Segment
+	err := r.conn(ctx).Where("name = ?", name).Take(&res).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, internal.NewError(404, fmt.Errorf("segment.repository.GetByName : %v", fmt.Errorf("no data found with name %s", name)))
+	}
+	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("segment.repository.GetByName : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func (r Repo) Create(ctx *gin.Context, segment *models.Segment) *internal.Error {
+	if err := r.conn(ctx).Create(segment).Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("segment.repository.Create : %v", err.Error()))
+	}
+	return nil
+}
+
+func (r Repo) Update(ctx *gin.Context, param *view.UpdateRequest) *internal.Error {
+	data := &models.Segment{Name: param.Name, Description: param.Description, Match: param.Match, Rules: param.Rules, UpdatedAt: utils.FormatTime()}
+	query := r.conn(ctx).Model(&models.Segment{}).Where("id = ?", param.Id).Select("name", "description", "match", "rules", "updated_at").Updates(data)
+	if err := query.Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("segment.repository.Update : %v", err.Error()))
+	}
+	if query.RowsAffected == 0 {
+		if _, err := r.GetById(ctx, param.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r Repo) Delete(ctx *gin.Context, param *view.DeleteRequest) *internal.Error {
+	query := r.conn(ctx).Where("id = ?", param.Id).Delete(&models.Segment{})
+	if err := query.Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("segment.repository.Delete : %v", err.Error()))
+	}
+	if query.RowsAffected == 0 {
+		return internal.NewError(404, fmt.Errorf("segment.repository.Delete : %v", fmt.Errorf("no data found with id %s", param.Id)))
+	}
+	return nil
+}
+
+// Transaction runs fn with a repository bound to a single database
+// transaction, which other repositories called with the same ctx join,
+// rolling everything back when fn returns an error.
+func (r Repo) Transaction(ctx *gin.Context, fn func(repo Repository) *internal.Error) *internal.Error {
+	var res *internal.Error
+	err := database.Transaction(ctx, r.Dbconn, func(tx *gorm.DB) error {
+		if res = fn(&Repo{Dbconn: tx}); res != nil {
+			return res.Message
+		}
+		return nil
+	})
+	if res != nil {
+		return res
+	}
+	if err != nil {
+		return internal.NewError(500, fmt.Errorf("segment.repository.Transaction : %v", err.Error()))
+	}
+	return nil
+}
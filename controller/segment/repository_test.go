@@ -0,0 +1,20 @@
+package segment
+
+import (
+	"testing"
+
+	"gin-dbo/framework/database/dbtest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetByIdNotFound(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `segments` WHERE id = \\? LIMIT 1").WithArgs("s1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	res, err := NewRepository(db).GetById(dbtest.Context(), "s1")
+	if err == nil || err.Code != 404 {
+		t.Fatalf("got %+v, %v; want 404", res, err)
+	}
+}
@@ -0,0 +1,247 @@
+package segment
+
+import (
+	"fmt"
+	"gin-dbo/framework/logger"
+	"gin-dbo/framework/utils"
+	mdl "gin-dbo/view/segment"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	Usecase Usecase
+}
+
+// @SecurityDefinitions jwt
+func Router(router *gin.Engine, uc Usecase, auth func(*gin.Context)) {
+	u := Handler{Usecase: uc}
+	authorized := router.Group("/", auth)
+	{
+		authorized.GET("api/segments", u.GetHandler)
+		authorized.GET("api/segments/:id", u.GetByIdHandler)
+		authorized.POST("api/segments", u.CreateHandler)
+		authorized.PUT("api/segments/:id", u.UpdateHandler)
+		authorized.DELETE("api/segments/:id", u.DeleteHandler)
+		authorized.GET("api/segments/:id/members", u.GetMembersHandler)
+	}
+}
+
+// @Summary Get All Segments
+// @Description Get All Customer Segments, admin only
+// @param limit query int false "limit"
+// @param page query string false "page"
+// @Produce json
+// @Security jwt
+// @Success 200 {object} mdl.ResponseData
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Router /api/segments [get]
+func (u Handler) GetHandler(c *gin.Context) {
+	limit, err := utils.GetLimit(c.Query(utils.Limit))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("segment.getHandler.BadRequest : %v", err.Message.Error())})
+		return
+	}
+
+	page, err := utils.GetTargetPage(c.Query(utils.Page))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("segment.getHandler.BadRequest : %v", err.Message.Error())})
+		return
+	}
+
+	param := &mdl.GetRequest{
+		Limit: limit,
+		Page:  page,
+	}
+	result, err := u.Usecase.Get(c, param)
+	if err == nil {
+		result.Success = true
+		result.Message = "success retrieve data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
+// @Summary Get Segment By Id
+// @Description Customer Segment By Id with its rules, admin only
+// @Produce json
+// @Security jwt
+// @Success 200 {object} mdl.ResponseDetail
+// @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.Response400
+// @Failure 500 {object} mdl.Response500
+// @Router /api/segments/{id} [get]
+func (u Handler) GetByIdHandler(c *gin.Context) {
+	result, err := u.Usecase.GetById(c, c.Param("id"))
+	if err == nil {
+		result.Success = true
+		result.Message = "success retrieve data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
+// @Summary Create Segment
+// @Description Define a Customer Segment by rules, its members being worked out whenever it is read. A rule on orders or items compares the number of orders or the ordered quantity over the last days (ever when 0) with gt, gte, lt, lte or eq; a rule on tag wants the customer tagged. Match all keeps the customers passing every rule, any those passing one
+// @Accept json
+// @Produce json
+// @Param request body mdl.CreateRequest true "Sample Create request payload"
+// @Security jwt
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 409 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/segments [post]
+func (u Handler) CreateHandler(c *gin.Context) {
+	param := new(mdl.CreateRequest)
+	if err := c.BindJSON(param); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("segment.createHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	logger.FromContext(c).Debugf("%+v", param)
+	if err := utils.ValidateCreateSegmentRequest(param); err == nil {
+		result, err := u.Usecase.Create(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success create data"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("segment.createHandler.BadRequest : %v", err.Error())})
+	}
+}
+
+// @Summary Update Segment
+// @Description Replace the name, description and rules of a Customer Segment
+// @Accept json
+// @Produce json
+// @Param request body mdl.UpdateRequest true "Sample Update request payload"
+// @Security jwt
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.Response400
+// @Failure 409 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/segments/{id} [put]
+func (u Handler) UpdateHandler(c *gin.Context) {
+	param := new(mdl.UpdateRequest)
+	if err := c.BindJSON(param); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("segment.updateHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	param.Id = c.Param("id")
+	logger.FromContext(c).Debugf("%+v", param)
+	if err := utils.ValidateUpdateSegmentRequest(param); err == nil {
+		result, err := u.Usecase.Update(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success update data"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("segment.updateHandler.BadRequest : %v", err.Error())})
+	}
+}
+
+// @Summary Delete Segment
+// @Description Delete a Customer Segment, its members are left untouched
+// @Produce json
+// @Security jwt
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.Response400
+// @Failure 500 {object} mdl.Response500
+// @Router /api/segments/{id} [delete]
+func (u Handler) DeleteHandler(c *gin.Context) {
+	param := &mdl.DeleteRequest{
+		Id: c.Param("id"),
+	}
+	logger.FromContext(c).Debugf("%+v", param)
+
+	if err := utils.ValidateDeleteSegmentRequest(param); err == nil {
+		result, err := u.Usecase.Delete(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success delete data"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("segment.deleteHandler.BadRequest : %v", err.Error())})
+	}
+}
+
+// @Summary Get Segment Members
+// @Description The customers passing the rules of a Customer Segment as they stand now, latest first
+// @param limit query int false "limit"
+// @param page query string false "page"
+// @param keyword query string false "name of some customer"
+// @Produce json
+// @Security jwt
+// @Success 200 {object} mdl.ResponseMembers
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.Response400
+// @Failure 500 {object} mdl.Response500
+// @Router /api/segments/{id}/members [get]
+func (u Handler) GetMembersHandler(c *gin.Context) {
+	limit, err := utils.GetLimit(c.Query(utils.Limit))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("segment.getMembersHandler.BadRequest : %v", err.Message.Error())})
+		return
+	}
+
+	page, err := utils.GetTargetPage(c.Query(utils.Page))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("segment.getMembersHandler.BadRequest : %v", err.Message.Error())})
+		return
+	}
+
+	param := &mdl.GetMembersRequest{
+		SegmentId: c.Param("id"),
+		Keyword:   c.Query(utils.Keyword),
+		Limit:     limit,
+		Page:      page,
+	}
+	result, err := u.Usecase.GetMembers(c, param)
+	if err == nil {
+		result.Success = true
+		result.Message = "success retrieve data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
@@ -0,0 +1,92 @@
+package segment
+
+import (
+	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/tracing"
+	models "gin-dbo/model/segment"
+	mdl "gin-dbo/view/segment"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tracedRepository opens a span around every repository method.
+type tracedRepository struct {
+	next Repository
+}
+
+func (t tracedRepository) Get(ctx *gin.Context, request *mdl.GetRequest, page int) (res []*models.Segment, err *internal.Error) {
+	defer tracing.Start(ctx, "segment.repository.Get").End(&err)
+	return t.next.Get(ctx, request, page)
+}
+
+func (t tracedRepository) Count(ctx *gin.Context, request *mdl.GetRequest) (res int, err *internal.Error) {
+	defer tracing.Start(ctx, "segment.repository.Count").End(&err)
+	return t.next.Count(ctx, request)
+}
+
+func (t tracedRepository) GetById(ctx *gin.Context, id string) (res *models.Segment, err *internal.Error) {
+	defer tracing.Start(ctx, "segment.repository.GetById").End(&err)
+	return t.next.GetById(ctx, id)
+}
+
+func (t tracedRepository) GetByName(ctx *gin.Context, name string) (res *models.Segment, err *internal.Error) {
+	defer tracing.Start(ctx, "segment.repository.GetByName").End(&err)
+	return t.next.GetByName(ctx, name)
+}
+
+func (t tracedRepository) Create(ctx *gin.Context, segment *models.Segment) (err *internal.Error) {
+	defer tracing.Start(ctx, "segment.repository.Create").End(&err)
+	return t.next.Create(ctx, segment)
+}
+
+func (t tracedRepository) Update(ctx *gin.Context, request *mdl.UpdateRequest) (err *internal.Error) {
+	defer tracing.Start(ctx, "segment.repository.Update").End(&err)
+	return t.next.Update(ctx, request)
+}
+
+func (t tracedRepository) Delete(ctx *gin.Context, request *mdl.DeleteRequest) (err *internal.Error) {
+	defer tracing.Start(ctx, "segment.repository.Delete").End(&err)
+	return t.next.Delete(ctx, request)
+}
+
+func (t tracedRepository) Transaction(ctx *gin.Context, fn func(repo Repository) *internal.Error) (err *internal.Error) {
+	defer tracing.Start(ctx, "segment.repository.Transaction").End(&err)
+	return t.next.Transaction(ctx, func(repo Repository) *internal.Error {
+		return fn(tracedRepository{next: repo})
+	})
+}
+
+// tracedUsecase opens a span around every usecase method.
+type tracedUsecase struct {
+	next Usecase
+}
+
+func (t tracedUsecase) Get(ctx *gin.Context, request *mdl.GetRequest) (res mdl.ResponseData, err *internal.Error) {
+	defer tracing.Start(ctx, "segment.usecase.Get").End(&err)
+	return t.next.Get(ctx, request)
+}
+
+func (t tracedUsecase) GetById(ctx *gin.Context, id string) (res mdl.ResponseDetail, err *internal.Error) {
+	defer tracing.Start(ctx, "segment.usecase.GetById").End(&err)
+	return t.next.GetById(ctx, id)
+}
+
+func (t tracedUsecase) Create(ctx *gin.Context, request *mdl.CreateRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "segment.usecase.Create").End(&err)
+	return t.next.Create(ctx, request)
+}
+
+func (t tracedUsecase) Update(ctx *gin.Context, request *mdl.UpdateRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "segment.usecase.Update").End(&err)
+	return t.next.Update(ctx, request)
+}
+
+func (t tracedUsecase) Delete(ctx *gin.Context, request *mdl.DeleteRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "segment.usecase.Delete").End(&err)
+	return t.next.Delete(ctx, request)
+}
+
+func (t tracedUsecase) GetMembers(ctx *gin.Context, request *mdl.GetMembersRequest) (res mdl.ResponseMembers, err *internal.Error) {
+	defer tracing.Start(ctx, "segment.usecase.GetMembers").End(&err)
+	return t.next.GetMembers(ctx, request)
+}
@@ -0,0 +1,215 @@
+package segment
+
+import (
+	"fmt"
+	auditModel "gin-dbo/model/audit"
+	models "gin-dbo/model/segment"
+	customerView "gin-dbo/view/customer"
+	mdl "gin-dbo/view/segment"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"gin-dbo/controller/audit"
+	"gin-dbo/controller/customer"
+	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/middleware"
+	"gin-dbo/framework/utils"
+)
+
+const resource = "segment"
+
+type UsecaseModul struct {
+	Repo         Repository
+	CustomerRepo customer.Repository
+	Audit        audit.Recorder
+}
+
+type Usecase interface {
+	Get(ctx *gin.Context, request *mdl.GetRequest) (res mdl.ResponseData, err *internal.Error)
+	GetById(ctx *gin.Context, id string) (res mdl.ResponseDetail, err *internal.Error)
+	Create(ctx *gin.Context, request *mdl.CreateRequest) (res mdl.GeneralResponse, err *internal.Error)
+	Update(ctx *gin.Context, request *mdl.UpdateRequest) (res mdl.GeneralResponse, err *internal.Error)
+	Delete(ctx *gin.Context, request *mdl.DeleteRequest) (res mdl.GeneralResponse, err *internal.Error)
+	GetMembers(ctx *gin.Context, request *mdl.GetMembersRequest) (res mdl.ResponseMembers, err *internal.Error)
+}
+
+func NewUsecase(u Repository, c customer.Repository, a audit.Recorder) Usecase {
+	return tracedUsecase{next: &UsecaseModul{Repo: u, CustomerRepo: c, Audit: a}}
+}
+
+func (u *UsecaseModul) Get(ctx *gin.Context, param *mdl.GetRequest) (mdl.ResponseData, *internal.Error) {
+	var res mdl.ResponseData
+	count, err := u.Repo.Count(ctx, param)
+	if err != nil {
+		return mdl.ResponseData{}, err
+	}
+	page := utils.GetPage(param.Page)
+	totalPage := utils.GetTotalPage(param.Limit, count)
+
+	if page > totalPage {
+		return mdl.ResponseData{}, internal.NewError(400, fmt.Errorf("page greater than totalPage"))
+	}
+
+	data, err := u.Repo.Get(ctx, param, page)
+	if err != nil {
+		return mdl.ResponseData{}, err
+	}
+
+	res.Data = data
+	res.Limit = param.Limit
+	res.Page = page
+	res.TotalPage = totalPage
+	return res, nil
+}
+
+func (u *UsecaseModul) GetById(ctx *gin.Context, id string) (mdl.ResponseDetail, *internal.Error) {
+	var res mdl.ResponseDetail
+	data, err := u.Repo.GetById(ctx, id)
+	if err != nil {
+		return mdl.ResponseDetail{}, err
+	}
+	res.Data = data
+	return res, nil
+}
+
+func (u *UsecaseModul) Create(ctx *gin.Context, param *mdl.CreateRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	var createdBy string
+	var JWT, _ = ctx.Get(middleware.JwtClaims)
+	if jwtClaims, ok := JWT.(*middleware.AuthCustomClaims); ok {
+		createdBy = jwtClaims.Username
+	}
+	now := utils.FormatTime()
+	segment := &models.Segment{
+		Id:          uuid.New().String(),
+		Name:        param.Name,
+		Description: param.Description,
+		Match:       match(param.Match),
+		Rules:       param.Rules,
+		CreatedBy:   createdBy,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		if err := checkName(ctx, repo, "segment.usecase.Create", segment.Id, segment.Name); err != nil {
+			return err
+		}
+		if err := repo.Create(ctx, segment); err != nil {
+			return err
+		}
+		return u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionCreate, resource, segment.Id, nil, segment))
+	})
+	if err != nil {
+		return res, err
+	}
+	res.Id = segment.Id
+	return res, nil
+}
+
+func (u *UsecaseModul) Update(ctx *gin.Context, param *mdl.UpdateRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	param.Match = match(param.Match)
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		before, err := repo.GetById(ctx, param.Id)
+		if err != nil {
+			return err
+		}
+		if err = checkName(ctx, repo, "segment.usecase.Update", param.Id, param.Name); err != nil {
+			return err
+		}
+		if err = repo.Update(ctx, param); err != nil {
+			return err
+		}
+		after, err := repo.GetById(ctx, param.Id)
+		if err != nil {
+			return err
+		}
+		return u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionUpdate, resource, param.Id, before, after))
+	})
+	if err != nil {
+		return res, err
+	}
+	res.Id = param.Id
+	return res, nil
+}
+
+func (u *UsecaseModul) Delete(ctx *gin.Context, param *mdl.DeleteRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	err := u.Repo.Transaction(ctx, func(repo Repository) *internal.Error {
+		before, err := repo.GetById(ctx, param.Id)
+		if err != nil {
+			return err
+		}
+		if err = repo.Delete(ctx, param); err != nil {
+			return err
+		}
+		return u.Audit.Record(ctx, audit.NewEntry(ctx, auditModel.ActionDelete, resource, param.Id, before, nil))
+	})
+	if err != nil {
+		return res, err
+	}
+	res.Id = param.Id
+	return res, nil
+}
+
+// GetMembers lists the customers passing the rules of the segment as they
+// stand now.
+func (u *UsecaseModul) GetMembers(ctx *gin.Context, param *mdl.GetMembersRequest) (mdl.ResponseMembers, *internal.Error) {
+	var res mdl.ResponseMembers
+	segment, err := u.Repo.GetById(ctx, param.SegmentId)
+	if err != nil {
+		return res, err
+	}
+	request := &customerView.GetRequest{
+		Keyword: param.Keyword,
+		Segment: segment,
+		Limit:   param.Limit,
+		Page:    param.Page,
+	}
+	count, err := u.CustomerRepo.Count(ctx, request)
+	if err != nil {
+		return mdl.ResponseMembers{}, err
+	}
+	page := utils.GetPage(param.Page)
+	totalPage := utils.GetTotalPage(param.Limit, count)
+
+	if page > totalPage {
+		return mdl.ResponseMembers{}, internal.NewError(400, fmt.Errorf("page greater than totalPage"))
+	}
+
+	data, err := u.CustomerRepo.Get(ctx, request, page)
+	if err != nil {
+		return mdl.ResponseMembers{}, err
+	}
+
+	res.Data = data
+	res.Limit = param.Limit
+	res.Page = page
+	res.TotalPage = totalPage
+	return res, nil
+}
+
+// checkName fails with 409 when another segment than id is already called
+// name.
+func checkName(ctx *gin.Context, repo Repository, op string, id string, name string) *internal.Error {
+	other, err := repo.GetByName(ctx, name)
+	if err != nil {
+		if err.Code == 404 {
+			return nil
+		}
+		return err
+	}
+	if other.Id != id {
+		return internal.NewError(409, fmt.Errorf("%s : segment %s already exists", op, name))
+	}
+	return nil
+}
+
+// match defaults to keeping the customers passing every rule.
+func match(v string) string {
+	if v == "" {
+		return models.MatchAll
+	}
+	return v
+}
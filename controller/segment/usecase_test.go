@@ -0,0 +1,29 @@
+package segment
+
+import (
+	"testing"
+
+	"gin-dbo/framework/database/dbtest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCheckNameOfUnusedName(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `segments` WHERE name = \\? LIMIT 1").WithArgs("vip").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	if err := checkName(dbtest.Context(), NewRepository(db), "segment.usecase.Create", "s1", "vip"); err != nil {
+		t.Fatalf("got %v, want the name free", err)
+	}
+}
+
+func TestCheckNameOfUsedName(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `segments` WHERE name = \\? LIMIT 1").WithArgs("vip").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("s2", "vip"))
+
+	if err := checkName(dbtest.Context(), NewRepository(db), "segment.usecase.Create", "s1", "vip"); err == nil || err.Code != 409 {
+		t.Fatalf("got %v, want 409", err)
+	}
+}
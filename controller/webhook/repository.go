@@ -0,0 +1,221 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"gin-dbo/framework/utils"
+	models "gin-dbo/model/webhook"
+	view "gin-dbo/view/webhook"
+
+	"gin-dbo/framework/database"
+	internal "gin-dbo/framework/error"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Repo struct {
+	Dbconn *gorm.DB
+}
+
+type Repository interface {
+	Get(ctx *gin.Context, request *view.GetRequest, page int) (res []*models.Subscription, err *internal.Error)
+	Count(ctx *gin.Context, request *view.GetRequest) (res int, err *internal.Error)
+	GetById(ctx *gin.Context, id string) (res *models.Subscription, err *internal.Error)
+	Create(ctx *gin.Context, subscription *models.Subscription) (err *internal.Error)
+	Update(ctx *gin.Context, request *view.UpdateRequest) (err *internal.Error)
+	Delete(ctx *gin.Context, request *view.DeleteRequest) (err *internal.Error)
+	GetDeliveries(ctx *gin.Context, request *view.GetDeliveriesRequest, page int) (res []*models.Delivery, err *internal.Error)
+	CountDeliveries(ctx *gin.Context, request *view.GetDeliveriesRequest) (res int, err *internal.Error)
+	GetDelivery(ctx *gin.Context, request *view.DeliveryRequest) (res *models.Delivery, err *internal.Error)
+	CreateDelivery(ctx *gin.Context, delivery *models.Delivery) (err *internal.Error)
+	Redeliver(ctx *gin.Context, request *view.DeliveryRequest) (err *internal.Error)
+}
+
+func NewRepository(dbconn *gorm.DB) Repository {
+	return tracedRepository{next: &Repo{Dbconn: dbconn}}
+}
+
+// conn joins the transaction carried by ctx, if any.
+func (r Repo) conn(ctx *gin.Context) *gorm.DB {
+	return database.Conn(ctx, r.Dbconn)
+}
+
+func (r Repo) Get(ctx *gin.Context, param *view.GetRequest, page int) ([]*models.Subscription, *internal.Error) {
+	var (
+		res []*models.Subscription
+	)
+	query := r.conn(ctx)
+	if param.Page > 0 {
+		query = query.Offset((page - 1) * param.Limit)
+	}
+
+	if param.Limit > 0 {
+		query = query.Limit(param.Limit)
+	}
+
+	if err := query.Order("created_at desc").Find(&res).Error; err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("webhook.repository.Get : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func (r Repo) Count(ctx *gin.Context, param *view.GetRequest) (int, *internal.Error) {
+	var (
+		res int
+	)
+	query := r.conn(ctx).Select("COUNT(1) as total").Model(&models.Subscription{})
+	if err := query.Pluck("total", &res).Error; err != nil {
+		return 0, internal.NewError(500, fmt.Errorf("webhook.repository.Count : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func (r Repo) GetById(ctx *gin.Context, id string) (*models.Subscription, *internal.Error) {
+	var (
+		res *models.Subscription
+		err error
+	)
+	query := r.conn(ctx).Model(&models.Subscription{}).Where("id = ?", id).Take(&res)
+	if err = query.Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, internal.NewError(404, fmt.Errorf("webhook.repository.GetById : %v", fmt.Errorf("no data found with id %s", id)))
+	}
+	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("webhook.repository.GetById : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func (r Repo) Create(ctx *gin.Context, subscription *models.Subscription) *internal.Error {
+	if err := r.conn(ctx).Create(subscription).Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("webhook.repository.Create : %v", err.Error()))
+	}
+	return nil
+}
+
+func (r Repo) Update(ctx *gin.Context, param *view.UpdateRequest) *internal.Error {
+	data := &models.Subscription{Url: param.Url, Secret: param.Secret, EventTypes: param.EventTypes, Active: *param.Active, UpdatedAt: utils.FormatTime()}
+	columns := []string{"url", "event_types", "active", "updated_at"}
+	if param.Secret != "" {
+		columns = append(columns, "secret")
+	}
+	if data.Active {
+		// a subscription switched back on starts over
+		columns = append(columns, "consecutive_failures", "disabled_at", "disabled_reason")
+	}
+
+	query := r.conn(ctx).Model(&models.Subscription{}).Where("id = ?", param.Id).Select(columns).Updates(data)
+	if err := query.Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("webhook.repository.Update : %v", err.Error()))
+	}
+	if query.RowsAffected == 0 {
+		if _, err := r.GetById(ctx, param.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r Repo) Delete(ctx *gin.Context, param *view.DeleteRequest) *internal.Error {
+	var rows int64
+	err := database.Transaction(ctx, r.Dbconn, func(tx *gorm.DB) error {
+		deliveries := tx.Model(&models.Delivery{}).Select("id").Where("subscription_id = ?", param.Id)
+		if err := tx.Where("delivery_id in (?)", deliveries).Delete(&models.Attempt{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subscription_id = ?", param.Id).Delete(&models.Delivery{}).Error; err != nil {
+			return err
+		}
+		query := tx.Where("id = ?", param.Id).Delete(&models.Subscription{})
+		rows = query.RowsAffected
+		return query.Error
+	})
+	if err != nil {
+		return internal.NewError(500, fmt.Errorf("webhook.repository.Delete : %v", err.Error()))
+	}
+	if rows == 0 {
+		return internal.NewError(404, fmt.Errorf("webhook.repository.Delete : %v", fmt.Errorf("no data found with id %s", param.Id)))
+	}
+	return nil
+}
+
+func (r Repo) GetDeliveries(ctx *gin.Context, param *view.GetDeliveriesRequest, page int) ([]*models.Delivery, *internal.Error) {
+	var (
+		res []*models.Delivery
+	)
+	query := deliveryFilter(r.conn(ctx), param)
+	if param.Page > 0 {
+		query = query.Offset((page - 1) * param.Limit)
+	}
+
+	if param.Limit > 0 {
+		query = query.Limit(param.Limit)
+	}
+
+	if err := query.Order("created_at desc, id").Find(&res).Error; err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("webhook.repository.GetDeliveries : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func (r Repo) CountDeliveries(ctx *gin.Context, param *view.GetDeliveriesRequest) (int, *internal.Error) {
+	var (
+		res int
+	)
+	query := deliveryFilter(r.conn(ctx).Select("COUNT(1) as total").Model(&models.Delivery{}), param)
+	if err := query.Pluck("total", &res).Error; err != nil {
+		return 0, internal.NewError(500, fmt.Errorf("webhook.repository.CountDeliveries : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func deliveryFilter(query *gorm.DB, param *view.GetDeliveriesRequest) *gorm.DB {
+	query = query.Where("subscription_id = ?", param.SubscriptionId)
+	if param.Status != "" {
+		query = query.Where("status = ?", param.Status)
+	}
+	return query
+}
+
+// GetDelivery returns a delivery of the subscription with its attempt log.
+func (r Repo) GetDelivery(ctx *gin.Context, param *view.DeliveryRequest) (*models.Delivery, *internal.Error) {
+	var res *models.Delivery
+	query := r.conn(ctx).Model(&models.Delivery{}).Where("id = ? and subscription_id = ?", param.DeliveryId, param.SubscriptionId).Take(&res)
+	err := query.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, internal.NewError(404, fmt.Errorf("webhook.repository.GetDelivery : %v", fmt.Errorf("no delivery found with id %s", param.DeliveryId)))
+	}
+	if err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("webhook.repository.GetDelivery : %v", err.Error()))
+	}
+
+	if err := r.conn(ctx).Where("delivery_id = ?", res.Id).Order("attempt").Find(&res.AttemptLog).Error; err != nil {
+		return nil, internal.NewError(500, fmt.Errorf("webhook.repository.GetDelivery : %v", err.Error()))
+	}
+	return res, nil
+}
+
+func (r Repo) CreateDelivery(ctx *gin.Context, delivery *models.Delivery) *internal.Error {
+	if err := r.conn(ctx).Create(delivery).Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("webhook.repository.CreateDelivery : %v", err.Error()))
+	}
+	return nil
+}
+
+// Redeliver schedules a delivery again right away, whatever its status. It
+// keeps its attempt count, so a delivery that already gave up gets one more
+// attempt.
+func (r Repo) Redeliver(ctx *gin.Context, param *view.DeliveryRequest) *internal.Error {
+	query := r.conn(ctx).Model(&models.Delivery{}).Where("id = ? and subscription_id = ?", param.DeliveryId, param.SubscriptionId).Updates(map[string]interface{}{
+		"status":          models.StatusPending,
+		"next_attempt_at": utils.FormatTime(),
+		"delivered_at":    "",
+	})
+	if err := query.Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("webhook.repository.Redeliver : %v", err.Error()))
+	}
+	if query.RowsAffected == 0 {
+		return internal.NewError(404, fmt.Errorf("webhook.repository.Redeliver : %v", fmt.Errorf("no delivery found with id %s", param.DeliveryId)))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"testing"
+
+	"gin-dbo/framework/database/dbtest"
+	view "gin-dbo/view/webhook"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUpdateMissingSubscription(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectExec("UPDATE `webhook_subscriptions` SET .* WHERE id = \\?").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT \\* FROM `webhook_subscriptions` WHERE id = \\?").WithArgs("s1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url"}))
+
+	active := true
+	err := NewRepository(db).Update(dbtest.Context(), &view.UpdateRequest{Id: "s1", Url: "https://example.com/hook", EventTypes: []string{"*"}, Active: &active})
+	if err == nil || err.Code != 404 {
+		t.Fatalf("got %v, want 404", err)
+	}
+}
+
+func TestUpdateUnchangedSubscription(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectExec("UPDATE `webhook_subscriptions` SET .* WHERE id = \\?").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT \\* FROM `webhook_subscriptions` WHERE id = \\?").WithArgs("s1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url"}).AddRow("s1", "https://example.com/hook"))
+
+	active := true
+	err := NewRepository(db).Update(dbtest.Context(), &view.UpdateRequest{Id: "s1", Url: "https://example.com/hook", EventTypes: []string{"*"}, Active: &active})
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+}
+
+func TestGetDelivery(t *testing.T) {
+	request := &view.DeliveryRequest{SubscriptionId: "s1", DeliveryId: "d1"}
+
+	t.Run("found with its attempts", func(t *testing.T) {
+		db, mock := dbtest.New(t)
+		mock.ExpectQuery("SELECT \\* FROM `webhook_deliveries` WHERE id = \\? and subscription_id = \\?").WithArgs("d1", "s1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "subscription_id", "status"}).AddRow("d1", "s1", "delivered"))
+		mock.ExpectQuery("SELECT \\* FROM `webhook_attempts` WHERE delivery_id = \\?").WithArgs("d1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "delivery_id", "attempt", "status_code"}).AddRow("a1", "d1", 1, 200))
+
+		res, err := NewRepository(db).GetDelivery(dbtest.Context(), request)
+		if err != nil {
+			t.Fatal(err.Message)
+		}
+		if res.Id != "d1" || len(res.AttemptLog) != 1 || res.AttemptLog[0].StatusCode != 200 {
+			t.Fatalf("got delivery %+v", res)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db, mock := dbtest.New(t)
+		mock.ExpectQuery("SELECT \\* FROM `webhook_deliveries` WHERE id = \\? and subscription_id = \\?").WithArgs("d1", "s1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "subscription_id", "status"}))
+
+		res, err := NewRepository(db).GetDelivery(dbtest.Context(), request)
+		if err == nil || err.Code != 404 {
+			t.Fatalf("got %+v, %v; want 404", res, err)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package webhook
+
+import (
+	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/tracing"
+	models "gin-dbo/model/webhook"
+	mdl "gin-dbo/view/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tracedRepository opens a span around every repository method.
+type tracedRepository struct {
+	next Repository
+}
+
+func (t tracedRepository) Get(ctx *gin.Context, request *mdl.GetRequest, page int) (res []*models.Subscription, err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.repository.Get").End(&err)
+	return t.next.Get(ctx, request, page)
+}
+
+func (t tracedRepository) Count(ctx *gin.Context, request *mdl.GetRequest) (res int, err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.repository.Count").End(&err)
+	return t.next.Count(ctx, request)
+}
+
+func (t tracedRepository) GetById(ctx *gin.Context, id string) (res *models.Subscription, err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.repository.GetById").End(&err)
+	return t.next.GetById(ctx, id)
+}
+
+func (t tracedRepository) Create(ctx *gin.Context, subscription *models.Subscription) (err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.repository.Create").End(&err)
+	return t.next.Create(ctx, subscription)
+}
+
+func (t tracedRepository) Update(ctx *gin.Context, request *mdl.UpdateRequest) (err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.repository.Update").End(&err)
+	return t.next.Update(ctx, request)
+}
+
+func (t tracedRepository) Delete(ctx *gin.Context, request *mdl.DeleteRequest) (err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.repository.Delete").End(&err)
+	return t.next.Delete(ctx, request)
+}
+
+func (t tracedRepository) GetDeliveries(ctx *gin.Context, request *mdl.GetDeliveriesRequest, page int) (res []*models.Delivery, err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.repository.GetDeliveries").End(&err)
+	return t.next.GetDeliveries(ctx, request, page)
+}
+
+func (t tracedRepository) CountDeliveries(ctx *gin.Context, request *mdl.GetDeliveriesRequest) (res int, err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.repository.CountDeliveries").End(&err)
+	return t.next.CountDeliveries(ctx, request)
+}
+
+func (t tracedRepository) GetDelivery(ctx *gin.Context, request *mdl.DeliveryRequest) (res *models.Delivery, err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.repository.GetDelivery").End(&err)
+	return t.next.GetDelivery(ctx, request)
+}
+
+func (t tracedRepository) CreateDelivery(ctx *gin.Context, delivery *models.Delivery) (err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.repository.CreateDelivery").End(&err)
+	return t.next.CreateDelivery(ctx, delivery)
+}
+
+func (t tracedRepository) Redeliver(ctx *gin.Context, request *mdl.DeliveryRequest) (err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.repository.Redeliver").End(&err)
+	return t.next.Redeliver(ctx, request)
+}
+
+// tracedUsecase opens a span around every usecase method.
+type tracedUsecase struct {
+	next Usecase
+}
+
+func (t tracedUsecase) Get(ctx *gin.Context, request *mdl.GetRequest) (res mdl.ResponseData, err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.usecase.Get").End(&err)
+	return t.next.Get(ctx, request)
+}
+
+func (t tracedUsecase) GetById(ctx *gin.Context, id string) (res mdl.ResponseDetail, err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.usecase.GetById").End(&err)
+	return t.next.GetById(ctx, id)
+}
+
+func (t tracedUsecase) Create(ctx *gin.Context, request *mdl.CreateRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.usecase.Create").End(&err)
+	return t.next.Create(ctx, request)
+}
+
+func (t tracedUsecase) Update(ctx *gin.Context, request *mdl.UpdateRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.usecase.Update").End(&err)
+	return t.next.Update(ctx, request)
+}
+
+func (t tracedUsecase) Delete(ctx *gin.Context, request *mdl.DeleteRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.usecase.Delete").End(&err)
+	return t.next.Delete(ctx, request)
+}
+
+func (t tracedUsecase) GetDeliveries(ctx *gin.Context, request *mdl.GetDeliveriesRequest) (res mdl.ResponseDeliveries, err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.usecase.GetDeliveries").End(&err)
+	return t.next.GetDeliveries(ctx, request)
+}
+
+func (t tracedUsecase) GetDelivery(ctx *gin.Context, request *mdl.DeliveryRequest) (res mdl.ResponseDelivery, err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.usecase.GetDelivery").End(&err)
+	return t.next.GetDelivery(ctx, request)
+}
+
+func (t tracedUsecase) Redeliver(ctx *gin.Context, request *mdl.DeliveryRequest) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.usecase.Redeliver").End(&err)
+	return t.next.Redeliver(ctx, request)
+}
+
+func (t tracedUsecase) Ping(ctx *gin.Context, id string) (res mdl.GeneralResponse, err *internal.Error) {
+	defer tracing.Start(ctx, "webhook.usecase.Ping").End(&err)
+	return t.next.Ping(ctx, id)
+}
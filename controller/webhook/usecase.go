@@ -0,0 +1,220 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	models "gin-dbo/model/webhook"
+	mdl "gin-dbo/view/webhook"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/event"
+	"gin-dbo/framework/utils"
+)
+
+const (
+	resource     = "webhook"
+	secretPrefix = "whsec_"
+	secretBytes  = 24
+)
+
+type UsecaseModul struct {
+	Repo Repository
+}
+
+type Usecase interface {
+	Get(ctx *gin.Context, request *mdl.GetRequest) (res mdl.ResponseData, err *internal.Error)
+	GetById(ctx *gin.Context, id string) (res mdl.ResponseDetail, err *internal.Error)
+	Create(ctx *gin.Context, request *mdl.CreateRequest) (res mdl.GeneralResponse, err *internal.Error)
+	Update(ctx *gin.Context, request *mdl.UpdateRequest) (res mdl.GeneralResponse, err *internal.Error)
+	Delete(ctx *gin.Context, request *mdl.DeleteRequest) (res mdl.GeneralResponse, err *internal.Error)
+	GetDeliveries(ctx *gin.Context, request *mdl.GetDeliveriesRequest) (res mdl.ResponseDeliveries, err *internal.Error)
+	GetDelivery(ctx *gin.Context, request *mdl.DeliveryRequest) (res mdl.ResponseDelivery, err *internal.Error)
+	Redeliver(ctx *gin.Context, request *mdl.DeliveryRequest) (res mdl.GeneralResponse, err *internal.Error)
+	Ping(ctx *gin.Context, id string) (res mdl.GeneralResponse, err *internal.Error)
+}
+
+func NewUsecase(u Repository) Usecase {
+	return tracedUsecase{next: &UsecaseModul{Repo: u}}
+}
+
+func (u *UsecaseModul) Get(ctx *gin.Context, param *mdl.GetRequest) (mdl.ResponseData, *internal.Error) {
+	var res mdl.ResponseData
+	count, err := u.Repo.Count(ctx, param)
+	if err != nil {
+		return mdl.ResponseData{}, err
+	}
+	page := utils.GetPage(param.Page)
+	totalPage := utils.GetTotalPage(param.Limit, count)
+
+	if page > totalPage {
+		return mdl.ResponseData{}, internal.NewError(400, fmt.Errorf("page greater than totalPage"))
+	}
+
+	data, err := u.Repo.Get(ctx, param, page)
+	if err != nil {
+		return mdl.ResponseData{}, err
+	}
+
+	res.Data = data
+	res.Limit = param.Limit
+	res.Page = page
+	res.TotalPage = totalPage
+	return res, nil
+}
+
+func (u *UsecaseModul) GetById(ctx *gin.Context, id string) (mdl.ResponseDetail, *internal.Error) {
+	var res mdl.ResponseDetail
+	data, err := u.Repo.GetById(ctx, id)
+	if err != nil {
+		return mdl.ResponseDetail{}, err
+	}
+	res.Data = data
+	return res, nil
+}
+
+// Create registers an active subscription. The secret is generated when the
+// request has none and is only returned here.
+func (u *UsecaseModul) Create(ctx *gin.Context, param *mdl.CreateRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	secret := param.Secret
+	if secret == "" {
+		generated, err := newSecret()
+		if err != nil {
+			return res, internal.NewError(500, fmt.Errorf("webhook.usecase.Create : %v", err))
+		}
+		secret = generated
+	}
+
+	now := utils.FormatTime()
+	subscription := &models.Subscription{
+		Id:         uuid.New().String(),
+		Url:        param.Url,
+		Secret:     secret,
+		EventTypes: param.EventTypes,
+		Active:     true,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := u.Repo.Create(ctx, subscription); err != nil {
+		return res, err
+	}
+	res.Id = subscription.Id
+	res.Secret = secret
+	return res, nil
+}
+
+func (u *UsecaseModul) Update(ctx *gin.Context, param *mdl.UpdateRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	if err := u.Repo.Update(ctx, param); err != nil {
+		return res, err
+	}
+	res.Id = param.Id
+	res.Secret = param.Secret
+	return res, nil
+}
+
+func (u *UsecaseModul) Delete(ctx *gin.Context, param *mdl.DeleteRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	return res, u.Repo.Delete(ctx, param)
+}
+
+func (u *UsecaseModul) GetDeliveries(ctx *gin.Context, param *mdl.GetDeliveriesRequest) (mdl.ResponseDeliveries, *internal.Error) {
+	var res mdl.ResponseDeliveries
+	if _, err := u.Repo.GetById(ctx, param.SubscriptionId); err != nil {
+		return res, err
+	}
+	count, err := u.Repo.CountDeliveries(ctx, param)
+	if err != nil {
+		return mdl.ResponseDeliveries{}, err
+	}
+	page := utils.GetPage(param.Page)
+	totalPage := utils.GetTotalPage(param.Limit, count)
+
+	if page > totalPage {
+		return mdl.ResponseDeliveries{}, internal.NewError(400, fmt.Errorf("page greater than totalPage"))
+	}
+
+	data, err := u.Repo.GetDeliveries(ctx, param, page)
+	if err != nil {
+		return mdl.ResponseDeliveries{}, err
+	}
+
+	res.Data = data
+	res.Limit = param.Limit
+	res.Page = page
+	res.TotalPage = totalPage
+	return res, nil
+}
+
+func (u *UsecaseModul) GetDelivery(ctx *gin.Context, param *mdl.DeliveryRequest) (mdl.ResponseDelivery, *internal.Error) {
+	var res mdl.ResponseDelivery
+	data, err := u.Repo.GetDelivery(ctx, param)
+	if err != nil {
+		return mdl.ResponseDelivery{}, err
+	}
+	res.Data = data
+	return res, nil
+}
+
+func (u *UsecaseModul) Redeliver(ctx *gin.Context, param *mdl.DeliveryRequest) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	if err := u.checkActive(ctx, param.SubscriptionId); err != nil {
+		return res, err
+	}
+	res.Id = param.DeliveryId
+	return res, u.Repo.Redeliver(ctx, param)
+}
+
+// Ping queues a webhook.ping delivery so an endpoint can be checked without
+// waiting for a real event.
+func (u *UsecaseModul) Ping(ctx *gin.Context, id string) (mdl.GeneralResponse, *internal.Error) {
+	var res mdl.GeneralResponse
+	if err := u.checkActive(ctx, id); err != nil {
+		return res, err
+	}
+
+	ping := event.New(ctx, models.EventPing, resource, id, map[string]string{"subscriptionId": id})
+	body, errn := json.Marshal(ping)
+	if errn != nil {
+		return res, internal.NewError(500, fmt.Errorf("webhook.usecase.Ping : %v", errn))
+	}
+	delivery := &models.Delivery{
+		Id:             uuid.New().String(),
+		SubscriptionId: id,
+		EventId:        ping.Id,
+		EventType:      ping.Type,
+		Payload:        body,
+		Status:         models.StatusPending,
+		NextAttemptAt:  ping.CreatedAt,
+		CreatedAt:      ping.CreatedAt,
+	}
+	if err := u.Repo.CreateDelivery(ctx, delivery); err != nil {
+		return res, err
+	}
+	res.Id = delivery.Id
+	return res, nil
+}
+
+func (u *UsecaseModul) checkActive(ctx *gin.Context, id string) *internal.Error {
+	subscription, err := u.Repo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !subscription.Active {
+		return internal.NewError(409, fmt.Errorf("subscription %s is disabled, set it active again first", id))
+	}
+	return nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, secretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
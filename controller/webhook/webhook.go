@@ -0,0 +1,338 @@
+package webhook
+
+import (
+	"fmt"
+	"gin-dbo/framework/logger"
+	"gin-dbo/framework/utils"
+	models "gin-dbo/model/webhook"
+	mdl "gin-dbo/view/webhook"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	Usecase Usecase
+}
+
+// @SecurityDefinitions jwt
+func Router(router *gin.Engine, uc Usecase, auth func(*gin.Context)) {
+	u := Handler{Usecase: uc}
+	authorized := router.Group("/", auth)
+	{
+		authorized.GET("api/webhook-subscriptions", u.GetHandler)
+		authorized.GET("api/webhook-subscriptions/:id", u.GetByIdHandler)
+		authorized.POST("api/webhook-subscriptions", u.CreateHandler)
+		authorized.PUT("api/webhook-subscriptions/:id", u.UpdateHandler)
+		authorized.DELETE("api/webhook-subscriptions/:id", u.DeleteHandler)
+		authorized.POST("api/webhook-subscriptions/:id/ping", u.PingHandler)
+		authorized.GET("api/webhook-subscriptions/:id/deliveries", u.GetDeliveriesHandler)
+		authorized.GET("api/webhook-subscriptions/:id/deliveries/:deliveryId", u.GetDeliveryHandler)
+		authorized.POST("api/webhook-subscriptions/:id/deliveries/:deliveryId/redeliver", u.RedeliverHandler)
+	}
+}
+
+// @Summary Get All Webhook Subscriptions
+// @Description Get All Webhook Subscriptions, admin only
+// @param limit query int false "limit"
+// @param page query string false "page"
+// @Produce json
+// @Security jwt
+// @Success 200 {object} mdl.ResponseData
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Router /api/webhook-subscriptions [get]
+func (u Handler) GetHandler(c *gin.Context) {
+	limit, err := utils.GetLimit(c.Query(utils.Limit))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("webhook.getHandler.BadRequest : %v", err.Message.Error())})
+		return
+	}
+
+	page, err := utils.GetTargetPage(c.Query(utils.Page))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("webhook.getHandler.BadRequest : %v", err.Message.Error())})
+		return
+	}
+
+	param := &mdl.GetRequest{
+		Limit: limit,
+		Page:  page,
+	}
+	result, err := u.Usecase.Get(c, param)
+	if err == nil {
+		result.Success = true
+		result.Message = "success retrieve data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
+// @Summary Get Webhook Subscription By Id
+// @Description Webhook Subscription By Id, admin only
+// @Produce json
+// @Security jwt
+// @Success 200 {object} mdl.ResponseDetail
+// @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.Response400
+// @Failure 500 {object} mdl.Response500
+// @Router /api/webhook-subscriptions/{id} [get]
+func (u Handler) GetByIdHandler(c *gin.Context) {
+	result, err := u.Usecase.GetById(c, c.Param("id"))
+	if err == nil {
+		result.Success = true
+		result.Message = "success retrieve data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
+// @Summary Create Webhook Subscription
+// @Description Subscribe an URL to event types such as order.created, order.* or *. Deliveries are signed with the secret, which is generated when empty and only returned once
+// @Accept json
+// @Produce json
+// @Param request body mdl.CreateRequest true "Sample Create request payload"
+// @Security jwt
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 500 {object} mdl.Response500
+// @Router /api/webhook-subscriptions [post]
+func (u Handler) CreateHandler(c *gin.Context) {
+	param := new(mdl.CreateRequest)
+	if err := c.BindJSON(param); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("webhook.createHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	logger.FromContext(c).Debugf("%s %v", param.Url, param.EventTypes)
+	if err := utils.ValidateCreateWebhookRequest(param); err == nil {
+		result, err := u.Usecase.Create(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success create data"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("webhook.createHandler.BadRequest : %v", err.Error())})
+	}
+}
+
+// @Summary Update Webhook Subscription
+// @Description Replace a Webhook Subscription. An empty secret keeps the current one, setting active again clears the failure count of a disabled subscription
+// @Accept json
+// @Produce json
+// @Param request body mdl.UpdateRequest true "Sample Update request payload"
+// @Security jwt
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.Response400
+// @Failure 500 {object} mdl.Response500
+// @Router /api/webhook-subscriptions/{id} [put]
+func (u Handler) UpdateHandler(c *gin.Context) {
+	param := new(mdl.UpdateRequest)
+	if err := c.BindJSON(param); err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("webhook.updateHandler.BadRequest : %v", err.Error())})
+		return
+	}
+
+	param.Id = c.Param("id")
+	logger.FromContext(c).Debugf("%s %s %v", param.Id, param.Url, param.EventTypes)
+	if err := utils.ValidateUpdateWebhookRequest(param); err == nil {
+		result, err := u.Usecase.Update(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success update data"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("webhook.updateHandler.BadRequest : %v", err.Error())})
+	}
+}
+
+// @Summary Delete Webhook Subscription
+// @Description Delete a Webhook Subscription together with its deliveries
+// @Produce json
+// @Security jwt
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.Response400
+// @Failure 500 {object} mdl.Response500
+// @Router /api/webhook-subscriptions/{id} [delete]
+func (u Handler) DeleteHandler(c *gin.Context) {
+	param := &mdl.DeleteRequest{
+		Id: c.Param("id"),
+	}
+	logger.FromContext(c).Debugf("%+v", param)
+
+	if err := utils.ValidateDeleteWebhookRequest(param); err == nil {
+		result, err := u.Usecase.Delete(c, param)
+		if err == nil {
+			result.Success = true
+			result.Message = "success delete data"
+			c.JSON(http.StatusOK, result)
+		} else {
+			logger.FromContext(c).Error(err)
+			result.Success = false
+			result.Message = err.Message.Error()
+			c.JSON(err.Code, result)
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("webhook.deleteHandler.BadRequest : %v", err.Error())})
+	}
+}
+
+// @Summary Ping Webhook Subscription
+// @Description Queue a webhook.ping delivery to check the endpoint and its signature verification
+// @Produce json
+// @Security jwt
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.Response400
+// @Failure 409 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/webhook-subscriptions/{id}/ping [post]
+func (u Handler) PingHandler(c *gin.Context) {
+	result, err := u.Usecase.Ping(c, c.Param("id"))
+	if err == nil {
+		result.Success = true
+		result.Message = "success queue ping"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
+// @Summary Get Webhook Deliveries
+// @Description Get the deliveries of a Webhook Subscription, newest first
+// @param limit query int false "limit"
+// @param page query string false "page"
+// @param status query string false "pending, delivered or failed"
+// @Produce json
+// @Security jwt
+// @Success 200 {object} mdl.ResponseDeliveries
+// @Failure 400 {object} mdl.Response400
+// @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.Response400
+// @Failure 500 {object} mdl.Response500
+// @Router /api/webhook-subscriptions/{id}/deliveries [get]
+func (u Handler) GetDeliveriesHandler(c *gin.Context) {
+	limit, err := utils.GetLimit(c.Query(utils.Limit))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("webhook.getDeliveriesHandler.BadRequest : %v", err.Message.Error())})
+		return
+	}
+
+	page, err := utils.GetTargetPage(c.Query(utils.Page))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("webhook.getDeliveriesHandler.BadRequest : %v", err.Message.Error())})
+		return
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", models.StatusPending, models.StatusDelivered, models.StatusFailed:
+	default:
+		c.JSON(http.StatusBadRequest, mdl.GeneralResponse{Success: false, Message: fmt.Sprintf("webhook.getDeliveriesHandler.BadRequest : unknown status %s", status)})
+		return
+	}
+
+	param := &mdl.GetDeliveriesRequest{
+		SubscriptionId: c.Param("id"),
+		Status:         status,
+		Limit:          limit,
+		Page:           page,
+	}
+	result, err := u.Usecase.GetDeliveries(c, param)
+	if err == nil {
+		result.Success = true
+		result.Message = "success retrieve data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
+// @Summary Get Webhook Delivery
+// @Description Get a delivery with the log of its attempts and response codes
+// @Produce json
+// @Security jwt
+// @Success 200 {object} mdl.ResponseDelivery
+// @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.Response400
+// @Failure 500 {object} mdl.Response500
+// @Router /api/webhook-subscriptions/{id}/deliveries/{deliveryId} [get]
+func (u Handler) GetDeliveryHandler(c *gin.Context) {
+	param := &mdl.DeliveryRequest{
+		SubscriptionId: c.Param("id"),
+		DeliveryId:     c.Param("deliveryId"),
+	}
+	result, err := u.Usecase.GetDelivery(c, param)
+	if err == nil {
+		result.Success = true
+		result.Message = "success retrieve data"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
+
+// @Summary Redeliver Webhook Delivery
+// @Description Send a delivery again right away, also when it already succeeded or gave up
+// @Produce json
+// @Security jwt
+// @Success 200 {object} mdl.GeneralResponse
+// @Failure 401 {object} middleware.Response
+// @Failure 404 {object} mdl.Response400
+// @Failure 409 {object} mdl.GeneralResponse
+// @Failure 500 {object} mdl.Response500
+// @Router /api/webhook-subscriptions/{id}/deliveries/{deliveryId}/redeliver [post]
+func (u Handler) RedeliverHandler(c *gin.Context) {
+	param := &mdl.DeliveryRequest{
+		SubscriptionId: c.Param("id"),
+		DeliveryId:     c.Param("deliveryId"),
+	}
+	logger.FromContext(c).Debugf("%+v", param)
+
+	result, err := u.Usecase.Redeliver(c, param)
+	if err == nil {
+		result.Success = true
+		result.Message = "success queue delivery"
+		c.JSON(http.StatusOK, result)
+	} else {
+		logger.FromContext(c).Error(err)
+		result.Success = false
+		result.Message = err.Message.Error()
+		c.JSON(err.Code, result)
+	}
+}
@@ -16,13 +16,18 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/customer": {
+        "/api/api-keys": {
             "get": {
-                "description": "Get All Customers",
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Get All API Keys, revoked ones included, admin only",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Get All Customers",
+                "summary": "Get All API Keys",
                 "parameters": [
                     {
                         "type": "integer",
@@ -35,25 +40,19 @@ const docTemplate = `{
                         "description": "page",
                         "name": "page",
                         "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "name of some customer",
-                        "name": "keyword",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/customer.ResponseData"
+                            "$ref": "#/definitions/apikey.ResponseData"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/customer.Response400"
+                            "$ref": "#/definitions/apikey.Response400"
                         }
                     },
                     "401": {
@@ -65,7 +64,7 @@ const docTemplate = `{
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/customer.Response500"
+                            "$ref": "#/definitions/apikey.Response500"
                         }
                     }
                 }
@@ -76,14 +75,14 @@ const docTemplate = `{
                         "jwt": []
                     }
                 ],
-                "description": "Create Some New Customer",
+                "description": "Issue an API key for a machine integration, sent as the Bearer token. Scopes are \u003cresource\u003e:read for GET requests and \u003cresource\u003e:write for the others, resources being customer, order, user, audit and webhook; within its scopes a key sees every customer. The key is only returned once",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Create Customer",
+                "summary": "Create API Key",
                 "parameters": [
                     {
                         "description": "Sample Create request payload",
@@ -91,7 +90,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/customer.CreateRequest"
+                            "$ref": "#/definitions/apikey.CreateRequest"
                         }
                     }
                 ],
@@ -99,13 +98,13 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/customer.GeneralResponse"
+                            "$ref": "#/definitions/apikey.GeneralResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/customer.Response400"
+                            "$ref": "#/definitions/apikey.Response400"
                         }
                     },
                     "401": {
@@ -117,30 +116,29 @@ const docTemplate = `{
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/customer.Response500"
+                            "$ref": "#/definitions/apikey.Response500"
                         }
                     }
                 }
             }
         },
-        "/api/customer/{id}": {
+        "/api/api-keys/{id}": {
             "get": {
-                "description": "Customer By Id",
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "API Key By Id with its last use, admin only",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Get Customer By Id",
+                "summary": "Get API Key By Id",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/customer.ResponseDetail"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/customer.Response400"
+                            "$ref": "#/definitions/apikey.ResponseDetail"
                         }
                     },
                     "401": {
@@ -149,50 +147,42 @@ const docTemplate = `{
                             "$ref": "#/definitions/middleware.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/apikey.Response400"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/customer.Response500"
+                            "$ref": "#/definitions/apikey.Response500"
                         }
                     }
                 }
             },
-            "put": {
+            "delete": {
                 "security": [
                     {
                         "jwt": []
                     }
                 ],
-                "description": "Update Some Customer",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Revoke an API key right away, it stays listed with its revocation time",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Update Customer",
-                "parameters": [
-                    {
-                        "description": "Sample Update request payload",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/customer.UpdateRequest"
-                        }
-                    }
-                ],
+                "summary": "Revoke API Key",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/customer.GeneralResponse"
+                            "$ref": "#/definitions/apikey.GeneralResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/customer.Response400"
+                            "$ref": "#/definitions/apikey.Response400"
                         }
                     },
                     "401": {
@@ -201,39 +191,94 @@ const docTemplate = `{
                             "$ref": "#/definitions/middleware.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/apikey.Response400"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/apikey.GeneralResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/customer.Response500"
+                            "$ref": "#/definitions/apikey.Response500"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/audit": {
+            "get": {
                 "security": [
                     {
                         "jwt": []
                     }
                 ],
-                "description": "Delete Some Customer",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Get the audit trail of every create, update and delete, admin only",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Delete Customer",
+                "summary": "Get Audit Log",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "username that did the change",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "customer, order or user",
+                        "name": "resource",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "id of the changed resource",
+                        "name": "resourceId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "start of the time range, 2006-01-02 or 2006-01-02 15:04:05",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end of the time range, 2006-01-02 or 2006-01-02 15:04:05",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/customer.GeneralResponse"
+                            "$ref": "#/definitions/audit.ResponseData"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/customer.Response400"
+                            "$ref": "#/definitions/audit.Response400"
                         }
                     },
                     "401": {
@@ -245,31 +290,38 @@ const docTemplate = `{
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/customer.Response500"
+                            "$ref": "#/definitions/audit.Response500"
                         }
                     }
                 }
             }
         },
-        "/api/login": {
-            "post": {
-                "description": "Handle Login of Some Users",
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/auth/oidc/callback": {
+            "get": {
+                "description": "Finish a sign in at the OIDC provider and get a token. Users are created on their first sign in, or linked to the user owning their verified email, and their role follows their groups at the provider.",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Login",
+                "summary": "OIDC Callback",
                 "parameters": [
                     {
-                        "description": "Sample Login request payload",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/login.LoginRequest"
-                        }
+                        "type": "string",
+                        "description": "authorization code",
+                        "name": "code",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "state of the sign in",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "error from the provider",
+                        "name": "error",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -287,12 +339,68 @@ const docTemplate = `{
                     },
                     "401": {
                         "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/oidc/login": {
+            "get": {
+                "description": "Redirect to the OIDC provider to sign in, which comes back to the callback",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "OIDC Login",
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/middleware.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
                         "schema": {
                             "$ref": "#/definitions/login.GeneralResponse"
                         }
@@ -300,24 +408,56 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/order": {
+        "/api/customer": {
             "get": {
-                "description": "Get All Orders",
+                "description": "Get All Customers",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Get All Orders",
+                "summary": "Get All Customers",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "name of some customer",
+                        "name": "keyword",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "tag of the customers",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "id of a segment the customers belong to",
+                        "name": "segment",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/order.ResponseData"
+                            "$ref": "#/definitions/customer.ResponseData"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/order.Response400"
+                            "$ref": "#/definitions/customer.Response400"
                         }
                     },
                     "401": {
@@ -326,10 +466,16 @@ const docTemplate = `{
                             "$ref": "#/definitions/middleware.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/customer.GeneralResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/order.Response500"
+                            "$ref": "#/definitions/customer.Response500"
                         }
                     }
                 }
@@ -340,14 +486,14 @@ const docTemplate = `{
                         "jwt": []
                     }
                 ],
-                "description": "Create Some New Orders",
+                "description": "Create Some New Customer",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Create Order",
+                "summary": "Create Customer",
                 "parameters": [
                     {
                         "description": "Sample Create request payload",
@@ -355,7 +501,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/order.CreateRequest"
+                            "$ref": "#/definitions/customer.CreateRequest"
                         }
                     }
                 ],
@@ -363,13 +509,13 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/order.GeneralResponse"
+                            "$ref": "#/definitions/customer.GeneralResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/order.Response400"
+                            "$ref": "#/definitions/customer.Response400"
                         }
                     },
                     "401": {
@@ -381,30 +527,49 @@ const docTemplate = `{
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/order.Response500"
+                            "$ref": "#/definitions/customer.Response500"
                         }
                     }
                 }
             }
         },
-        "/api/order/{id}": {
+        "/api/customer/duplicates": {
             "get": {
-                "description": "Get Order By Id",
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Groups of customers likely to be the same, by names reading alike or the same email or phone, only admins can",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Get Order By Id",
+                "summary": "Get Duplicate Customers",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/order.ResponseData"
+                            "$ref": "#/definitions/customer.ResponseDuplicates"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/order.Response400"
+                            "$ref": "#/definitions/customer.Response400"
                         }
                     },
                     "401": {
@@ -413,91 +578,77 @@ const docTemplate = `{
                             "$ref": "#/definitions/middleware.Response"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/customer.GeneralResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/order.Response500"
+                            "$ref": "#/definitions/customer.Response500"
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/api/customer/export": {
+            "get": {
                 "security": [
                     {
                         "jwt": []
                     }
                 ],
-                "description": "Update Some Orders",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Export Customers as CSV or XLSX",
                 "produces": [
-                    "application/json"
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
                 ],
-                "summary": "Update Order",
+                "summary": "Export Customers",
                 "parameters": [
                     {
-                        "description": "Sample Update request payload",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/order.UpdateRequest"
-                        }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/order.GeneralResponse"
-                        }
+                        "type": "string",
+                        "description": "csv or xlsx, default csv",
+                        "name": "format",
+                        "in": "query"
                     },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/order.Response400"
-                        }
+                    {
+                        "type": "string",
+                        "description": "comma separated columns to export, default all",
+                        "name": "columns",
+                        "in": "query"
                     },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/middleware.Response"
-                        }
+                    {
+                        "type": "string",
+                        "description": "name of some customer",
+                        "name": "keyword",
+                        "in": "query"
                     },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/order.Response500"
-                        }
-                    }
-                }
-            },
-            "delete": {
-                "security": [
                     {
-                        "jwt": []
+                        "type": "string",
+                        "description": "tag of the customers",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "id of a segment the customers belong to",
+                        "name": "segment",
+                        "in": "query"
                     }
                 ],
-                "description": "Delete Some Orders",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "summary": "Delete Order",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/order.GeneralResponse"
+                            "type": "string"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/order.Response400"
+                            "$ref": "#/definitions/customer.Response400"
                         }
                     },
                     "401": {
@@ -506,52 +657,69 @@ const docTemplate = `{
                             "$ref": "#/definitions/middleware.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/customer.GeneralResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/order.Response500"
+                            "$ref": "#/definitions/customer.Response500"
                         }
                     }
                 }
             }
         },
-        "/api/register": {
+        "/api/customer/import": {
             "post": {
                 "security": [
                     {
                         "jwt": []
                     }
                 ],
-                "description": "Create Some New Users",
+                "description": "Import Customers from a CSV whose header names the columns name and optionally email, phone and tags, the tags separated by commas",
                 "consumes": [
-                    "application/json"
+                    "text/csv",
+                    "multipart/form-data"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv"
                 ],
-                "summary": "Register",
+                "summary": "Import Customers",
                 "parameters": [
                     {
-                        "description": "Sample Create request payload",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/login.CreateRequest"
-                        }
+                        "type": "file",
+                        "description": "CSV file when sent as multipart form",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "validate rows without creating them",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv to download the report as CSV",
+                        "name": "report",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/login.GeneralResponse"
+                            "$ref": "#/definitions/customer.ImportResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/login.Response400"
+                            "$ref": "#/definitions/customer.Response400"
                         }
                     },
                     "401": {
@@ -563,30 +731,51 @@ const docTemplate = `{
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/login.Response500"
+                            "$ref": "#/definitions/customer.Response500"
                         }
                     }
                 }
             }
         },
-        "/api/user": {
+        "/api/customer/{id}": {
             "get": {
-                "description": "Get All Users",
+                "description": "Customer By Id, the id of a merged customer resolving to the customer it was merged into",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Get All Users",
+                "summary": "Get Customer By Id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "etag of a cached copy",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/login.ResponseData"
+                            "$ref": "#/definitions/customer.ResponseDetail"
+                        },
+                        "headers": {
+                            "Content-Location": {
+                                "type": "string",
+                                "description": "where the customer lives now, when it was merged into another"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the data"
+                            }
                         }
                     },
+                    "304": {
+                        "description": "cached copy is still current"
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/login.Response400"
+                            "$ref": "#/definitions/customer.Response400"
                         }
                     },
                     "401": {
@@ -598,33 +787,39 @@ const docTemplate = `{
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/login.Response500"
+                            "$ref": "#/definitions/customer.Response500"
                         }
                     }
                 }
             },
-            "post": {
+            "put": {
                 "security": [
                     {
                         "jwt": []
                     }
                 ],
-                "description": "Create Some New Users",
+                "description": "Update Some Customer, replacing every field with the request payload but the tags when left out; only admins can change tags",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Create User",
+                "summary": "Update Customer",
                 "parameters": [
                     {
-                        "description": "Sample Create request payload",
+                        "type": "string",
+                        "description": "etag returned by get by id, required when REQUIRE_IF_MATCH is enabled",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Sample Update request payload",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/login.CreateRequest"
+                            "$ref": "#/definitions/customer.UpdateRequest"
                         }
                     }
                 ],
@@ -632,13 +827,13 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/login.GeneralResponse"
+                            "$ref": "#/definitions/customer.GeneralResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/login.Response400"
+                            "$ref": "#/definitions/customer.Response400"
                         }
                     },
                     "401": {
@@ -647,33 +842,59 @@ const docTemplate = `{
                             "$ref": "#/definitions/middleware.Response"
                         }
                     },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/customer.GeneralResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/customer.GeneralResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/login.Response500"
+                            "$ref": "#/definitions/customer.Response500"
                         }
                     }
                 }
-            }
-        },
-        "/api/user/{id}": {
-            "get": {
-                "description": "Get User By Id",
+            },
+            "delete": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Delete Some Customer",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Get User By Id",
+                "summary": "Delete Customer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "etag returned by get by id, required when REQUIRE_IF_MATCH is enabled",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/login.ResponseDetail"
+                            "$ref": "#/definitions/customer.GeneralResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/login.Response400"
+                            "$ref": "#/definitions/customer.Response400"
                         }
                     },
                     "401": {
@@ -682,36 +903,55 @@ const docTemplate = `{
                             "$ref": "#/definitions/middleware.Response"
                         }
                     },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/customer.GeneralResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/customer.GeneralResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/login.Response500"
+                            "$ref": "#/definitions/customer.Response500"
                         }
                     }
                 }
             },
-            "put": {
+            "patch": {
                 "security": [
                     {
                         "jwt": []
                     }
                 ],
-                "description": "Update Some Users",
+                "description": "Partially Update Some Customer with a JSON Merge Patch (RFC 7396)",
                 "consumes": [
+                    "application/merge-patch+json",
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Update User",
+                "summary": "Patch Customer",
                 "parameters": [
                     {
-                        "description": "Sample Update request payload",
+                        "type": "string",
+                        "description": "etag returned by get by id, required when REQUIRE_IF_MATCH is enabled",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Sample Patch request payload, only the fields to change",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/login.UpdateRequest"
+                            "$ref": "#/definitions/customer.CreateRequest"
                         }
                     }
                 ],
@@ -719,13 +959,13 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/login.GeneralResponse"
+                            "$ref": "#/definitions/customer.GeneralResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/login.Response400"
+                            "$ref": "#/definitions/customer.Response400"
                         }
                     },
                     "401": {
@@ -734,39 +974,76 @@ const docTemplate = `{
                             "$ref": "#/definitions/middleware.Response"
                         }
                     },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/customer.GeneralResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/customer.GeneralResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/customer.GeneralResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/login.Response500"
+                            "$ref": "#/definitions/customer.Response500"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/customer/{id}/merge": {
+            "post": {
                 "security": [
                     {
                         "jwt": []
                     }
                 ],
-                "description": "Delete Some Users",
+                "description": "Merge the source customers into this one in one transaction: their orders and users move here, blank email and phone are filled from them, their tags are added, and their ids keep resolving to this customer",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Delete User",
+                "summary": "Merge Customers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "etag of this customer returned by get by id, required when REQUIRE_IF_MATCH is enabled",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Sample Merge request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/customer.MergeRequest"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/login.GeneralResponse"
+                            "$ref": "#/definitions/customer.ResponseMerge"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/login.Response400"
+                            "$ref": "#/definitions/customer.Response400"
                         }
                     },
                     "401": {
@@ -775,48 +1052,4057 @@ const docTemplate = `{
                             "$ref": "#/definitions/middleware.Response"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/customer.GeneralResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/customer.GeneralResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/customer.GeneralResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/customer.GeneralResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/login.Response500"
+                            "$ref": "#/definitions/customer.Response500"
                         }
                     }
                 }
             }
-        }
+        },
+        "/api/customer/{id}/merges": {
+            "get": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "The history of the customers merged into this one, latest first, only admins can",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get Customer Merges",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/customer.ResponseMerges"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/customer.GeneralResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/customer.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/customer.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/login": {
+            "post": {
+                "description": "Handle Login of Some Users. Users with two-factor authentication get a challenge token instead of a token, to send with their code to login 2fa. When two-factor authentication is required of admins, admins without it get a token only good to set it up.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Login",
+                "parameters": [
+                    {
+                        "description": "Sample Login request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/login.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.ResponseLogin"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/login/2fa": {
+            "post": {
+                "description": "Complete a login with the challenge token of login and a code of the authenticator app or a recovery code. Wrong codes count towards the lockout like wrong passwords.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Login Two-Factor",
+                "parameters": [
+                    {
+                        "description": "Sample Login Two-Factor request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/login.LoginTwoFactorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.ResponseLogin"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/me": {
+            "get": {
+                "description": "Get the account of the caller",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get Me",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "etag of a cached copy",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.ResponseDetail"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the data"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "cached copy is still current"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Partially Update the account of the caller with a JSON Merge Patch (RFC 7396), only the email can be changed and is verified again",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Patch Me",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "etag returned by get me, required when REQUIRE_IF_MATCH is enabled",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Sample Patch Me request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/login.UpdateMeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/me/customer": {
+            "get": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Get the customer of the caller",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get My Customer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "etag of a cached copy",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/customer.ResponseDetail"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the data"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "cached copy is still current"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/customer.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/customer.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/me/orders": {
+            "get": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Get the orders of the customer of the caller",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get My Orders",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "name of some order",
+                        "name": "keyword",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/order.ResponseData"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/order.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/me/password": {
+            "post": {
+                "description": "Change the password of the caller given the current one; the new password follows the same rules as register. Wrong current passwords count towards the lockout.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Change Password",
+                "parameters": [
+                    {
+                        "description": "Sample Change Password request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/login.ChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/order": {
+            "get": {
+                "description": "Get All Orders",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get All Orders",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/order.ResponseData"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response500"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Create Some New Orders",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create Order",
+                "parameters": [
+                    {
+                        "description": "Sample Create request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/order.CreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/order.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/order/batch": {
+            "post": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Create, Update and Delete Orders in one request, either atomically or best-effort",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Batch Orders",
+                "parameters": [
+                    {
+                        "description": "Sample Batch request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/order.BatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/order.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/order.BatchResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/order/export": {
+            "get": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Export Orders as CSV or XLSX",
+                "produces": [
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "summary": "Export Orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv or xlsx, default csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "comma separated columns to export, default all",
+                        "name": "columns",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "name of some order",
+                        "name": "keyword",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/order/import": {
+            "post": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Import Orders from a CSV whose header names the columns customer_id, name and qty, as exported",
+                "consumes": [
+                    "text/csv",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "summary": "Import Orders",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV file when sent as multipart form",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "validate rows without creating them",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv to download the report as CSV",
+                        "name": "report",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/order.ImportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/order/{id}": {
+            "get": {
+                "description": "Get Order By Id",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get Order By Id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "etag of a cached copy",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/order.ResponseData"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the data"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "cached copy is still current"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response500"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Update Some Orders, replacing every field with the request payload",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update Order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "etag returned by get by id, required when REQUIRE_IF_MATCH is enabled",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Sample Update request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/order.UpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/order.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/order.GeneralResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/order.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response500"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Delete Some Orders",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete Order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "etag returned by get by id, required when REQUIRE_IF_MATCH is enabled",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/order.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/order.GeneralResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/order.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response500"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Partially Update Some Orders with a JSON Merge Patch (RFC 7396), customer users can not move an order to another customer",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Patch Orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "etag returned by get by id, required when REQUIRE_IF_MATCH is enabled",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Sample Patch request payload, only the fields to change",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/order.CreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/order.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/order.GeneralResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/order.GeneralResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/order.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/order.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/order/{id}/invoice": {
+            "get": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Get Invoice of Some Order as HTML or PDF depending on the Accept header",
+                "produces": [
+                    "text/html",
+                    "application/pdf"
+                ],
+                "summary": "Get Order Invoice",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/invoice.GeneralResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/invoice.GeneralResponse"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/invoice.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/invoice.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/password/forgot": {
+            "post": {
+                "description": "Mail a single use password reset token to the user owning the email. The answer is the same whether or not the email is registered.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Forgot Password",
+                "parameters": [
+                    {
+                        "description": "Sample Forgot Password request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/login.ForgotPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/password/reset": {
+            "post": {
+                "description": "Set a new password with a token from forgot password, the password follows the same rules as register",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Reset Password",
+                "parameters": [
+                    {
+                        "description": "Sample Reset Password request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/login.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/register": {
+            "post": {
+                "description": "Sign up as a customer, or with the role of an invitation when its token is given. Usernames are 3 to 32 letters, digits, dots, dashes or underscores; passwords are 8 to 72 characters mixing upper and lower case letters and digits and must not contain the username.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Register",
+                "parameters": [
+                    {
+                        "description": "Sample Register request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/login.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/segments": {
+            "get": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Get All Customer Segments, admin only",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get All Segments",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/segment.ResponseData"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/segment.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/segment.Response500"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Define a Customer Segment by rules, its members being worked out whenever it is read. A rule on orders or items compares the number of orders or the ordered quantity over the last days (ever when 0) with gt, gte, lt, lte or eq; a rule on tag wants the customer tagged. Match all keeps the customers passing every rule, any those passing one",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create Segment",
+                "parameters": [
+                    {
+                        "description": "Sample Create request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/segment.CreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/segment.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/segment.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/segment.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/segment.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/segments/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Customer Segment By Id with its rules, admin only",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get Segment By Id",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/segment.ResponseDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/segment.Response400"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/segment.Response500"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Replace the name, description and rules of a Customer Segment",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update Segment",
+                "parameters": [
+                    {
+                        "description": "Sample Update request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/segment.UpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/segment.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/segment.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/segment.Response400"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/segment.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/segment.Response500"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Delete a Customer Segment, its members are left untouched",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete Segment",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/segment.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/segment.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/segment.Response400"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/segment.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/segments/{id}/members": {
+            "get": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "The customers passing the rules of a Customer Segment as they stand now, latest first",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get Segment Members",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "name of some customer",
+                        "name": "keyword",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/segment.ResponseMembers"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/segment.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/segment.Response400"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/segment.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user": {
+            "get": {
+                "description": "Get All Users",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get All Users",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.ResponseData"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create users of any role, only admins can; the username and password follow the same rules as register",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create User",
+                "parameters": [
+                    {
+                        "description": "Sample Create request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/login.CreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/export": {
+            "get": {
+                "description": "Export Users as CSV or XLSX",
+                "produces": [
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "summary": "Export Users",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv or xlsx, default csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "comma separated columns to export, default all",
+                        "name": "columns",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "username of some user",
+                        "name": "keyword",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/invitations": {
+            "get": {
+                "description": "Get the invitations that can still be used to register",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get Invitations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.ResponseInvitations"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a single use token to register with the given role, e.g. to onboard staff. The token is only returned here.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create Invitation",
+                "parameters": [
+                    {
+                        "description": "Sample Create Invitation request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/login.CreateInvitationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.ResponseInvitation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/invitations/{id}": {
+            "delete": {
+                "description": "Revoke an invitation",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete Invitation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "invitation id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/me/2fa/confirm": {
+            "post": {
+                "description": "Enable two-factor authentication with a code of the secret from set up. The recovery codes are returned once, along with a full token when logged in with a set up only token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Confirm Two-Factor",
+                "parameters": [
+                    {
+                        "description": "Sample Two-Factor Code request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/login.TwoFactorCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.ResponseRecoveryCodes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/me/2fa/disable": {
+            "post": {
+                "description": "Disable two-factor authentication with a code of the authenticator app or a recovery code",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Disable Two-Factor",
+                "parameters": [
+                    {
+                        "description": "Sample Two-Factor Code request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/login.TwoFactorCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/me/2fa/recovery-codes": {
+            "post": {
+                "description": "Replace all recovery codes, used or not, given a code of the authenticator app or a recovery code. The new codes are returned once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Regenerate Recovery Codes",
+                "parameters": [
+                    {
+                        "description": "Sample Two-Factor Code request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/login.TwoFactorCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.ResponseRecoveryCodes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/me/2fa/setup": {
+            "post": {
+                "description": "Generate a TOTP secret for the logged in user, as an otpauth URI and its QR code to scan with an authenticator app. It stays pending until confirmed, setting up again replaces it.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Set Up Two-Factor",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.ResponseTwoFactorSetup"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/{id}": {
+            "get": {
+                "description": "Get User By Id",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get User By Id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "etag of a cached copy",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.ResponseDetail"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the data"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "cached copy is still current"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update Some Users, replacing every field with the request payload",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update User",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "etag returned by get by id, required when REQUIRE_IF_MATCH is enabled",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Sample Update request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/login.UpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete Some Users",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete User",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "etag returned by get by id, required when REQUIRE_IF_MATCH is enabled",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Partially Update Some Users with a JSON Merge Patch (RFC 7396), the password is only changed when it is part of the patch",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Patch Users",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "etag returned by get by id, required when REQUIRE_IF_MATCH is enabled",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Sample Patch request payload, only the fields to change",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/login.UpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/verify-email": {
+            "get": {
+                "description": "Confirm the email of a user with the token mailed to it, given as the token query parameter of the link or in the body",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Verify Email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "verification token",
+                        "name": "token",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Sample Verify Email request payload",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/login.VerifyEmailRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Confirm the email of a user with the token mailed to it, given as the token query parameter of the link or in the body",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Verify Email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "verification token",
+                        "name": "token",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Sample Verify Email request payload",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/login.VerifyEmailRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response400"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/login.GeneralResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/login.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhook-subscriptions": {
+            "get": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Get All Webhook Subscriptions, admin only",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get All Webhook Subscriptions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.ResponseData"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response500"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Subscribe an URL to event types such as order.created, order.* or *. Deliveries are signed with the secret, which is generated when empty and only returned once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create Webhook Subscription",
+                "parameters": [
+                    {
+                        "description": "Sample Create request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/webhook.CreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhook-subscriptions/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Webhook Subscription By Id, admin only",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get Webhook Subscription By Id",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.ResponseDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response400"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response500"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Replace a Webhook Subscription. An empty secret keeps the current one, setting active again clears the failure count of a disabled subscription",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update Webhook Subscription",
+                "parameters": [
+                    {
+                        "description": "Sample Update request payload",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/webhook.UpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response400"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response500"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Delete a Webhook Subscription together with its deliveries",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete Webhook Subscription",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.GeneralResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response400"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhook-subscriptions/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Get the deliveries of a Webhook Subscription, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get Webhook Deliveries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "pending, delivered or failed",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.ResponseDeliveries"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response400"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response400"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhook-subscriptions/{id}/deliveries/{deliveryId}": {
+            "get": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Get a delivery with the log of its attempts and response codes",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get Webhook Delivery",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.ResponseDelivery"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response400"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhook-subscriptions/{id}/deliveries/{deliveryId}/redeliver": {
+            "post": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Send a delivery again right away, also when it already succeeded or gave up",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Redeliver Webhook Delivery",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.GeneralResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response400"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhook-subscriptions/{id}/ping": {
+            "post": {
+                "security": [
+                    {
+                        "jwt": []
+                    }
+                ],
+                "description": "Queue a webhook.ping delivery to check the endpoint and its signature verification",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Ping Webhook Subscription",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.GeneralResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response400"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.GeneralResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.Response500"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Liveness probe, succeeds as long as the process serves requests",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Liveness",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.ResponseHealth"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Readiness probe, pings the database and every registered dependency and fails while the service shuts down",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Readiness",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.ResponseReady"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.ResponseReady"
+                        }
+                    }
+                }
+            }
+        },
+        "/version": {
+            "get": {
+                "description": "Build version, commit and start time of the running instance",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Version",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.ResponseVersion"
+                        }
+                    }
+                }
+            }
+        }
     },
     "definitions": {
+        "apikey.CreateRequest": {
+            "type": "object",
+            "properties": {
+                "allowedIps": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "203.0.113.0/24"
+                    ]
+                },
+                "expiresAt": {
+                    "type": "string",
+                    "example": "2030-01-01 00:00:00"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "erp"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "order:read",
+                        "order:write",
+                        "customer:read"
+                    ]
+                }
+            }
+        },
+        "apikey.GeneralResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "description": "Key is only returned on create, it cannot be read back later.",
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "apikey.Key": {
+            "type": "object",
+            "properties": {
+                "allowedIps": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "createdBy": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "hint": {
+                    "type": "string",
+                    "example": "gdk_Xy3f"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lastUsedAt": {
+                    "type": "string"
+                },
+                "lastUsedIp": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "revokedAt": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "apikey.Response400": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "invalid request"
+                },
+                "success": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "apikey.Response500": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "something went wrong"
+                },
+                "success": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "apikey.ResponseData": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apikey.Key"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "totalPage": {
+                    "type": "integer"
+                }
+            }
+        },
+        "apikey.ResponseDetail": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/apikey.Key"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "audit.Audit": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "changes": {
+                    "type": "object"
+                },
+                "clientIp": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "requestId": {
+                    "type": "string"
+                },
+                "resource": {
+                    "type": "string"
+                },
+                "resourceId": {
+                    "type": "string"
+                }
+            }
+        },
+        "audit.Response400": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "invalid request"
+                },
+                "success": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "audit.Response500": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "something went wrong"
+                },
+                "success": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "audit.ResponseData": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/audit.Audit"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "totalPage": {
+                    "type": "integer"
+                }
+            }
+        },
         "customer.CreateRequest": {
             "type": "object",
             "properties": {
-                "name": {
+                "email": {
+                    "type": "string",
+                    "example": "jane.doe@example.com"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string",
+                    "example": "+62 812 3456 7890"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "vip",
+                        "newsletter"
+                    ]
+                }
+            }
+        },
+        "customer.Customer": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "email": {
+                    "description": "Email and Phone are how to reach the customer, also used to tell\nduplicates apart.",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "Tags are free-form labels marketing groups customers by.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "vip",
+                        "newsletter"
+                    ]
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "customer.DuplicateGroup": {
+            "type": "object",
+            "properties": {
+                "customers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/customer.Customer"
+                    }
+                },
+                "reasons": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "name",
+                        "email"
+                    ]
+                }
+            }
+        },
+        "customer.GeneralResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "customer.ImportResponse": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer"
+                },
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/importer.Result"
+                    }
+                },
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "customer.Merge": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "mergedBy": {
+                    "type": "string"
+                },
+                "orders": {
+                    "type": "integer"
+                },
+                "sourceIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "sources": {
+                    "description": "Sources are the source customers as they were before the merge.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/customer.Customer"
+                    }
+                },
+                "targetId": {
+                    "type": "string"
+                },
+                "users": {
+                    "type": "integer"
+                }
+            }
+        },
+        "customer.MergeRequest": {
+            "type": "object",
+            "properties": {
+                "sourceIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "customer.Response400": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "invalid request"
+                },
+                "success": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "customer.Response500": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "something went wrong"
+                },
+                "success": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "customer.ResponseData": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/customer.Customer"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "totalPage": {
+                    "type": "integer"
+                }
+            }
+        },
+        "customer.ResponseDetail": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/customer.Customer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "customer.ResponseDuplicates": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/customer.DuplicateGroup"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "totalPage": {
+                    "type": "integer"
+                }
+            }
+        },
+        "customer.ResponseMerge": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/customer.Merge"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "customer.ResponseMerges": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/customer.Merge"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "customer.UpdateRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "jane.doe@example.com"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string",
+                    "example": "+62 812 3456 7890"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "vip",
+                        "newsletter"
+                    ]
+                }
+            }
+        },
+        "health.Build": {
+            "type": "object",
+            "properties": {
+                "commit": {
+                    "type": "string",
+                    "example": "4f2c1a9"
+                },
+                "goVersion": {
+                    "type": "string",
+                    "example": "go1.21.5"
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "uptime": {
+                    "type": "string",
+                    "example": "1h2m3s"
+                },
+                "version": {
+                    "type": "string",
+                    "example": "1.2.0"
+                }
+            }
+        },
+        "health.ResponseHealth": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string",
+                    "example": "up"
+                }
+            }
+        },
+        "health.ResponseReady": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/health.Result"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "example": "up"
+                }
+            }
+        },
+        "health.ResponseVersion": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/health.Build"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "health.Result": {
+            "type": "object",
+            "properties": {
+                "durationMs": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "error": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "database"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "up"
+                }
+            }
+        },
+        "importer.Result": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "row": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "invoice.GeneralResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "invoice.Response500": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "something went wrong"
+                },
+                "success": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "login.ChangePasswordRequest": {
+            "type": "object",
+            "properties": {
+                "currentPassword": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "Correct-Horse-2"
+                }
+            }
+        },
+        "login.CreateInvitationRequest": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "username": {
+                    "description": "Username, when set, is the only username the invitation registers.",
+                    "type": "string"
+                }
+            }
+        },
+        "login.CreateRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "login.ForgotPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "jane.doe@example.com"
+                }
+            }
+        },
+        "login.GeneralResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "login.Invitation": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "createdBy": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "usedAt": {
+                    "type": "string"
+                },
+                "usedBy": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "login.LoginRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "login.LoginTwoFactorRequest": {
+            "type": "object",
+            "properties": {
+                "challengeToken": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "login.RegisterRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "jane.doe@example.com"
+                },
+                "invitationToken": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "Correct-Horse-1"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "jane.doe"
+                }
+            }
+        },
+        "login.ResetPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "Correct-Horse-2"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "login.Response400": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "invalid request"
+                },
+                "success": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "login.Response500": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "something went wrong"
+                },
+                "success": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "login.ResponseData": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/login.User"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "totalPage": {
+                    "type": "integer"
+                }
+            }
+        },
+        "login.ResponseDetail": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/login.User"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "login.ResponseInvitation": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/login.Invitation"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "token": {
+                    "description": "Token is only returned when the invitation is created, it cannot be\nread back later.",
+                    "type": "string"
+                }
+            }
+        },
+        "login.ResponseInvitations": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/login.Invitation"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "login.ResponseLogin": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "object",
+                    "properties": {
+                        "challengeToken": {
+                            "type": "string"
+                        },
+                        "token": {
+                            "type": "string"
+                        },
+                        "twoFactorRequired": {
+                            "type": "boolean"
+                        },
+                        "twoFactorSetupRequired": {
+                            "type": "boolean"
+                        }
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "login.ResponseRecoveryCodes": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "object",
+                    "properties": {
+                        "recoveryCodes": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        },
+                        "token": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "message": {
                     "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
                 }
             }
         },
-        "customer.Customer": {
+        "login.ResponseTwoFactorSetup": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "object",
+                    "properties": {
+                        "qrCode": {
+                            "description": "QrCode is the uri as a PNG data URI.",
+                            "type": "string",
+                            "example": "data:image/png;base64,..."
+                        },
+                        "secret": {
+                            "type": "string"
+                        },
+                        "uri": {
+                            "type": "string",
+                            "example": "otpauth://totp/gin-dbo:jane.doe?issuer=gin-dbo\u0026secret=..."
+                        }
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "login.TwoFactorCodeRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "login.UpdateMeRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "jane.doe@example.com"
+                }
+            }
+        },
+        "login.UpdateRequest": {
+            "type": "object",
+            "properties": {
+                "customerId": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "login.User": {
             "type": "object",
             "properties": {
                 "createdAt": {
                     "type": "string"
                 },
-                "id": {
+                "customerId": {
                     "type": "string"
                 },
-                "name": {
+                "email": {
+                    "type": "string"
+                },
+                "emailVerifiedAt": {
+                    "description": "EmailVerifiedAt is set once the owner of Email followed the link sent\nto it.",
+                    "type": "string"
+                },
+                "oidcSubject": {
+                    "description": "OidcSubject links the user to its identity at the OIDC provider.",
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "twoFactorEnabledAt": {
                     "type": "string"
                 },
                 "updatedAt": {
                     "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
                 }
             }
         },
-        "customer.GeneralResponse": {
+        "login.VerifyEmailRequest": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "middleware.Response": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 401
+                },
+                "message": {
+                    "type": "string",
+                    "example": "unauthorized"
+                },
+                "success": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "order.BatchOperation": {
             "type": "object",
             "properties": {
+                "customerId": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
+                "name": {
+                    "type": "string"
+                },
+                "op": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete"
+                    ]
+                },
+                "qty": {
+                    "type": "integer"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "order.BatchRequest": {
+            "type": "object",
+            "properties": {
+                "atomic": {
+                    "type": "boolean"
+                },
+                "operations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/order.BatchOperation"
+                    }
+                }
+            }
+        },
+        "order.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "atomic": {
+                    "type": "boolean"
+                },
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/order.BatchResult"
+                    }
+                },
                 "message": {
                     "type": "string"
                 },
@@ -825,97 +5111,204 @@ const docTemplate = `{
                 }
             }
         },
-        "customer.Response400": {
+        "order.BatchResult": {
             "type": "object",
             "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
                 "message": {
-                    "type": "string",
-                    "example": "invalid request"
+                    "type": "string"
+                },
+                "op": {
+                    "type": "string"
                 },
                 "success": {
-                    "type": "boolean",
-                    "example": false
+                    "type": "boolean"
                 }
             }
         },
-        "customer.Response500": {
+        "order.CreateRequest": {
+            "type": "object",
+            "properties": {
+                "customerId": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "qty": {
+                    "type": "integer"
+                }
+            }
+        },
+        "order.GeneralResponse": {
             "type": "object",
             "properties": {
+                "id": {
+                    "type": "string"
+                },
                 "message": {
-                    "type": "string",
-                    "example": "something went wrong"
+                    "type": "string"
                 },
                 "success": {
-                    "type": "boolean",
-                    "example": false
+                    "type": "boolean"
                 }
             }
         },
-        "customer.ResponseData": {
+        "order.ImportResponse": {
             "type": "object",
             "properties": {
+                "created": {
+                    "type": "integer"
+                },
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/customer.Customer"
+                        "$ref": "#/definitions/importer.Result"
                     }
                 },
-                "limit": {
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "failed": {
                     "type": "integer"
                 },
                 "message": {
                     "type": "string"
                 },
-                "page": {
+                "skipped": {
                     "type": "integer"
                 },
                 "success": {
                     "type": "boolean"
+                }
+            }
+        },
+        "order.Order": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
                 },
-                "totalPage": {
+                "customer_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "qty": {
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "version": {
                     "type": "integer"
                 }
             }
         },
-        "customer.ResponseDetail": {
+        "order.Response400": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "invalid request"
+                },
+                "success": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "order.Response500": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "something went wrong"
+                },
+                "success": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "order.ResponseData": {
             "type": "object",
             "properties": {
                 "data": {
-                    "$ref": "#/definitions/customer.Customer"
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/order.Order"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
                 },
                 "message": {
                     "type": "string"
                 },
+                "page": {
+                    "type": "integer"
+                },
                 "success": {
                     "type": "boolean"
+                },
+                "totalPage": {
+                    "type": "integer"
                 }
             }
         },
-        "customer.UpdateRequest": {
+        "order.UpdateRequest": {
             "type": "object",
             "properties": {
+                "customerId": {
+                    "type": "string"
+                },
                 "name": {
                     "type": "string"
+                },
+                "qty": {
+                    "type": "integer"
                 }
             }
         },
-        "login.CreateRequest": {
+        "segment.CreateRequest": {
             "type": "object",
             "properties": {
-                "password": {
-                    "type": "string"
+                "description": {
+                    "type": "string",
+                    "example": "ordered more than 10 items in the last 30 days"
                 },
-                "role": {
-                    "type": "string"
+                "match": {
+                    "type": "string",
+                    "example": "all"
                 },
-                "username": {
-                    "type": "string"
+                "name": {
+                    "type": "string",
+                    "example": "frequent buyers"
+                },
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/segment.Rule"
+                    }
                 }
             }
         },
-        "login.GeneralResponse": {
+        "segment.GeneralResponse": {
             "type": "object",
             "properties": {
+                "id": {
+                    "type": "string"
+                },
                 "message": {
                     "type": "string"
                 },
@@ -924,18 +5317,7 @@ const docTemplate = `{
                 }
             }
         },
-        "login.LoginRequest": {
-            "type": "object",
-            "properties": {
-                "password": {
-                    "type": "string"
-                },
-                "username": {
-                    "type": "string"
-                }
-            }
-        },
-        "login.Response400": {
+        "segment.Response400": {
             "type": "object",
             "properties": {
                 "message": {
@@ -948,7 +5330,7 @@ const docTemplate = `{
                 }
             }
         },
-        "login.Response500": {
+        "segment.Response500": {
             "type": "object",
             "properties": {
                 "message": {
@@ -961,13 +5343,13 @@ const docTemplate = `{
                 }
             }
         },
-        "login.ResponseData": {
+        "segment.ResponseData": {
             "type": "object",
             "properties": {
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/login.User"
+                        "$ref": "#/definitions/segment.Segment"
                     }
                 },
                 "limit": {
@@ -987,11 +5369,11 @@ const docTemplate = `{
                 }
             }
         },
-        "login.ResponseDetail": {
+        "segment.ResponseDetail": {
             "type": "object",
             "properties": {
                 "data": {
-                    "$ref": "#/definitions/login.User"
+                    "$ref": "#/definitions/segment.Segment"
                 },
                 "message": {
                     "type": "string"
@@ -1001,128 +5383,229 @@ const docTemplate = `{
                 }
             }
         },
-        "login.ResponseLogin": {
+        "segment.ResponseMembers": {
             "type": "object",
             "properties": {
                 "data": {
-                    "type": "object",
-                    "properties": {
-                        "token": {
-                            "type": "string"
-                        }
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/customer.Customer"
                     }
                 },
+                "limit": {
+                    "type": "integer"
+                },
                 "message": {
                     "type": "string"
                 },
+                "page": {
+                    "type": "integer"
+                },
                 "success": {
                     "type": "boolean"
+                },
+                "totalPage": {
+                    "type": "integer"
                 }
             }
         },
-        "login.UpdateRequest": {
+        "segment.Rule": {
             "type": "object",
             "properties": {
-                "customerId": {
-                    "type": "string"
+                "days": {
+                    "type": "integer",
+                    "example": 30
                 },
-                "password": {
-                    "type": "string"
+                "field": {
+                    "type": "string",
+                    "example": "items"
                 },
-                "role": {
+                "operator": {
+                    "type": "string",
+                    "example": "gt"
+                },
+                "tag": {
                     "type": "string"
+                },
+                "value": {
+                    "type": "integer",
+                    "example": 10
                 }
             }
         },
-        "login.User": {
+        "segment.Segment": {
             "type": "object",
             "properties": {
                 "createdAt": {
                     "type": "string"
                 },
-                "customerId": {
+                "createdBy": {
                     "type": "string"
                 },
-                "role": {
+                "description": {
                     "type": "string"
                 },
-                "updatedAt": {
+                "id": {
                     "type": "string"
                 },
-                "username": {
+                "match": {
+                    "type": "string",
+                    "example": "all"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/segment.Rule"
+                    }
+                },
+                "updatedAt": {
                     "type": "string"
                 }
             }
         },
-        "middleware.Response": {
+        "segment.UpdateRequest": {
             "type": "object",
             "properties": {
-                "code": {
-                    "type": "integer",
-                    "example": 401
+                "description": {
+                    "type": "string",
+                    "example": "ordered more than 10 items in the last 30 days"
                 },
-                "message": {
+                "match": {
                     "type": "string",
-                    "example": "unauthorized"
+                    "example": "all"
                 },
-                "success": {
-                    "type": "boolean",
-                    "example": false
+                "name": {
+                    "type": "string",
+                    "example": "frequent buyers"
+                },
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/segment.Rule"
+                    }
                 }
             }
         },
-        "order.CreateRequest": {
+        "webhook.Attempt": {
             "type": "object",
             "properties": {
-                "customerId": {
+                "attempt": {
+                    "type": "integer"
+                },
+                "createdAt": {
                     "type": "string"
                 },
-                "name": {
+                "deliveryId": {
                     "type": "string"
                 },
-                "qty": {
+                "durationMs": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "responseBody": {
+                    "type": "string"
+                },
+                "statusCode": {
                     "type": "integer"
                 }
             }
         },
-        "order.GeneralResponse": {
+        "webhook.CreateRequest": {
             "type": "object",
             "properties": {
-                "id": {
-                    "type": "string"
+                "eventTypes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "order.*",
+                        "customer.created"
+                    ]
                 },
-                "message": {
+                "secret": {
                     "type": "string"
                 },
-                "success": {
-                    "type": "boolean"
+                "url": {
+                    "type": "string",
+                    "example": "https://partner.example.com/hooks"
                 }
             }
         },
-        "order.Order": {
+        "webhook.Delivery": {
             "type": "object",
             "properties": {
+                "attemptLog": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/webhook.Attempt"
+                    }
+                },
+                "attempts": {
+                    "type": "integer"
+                },
                 "createdAt": {
                     "type": "string"
                 },
-                "customer_id": {
+                "deliveredAt": {
+                    "type": "string"
+                },
+                "eventId": {
+                    "type": "string"
+                },
+                "eventType": {
                     "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
-                "name": {
+                "lastError": {
                     "type": "string"
                 },
-                "qty": {
+                "lastStatusCode": {
                     "type": "integer"
                 },
-                "updatedAt": {
+                "nextAttemptAt": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "subscriptionId": {
                     "type": "string"
                 }
             }
         },
-        "order.Response400": {
+        "webhook.GeneralResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "Secret is only returned when it is set, it cannot be read back later.",
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "webhook.Response400": {
             "type": "object",
             "properties": {
                 "message": {
@@ -1135,7 +5618,7 @@ const docTemplate = `{
                 }
             }
         },
-        "order.Response500": {
+        "webhook.Response500": {
             "type": "object",
             "properties": {
                 "message": {
@@ -1148,13 +5631,13 @@ const docTemplate = `{
                 }
             }
         },
-        "order.ResponseData": {
+        "webhook.ResponseData": {
             "type": "object",
             "properties": {
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/order.Order"
+                        "$ref": "#/definitions/webhook.Subscription"
                     }
                 },
                 "limit": {
@@ -1174,17 +5657,112 @@ const docTemplate = `{
                 }
             }
         },
-        "order.UpdateRequest": {
+        "webhook.ResponseDeliveries": {
             "type": "object",
             "properties": {
-                "customerId": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/webhook.Delivery"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "message": {
                     "type": "string"
                 },
-                "name": {
+                "page": {
+                    "type": "integer"
+                },
+                "success": {
+                    "type": "boolean"
+                },
+                "totalPage": {
+                    "type": "integer"
+                }
+            }
+        },
+        "webhook.ResponseDelivery": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/webhook.Delivery"
+                },
+                "message": {
                     "type": "string"
                 },
-                "qty": {
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "webhook.ResponseDetail": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/webhook.Subscription"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "webhook.Subscription": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "consecutiveFailures": {
                     "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "disabledAt": {
+                    "type": "string"
+                },
+                "disabledReason": {
+                    "type": "string"
+                },
+                "eventTypes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "webhook.UpdateRequest": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "eventTypes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
                 }
             }
         }
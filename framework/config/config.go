@@ -0,0 +1,493 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	EnvironmentDevelopment = "development"
+	EnvironmentProduction  = "production"
+	redacted               = "******"
+)
+
+// Config is the whole service configuration. Every field can be set, from
+// lowest to highest precedence, by its default, the YAML file under its yaml
+// path, the .env file or the environment under its env names, and a command
+// line flag named after its yaml path, e.g. -server.port=30001.
+type Config struct {
+	Environment    string      `yaml:"environment" env:"ENVIRONMENT,environment"`
+	Server         Server      `yaml:"server"`
+	Database       Database    `yaml:"database"`
+	JWT            JWT         `yaml:"jwt"`
+	Log            Log         `yaml:"log"`
+	Idempotency    Idempotency `yaml:"idempotency"`
+	Health         Health      `yaml:"health"`
+	Tracing        Tracing     `yaml:"tracing"`
+	RateLimit      RateLimit   `yaml:"rateLimit"`
+	Lockout        Lockout     `yaml:"lockout"`
+	Invitation     Invitation  `yaml:"invitation"`
+	Mail           Mail        `yaml:"mail"`
+	Account        Account     `yaml:"account"`
+	TwoFactor      TwoFactor   `yaml:"twoFactor"`
+	OIDC           OIDC        `yaml:"oidc"`
+	RequireIfMatch bool        `yaml:"requireIfMatch" env:"REQUIRE_IF_MATCH"`
+}
+
+type Server struct {
+	Port              int      `yaml:"port" env:"PORT"`
+	ReadTimeout       Duration `yaml:"readTimeout" env:"HTTP_READ_TIMEOUT"`
+	ReadHeaderTimeout Duration `yaml:"readHeaderTimeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+	// WriteTimeout is generous because exports stream large files.
+	WriteTimeout    Duration `yaml:"writeTimeout" env:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout     Duration `yaml:"idleTimeout" env:"HTTP_IDLE_TIMEOUT"`
+	ShutdownTimeout Duration `yaml:"shutdownTimeout" env:"SHUTDOWN_TIMEOUT"`
+	// ShutdownDelay keeps serving once shutdown began, with /readyz failing,
+	// so load balancers stop sending requests before the listener closes.
+	ShutdownDelay Duration `yaml:"shutdownDelay" env:"SHUTDOWN_DELAY"`
+	// TrustedProxies are the addresses or CIDR ranges of the proxies whose
+	// X-Forwarded-For is believed. Without any the client is the peer
+	// address, which callers cannot pick.
+	TrustedProxies []string `yaml:"trustedProxies" env:"TRUSTED_PROXIES"`
+}
+
+type Database struct {
+	Dsn             Secret   `yaml:"dsn" env:"MYSQL_DIALECTOR"`
+	MaxOpenConns    int      `yaml:"maxOpenConns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int      `yaml:"maxIdleConns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime Duration `yaml:"connMaxLifetime" env:"DB_CONN_MAX_LIFETIME"`
+	// SlowQueryThreshold logs statements running longer as warnings.
+	SlowQueryThreshold Duration `yaml:"slowQueryThreshold" env:"DB_SLOW_QUERY_THRESHOLD"`
+}
+
+type JWT struct {
+	SecretKey Secret   `yaml:"secretKey" env:"JWT_SECRET_KEY"`
+	Issuer    string   `yaml:"issuer" env:"JWT_ISSUER"`
+	Expiry    Duration `yaml:"expiry" env:"JWT_EXPIRY"`
+}
+
+type Log struct {
+	// Level defaults to debug in development and info elsewhere.
+	Level  string `yaml:"level" env:"LOG_LEVEL"`
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+}
+
+type Idempotency struct {
+	TTL Duration `yaml:"ttl" env:"IDEMPOTENCY_TTL"`
+}
+
+type Health struct {
+	// CheckTimeout bounds each readiness check that sets no timeout itself.
+	CheckTimeout Duration `yaml:"checkTimeout" env:"HEALTH_CHECK_TIMEOUT"`
+}
+
+type Tracing struct {
+	// Exporter is none, stdout to print spans locally or otlp to send them
+	// to a collector over OTLP/HTTP.
+	Exporter    string  `yaml:"exporter" env:"OTEL_TRACES_EXPORTER"`
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"`
+	Headers     Secret  `yaml:"headers" env:"OTEL_EXPORTER_OTLP_HEADERS"`
+	ServiceName string  `yaml:"serviceName" env:"OTEL_SERVICE_NAME"`
+	SampleRatio float64 `yaml:"sampleRatio" env:"OTEL_TRACES_SAMPLER_ARG"`
+}
+
+// RateLimit holds the token bucket of every route group, written as
+// <requests>/<period> by <ip|username|subject>, e.g. 10/1m by ip. A group
+// set to off is not limited.
+type RateLimit struct {
+	Enabled  bool `yaml:"enabled" env:"RATE_LIMIT_ENABLED"`
+	Login    Rate `yaml:"login" env:"RATE_LIMIT_LOGIN"`
+	Register Rate `yaml:"register" env:"RATE_LIMIT_REGISTER"`
+	// Password covers the password reset and email verification endpoints.
+	Password Rate `yaml:"password" env:"RATE_LIMIT_PASSWORD"`
+	API      Rate `yaml:"api" env:"RATE_LIMIT_API"`
+}
+
+// Lockout locks an account once Threshold logins failed in a row within
+// Window, for Duration doubling on every further failure up to MaxDuration.
+// A zero Threshold disables it.
+type Lockout struct {
+	Threshold   int      `yaml:"threshold" env:"LOGIN_LOCKOUT_THRESHOLD"`
+	Duration    Duration `yaml:"duration" env:"LOGIN_LOCKOUT_DURATION"`
+	MaxDuration Duration `yaml:"maxDuration" env:"LOGIN_LOCKOUT_MAX_DURATION"`
+	Window      Duration `yaml:"window" env:"LOGIN_LOCKOUT_WINDOW"`
+}
+
+type Invitation struct {
+	// TTL is how long an invitation token can be used to register.
+	TTL Duration `yaml:"ttl" env:"INVITATION_TTL"`
+}
+
+type Mail struct {
+	// Sender is smtp, file to write .eml files into Dir or memory.
+	Sender       string `yaml:"sender" env:"MAIL_SENDER"`
+	From         string `yaml:"from" env:"MAIL_FROM"`
+	Dir          string `yaml:"dir" env:"MAIL_DIR"`
+	SMTPHost     string `yaml:"smtpHost" env:"SMTP_HOST"`
+	SMTPPort     int    `yaml:"smtpPort" env:"SMTP_PORT"`
+	SMTPUsername string `yaml:"smtpUsername" env:"SMTP_USERNAME"`
+	SMTPPassword Secret `yaml:"smtpPassword" env:"SMTP_PASSWORD"`
+	// LinkBaseURL prefixes the links put in emails.
+	LinkBaseURL string `yaml:"linkBaseUrl" env:"MAIL_LINK_BASE_URL"`
+}
+
+type Account struct {
+	VerificationTTL  Duration `yaml:"verificationTtl" env:"EMAIL_VERIFICATION_TTL"`
+	PasswordResetTTL Duration `yaml:"passwordResetTtl" env:"PASSWORD_RESET_TTL"`
+}
+
+type TwoFactor struct {
+	// Issuer names the service in authenticator apps.
+	Issuer string `yaml:"issuer" env:"TWO_FACTOR_ISSUER"`
+	// RequireAdmin only lets admins without two-factor authentication set
+	// it up until they did.
+	RequireAdmin bool `yaml:"requireAdmin" env:"TWO_FACTOR_REQUIRE_ADMIN"`
+	// ChallengeTTL is how long the second login step may take.
+	ChallengeTTL  Duration `yaml:"challengeTtl" env:"TWO_FACTOR_CHALLENGE_TTL"`
+	RecoveryCodes int      `yaml:"recoveryCodes" env:"TWO_FACTOR_RECOVERY_CODES"`
+}
+
+// OIDC signs users in through the identity provider at Issuer with the
+// authorization code flow. Their groups in GroupsClaim pick the role: any of
+// AdminGroups makes an admin, any of CustomerGroups a customer, and users in
+// neither get DefaultRole, or are refused when it is empty.
+type OIDC struct {
+	Enabled      bool   `yaml:"enabled" env:"OIDC_ENABLED"`
+	Issuer       string `yaml:"issuer" env:"OIDC_ISSUER"`
+	ClientID     string `yaml:"clientId" env:"OIDC_CLIENT_ID"`
+	ClientSecret Secret `yaml:"clientSecret" env:"OIDC_CLIENT_SECRET"`
+	// RedirectURL is the callback registered with the identity provider.
+	RedirectURL    string   `yaml:"redirectUrl" env:"OIDC_REDIRECT_URL"`
+	Scopes         []string `yaml:"scopes" env:"OIDC_SCOPES"`
+	UsernameClaim  string   `yaml:"usernameClaim" env:"OIDC_USERNAME_CLAIM"`
+	GroupsClaim    string   `yaml:"groupsClaim" env:"OIDC_GROUPS_CLAIM"`
+	AdminGroups    []string `yaml:"adminGroups" env:"OIDC_ADMIN_GROUPS"`
+	CustomerGroups []string `yaml:"customerGroups" env:"OIDC_CUSTOMER_GROUPS"`
+	DefaultRole    string   `yaml:"defaultRole" env:"OIDC_DEFAULT_ROLE"`
+	// StateTTL is how long a user may take to sign in at the provider.
+	StateTTL Duration `yaml:"stateTtl" env:"OIDC_STATE_TTL"`
+}
+
+// Default is the configuration before any source is applied.
+func Default() *Config {
+	return &Config{
+		Environment: EnvironmentProduction,
+		Server: Server{
+			Port:              30001,
+			ReadTimeout:       Duration(30 * time.Second),
+			ReadHeaderTimeout: Duration(10 * time.Second),
+			WriteTimeout:      Duration(5 * time.Minute),
+			IdleTimeout:       Duration(2 * time.Minute),
+			ShutdownTimeout:   Duration(30 * time.Second),
+			ShutdownDelay:     Duration(5 * time.Second),
+		},
+		Database: Database{
+			MaxOpenConns:       25,
+			MaxIdleConns:       25,
+			ConnMaxLifetime:    Duration(5 * time.Minute),
+			SlowQueryThreshold: Duration(200 * time.Millisecond),
+		},
+		JWT: JWT{
+			Expiry: Duration(24 * time.Hour),
+		},
+		Log: Log{
+			Format: "json",
+		},
+		Idempotency: Idempotency{
+			TTL: Duration(24 * time.Hour),
+		},
+		Health: Health{
+			CheckTimeout: Duration(2 * time.Second),
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			Endpoint:    "http://localhost:4318/v1/traces",
+			ServiceName: "gin-dbo",
+			SampleRatio: 1,
+		},
+		RateLimit: RateLimit{
+			Enabled:  true,
+			Login:    Rate{Requests: 10, Period: Duration(time.Minute), Key: RateKeyIP},
+			Register: Rate{Requests: 5, Period: Duration(10 * time.Minute), Key: RateKeyIP},
+			Password: Rate{Requests: 5, Period: Duration(10 * time.Minute), Key: RateKeyIP},
+			API:      Rate{Requests: 300, Period: Duration(time.Minute), Key: RateKeySubject},
+		},
+		Lockout: Lockout{
+			Threshold:   5,
+			Duration:    Duration(time.Minute),
+			MaxDuration: Duration(time.Hour),
+			Window:      Duration(time.Hour),
+		},
+		Invitation: Invitation{
+			TTL: Duration(72 * time.Hour),
+		},
+		Mail: Mail{
+			Sender:      "file",
+			From:        "gin-dbo <no-reply@localhost>",
+			Dir:         "mail",
+			SMTPPort:    587,
+			LinkBaseURL: "http://localhost:30001",
+		},
+		Account: Account{
+			VerificationTTL:  Duration(48 * time.Hour),
+			PasswordResetTTL: Duration(time.Hour),
+		},
+		TwoFactor: TwoFactor{
+			Issuer:        "gin-dbo",
+			ChallengeTTL:  Duration(5 * time.Minute),
+			RecoveryCodes: 10,
+		},
+		OIDC: OIDC{
+			RedirectURL:   "http://localhost:30001/api/auth/oidc/callback",
+			Scopes:        []string{"openid", "profile", "email"},
+			UsernameClaim: "preferred_username",
+			GroupsClaim:   "groups",
+			StateTTL:      Duration(10 * time.Minute),
+		},
+	}
+}
+
+// Validate reports every problem of the configuration at once.
+func (c *Config) Validate() []string {
+	var problems []string
+	check := func(ok bool, field string, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, field+" "+fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Environment != "", "environment (ENVIRONMENT)", "is required")
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "server.port (PORT)", "must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.Server.ReadTimeout >= 0, "server.readTimeout (HTTP_READ_TIMEOUT)", "must not be negative")
+	check(c.Server.ReadHeaderTimeout >= 0, "server.readHeaderTimeout (HTTP_READ_HEADER_TIMEOUT)", "must not be negative")
+	check(c.Server.WriteTimeout >= 0, "server.writeTimeout (HTTP_WRITE_TIMEOUT)", "must not be negative")
+	check(c.Server.IdleTimeout >= 0, "server.idleTimeout (HTTP_IDLE_TIMEOUT)", "must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdownTimeout (SHUTDOWN_TIMEOUT)", "must be positive")
+	check(c.Server.ShutdownDelay >= 0, "server.shutdownDelay (SHUTDOWN_DELAY)", "must not be negative")
+	for _, proxy := range c.Server.TrustedProxies {
+		_, _, err := net.ParseCIDR(proxy)
+		check(err == nil || net.ParseIP(proxy) != nil, "server.trustedProxies (TRUSTED_PROXIES)", "must be addresses or CIDR ranges, got %q", proxy)
+	}
+
+	check(c.Database.Dsn != "", "database.dsn (MYSQL_DIALECTOR)", "is required")
+	check(c.Database.MaxOpenConns >= 0, "database.maxOpenConns (DB_MAX_OPEN_CONNS)", "must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "database.maxIdleConns (DB_MAX_IDLE_CONNS)", "must not be negative")
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns, "database.maxIdleConns (DB_MAX_IDLE_CONNS)", "must not exceed database.maxOpenConns")
+	check(c.Database.ConnMaxLifetime >= 0, "database.connMaxLifetime (DB_CONN_MAX_LIFETIME)", "must not be negative")
+	check(c.Database.SlowQueryThreshold >= 0, "database.slowQueryThreshold (DB_SLOW_QUERY_THRESHOLD)", "must not be negative")
+
+	check(c.JWT.SecretKey != "", "jwt.secretKey (JWT_SECRET_KEY)", "is required")
+	check(c.JWT.Expiry > 0, "jwt.expiry (JWT_EXPIRY)", "must be positive")
+
+	switch strings.ToLower(c.Log.Level) {
+	case "", "trace", "debug", "info", "warn", "warning", "error":
+	default:
+		check(false, "log.level (LOG_LEVEL)", "must be one of trace, debug, info, warn, error, got %q", c.Log.Level)
+	}
+	check(c.Log.Format == "json" || c.Log.Format == "text", "log.format (LOG_FORMAT)", "must be json or text, got %q", c.Log.Format)
+
+	check(c.Idempotency.TTL > 0, "idempotency.ttl (IDEMPOTENCY_TTL)", "must be positive")
+	check(c.Health.CheckTimeout > 0, "health.checkTimeout (HEALTH_CHECK_TIMEOUT)", "must be positive")
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		_, err := url.ParseRequestURI(c.Tracing.Endpoint)
+		check(err == nil, "tracing.endpoint (OTEL_EXPORTER_OTLP_TRACES_ENDPOINT)", "must be a URL such as http://localhost:4318/v1/traces, got %q", c.Tracing.Endpoint)
+	default:
+		check(false, "tracing.exporter (OTEL_TRACES_EXPORTER)", "must be one of none, stdout, otlp, got %q", c.Tracing.Exporter)
+	}
+	check(c.Tracing.ServiceName != "", "tracing.serviceName (OTEL_SERVICE_NAME)", "is required")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sampleRatio (OTEL_TRACES_SAMPLER_ARG)", "must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+
+	check(c.Lockout.Threshold >= 0, "lockout.threshold (LOGIN_LOCKOUT_THRESHOLD)", "must not be negative")
+	if c.Lockout.Threshold > 0 {
+		check(c.Lockout.Duration > 0, "lockout.duration (LOGIN_LOCKOUT_DURATION)", "must be positive")
+		check(c.Lockout.MaxDuration >= c.Lockout.Duration, "lockout.maxDuration (LOGIN_LOCKOUT_MAX_DURATION)", "must not be shorter than lockout.duration")
+		check(c.Lockout.Window > 0, "lockout.window (LOGIN_LOCKOUT_WINDOW)", "must be positive")
+	}
+	check(c.Invitation.TTL > 0, "invitation.ttl (INVITATION_TTL)", "must be positive")
+
+	switch c.Mail.Sender {
+	case "memory":
+	case "file":
+		check(c.Mail.Dir != "", "mail.dir (MAIL_DIR)", "is required with the file sender")
+	case "smtp":
+		check(c.Mail.SMTPHost != "", "mail.smtpHost (SMTP_HOST)", "is required with the smtp sender")
+		check(c.Mail.SMTPPort > 0 && c.Mail.SMTPPort < 65536, "mail.smtpPort (SMTP_PORT)", "must be between 1 and 65535, got %d", c.Mail.SMTPPort)
+	default:
+		check(false, "mail.sender (MAIL_SENDER)", "must be one of smtp, file, memory, got %q", c.Mail.Sender)
+	}
+	_, err := mail.ParseAddress(c.Mail.From)
+	check(err == nil, "mail.from (MAIL_FROM)", "must be an address such as gin-dbo <no-reply@example.com>, got %q", c.Mail.From)
+	_, err = url.ParseRequestURI(c.Mail.LinkBaseURL)
+	check(err == nil, "mail.linkBaseUrl (MAIL_LINK_BASE_URL)", "must be a URL such as https://example.com, got %q", c.Mail.LinkBaseURL)
+	check(c.Account.VerificationTTL > 0, "account.verificationTtl (EMAIL_VERIFICATION_TTL)", "must be positive")
+	check(c.Account.PasswordResetTTL > 0, "account.passwordResetTtl (PASSWORD_RESET_TTL)", "must be positive")
+
+	check(c.TwoFactor.Issuer != "" && !strings.Contains(c.TwoFactor.Issuer, ":"), "twoFactor.issuer (TWO_FACTOR_ISSUER)", "is required and must not contain a colon, got %q", c.TwoFactor.Issuer)
+	check(c.TwoFactor.ChallengeTTL > 0, "twoFactor.challengeTtl (TWO_FACTOR_CHALLENGE_TTL)", "must be positive")
+	check(c.TwoFactor.RecoveryCodes > 0 && c.TwoFactor.RecoveryCodes <= 50, "twoFactor.recoveryCodes (TWO_FACTOR_RECOVERY_CODES)", "must be between 1 and 50, got %d", c.TwoFactor.RecoveryCodes)
+
+	if c.OIDC.Enabled {
+		_, err = url.ParseRequestURI(c.OIDC.Issuer)
+		check(err == nil, "oidc.issuer (OIDC_ISSUER)", "must be a URL such as https://idp.example.com, got %q", c.OIDC.Issuer)
+		check(c.OIDC.ClientID != "", "oidc.clientId (OIDC_CLIENT_ID)", "is required")
+		_, err = url.ParseRequestURI(c.OIDC.RedirectURL)
+		check(err == nil, "oidc.redirectUrl (OIDC_REDIRECT_URL)", "must be a URL such as https://example.com/api/auth/oidc/callback, got %q", c.OIDC.RedirectURL)
+		check(contains(c.OIDC.Scopes, "openid"), "oidc.scopes (OIDC_SCOPES)", "must include openid, got %v", c.OIDC.Scopes)
+		check(c.OIDC.UsernameClaim != "", "oidc.usernameClaim (OIDC_USERNAME_CLAIM)", "is required")
+		check(c.OIDC.DefaultRole == "" || c.OIDC.DefaultRole == "admin" || c.OIDC.DefaultRole == "customer", "oidc.defaultRole (OIDC_DEFAULT_ROLE)", "must be empty, admin or customer, got %q", c.OIDC.DefaultRole)
+		check(c.OIDC.StateTTL > 0, "oidc.stateTtl (OIDC_STATE_TTL)", "must be positive")
+	}
+	return problems
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) IsDevelopment() bool {
+	return c.Environment == EnvironmentDevelopment
+}
+
+// String prints the configuration as YAML with every secret redacted.
+func (c *Config) String() string {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+// Secret is a string that never shows its value when printed or encoded.
+type Secret string
+
+// Value is the secret itself, for the code that actually uses it.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return s.String()
+}
+
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + s.String() + `"`), nil
+}
+
+// Duration reads durations such as 30s or 24h from every source.
+type Duration time.Duration
+
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("%q is not a duration such as 30s or 24h", text)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	return d.UnmarshalText([]byte(node.Value))
+}
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+const (
+	RateKeyIP       = "ip"
+	RateKeyUsername = "username"
+	RateKeySubject  = "subject"
+)
+
+// Rate is a token bucket holding Requests tokens, refilled evenly over
+// Period, and what callers are told apart by. A zero Requests means no limit.
+type Rate struct {
+	Requests int
+	Period   Duration
+	Key      string
+}
+
+// PerSecond is how many tokens the bucket gets back every second.
+func (r Rate) PerSecond() float64 {
+	return float64(r.Requests) / r.Period.Duration().Seconds()
+}
+
+func (r Rate) String() string {
+	if r.Requests == 0 {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s by %s", r.Requests, r.Period, r.Key)
+}
+
+func (r *Rate) UnmarshalText(text []byte) error {
+	value := strings.TrimSpace(string(text))
+	if value == "" || value == "off" {
+		*r = Rate{}
+		return nil
+	}
+	invalid := fmt.Errorf("%q is not a rate such as 10/1m by ip", text)
+
+	limit, key, _ := strings.Cut(value, " by ")
+	requests, period, ok := strings.Cut(strings.TrimSpace(limit), "/")
+	if !ok {
+		return invalid
+	}
+	rate := Rate{Key: RateKeyIP}
+	if _, err := fmt.Sscanf(requests, "%d", &rate.Requests); err != nil || rate.Requests <= 0 {
+		return invalid
+	}
+	if err := rate.Period.UnmarshalText([]byte(period)); err != nil || rate.Period <= 0 {
+		return invalid
+	}
+	if key = strings.TrimSpace(key); key != "" {
+		rate.Key = key
+	}
+	switch rate.Key {
+	case RateKeyIP, RateKeyUsername, RateKeySubject:
+	default:
+		return fmt.Errorf("%q must be keyed by ip, username or subject", text)
+	}
+	*r = rate
+	return nil
+}
+
+func (r *Rate) UnmarshalYAML(node *yaml.Node) error {
+	return r.UnmarshalText([]byte(node.Value))
+}
+
+func (r Rate) MarshalYAML() (interface{}, error) {
+	return r.String(), nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func valid() *Config {
+	cfg := Default()
+	cfg.Database.Dsn = "user:pass@tcp(localhost:3306)/dbo"
+	cfg.JWT.SecretKey = "secret"
+	return cfg
+}
+
+func TestValidateDefaults(t *testing.T) {
+	if problems := valid().Validate(); len(problems) != 0 {
+		t.Fatalf("got problems %v, want none", problems)
+	}
+	problems := Default().Validate()
+	if len(problems) != 2 || !strings.HasPrefix(problems[0], "database.dsn") || !strings.HasPrefix(problems[1], "jwt.secretKey") {
+		t.Fatalf("got problems %v, want the missing dsn and secret key", problems)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(cfg *Config)
+		want   string
+	}{
+		{"port out of range", func(cfg *Config) { cfg.Server.Port = 70000 }, "server.port (PORT) must be between 1 and 65535, got 70000"},
+		{"negative timeout", func(cfg *Config) { cfg.Server.ReadTimeout = Duration(-time.Second) }, "server.readTimeout (HTTP_READ_TIMEOUT) must not be negative"},
+		{"no shutdown timeout", func(cfg *Config) { cfg.Server.ShutdownTimeout = 0 }, "server.shutdownTimeout (SHUTDOWN_TIMEOUT) must be positive"},
+		{"more idle than open connections", func(cfg *Config) { cfg.Database.MaxIdleConns = 30 }, "database.maxIdleConns (DB_MAX_IDLE_CONNS) must not exceed database.maxOpenConns"},
+		{"unknown log level", func(cfg *Config) { cfg.Log.Level = "verbose" }, `log.level (LOG_LEVEL) must be one of trace, debug, info, warn, error, got "verbose"`},
+		{"unknown log format", func(cfg *Config) { cfg.Log.Format = "xml" }, `log.format (LOG_FORMAT) must be json or text, got "xml"`},
+		{"no idempotency ttl", func(cfg *Config) { cfg.Idempotency.TTL = 0 }, "idempotency.ttl (IDEMPOTENCY_TTL) must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.change(cfg)
+			if problems := cfg.Validate(); len(problems) != 1 || problems[0] != tt.want {
+				t.Fatalf("got problems %v, want %q", problems, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := valid()
+	cfg.Environment = ""
+	cfg.Server.Port = 0
+	cfg.JWT.Expiry = 0
+	if problems := cfg.Validate(); len(problems) != 3 {
+		t.Fatalf("got problems %v, want 3", problems)
+	}
+}
+
+func TestSecretIsRedacted(t *testing.T) {
+	cfg := valid()
+	if out := cfg.String(); strings.Contains(out, `secretKey: secret`) || strings.Contains(out, "pass@") {
+		t.Fatalf("printed a secret:\n%s", out)
+	}
+	if cfg.JWT.SecretKey.Value() != "secret" {
+		t.Fatalf("got secret key %q", cfg.JWT.SecretKey.Value())
+	}
+}
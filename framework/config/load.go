@@ -0,0 +1,174 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/subosito/gotenv"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultFile    = "config.yaml"
+	DefaultEnvFile = ".env"
+	FileEnv        = "CONFIG_FILE"
+)
+
+// Error lists every problem found while loading the configuration, so a
+// broken deployment is fixed in one go instead of one restart per field.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Load builds the configuration from defaults, the YAML file, the .env file,
+// the environment and the command line flags in args, later sources
+// overriding earlier ones, and validates the result.
+func Load(args []string) (*Config, error) {
+	var (
+		cfg      = Default()
+		problems []string
+		flags    = map[string]string{}
+	)
+
+	fs := flag.NewFlagSet("gin-dbo", flag.ContinueOnError)
+	file := fs.String("config", "", "YAML configuration file, "+FileEnv+" or "+DefaultFile+" when empty")
+	envFile := fs.String("env-file", DefaultEnvFile, "dotenv file, ignored when missing")
+	walk(reflect.ValueOf(cfg).Elem(), "", func(_ reflect.Value, path string, env []string) {
+		fs.Func(path, "overrides "+strings.Join(env, ", "), func(value string) error {
+			flags[path] = value
+			return nil
+		})
+	})
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	explicit := *file != ""
+	if !explicit {
+		*file = os.Getenv(FileEnv)
+		explicit = *file != ""
+	}
+	if !explicit {
+		*file = DefaultFile
+	}
+	if data, err := os.ReadFile(*file); err == nil {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err = decoder.Decode(cfg); err != nil && err != io.EOF {
+			problems = append(problems, fmt.Sprintf("%s: %v", *file, err))
+		}
+	} else if explicit || !errors.Is(err, os.ErrNotExist) {
+		problems = append(problems, fmt.Sprintf("%s: %v", *file, err))
+	}
+
+	dotenv, err := gotenv.Read(*envFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		problems = append(problems, fmt.Sprintf("%s: %v", *envFile, err))
+	}
+
+	walk(reflect.ValueOf(cfg).Elem(), "", func(field reflect.Value, path string, env []string) {
+		source, name, value, ok := lookup(env, dotenv)
+		if raw, set := flags[path]; set {
+			source, name, value, ok = "flag", "-"+path, raw, true
+		}
+		if !ok {
+			return
+		}
+		if err := set(field, value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s (%s) from %s: %v", path, name, source, err))
+		}
+	})
+
+	problems = append(problems, cfg.Validate()...)
+	if len(problems) > 0 {
+		return nil, &Error{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// lookup finds the first of names set in the environment, falling back to
+// the .env file so that real environment variables always win.
+func lookup(names []string, dotenv gotenv.Env) (source, name, value string, ok bool) {
+	for _, name = range names {
+		if value, ok = os.LookupEnv(name); ok {
+			return "environment", name, value, true
+		}
+	}
+	for _, name = range names {
+		if value, ok = dotenv[name]; ok {
+			return DefaultEnvFile, name, value, true
+		}
+	}
+	return "", "", "", false
+}
+
+var textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// walk calls fn for every settable leaf of the struct v with its dotted yaml
+// path and env names.
+func walk(v reflect.Value, prefix string, fn func(field reflect.Value, path string, env []string)) {
+	for i := 0; i < v.NumField(); i++ {
+		field, info := v.Field(i), v.Type().Field(i)
+		path := prefix + info.Tag.Get("yaml")
+		if field.Kind() == reflect.Struct && !field.Addr().Type().Implements(textUnmarshaler) {
+			walk(field, path+".", fn)
+			continue
+		}
+		fn(field, path, strings.Split(info.Tag.Get("env"), ","))
+	}
+}
+
+func set(field reflect.Value, value string) error {
+	if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(value))
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		field.SetInt(int64(v))
+	case reflect.Float64:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		field.SetFloat(v)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		field.SetBool(v)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", field.Type())
+		}
+		// a comma separated list, empty for none
+		values := reflect.MakeSlice(field.Type(), 0, 0)
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = reflect.Append(values, reflect.ValueOf(v).Convert(field.Type().Elem()))
+			}
+		}
+		field.Set(values)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// files writes the YAML and .env files of a test and returns the flags
+// loading them.
+func files(t *testing.T, yaml string, dotenv string) []string {
+	dir := t.TempDir()
+	file, envFile := filepath.Join(dir, "config.yaml"), filepath.Join(dir, ".env")
+	if err := os.WriteFile(file, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(envFile, []byte(dotenv), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return []string{"-config", file, "-env-file", envFile}
+}
+
+func TestLoadLayers(t *testing.T) {
+	args := files(t, `
+server:
+  port: 1001
+  readTimeout: 1s
+  writeTimeout: 1s
+  idleTimeout: 1s
+database:
+  dsn: from-yaml
+jwt:
+  secretKey: from-yaml
+`, "HTTP_WRITE_TIMEOUT=2s\nHTTP_IDLE_TIMEOUT=2s\nJWT_SECRET_KEY=from-dotenv\n")
+	t.Setenv("HTTP_IDLE_TIMEOUT", "3s")
+	t.Setenv("LOG_FORMAT", "text")
+
+	cfg, err := Load(append(args, "-server.port=1004", "-database.maxOpenConns=5", "-database.maxIdleConns=5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"default", cfg.Server.ShutdownTimeout, Duration(30 * time.Second)},
+		{"yaml over default", cfg.Server.ReadTimeout, Duration(time.Second)},
+		{"dotenv over yaml", cfg.Server.WriteTimeout, Duration(2 * time.Second)},
+		{"environment over dotenv", cfg.Server.IdleTimeout, Duration(3 * time.Second)},
+		{"environment over default", cfg.Log.Format, "text"},
+		{"flag over yaml", cfg.Server.Port, 1004},
+		{"flag over default", cfg.Database.MaxOpenConns, 5},
+		{"secret from dotenv", cfg.JWT.SecretKey.Value(), "from-dotenv"},
+		{"secret from yaml", cfg.Database.Dsn.Value(), "from-yaml"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s : got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	args := files(t, "database:\n  dsn: from-yaml\n", "")
+	t.Setenv("PORT", "eighty")
+	t.Setenv("JWT_EXPIRY", "a day")
+
+	_, err := Load(append(args, "-log.format=xml"))
+	var invalid *Error
+	if !errors.As(err, &invalid) {
+		t.Fatalf("got %v, want a configuration error", err)
+	}
+	want := []string{
+		`server.port (PORT) from environment: "eighty" is not an integer`,
+		`jwt.expiry (JWT_EXPIRY) from environment: "a day" is not a duration such as 30s or 24h`,
+		`jwt.secretKey (JWT_SECRET_KEY) is required`,
+		`log.format (LOG_FORMAT) must be json or text, got "xml"`,
+	}
+	if len(invalid.Problems) != len(want) {
+		t.Fatalf("got problems %q, want %q", invalid.Problems, want)
+	}
+	for i := range want {
+		if invalid.Problems[i] != want[i] {
+			t.Fatalf("got problems %q, want %q", invalid.Problems, want)
+		}
+	}
+}
+
+func TestLoadRefusesUnknownYAMLFields(t *testing.T) {
+	args := files(t, "database:\n  dsn: from-yaml\n  password: secret\njwt:\n  secretKey: from-yaml\n", "")
+	_, err := Load(args)
+	if err == nil || !strings.Contains(err.Error(), "field password not found") {
+		t.Fatalf("got %v, want the unknown field", err)
+	}
+}
+
+func TestLoadNeedsAnExplicitFile(t *testing.T) {
+	_, err := Load([]string{"-config", filepath.Join(t.TempDir(), "missing.yaml")})
+	if err == nil || !strings.Contains(err.Error(), "missing.yaml") {
+		t.Fatalf("got %v, want the missing file", err)
+	}
+}
@@ -1,29 +1,55 @@
 package database
 
 import (
-	"os"
+	"gin-dbo/framework/config"
+	"gin-dbo/framework/logger"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 
+	apikey "gin-dbo/model/apikey"
+	audit "gin-dbo/model/audit"
 	customer "gin-dbo/model/customer"
+	event "gin-dbo/model/event"
+	"gin-dbo/model/idempotency"
+	invoice "gin-dbo/model/invoice"
 	login "gin-dbo/model/login"
 	order "gin-dbo/model/order"
+	segment "gin-dbo/model/segment"
+	webhook "gin-dbo/model/webhook"
 )
 
 var Db *gorm.DB
 
-func ConnectSQL(log *logrus.Logger) (*gorm.DB, error) {
-	mysqlDialector := os.Getenv("MYSQL_DIALECTOR")
-	Db, err := gorm.Open(mysql.Open(mysqlDialector), &gorm.Config{})
+func ConnectSQL(cfg config.Database, parameterized bool, log *logrus.Logger) (*gorm.DB, error) {
+	Db, err := gorm.Open(mysql.Open(cfg.Dsn.Value()), &gorm.Config{
+		Logger: logger.NewGorm(cfg.SlowQueryThreshold.Duration(), parameterized),
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if err = Db.AutoMigrate(&login.User{}, &customer.Customer{}, &order.Order{}); err != nil {
+	sqlDB, err := Db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime.Duration())
+
+	if err = Db.AutoMigrate(&login.User{}, &customer.Customer{}, &customer.Merge{}, &customer.Redirect{}, &order.Order{}, &invoice.Invoice{}, &invoice.Sequence{}, &idempotency.Record{}, &audit.Audit{}, &event.Event{}, &webhook.Subscription{}, &webhook.Delivery{}, &webhook.Attempt{}, &login.Attempt{}, &login.Invitation{}, &login.Token{}, &login.RecoveryCode{}, &login.OIDCState{}, &apikey.Key{}, &segment.Segment{}); err != nil {
 		return nil, err
 	}
 
 	return Db, err
 }
+
+// Close releases the connection pool once nothing uses it anymore.
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const transactionKey = "DB_TRANSACTION"
+
+// Conn returns the transaction bound to ctx by Transaction, so repositories
+// called inside it join the same unit of work, or db when there is none.
+// Statements carry ctx, which logs them with the request logger.
+func Conn(ctx *gin.Context, db *gorm.DB) *gorm.DB {
+	if ctx == nil {
+		return db
+	}
+	if tx, ok := ctx.Get(transactionKey); ok && tx != nil {
+		return tx.(*gorm.DB).WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// Transaction runs fn inside a database transaction bound to ctx. When ctx
+// already carries one, fn simply joins it and the outer call decides whether
+// everything is committed.
+func Transaction(ctx *gin.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if tx, ok := ctx.Get(transactionKey); ok && tx != nil {
+		return fn(tx.(*gorm.DB))
+	}
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ctx.Set(transactionKey, tx)
+		defer ctx.Set(transactionKey, nil)
+		return fn(tx)
+	})
+}
@@ -0,0 +1,252 @@
+package dedupe
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	ReasonName  = "name"
+	ReasonEmail = "email"
+	ReasonPhone = "phone"
+
+	// prefixLength is how many leading characters two names share before
+	// they are compared at all, sparing the comparison of every pair.
+	prefixLength = 3
+	// phoneDigits is how many trailing digits two phones share to match, so
+	// the same number with and without its country code matches.
+	phoneDigits = 9
+	minPhoneLen = 6
+)
+
+// Record is what tells one party from another.
+type Record struct {
+	Id    string
+	Name  string
+	Email string
+	Phone string
+}
+
+// Group is a set of records likely to stand for the same party, with the
+// reasons they were put together.
+type Group struct {
+	Ids     []string
+	Reasons []string
+}
+
+// Find groups the records whose names read alike or which share an email or
+// a phone. Groups come in the order of their first record, and so do the
+// ids within a group.
+func Find(records []Record) []Group {
+	set := newUnion(len(records))
+	var edges []edge
+	link := func(i int, j int, reason string) {
+		set.join(i, j)
+		edges = append(edges, edge{i: i, reason: reason})
+	}
+
+	emails, phones, names := map[string]int{}, map[string]int{}, map[string]int{}
+	for i, record := range records {
+		if key := EmailKey(record.Email); key != "" {
+			if j, ok := emails[key]; ok {
+				link(j, i, ReasonEmail)
+			} else {
+				emails[key] = i
+			}
+		}
+		if key := PhoneKey(record.Phone); key != "" {
+			if j, ok := phones[key]; ok {
+				link(j, i, ReasonPhone)
+			} else {
+				phones[key] = i
+			}
+		}
+		if key := NameKey(record.Name); key != "" {
+			if j, ok := names[key]; ok {
+				link(j, i, ReasonName)
+			} else {
+				names[key] = i
+			}
+		}
+	}
+
+	// names differing by a typo, compared within the same leading characters
+	buckets := map[string][]string{}
+	for key := range names {
+		prefix := []rune(key)
+		if len(prefix) > prefixLength {
+			prefix = prefix[:prefixLength]
+		}
+		buckets[string(prefix)] = append(buckets[string(prefix)], key)
+	}
+	for _, keys := range buckets {
+		for a := 0; a < len(keys); a++ {
+			for b := a + 1; b < len(keys); b++ {
+				if Similar(keys[a], keys[b]) {
+					link(names[keys[a]], names[keys[b]], ReasonName)
+				}
+			}
+		}
+	}
+
+	var (
+		groups []Group
+		index  = map[int]int{}
+	)
+	for i, record := range records {
+		root := set.find(i)
+		if set.size[root] < 2 {
+			continue
+		}
+		g, ok := index[root]
+		if !ok {
+			g = len(groups)
+			index[root] = g
+			groups = append(groups, Group{})
+		}
+		groups[g].Ids = append(groups[g].Ids, record.Id)
+	}
+	found := make([]map[string]bool, len(groups))
+	for _, e := range edges {
+		g := index[set.find(e.i)]
+		if found[g] == nil {
+			found[g] = map[string]bool{}
+		}
+		found[g][e.reason] = true
+	}
+	for g := range groups {
+		for _, reason := range []string{ReasonName, ReasonEmail, ReasonPhone} {
+			if found[g][reason] {
+				groups[g].Reasons = append(groups[g].Reasons, reason)
+			}
+		}
+	}
+	return groups
+}
+
+// NameKey keeps the lower case letters and digits of name, so "Jane Doe",
+// "jane.doe" and "JANE_DOE" read alike.
+func NameKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func EmailKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// PhoneKey keeps the last digits of phone, empty when it has too few to
+// tell numbers apart.
+func PhoneKey(phone string) string {
+	var digits []rune
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, r)
+		}
+	}
+	if len(digits) < minPhoneLen {
+		return ""
+	}
+	if len(digits) > phoneDigits {
+		digits = digits[len(digits)-phoneDigits:]
+	}
+	return string(digits)
+}
+
+// Similar tells whether two name keys differ by at most one edit, two for
+// long names. Short names must be equal, a single letter telling them apart
+// too often.
+func Similar(a string, b string) bool {
+	x, y := []rune(a), []rune(b)
+	shortest := len(x)
+	if len(y) < shortest {
+		shortest = len(y)
+	}
+	allowed := 0
+	switch {
+	case shortest >= 10:
+		allowed = 2
+	case shortest >= 5:
+		allowed = 1
+	}
+	if len(x)-len(y) > allowed || len(y)-len(x) > allowed {
+		return false
+	}
+	return distance(x, y) <= allowed
+}
+
+// distance is the Levenshtein distance of a and b.
+func distance(a []rune, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = smallest(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func smallest(values ...int) int {
+	res := values[0]
+	for _, v := range values[1:] {
+		if v < res {
+			res = v
+		}
+	}
+	return res
+}
+
+type edge struct {
+	i      int
+	reason string
+}
+
+// union is a disjoint set of record indexes.
+type union struct {
+	parent []int
+	size   []int
+}
+
+func newUnion(n int) *union {
+	u := &union{parent: make([]int, n), size: make([]int, n)}
+	for i := range u.parent {
+		u.parent[i] = i
+		u.size[i] = 1
+	}
+	return u
+}
+
+func (u *union) find(i int) int {
+	for u.parent[i] != i {
+		u.parent[i] = u.parent[u.parent[i]]
+		i = u.parent[i]
+	}
+	return i
+}
+
+func (u *union) join(i int, j int) {
+	a, b := u.find(i), u.find(j)
+	if a == b {
+		return
+	}
+	if u.size[a] < u.size[b] {
+		a, b = b, a
+	}
+	u.parent[b] = a
+	u.size[a] += u.size[b]
+}
@@ -0,0 +1,155 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gin-dbo/framework/utils"
+	models "gin-dbo/model/event"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Run delivers the outbox until ctx is cancelled. Events are claimed for a
+// lease so several instances can share the outbox, and an event is only
+// marked delivered once every subscriber and transport accepted it; failed
+// events are retried with exponential backoff up to MaxAttempts.
+func (b *Bus) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.Interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := b.Dispatch(ctx)
+		if err != nil {
+			b.Log.Errorf("event.Run : %v", err)
+		}
+		if n < b.BatchSize || err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		} else if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Dispatch delivers one batch of due events and returns how many were
+// claimed.
+func (b *Bus) Dispatch(ctx context.Context) (int, error) {
+	if err := b.purge(); err != nil {
+		return 0, err
+	}
+
+	events, err := b.claim()
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	for _, event := range events {
+		if ctx.Err() != nil {
+			// the lease runs out and another run picks the rest up
+			break
+		}
+		if err := b.deliver(ctx, event); err != nil {
+			b.fail(event, err)
+			continue
+		}
+		b.complete(event)
+	}
+	return len(events), nil
+}
+
+func (b *Bus) claim() ([]*models.Event, error) {
+	var events []*models.Event
+	err := b.Dbconn.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? and next_attempt_at <= ?", models.StatusPending, utils.FormatTime()).
+			Order("created_at, id").Limit(b.BatchSize).Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		ids := make([]string, len(events))
+		for i, event := range events {
+			ids[i] = event.Id
+		}
+		return tx.Model(&models.Event{}).Where("id in ?", ids).Update("next_attempt_at", utils.FormatTimeAfter(b.Lease)).Error
+	})
+	return events, err
+}
+
+func (b *Bus) deliver(ctx context.Context, event *models.Event) error {
+	b.mu.RLock()
+	handlers := append(append([]Handler{}, b.subscribers[event.Type]...), b.subscribers[All]...)
+	transports := append([]Transport{}, b.transports...)
+	b.mu.RUnlock()
+
+	var errs []string
+	for i, handler := range handlers {
+		if err := safeCall(func() error { return handler(ctx, event) }); err != nil {
+			errs = append(errs, fmt.Sprintf("subscriber %d : %v", i, err))
+		}
+	}
+	for _, transport := range transports {
+		if err := safeCall(func() error { return transport.Send(ctx, event) }); err != nil {
+			errs = append(errs, fmt.Sprintf("transport %s : %v", transport.Name(), err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (b *Bus) complete(event *models.Event) {
+	err := b.Dbconn.Model(&models.Event{}).Where("id = ?", event.Id).Updates(map[string]interface{}{
+		"status":       models.StatusDelivered,
+		"attempts":     event.Attempts + 1,
+		"last_error":   "",
+		"delivered_at": utils.FormatTime(),
+	}).Error
+	if err != nil {
+		b.Log.Errorf("event.complete : %v", err)
+	}
+}
+
+func (b *Bus) fail(event *models.Event, cause error) {
+	attempts := event.Attempts + 1
+	data := map[string]interface{}{
+		"attempts":        attempts,
+		"last_error":      cause.Error(),
+		"next_attempt_at": utils.FormatTimeAfter(utils.Backoff(attempts, minBackoff, maxBackoff)),
+	}
+	if attempts >= b.MaxAttempts {
+		data["status"] = models.StatusFailed
+		b.Log.Errorf("event.fail : giving up on %s %s after %d attempts : %v", event.Type, event.Id, attempts, cause)
+	} else {
+		b.Log.Warnf("event.fail : %s %s attempt %d : %v", event.Type, event.Id, attempts, cause)
+	}
+	if err := b.Dbconn.Model(&models.Event{}).Where("id = ?", event.Id).Updates(data).Error; err != nil {
+		b.Log.Errorf("event.fail : %v", err)
+	}
+}
+
+// purge forgets delivered events once they are older than Retention.
+func (b *Bus) purge() error {
+	if b.Retention <= 0 {
+		return nil
+	}
+	return b.Dbconn.Where("status = ? and delivered_at < ?", models.StatusDelivered, utils.FormatTimeAfter(-b.Retention)).Delete(&models.Event{}).Error
+}
+
+func safeCall(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic : %v", r)
+		}
+	}()
+	return fn()
+}
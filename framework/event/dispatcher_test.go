@@ -0,0 +1,175 @@
+package event
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"gin-dbo/framework/database/dbtest"
+	"gin-dbo/framework/utils"
+	models "gin-dbo/model/event"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+)
+
+var eventColumns = []string{"id", "type", "resource", "resource_id", "status", "attempts"}
+
+// transport counts what it is sent and fails with err.
+type transport struct {
+	sent int
+	err  error
+}
+
+func (t *transport) Name() string {
+	return "test"
+}
+
+func (t *transport) Send(ctx context.Context, event *models.Event) error {
+	t.sent++
+	return t.err
+}
+
+// after matches a time formatted by utils.FormatTimeAfter(d), give or take a
+// second for the test to run.
+type after time.Duration
+
+func (a after) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	at, err := time.ParseInLocation(utils.TimeLayout, s, time.Local)
+	if err != nil {
+		return false
+	}
+	diff := time.Until(at) - time.Duration(a)
+	return diff > -2*time.Second && diff < 2*time.Second
+}
+
+func newBus(t *testing.T) (*Bus, sqlmock.Sqlmock) {
+	db, mock := dbtest.New(t)
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	bus := NewBus(db, log)
+	bus.MaxAttempts = 5
+	return bus, mock
+}
+
+// expectClaim expects the purge of delivered events and the claim of a due
+// event that failed attempts times already.
+func expectClaim(mock sqlmock.Sqlmock, attempts int) {
+	mock.ExpectExec("DELETE FROM `outbox_events` WHERE status = \\? and delivered_at < \\?").
+		WithArgs(models.StatusDelivered, after(-defaultRetention)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `outbox_events` WHERE status = \\? and next_attempt_at <= \\? ORDER BY created_at, id LIMIT 100 FOR UPDATE SKIP LOCKED").
+		WithArgs(models.StatusPending, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(eventColumns).AddRow("e1", models.OrderCreated, "order", "o1", models.StatusPending, attempts))
+	mock.ExpectExec("UPDATE `outbox_events` SET `next_attempt_at`=\\? WHERE id in \\(\\?\\)").
+		WithArgs(after(defaultLease), "e1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func TestDispatchDelivers(t *testing.T) {
+	bus, mock := newBus(t)
+	expectClaim(mock, 0)
+	mock.ExpectExec("UPDATE `outbox_events` SET `attempts`=\\?,`delivered_at`=\\?,`last_error`=\\?,`status`=\\? WHERE id = \\?").
+		WithArgs(1, sqlmock.AnyArg(), "", models.StatusDelivered, "e1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	var handled []string
+	bus.Subscribe(models.OrderCreated, func(ctx context.Context, event *models.Event) error {
+		handled = append(handled, "order")
+		return nil
+	})
+	bus.Subscribe(All, func(ctx context.Context, event *models.Event) error {
+		handled = append(handled, "all")
+		return nil
+	})
+	bus.Subscribe(models.OrderDeleted, func(ctx context.Context, event *models.Event) error {
+		handled = append(handled, "deleted")
+		return nil
+	})
+	sent := &transport{}
+	bus.AddTransport(sent)
+
+	n, err := bus.Dispatch(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("got %d and %v, want 1 event", n, err)
+	}
+	if len(handled) != 2 || handled[0] != "order" || handled[1] != "all" || sent.sent != 1 {
+		t.Fatalf("got handlers %v and %d sent, want order, all and 1 sent", handled, sent.sent)
+	}
+}
+
+func TestDispatchRetriesWithBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		backoff  time.Duration
+	}{
+		{"first failure", 0, minBackoff},
+		{"second failure", 1, 2 * minBackoff},
+		{"fourth failure", 3, 8 * minBackoff},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus, mock := newBus(t)
+			expectClaim(mock, tt.attempts)
+			mock.ExpectExec("UPDATE `outbox_events` SET `attempts`=\\?,`last_error`=\\?,`next_attempt_at`=\\? WHERE id = \\?").
+				WithArgs(tt.attempts+1, "transport test : broker down", after(tt.backoff), "e1").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			bus.AddTransport(&transport{err: errors.New("broker down")})
+			if _, err := bus.Dispatch(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestDispatchCapsTheBackoff(t *testing.T) {
+	bus, mock := newBus(t)
+	bus.MaxAttempts = 100
+	expectClaim(mock, 20)
+	mock.ExpectExec("UPDATE `outbox_events` SET `attempts`=\\?,`last_error`=\\?,`next_attempt_at`=\\? WHERE id = \\?").
+		WithArgs(21, sqlmock.AnyArg(), after(maxBackoff), "e1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	bus.AddTransport(&transport{err: errors.New("broker down")})
+	if _, err := bus.Dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDispatchGivesUpAfterMaxAttempts(t *testing.T) {
+	bus, mock := newBus(t)
+	expectClaim(mock, 4)
+	mock.ExpectExec("UPDATE `outbox_events` SET `attempts`=\\?,`last_error`=\\?,`next_attempt_at`=\\?,`status`=\\? WHERE id = \\?").
+		WithArgs(5, "subscriber 0 : panic : boom", sqlmock.AnyArg(), models.StatusFailed, "e1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	bus.Subscribe(All, func(ctx context.Context, event *models.Event) error {
+		panic("boom")
+	})
+	if _, err := bus.Dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDispatchWithoutDueEvents(t *testing.T) {
+	bus, mock := newBus(t)
+	mock.ExpectExec("DELETE FROM `outbox_events`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `outbox_events`").WillReturnRows(sqlmock.NewRows(eventColumns))
+	mock.ExpectCommit()
+
+	if n, err := bus.Dispatch(context.Background()); err != nil || n != 0 {
+		t.Fatalf("got %d and %v, want no event", n, err)
+	}
+}
@@ -0,0 +1,132 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"gin-dbo/framework/database"
+	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/logger"
+	"gin-dbo/framework/middleware"
+	"gin-dbo/framework/utils"
+	models "gin-dbo/model/event"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// All subscribes a handler to every event type.
+	All = "*"
+
+	defaultInterval    = 2 * time.Second
+	defaultBatchSize   = 100
+	defaultMaxAttempts = 10
+	defaultLease       = time.Minute
+	defaultRetention   = 7 * 24 * time.Hour
+	minBackoff         = 2 * time.Second
+	maxBackoff         = 10 * time.Minute
+)
+
+// Publisher is what the usecases need to emit domain events. Events are
+// written to the outbox inside the transaction carried by ctx, so they are
+// only delivered when the change that raised them is committed.
+type Publisher interface {
+	Publish(ctx *gin.Context, events ...*models.Event) (err *internal.Error)
+}
+
+// Handler is an in-process subscriber. It may see the same event more than
+// once and should use its id to stay idempotent.
+type Handler func(ctx context.Context, event *models.Event) error
+
+// Transport forwards events out of the process, e.g. to a message broker.
+type Transport interface {
+	Name() string
+	Send(ctx context.Context, event *models.Event) error
+}
+
+// Bus keeps the outbox and runs the dispatcher delivering it.
+type Bus struct {
+	Dbconn      *gorm.DB
+	Log         *logrus.Logger
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	Lease       time.Duration
+	Retention   time.Duration
+
+	mu          sync.RWMutex
+	subscribers map[string][]Handler
+	transports  []Transport
+}
+
+func NewBus(dbconn *gorm.DB, log *logrus.Logger) *Bus {
+	return &Bus{
+		Dbconn:      dbconn,
+		Log:         log,
+		Interval:    defaultInterval,
+		BatchSize:   defaultBatchSize,
+		MaxAttempts: defaultMaxAttempts,
+		Lease:       defaultLease,
+		Retention:   defaultRetention,
+		subscribers: map[string][]Handler{},
+	}
+}
+
+// New describes an event raised by the caller of ctx about a resource.
+// Payload is the state of the resource after the change, or before it for
+// deletions.
+func New(ctx *gin.Context, eventType string, resource string, id string, payload interface{}) *models.Event {
+	now := utils.FormatTime()
+	event := &models.Event{
+		Id:            uuid.New().String(),
+		Type:          eventType,
+		Resource:      resource,
+		ResourceId:    id,
+		Status:        models.StatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	if JWT, ok := ctx.Get(middleware.JwtClaims); ok {
+		event.Actor = JWT.(*middleware.AuthCustomClaims).Username
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	event.Payload = data
+	return event
+}
+
+func (b *Bus) Publish(ctx *gin.Context, events ...*models.Event) *internal.Error {
+	if len(events) == 0 {
+		return nil
+	}
+	if err := database.Conn(ctx, b.Dbconn).Create(events).Error; err != nil {
+		return internal.NewError(500, fmt.Errorf("event.Publish : %v", err.Error()))
+	}
+	for _, e := range events {
+		logger.FromContext(ctx).Debugf("event.Publish : %s %s %s", e.Type, e.Resource, e.ResourceId)
+	}
+	return nil
+}
+
+// Subscribe registers handler for eventType, or for every event with All.
+func (b *Bus) Subscribe(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[eventType] = append(b.subscribers[eventType], handler)
+}
+
+// AddTransport registers a transport receiving every event.
+func (b *Bus) AddTransport(transport Transport) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.transports = append(b.transports, transport)
+}
@@ -0,0 +1,34 @@
+package event
+
+import (
+	"context"
+
+	models "gin-dbo/model/event"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogTransport writes every event to the logger at debug level, which is
+// handy to follow the outbox locally.
+type LogTransport struct {
+	Log *logrus.Logger
+}
+
+func NewLogTransport(log *logrus.Logger) Transport {
+	return &LogTransport{Log: log}
+}
+
+func (t *LogTransport) Name() string {
+	return "log"
+}
+
+func (t *LogTransport) Send(ctx context.Context, event *models.Event) error {
+	t.Log.WithFields(logrus.Fields{
+		"id":         event.Id,
+		"type":       event.Type,
+		"resource":   event.Resource,
+		"resourceId": event.ResourceId,
+		"actor":      event.Actor,
+	}).Debug(string(event.Payload))
+	return nil
+}
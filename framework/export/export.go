@@ -0,0 +1,102 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	Format     = "format"
+	Columns    = "columns"
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+	BatchSize  = 500
+)
+
+// Writer receives exported records one by one; Close must be called once
+// every record is written so buffered output and trailers are flushed.
+type Writer interface {
+	Write(record []string) error
+	Close() error
+}
+
+type Column[T any] struct {
+	Name  string
+	Value func(T) string
+}
+
+func CheckFormat(format string) error {
+	switch format {
+	case "", FormatCSV, FormatXLSX:
+		return nil
+	default:
+		return fmt.Errorf("unsupported export format %s", format)
+	}
+}
+
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case "", FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported export format %s", format)
+	}
+}
+
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+func Filename(name string, format string) string {
+	if format == "" {
+		format = FormatCSV
+	}
+	return name + "." + format
+}
+
+// SelectColumns picks the requested comma separated columns in the order they
+// were asked for, or every available column when none are requested.
+func SelectColumns[T any](available []Column[T], requested string) ([]Column[T], error) {
+	if strings.TrimSpace(requested) == "" {
+		return available, nil
+	}
+
+	var res []Column[T]
+	for _, name := range strings.Split(requested, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, col := range available {
+			if col.Name == name {
+				res = append(res, col)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column %s", name)
+		}
+	}
+	return res, nil
+}
+
+func Header[T any](cols []Column[T]) []string {
+	res := make([]string, len(cols))
+	for i, col := range cols {
+		res[i] = col.Name
+	}
+	return res
+}
+
+func Record[T any](cols []Column[T], row T) []string {
+	res := make([]string, len(cols))
+	for i, col := range cols {
+		res[i] = col.Value(row)
+	}
+	return res
+}
@@ -0,0 +1,163 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusStopping = "stopping"
+)
+
+// Version and Commit are stamped at build time, e.g.
+// go build -ldflags "-X gin-dbo/framework/health.Version=1.2.0 -X gin-dbo/framework/health.Commit=$(git rev-parse HEAD)"
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+// CheckFunc reports whether a dependency is usable; it must give up once
+// ctx is done.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      CheckFunc
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Name       string `json:"name" example:"database"`
+	Status     string `json:"status" example:"up"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs" example:"2"`
+}
+
+// Health answers liveness, readiness and build info questions about the
+// running instance.
+type Health struct {
+	Timeout   time.Duration
+	StartedAt time.Time
+
+	stopping func() bool
+	mu       sync.Mutex
+	checks   []check
+}
+
+// New builds a registry whose readiness fails as soon as stopping reports
+// that shutdown has begun. Checks registered without a timeout get timeout.
+func New(stopping func() bool, timeout time.Duration) *Health {
+	return &Health{Timeout: timeout, StartedAt: time.Now(), stopping: stopping}
+}
+
+// Register adds a dependency readiness depends on.
+func (h *Health) Register(name string, timeout time.Duration, fn CheckFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if timeout <= 0 {
+		timeout = h.Timeout
+	}
+	h.checks = append(h.checks, check{name: name, timeout: timeout, fn: fn})
+}
+
+// Ready runs every check concurrently, each within its own timeout, and
+// tells whether all of them passed.
+func (h *Health) Ready(ctx context.Context) (string, []*Result) {
+	if h.stopping != nil && h.stopping() {
+		return StatusStopping, nil
+	}
+
+	h.mu.Lock()
+	checks := append([]check{}, h.checks...)
+	h.mu.Unlock()
+
+	var (
+		wg      sync.WaitGroup
+		results = make([]*Result, len(checks))
+	)
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	status := StatusUp
+	for _, result := range results {
+		if result.Status != StatusUp {
+			status = StatusDown
+		}
+	}
+	return status, results
+}
+
+func run(ctx context.Context, c check) *Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	res := &Result{Name: c.name, Status: StatusUp}
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- c.fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			res.Status, res.Error = StatusDown, err.Error()
+		}
+	case <-ctx.Done():
+		res.Status, res.Error = StatusDown, fmt.Sprintf("timed out after %s", c.timeout)
+	}
+	res.DurationMs = time.Since(start).Milliseconds()
+	return res
+}
+
+// Database pings the connection pool of db.
+func Database(db *gorm.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// Build describes the running binary.
+type Build struct {
+	Version   string    `json:"version" example:"1.2.0"`
+	Commit    string    `json:"commit" example:"4f2c1a9"`
+	GoVersion string    `json:"goVersion" example:"go1.21.5"`
+	StartedAt time.Time `json:"startedAt"`
+	Uptime    string    `json:"uptime" example:"1h2m3s"`
+}
+
+func (h *Health) Build() Build {
+	res := Build{Version: Version, Commit: Commit, StartedAt: h.StartedAt, Uptime: time.Since(h.StartedAt).Round(time.Second).String()}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		res.GoVersion = info.GoVersion
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" && res.Commit == "" {
+				res.Commit = setting.Value
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,108 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	File          = "file"
+	DryRun        = "dryRun"
+	Report        = "report"
+	ReportCSV     = "csv"
+	BatchSize     = 500
+	MaxRows       = 10000
+	StatusCreated = "created"
+	StatusSkipped = "skipped"
+	StatusError   = "error"
+)
+
+type Result struct {
+	Row    int    `json:"row"`
+	Status string `json:"status"`
+	Id     string `json:"id,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Open returns the uploaded CSV, either the multipart form file or the raw
+// request body.
+func Open(c *gin.Context) (io.ReadCloser, error) {
+	if strings.HasPrefix(c.ContentType(), gin.MIMEMultipartPOSTForm) {
+		header, err := c.FormFile(File)
+		if err != nil {
+			return nil, err
+		}
+		return header.Open()
+	}
+	return c.Request.Body, nil
+}
+
+// ReadCSV reads a CSV whose first line names the columns and calls fn for
+// every following record, numbered the way a spreadsheet numbers its rows.
+func ReadCSV(r io.Reader, fn func(row int, record map[string]string) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return errors.New("csv is empty")
+	}
+	if err != nil {
+		return err
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+	}
+
+	for row := 2; ; row++ {
+		values, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if row-1 > MaxRows {
+			return fmt.Errorf("csv has more than %d rows", MaxRows)
+		}
+
+		record := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(values) {
+				record[name] = strings.TrimSpace(values[i])
+			}
+		}
+		if err = fn(row, record); err != nil {
+			return err
+		}
+	}
+}
+
+func IsBlank(record map[string]string) bool {
+	for _, v := range record {
+		if v != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func WriteReport(w io.Writer, results []*Result) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"row", "status", "id", "reason"}); err != nil {
+		return err
+	}
+	for _, result := range results {
+		if err := writer.Write([]string{fmt.Sprint(result.Row), result.Status, result.Id, result.Reason}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,203 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Hook is a subsystem taking part in the application lifecycle. OnStart
+// must not block; OnStop should return once the subsystem released its
+// resources or ctx is done.
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// Lifecycle starts hooks in the order they were appended and stops them in
+// reverse, so the HTTP server appended last is drained before the workers
+// and the database it relies on.
+type Lifecycle struct {
+	Log     *logrus.Logger
+	Timeout time.Duration
+	// Delay is how long Run keeps every hook running once shutdown began,
+	// Stopping telling readiness checks to fail meanwhile.
+	Delay time.Duration
+
+	mu       sync.Mutex
+	hooks    []Hook
+	started  int
+	stopping atomic.Bool
+	failed   chan error
+}
+
+// New builds a lifecycle whose stop gives up on hooks still running after
+// timeout.
+func New(log *logrus.Logger, timeout time.Duration) *Lifecycle {
+	return &Lifecycle{Log: log, Timeout: timeout, failed: make(chan error, 1)}
+}
+
+func (l *Lifecycle) Append(hook Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, hook)
+}
+
+// Go appends a background worker. It runs with a context that is cancelled
+// on stop, and stopping waits for run to return.
+func (l *Lifecycle) Go(name string, run func(ctx context.Context)) {
+	var (
+		cancel context.CancelFunc
+		done   = make(chan struct{})
+	)
+	l.Append(Hook{
+		Name: name,
+		OnStart: func(context.Context) error {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			go func() {
+				defer close(done)
+				run(ctx)
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+}
+
+// Stopping tells whether shutdown has begun, readiness checks use it to
+// take the instance out of rotation while it drains.
+func (l *Lifecycle) Stopping() bool {
+	return l.stopping.Load()
+}
+
+// Shutdown asks Run to stop the application because a subsystem failed.
+func (l *Lifecycle) Shutdown(err error) {
+	select {
+	case l.failed <- err:
+	default:
+	}
+}
+
+// Start runs every OnStart in order. When one fails the hooks already
+// started are stopped again.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	hooks := append([]Hook{}, l.hooks...)
+	l.mu.Unlock()
+
+	for i, hook := range hooks {
+		if hook.OnStart != nil {
+			if err := hook.OnStart(ctx); err != nil {
+				l.started = i
+				stopCtx, cancel := context.WithTimeout(context.Background(), l.Timeout)
+				defer cancel()
+				return join(fmt.Errorf("start %s : %w", hook.Name, err), l.Stop(stopCtx))
+			}
+		}
+		l.Log.Debugf("lifecycle.Start : %s started", hook.Name)
+	}
+	l.started = len(hooks)
+	return nil
+}
+
+// Stop runs OnStop of every started hook in reverse order within the
+// deadline of ctx. A failing hook does not keep the others from stopping.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.stopping.Store(true)
+	l.mu.Lock()
+	hooks := append([]Hook{}, l.hooks[:l.started]...)
+	l.mu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hook := hooks[i]
+		if hook.OnStop == nil {
+			continue
+		}
+		if err := hook.OnStop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s : %w", hook.Name, err))
+			continue
+		}
+		l.Log.Debugf("lifecycle.Stop : %s stopped", hook.Name)
+	}
+	return join(errs...)
+}
+
+// Run starts the application and blocks until SIGINT or SIGTERM is received
+// or a subsystem calls Shutdown, then stops everything within Timeout once
+// Delay passed. A second signal skips what is left of Delay.
+func (l *Lifecycle) Run() error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	if err := l.Start(context.Background()); err != nil {
+		return err
+	}
+
+	var cause error
+	select {
+	case sig := <-signals:
+		l.Log.Infof("lifecycle.Run : received %s, shutting down within %s", sig, l.Timeout)
+	case cause = <-l.failed:
+		l.Log.Errorf("lifecycle.Run : shutting down : %v", cause)
+	}
+
+	if err := l.drain(signals); err != nil {
+		return join(cause, err)
+	}
+	l.Log.Info("lifecycle.Run : stopped")
+	return cause
+}
+
+// drain marks the application stopping and waits for Delay, or a signal on
+// skip, before stopping the hooks within Timeout.
+func (l *Lifecycle) drain(skip <-chan os.Signal) error {
+	l.stopping.Store(true)
+	if l.Delay > 0 {
+		l.Log.Infof("lifecycle.Run : failing readiness for %s before stopping", l.Delay)
+		timer := time.NewTimer(l.Delay)
+		select {
+		case <-timer.C:
+		case sig := <-skip:
+			timer.Stop()
+			l.Log.Infof("lifecycle.Run : received %s, stopping now", sig)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.Timeout)
+	defer cancel()
+	return l.Stop(ctx)
+}
+
+// join folds errs into one error, skipping nil ones.
+func join(errs ...error) error {
+	var messages []string
+	for _, err := range errs {
+		if err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(messages, "; "))
+}
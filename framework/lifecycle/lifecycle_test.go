@@ -0,0 +1,89 @@
+package lifecycle
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestLifecycle(delay time.Duration) (*Lifecycle, *atomic.Bool) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	l := New(log, time.Second)
+	l.Delay = delay
+	stopped := &atomic.Bool{}
+	l.Append(Hook{
+		Name: "http server",
+		OnStop: func(ctx context.Context) error {
+			stopped.Store(true)
+			return nil
+		},
+	})
+	return l, stopped
+}
+
+func TestDrainKeepsServingWhileNotReady(t *testing.T) {
+	l, stopped := newTestLifecycle(100 * time.Millisecond)
+	if err := l.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- l.drain(nil)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	if !l.Stopping() || stopped.Load() {
+		t.Fatalf("during the delay got stopping %v and stopped %v, want not ready but still serving", l.Stopping(), stopped.Load())
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !stopped.Load() {
+		t.Fatal("hook not stopped after the delay")
+	}
+}
+
+func TestDrainSkipsTheDelayOnSecondSignal(t *testing.T) {
+	l, stopped := newTestLifecycle(time.Hour)
+	if err := l.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	skip := make(chan os.Signal, 1)
+	skip <- syscall.SIGTERM
+	if err := l.drain(skip); err != nil {
+		t.Fatal(err)
+	}
+	if !stopped.Load() {
+		t.Fatal("hook not stopped")
+	}
+}
+
+func TestStopRunsHooksInReverse(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	l := New(log, time.Second)
+	var order []string
+	for _, name := range []string{"database", "worker", "http server"} {
+		name := name
+		l.Append(Hook{Name: name, OnStop: func(ctx context.Context) error {
+			order = append(order, name)
+			return nil
+		}})
+	}
+	if err := l.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 3 || order[0] != "http server" || order[2] != "database" {
+		t.Fatalf("stopped in order %v, want the reverse of appending", order)
+	}
+}
@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// Gorm routes gorm's logs through the request logger of the statement's
+// context: every statement at debug, slow ones as warnings and failures,
+// except record not found, as errors.
+type Gorm struct {
+	SlowThreshold time.Duration
+	Level         gormLogger.LogLevel
+	// Parameterized logs statements with placeholders instead of their
+	// values, keeping credentials and personal data out of the logs.
+	Parameterized bool
+}
+
+func NewGorm(slowThreshold time.Duration, parameterized bool) *Gorm {
+	return &Gorm{SlowThreshold: slowThreshold, Level: gormLogger.Info, Parameterized: parameterized}
+}
+
+func (g *Gorm) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if g.Parameterized {
+		return sql, nil
+	}
+	return sql, params
+}
+
+func (g *Gorm) LogMode(level gormLogger.LogLevel) gormLogger.Interface {
+	res := *g
+	res.Level = level
+	return &res
+}
+
+func (g *Gorm) Info(ctx context.Context, format string, args ...interface{}) {
+	if g.Level >= gormLogger.Info {
+		FromContext(ctx).Infof(format, args...)
+	}
+}
+
+func (g *Gorm) Warn(ctx context.Context, format string, args ...interface{}) {
+	if g.Level >= gormLogger.Warn {
+		FromContext(ctx).Warnf(format, args...)
+	}
+}
+
+func (g *Gorm) Error(ctx context.Context, format string, args ...interface{}) {
+	if g.Level >= gormLogger.Error {
+		FromContext(ctx).Errorf(format, args...)
+	}
+}
+
+func (g *Gorm) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if g.Level <= gormLogger.Silent {
+		return
+	}
+	entry := FromContext(ctx)
+	elapsed := time.Since(begin)
+	slow := g.SlowThreshold > 0 && elapsed > g.SlowThreshold
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	if !failed && !slow && !entry.Logger.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+
+	sql, rows := fc()
+	entry = entry.WithFields(logrus.Fields{"sql": sql, "rows": rows, "elapsedMs": elapsed.Milliseconds()})
+	switch {
+	case failed && g.Level >= gormLogger.Error:
+		entry.WithError(err).Error("query failed")
+	case slow && g.Level >= gormLogger.Warn:
+		entry.Warnf("slow query over %s", g.SlowThreshold)
+	case g.Level >= gormLogger.Info:
+		entry.Debug("query")
+	}
+}
@@ -1,21 +1,58 @@
 package logger
 
 import (
-	"os"
+	"context"
 
+	"gin-dbo/framework/config"
+
+	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
-func Logger() *logrus.Logger {
+const (
+	RequestIDHeader = "X-Request-ID"
+	RequestIDKey    = "REQUEST_ID"
+	// EntryKey is a string so that *gin.Context, which gorm receives as its
+	// context, resolves it through Value.
+	EntryKey = "LOGGER"
+)
+
+// base is the logger used outside of a request.
+var base = logrus.StandardLogger()
+
+func Logger(cfg *config.Config) *logrus.Logger {
 	var logger = logrus.New()
 
-	logger.Formatter = &logrus.JSONFormatter{}
+	if cfg.Log.Format == "text" {
+		logger.Formatter = &logrus.TextFormatter{FullTimestamp: true}
+	} else {
+		logger.Formatter = &logrus.JSONFormatter{}
+	}
 
-	if os.Getenv("environment") == "development" {
+	if level, err := logrus.ParseLevel(cfg.Log.Level); err == nil {
+		logger.SetLevel(level)
+	} else if cfg.IsDevelopment() {
 		logger.SetLevel(logrus.DebugLevel)
 	} else {
 		logger.SetLevel(logrus.InfoLevel)
 	}
 
+	base = logger
 	return logger
 }
+
+// FromContext returns the logger of the request ctx belongs to, carrying its
+// request id and, once authenticated, the username.
+func FromContext(ctx context.Context) *logrus.Entry {
+	if ctx != nil {
+		if entry, ok := ctx.Value(EntryKey).(*logrus.Entry); ok {
+			return entry
+		}
+	}
+	return logrus.NewEntry(base)
+}
+
+// WithField adds a field to every later log line of the request.
+func WithField(c *gin.Context, key string, value interface{}) {
+	c.Set(EntryKey, FromContext(c).WithField(key, value))
+}
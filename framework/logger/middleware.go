@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const maxRequestID = 128
+
+// Middleware propagates the X-Request-ID of the caller, or assigns one,
+// binds a logger carrying it to the request and writes one access log line
+// once the request is served. Lines carry the trace id when the tracing
+// middleware runs first.
+func Middleware(log *logrus.Logger) func(*gin.Context) {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+		c.Set(RequestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+		entry := log.WithField("requestId", requestID)
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.HasTraceID() {
+			entry = entry.WithField("traceId", sc.TraceID().String())
+		}
+		c.Set(EntryKey, entry)
+
+		c.Next()
+
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		entry = FromContext(c).WithFields(logrus.Fields{
+			"method":    c.Request.Method,
+			"route":     route,
+			"status":    c.Writer.Status(),
+			"latencyMs": time.Since(start).Milliseconds(),
+			"bytes":     size,
+			"clientIp":  c.ClientIP(),
+		})
+		switch status := c.Writer.Status(); {
+		case status >= 500:
+			entry.Error("request served")
+		case status >= 400:
+			entry.Warn("request served")
+		default:
+			entry.Info("request served")
+		}
+	}
+}
+
+// validRequestID accepts ids of printable ASCII only, so a caller cannot
+// forge log lines or headers through it.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestID {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestID is the correlation id of the request c belongs to.
+func RequestID(c *gin.Context) string {
+	return c.GetString(RequestIDKey)
+}
@@ -0,0 +1,43 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileSender writes every message as an .eml file into Dir, so local runs
+// can open them with a mail client instead of sending anything.
+type FileSender struct {
+	Dir  string
+	From string
+}
+
+func NewFileSender(dir string, from string) *FileSender {
+	return &FileSender{Dir: dir, From: from}
+}
+
+func (s *FileSender) Send(ctx context.Context, message Message) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	name := time.Now().UTC().Format("20060102T150405") + "-" + uuid.New().String() + ".eml"
+	return os.WriteFile(filepath.Join(s.Dir, name), encode(s.From, message), 0o600)
+}
+
+// encode renders message as a plain text RFC 5322 email.
+func encode(from string, message Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + message.To + "\r\n")
+	b.WriteString("Subject: " + message.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
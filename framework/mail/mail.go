@@ -0,0 +1,65 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"gin-dbo/framework/config"
+)
+
+const (
+	SenderSMTP   = "smtp"
+	SenderFile   = "file"
+	SenderMemory = "memory"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers notification emails. Implementations must be safe for
+// concurrent use.
+type Sender interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// New builds the sender selected by cfg.Sender.
+func New(cfg config.Mail) (Sender, error) {
+	switch cfg.Sender {
+	case SenderSMTP:
+		return NewSMTPSender(cfg), nil
+	case SenderFile:
+		return NewFileSender(cfg.Dir, cfg.From), nil
+	case SenderMemory:
+		return NewMemorySender(), nil
+	default:
+		return nil, fmt.Errorf("mail.New : unknown sender %q", cfg.Sender)
+	}
+}
+
+// MemorySender keeps every message, for tests.
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+func (s *MemorySender) Send(ctx context.Context, message Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+// Messages returns what was sent so far, oldest first.
+func (s *MemorySender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message{}, s.messages...)
+}
@@ -0,0 +1,62 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultQueueSize = 256
+
+// Queue hands messages to a worker delivering them through Next, so no
+// request waits on the mail server and how long a request took does not
+// tell whether a mail was sent.
+type Queue struct {
+	Next Sender
+	Log  *logrus.Logger
+
+	messages chan Message
+}
+
+func NewQueue(next Sender, log *logrus.Logger) *Queue {
+	return &Queue{Next: next, Log: log, messages: make(chan Message, defaultQueueSize)}
+}
+
+// Send queues message, failing right away when the queue is full.
+func (q *Queue) Send(ctx context.Context, message Message) error {
+	select {
+	case q.messages <- message:
+		return nil
+	default:
+		return fmt.Errorf("mail.Queue : queue is full, dropped %q to %s", message.Subject, message.To)
+	}
+}
+
+// Run delivers queued messages until ctx is cancelled, then delivers the
+// ones still queued before returning.
+func (q *Queue) Run(ctx context.Context) {
+	for {
+		select {
+		case message := <-q.messages:
+			q.deliver(message)
+		case <-ctx.Done():
+			for {
+				select {
+				case message := <-q.messages:
+					q.deliver(message)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver sends message apart from the stop of Run, which only waits for
+// the queue to drain.
+func (q *Queue) deliver(message Message) {
+	if err := q.Next.Send(context.Background(), message); err != nil {
+		q.Log.Errorf("mail.Queue : %q to %s : %v", message.Subject, message.To, err)
+	}
+}
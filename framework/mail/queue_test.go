@@ -0,0 +1,72 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// blockingSender holds every message until released.
+type blockingSender struct {
+	release chan struct{}
+	sent    chan Message
+}
+
+func (s *blockingSender) Send(ctx context.Context, message Message) error {
+	<-s.release
+	s.sent <- message
+	return nil
+}
+
+func newTestQueue(next Sender) *Queue {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return NewQueue(next, log)
+}
+
+func TestQueueSendDoesNotWaitForDelivery(t *testing.T) {
+	next := &blockingSender{release: make(chan struct{}), sent: make(chan Message, 1)}
+	q := newTestQueue(next)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+
+	if err := q.Send(context.Background(), Message{To: "jane@example.com", Subject: "Reset your password"}); err != nil {
+		t.Fatal(err)
+	}
+	close(next.release)
+	if got := <-next.sent; got.To != "jane@example.com" {
+		t.Fatalf("delivered %+v, want the queued message", got)
+	}
+}
+
+func TestQueueDeliversTheRestOnStop(t *testing.T) {
+	next := NewMemorySender()
+	q := newTestQueue(next)
+	for _, to := range []string{"jane@example.com", "joe@example.com"} {
+		if err := q.Send(context.Background(), Message{To: to}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	q.Run(ctx)
+
+	if sent := next.Messages(); len(sent) != 2 {
+		t.Fatalf("delivered %+v, want both queued messages", sent)
+	}
+}
+
+func TestQueueRefusesWhenFull(t *testing.T) {
+	q := newTestQueue(NewMemorySender())
+	for i := 0; i < defaultQueueSize; i++ {
+		if err := q.Send(context.Background(), Message{To: "jane@example.com"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.Send(context.Background(), Message{To: "jane@example.com"}); err == nil {
+		t.Fatal("queued past the size of the queue")
+	}
+}
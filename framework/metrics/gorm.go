@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+const startKey = "metrics:start"
+
+var (
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Database statement latency by operation and table.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"operation", "table"})
+	dbQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "db",
+		Name:      "query_errors_total",
+		Help:      "Failed database statements by operation and table, not found excluded.",
+	}, []string{"operation", "table"})
+)
+
+// Plugin times every statement gorm runs.
+type Plugin struct{}
+
+func (Plugin) Name() string {
+	return "metrics"
+}
+
+func (p Plugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	for _, err := range []error{
+		callback.Create().Before("gorm:create").Register("metrics:before_create", before),
+		callback.Create().After("gorm:create").Register("metrics:after_create", after("create")),
+		callback.Query().Before("gorm:query").Register("metrics:before_query", before),
+		callback.Query().After("gorm:query").Register("metrics:after_query", after("query")),
+		callback.Update().Before("gorm:update").Register("metrics:before_update", before),
+		callback.Update().After("gorm:update").Register("metrics:after_update", after("update")),
+		callback.Delete().Before("gorm:delete").Register("metrics:before_delete", before),
+		callback.Delete().After("gorm:delete").Register("metrics:after_delete", after("delete")),
+		callback.Row().Before("gorm:row").Register("metrics:before_row", before),
+		callback.Row().After("gorm:row").Register("metrics:after_row", after("row")),
+		callback.Raw().Before("gorm:raw").Register("metrics:before_raw", before),
+		callback.Raw().After("gorm:raw").Register("metrics:after_raw", after("raw")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func before(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		dbQueryDuration.WithLabelValues(operation, table).Observe(time.Since(v.(time.Time)).Seconds())
+		if db.Error != nil && db.Error != gorm.ErrRecordNotFound {
+			dbQueryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
+
+// Instrument installs the plugin on db and exports its connection pool
+// stats.
+func Instrument(db *gorm.DB) error {
+	if err := db.Use(Plugin{}); err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return Registry.Register(collectors.NewDBStatsCollector(sqlDB, "mysql"))
+}
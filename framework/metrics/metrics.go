@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	Namespace = "gin_dbo"
+
+	LoginSucceeded = "succeeded"
+	LoginFailed    = "failed"
+	LoginError     = "error"
+	LoginLocked    = "locked"
+	// LoginChallenged is a valid password waiting for the two-factor code.
+	LoginChallenged = "challenged"
+)
+
+// Registry holds every collector of the service, next to the Go runtime and
+// process ones.
+var Registry = prometheus.NewRegistry()
+
+var (
+	OrdersCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "orders_created_total",
+		Help:      "Orders created through the API, imports and batches.",
+	})
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts by result: succeeded, challenged (two-factor code pending), failed (bad credentials), locked (account locked out) or error.",
+	}, []string{"result"})
+	UsersRegistered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "users_registered_total",
+		Help:      "Users created by role.",
+	}, []string{"role"})
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected with 429 by route group.",
+	}, []string{"group"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		OrdersCreated,
+		Logins,
+		UsersRegistered,
+		RateLimited,
+		httpRequests,
+		httpDuration,
+		httpInFlight,
+		dbQueryDuration,
+		dbQueryErrors,
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
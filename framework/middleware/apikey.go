@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/logger"
+	"gin-dbo/framework/tracing"
+	"gin-dbo/framework/utils"
+	"gin-dbo/model/apikey"
+	"gin-dbo/model/login"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	ErrorInvalidAPIKey   = "API key is not valid, revoked or expired"
+	ErrorAPIKeyAddress   = "API key is not allowed from this address"
+	ErrorAPIKeyScope     = "API key lacks the scope %s"
+	ErrorAPIKeyEndpoint  = "API keys cannot reach this endpoint"
+	MethodHead           = "HEAD"
+	apiKeyUsernamePrefix = "apikey:"
+	apiKeyTouchInterval  = time.Minute
+)
+
+// scopeResources names the scope resource of each route group, routes
+// outside of it cannot be reached with an API key.
+var scopeResources = map[string]string{
+	"customer":              "customer",
+	"segments":              "customer",
+	"order":                 "order",
+	"user":                  "user",
+	"audit":                 "audit",
+	"webhook-subscriptions": "webhook",
+}
+
+type APIKeyStore interface {
+	// Find returns the key stored with keyHash, revoked or not, or nil when
+	// there is none.
+	Find(keyHash string) (*apikey.Key, error)
+	// Touch records the last use of a key.
+	Touch(id string, ip string) error
+}
+
+type apiKeyStore struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyStore(db *gorm.DB) APIKeyStore {
+	return &apiKeyStore{db: db}
+}
+
+func (s *apiKeyStore) Find(keyHash string) (*apikey.Key, error) {
+	var key *apikey.Key
+	err := s.db.Where("key_hash = ?", keyHash).Take(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (s *apiKeyStore) Touch(id string, ip string) error {
+	return s.db.Model(&apikey.Key{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_used_at": utils.FormatTime(),
+		"last_used_ip": ip,
+	}).Error
+}
+
+// RequiredScope is the scope a request needs, empty when no API key may
+// make it.
+func RequiredScope(method string, path string) string {
+	route := strings.TrimPrefix(path, "/api/")
+	if route == path {
+		return ""
+	}
+	route, _, _ = strings.Cut(route, "/")
+	resource, ok := scopeResources[route]
+	if !ok {
+		return ""
+	}
+	if method == MethodGet || method == MethodHead {
+		return resource + ":read"
+	}
+	return resource + ":write"
+}
+
+// authorizeAPIKey lets a request through with an API key that is neither
+// revoked nor expired, sent from an allowed address and granted the scope
+// of the route. Within its scopes the key acts as an admin, seeing every
+// customer.
+func authorizeAPIKey(c *gin.Context, keys APIKeyStore, token string) {
+	span := tracing.Start(c, "middleware.AuthorizeAPIKey")
+	key, err := keys.Find(utils.Encrypt(token))
+	var failure *internal.Error
+	if err != nil {
+		failure = internal.NewError(http.StatusInternalServerError, err)
+	}
+	span.End(&failure)
+	if err != nil {
+		logger.FromContext(c).Errorf("middleware.AuthorizeAPIKey : %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, &Response{Code: http.StatusInternalServerError, Success: false, Message: "something went wrong"})
+		return
+	}
+	if key == nil || key.RevokedAt != "" || (key.ExpiresAt != "" && key.ExpiresAt <= utils.FormatTime()) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, &Response{Code: http.StatusUnauthorized, Success: false, Message: ErrorInvalidAPIKey})
+		return
+	}
+
+	ip := c.ClientIP()
+	if !allowedAddress(key.AllowedIps, ip) {
+		logger.FromContext(c).Warnf("middleware.AuthorizeAPIKey : key %s used from %s", key.Id, ip)
+		c.AbortWithStatusJSON(http.StatusForbidden, &Response{Code: http.StatusForbidden, Success: false, Message: ErrorAPIKeyAddress})
+		return
+	}
+	scope := RequiredScope(c.Request.Method, c.Request.URL.Path)
+	if scope == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, &Response{Code: http.StatusForbidden, Success: false, Message: ErrorAPIKeyEndpoint})
+		return
+	}
+	if !hasScope(key.Scopes, scope) {
+		c.AbortWithStatusJSON(http.StatusForbidden, &Response{Code: http.StatusForbidden, Success: false, Message: fmt.Sprintf(ErrorAPIKeyScope, scope)})
+		return
+	}
+
+	claims := &AuthCustomClaims{
+		Username: apiKeyUsernamePrefix + key.Name,
+		Role:     login.RoleAdmin,
+		APIKeyId: key.Id,
+		Scopes:   key.Scopes,
+		StandardClaims: jwt.StandardClaims{
+			Subject: apiKeyUsernamePrefix + key.Id,
+		},
+	}
+	c.Set(JwtClaims, claims)
+	logger.WithField(c, "username", claims.Username)
+	if stale(key.LastUsedAt) || key.LastUsedIp != ip {
+		if err = keys.Touch(key.Id, ip); err != nil {
+			logger.FromContext(c).Errorf("middleware.AuthorizeAPIKey : %v", err)
+		}
+	}
+	c.Next()
+}
+
+// allowedAddress matches ip against a list of addresses and CIDR ranges,
+// an empty list allowing any address.
+func allowedAddress(allowed []string, ip string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	for _, entry := range allowed {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if addr != nil && network.Contains(addr) {
+				return true
+			}
+		} else if other := net.ParseIP(entry); other != nil && other.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// stale tells whether the last use recorded at lastUsedAt is old enough to
+// be written again, sparing a write on every request.
+func stale(lastUsedAt string) bool {
+	return lastUsedAt < utils.FormatTimeAfter(-apiKeyTouchInterval)
+}
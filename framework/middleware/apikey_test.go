@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gin-dbo/framework/config"
+	"gin-dbo/framework/database/dbtest"
+	"gin-dbo/framework/utils"
+	"gin-dbo/model/apikey"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+)
+
+const testAPIKey = apikey.Prefix + "secret"
+
+var keyColumns = []string{"id", "name", "key_hash", "scopes", "allowed_ips", "last_used_at", "last_used_ip"}
+
+// apiKeyRouter trusts no proxy, as the router does unless configured.
+func apiKeyRouter(t *testing.T, keys APIKeyStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := router.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	router.Use(AuthorizeJWT(JWTAuthService(config.JWT{SecretKey: "secret"}), keys))
+	router.GET("/api/customer", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	router.GET("/api/order", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	return router
+}
+
+func sendAPIKey(router *gin.Engine, path string, remoteAddr string, forwardedFor string) (int, string) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set(Authorization, BEARER_SCHEMA+" "+testAPIKey)
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	var body Response
+	json.Unmarshal(res.Body.Bytes(), &body)
+	return res.Code, body.Message
+}
+
+func expectKey(mock sqlmock.Sqlmock, scopes string, allowedIps string) {
+	mock.ExpectQuery("SELECT \\* FROM `api_keys` WHERE key_hash = \\? LIMIT 1").WithArgs(utils.Encrypt(testAPIKey)).
+		WillReturnRows(sqlmock.NewRows(keyColumns).AddRow("k1", "billing", utils.Encrypt(testAPIKey), []byte(scopes), []byte(allowedIps), "", ""))
+}
+
+func TestAPIKeyUnknown(t *testing.T) {
+	db, mock := dbtest.New(t)
+	mock.ExpectQuery("SELECT \\* FROM `api_keys` WHERE key_hash = \\?").WithArgs(utils.Encrypt(testAPIKey)).
+		WillReturnRows(sqlmock.NewRows(keyColumns))
+
+	code, message := sendAPIKey(apiKeyRouter(t, NewAPIKeyStore(db)), "/api/customer", "192.0.2.1:1234", "")
+	if code != http.StatusUnauthorized || message != ErrorInvalidAPIKey {
+		t.Fatalf("got %d %q, want 401 %q", code, message, ErrorInvalidAPIKey)
+	}
+}
+
+func TestAPIKeyWithinItsScopes(t *testing.T) {
+	db, mock := dbtest.New(t)
+	expectKey(mock, `["customer:read"]`, `null`)
+	mock.ExpectExec("UPDATE `api_keys` SET .* WHERE id = \\?").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if code, message := sendAPIKey(apiKeyRouter(t, NewAPIKeyStore(db)), "/api/customer", "192.0.2.1:1234", ""); code != http.StatusOK {
+		t.Fatalf("got %d %q, want 200", code, message)
+	}
+}
+
+func TestAPIKeyOutsideItsScopes(t *testing.T) {
+	db, mock := dbtest.New(t)
+	expectKey(mock, `["customer:read"]`, `null`)
+
+	code, _ := sendAPIKey(apiKeyRouter(t, NewAPIKeyStore(db)), "/api/order", "192.0.2.1:1234", "")
+	if code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403", code)
+	}
+}
+
+func TestAPIKeyIgnoresForwardedAddresses(t *testing.T) {
+	db, mock := dbtest.New(t)
+	expectKey(mock, `["customer:read"]`, `["10.0.0.0/8"]`)
+
+	code, message := sendAPIKey(apiKeyRouter(t, NewAPIKeyStore(db)), "/api/customer", "192.0.2.1:1234", "10.1.2.3")
+	if code != http.StatusForbidden || message != ErrorAPIKeyAddress {
+		t.Fatalf("got %d %q, want 403 %q", code, message, ErrorAPIKeyAddress)
+	}
+}
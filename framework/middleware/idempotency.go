@@ -0,0 +1,236 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"gin-dbo/framework/config"
+	"gin-dbo/framework/utils"
+	"gin-dbo/model/apikey"
+	"gin-dbo/model/idempotency"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	IdempotencyKey         = "Idempotency-Key"
+	IdempotencyReplayed    = "Idempotency-Replayed"
+	CacheControl           = "Cache-Control"
+	CacheNoStore           = "no-store"
+	MethodPut              = "PUT"
+	MethodPatch            = "PATCH"
+	maxIdempotencyKey      = 191
+	requestClaimsKey       = "REQUEST_CLAIMS"
+	ErrorIdempotencyKey    = "idempotency key is too long"
+	ErrorIdempotencyReused = "idempotency key was already used with a different request"
+	ErrorIdempotencyBusy   = "a request with this idempotency key is still in progress"
+)
+
+type IdempotencyStore interface {
+	// Reserve claims the key of record for its user. When the key is already
+	// taken the stored record is returned and nothing is written.
+	Reserve(record *idempotency.Record) (existing *idempotency.Record, err error)
+	Complete(record *idempotency.Record) error
+	Release(record *idempotency.Record) error
+}
+
+type idempotencyStore struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyStore(db *gorm.DB) IdempotencyStore {
+	return &idempotencyStore{db: db}
+}
+
+func (s *idempotencyStore) Reserve(record *idempotency.Record) (*idempotency.Record, error) {
+	if err := s.db.Where("expires_at < ?", utils.FormatTime()).Delete(&idempotency.Record{}).Error; err != nil {
+		return nil, err
+	}
+
+	query := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if query.Error != nil {
+		return nil, query.Error
+	}
+	if query.RowsAffected > 0 {
+		return nil, nil
+	}
+
+	var existing idempotency.Record
+	err := s.db.Where(recordKey(record)).Take(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// the owner released the key between our insert and read
+		return &idempotency.Record{Fingerprint: record.Fingerprint, Status: idempotency.StatusProcessing}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+func (s *idempotencyStore) Complete(record *idempotency.Record) error {
+	return s.db.Model(&idempotency.Record{}).Where(recordKey(record)).Updates(map[string]interface{}{
+		"status":       idempotency.StatusCompleted,
+		"status_code":  record.StatusCode,
+		"content_type": record.ContentType,
+		"body":         record.Body,
+	}).Error
+}
+
+func (s *idempotencyStore) Release(record *idempotency.Record) error {
+	return s.db.Where(recordKey(record)).Delete(&idempotency.Record{}).Error
+}
+
+func recordKey(record *idempotency.Record) map[string]interface{} {
+	return map[string]interface{}{"key": record.Key, "username": record.Username}
+}
+
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// NoStore marks a response carrying credentials, which neither caches nor
+// Idempotency keep.
+func NoStore(c *gin.Context) {
+	c.Header(CacheControl, CacheNoStore)
+}
+
+// Idempotency replays the first response of a mutating request sent with an
+// Idempotency-Key header instead of running the handler again. Keys are
+// scoped per caller and forgotten after the configured TTL; server errors
+// and responses marked with NoStore are not stored so the client can retry
+// them. Routes starting with one of excluded are never recorded.
+func Idempotency(store IdempotencyStore, cfg config.Idempotency, jwt JWTService, excluded []string) func(*gin.Context) {
+	ttl := cfg.TTL.Duration()
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKey)
+		switch c.Request.Method {
+		case MethodPost, MethodPut, MethodPatch, MethodDelete:
+		default:
+			key = ""
+		}
+		for _, route := range excluded {
+			if strings.HasPrefix(c.FullPath(), route) {
+				key = ""
+			}
+		}
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			c.AbortWithStatusJSON(http.StatusBadRequest, &Response{Code: http.StatusBadRequest, Success: false, Message: ErrorIdempotencyKey})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, &Response{Code: http.StatusBadRequest, Success: false, Message: err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		io.WriteString(hash, c.Request.Method+" "+c.Request.URL.RequestURI()+"\n")
+		hash.Write(body)
+		record := &idempotency.Record{
+			Key:         key,
+			Username:    idempotencyScope(c, jwt),
+			Fingerprint: hex.EncodeToString(hash.Sum(nil)),
+			Status:      idempotency.StatusProcessing,
+			CreatedAt:   utils.FormatTime(),
+			ExpiresAt:   utils.FormatTimeAfter(ttl),
+		}
+
+		existing, err := store.Reserve(record)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, &Response{Code: http.StatusInternalServerError, Success: false, Message: err.Error()})
+			return
+		}
+		if existing != nil {
+			switch {
+			case existing.Fingerprint != record.Fingerprint:
+				c.AbortWithStatusJSON(http.StatusConflict, &Response{Code: http.StatusConflict, Success: false, Message: ErrorIdempotencyReused})
+			case existing.Status != idempotency.StatusCompleted:
+				c.AbortWithStatusJSON(http.StatusConflict, &Response{Code: http.StatusConflict, Success: false, Message: ErrorIdempotencyBusy})
+			default:
+				c.Header(IdempotencyReplayed, "true")
+				c.Data(existing.StatusCode, existing.ContentType, existing.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		completed := false
+		defer func() {
+			if !completed {
+				store.Release(record)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		if recorder.Status() >= http.StatusInternalServerError || strings.Contains(recorder.Header().Get(CacheControl), CacheNoStore) {
+			return
+		}
+		record.StatusCode = recorder.Status()
+		record.ContentType = recorder.Header().Get("Content-Type")
+		record.Body = recorder.body.Bytes()
+		completed = store.Complete(record) == nil
+	}
+}
+
+// idempotencyScope names the caller owning a key: the user of a valid token,
+// the API key sent or else the address of an anonymous caller. Usernames
+// cannot contain a colon, so scopes never collide.
+func idempotencyScope(c *gin.Context, jwt JWTService) string {
+	if username := requestClaims(c, jwt).Username; username != "" {
+		return username
+	}
+	authHeader := strings.Split(c.GetHeader(Authorization), " ")
+	if len(authHeader) == 2 && authHeader[0] == BEARER_SCHEMA && strings.HasPrefix(authHeader[1], apikey.Prefix) {
+		return apiKeyUsernamePrefix + utils.Encrypt(authHeader[1])
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// requestClaims resolves the caller before AuthorizeJWT has run, so keys of
+// different users never collide. Anonymous callers get empty claims. The
+// result is kept on the context, the token is only parsed once.
+func requestClaims(c *gin.Context, jwt JWTService) *AuthCustomClaims {
+	if JWT, ok := c.Get(JwtClaims); ok {
+		return JWT.(*AuthCustomClaims)
+	}
+	if claims, ok := c.Get(requestClaimsKey); ok {
+		return claims.(*AuthCustomClaims)
+	}
+	claims := &AuthCustomClaims{}
+	authHeader := strings.Split(c.GetHeader(Authorization), " ")
+	if len(authHeader) == 2 && authHeader[0] == BEARER_SCHEMA {
+		if valid, err := jwt.ValidateToken(authHeader[1]); err == nil {
+			claims = valid
+		}
+	}
+	c.Set(requestClaimsKey, claims)
+	return claims
+}
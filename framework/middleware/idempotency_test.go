@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"gin-dbo/framework/config"
+	"gin-dbo/framework/database/dbtest"
+	"gin-dbo/model/idempotency"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+)
+
+// memoryIdempotencyStore keeps records the way the table does, keyed by key
+// and scope.
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[[2]string]*idempotency.Record
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: map[[2]string]*idempotency.Record{}}
+}
+
+func (s *memoryIdempotencyStore) Reserve(record *idempotency.Record) (*idempotency.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.records[[2]string{record.Key, record.Username}]; ok {
+		return existing, nil
+	}
+	stored := *record
+	s.records[[2]string{record.Key, record.Username}] = &stored
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(record *idempotency.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *record
+	stored.Status = idempotency.StatusCompleted
+	s.records[[2]string{record.Key, record.Username}] = &stored
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(record *idempotency.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, [2]string{record.Key, record.Username})
+	return nil
+}
+
+// idempotentRouter counts the requests that reached a handler.
+func idempotentRouter(store IdempotencyStore, calls *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Idempotency(store, config.Idempotency{}, JWTAuthService(config.JWT{SecretKey: "secret"}), []string{"/api/login"}))
+	router.POST("/api/order", func(c *gin.Context) {
+		*calls++
+		c.JSON(http.StatusOK, gin.H{"call": *calls})
+	})
+	router.POST("/api/login", func(c *gin.Context) {
+		*calls++
+		c.JSON(http.StatusOK, gin.H{"token": "secret"})
+	})
+	router.POST("/api/api-keys", func(c *gin.Context) {
+		NoStore(c)
+		*calls++
+		c.JSON(http.StatusOK, gin.H{"key": "secret"})
+	})
+	return router
+}
+
+func sendIdempotent(router *gin.Engine, path string, remoteAddr string, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`))
+	req.RemoteAddr = remoteAddr
+	req.Header.Set(IdempotencyKey, key)
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	return res
+}
+
+func TestIdempotencyReplaysTheFirstResponse(t *testing.T) {
+	var calls int
+	router := idempotentRouter(newMemoryIdempotencyStore(), &calls)
+
+	first := sendIdempotent(router, "/api/order", "10.0.0.1:1000", "k1")
+	second := sendIdempotent(router, "/api/order", "10.0.0.1:1000", "k1")
+	if calls != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls)
+	}
+	if second.Header().Get(IdempotencyReplayed) != "true" || second.Body.String() != first.Body.String() {
+		t.Fatalf("got %q replayed=%q, want %q replayed", second.Body.String(), second.Header().Get(IdempotencyReplayed), first.Body.String())
+	}
+}
+
+func TestIdempotencyKeepsAnonymousCallersApart(t *testing.T) {
+	var calls int
+	router := idempotentRouter(newMemoryIdempotencyStore(), &calls)
+
+	sendIdempotent(router, "/api/order", "10.0.0.1:1000", "k1")
+	res := sendIdempotent(router, "/api/order", "10.0.0.2:1000", "k1")
+	if calls != 2 || res.Header().Get(IdempotencyReplayed) != "" {
+		t.Fatalf("handler ran %d times, want the second caller to get its own response", calls)
+	}
+}
+
+func TestIdempotencyScopesAPIKeysByKey(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/order", nil)
+	c.Request.Header.Set(Authorization, BEARER_SCHEMA+" gdk_one")
+	jwt := JWTAuthService(config.JWT{SecretKey: "secret"})
+	one := idempotencyScope(c, jwt)
+
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/order", nil)
+	c.Request.Header.Set(Authorization, BEARER_SCHEMA+" gdk_two")
+	two := idempotencyScope(c, jwt)
+	if one == two || !strings.HasPrefix(one, apiKeyUsernamePrefix) || strings.Contains(one, "gdk_one") {
+		t.Fatalf("got scopes %q and %q, want distinct hashed key scopes", one, two)
+	}
+}
+
+func TestIdempotencySkipsExcludedRoutes(t *testing.T) {
+	var calls int
+	store := newMemoryIdempotencyStore()
+	router := idempotentRouter(store, &calls)
+
+	sendIdempotent(router, "/api/login", "10.0.0.1:1000", "k1")
+	sendIdempotent(router, "/api/login", "10.0.0.1:1000", "k1")
+	if calls != 2 || len(store.records) != 0 {
+		t.Fatalf("handler ran %d times with %d records, want 2 and none", calls, len(store.records))
+	}
+}
+
+func TestIdempotencyDoesNotStoreNoStoreResponses(t *testing.T) {
+	var calls int
+	store := newMemoryIdempotencyStore()
+	router := idempotentRouter(store, &calls)
+
+	res := sendIdempotent(router, "/api/api-keys", "10.0.0.1:1000", "k1")
+	if res.Header().Get(CacheControl) != CacheNoStore {
+		t.Fatalf("got Cache-Control %q, want %q", res.Header().Get(CacheControl), CacheNoStore)
+	}
+	if len(store.records) != 0 {
+		t.Fatalf("stored %d records, want none", len(store.records))
+	}
+}
+
+func TestIdempotencyStoreReserve(t *testing.T) {
+	record := &idempotency.Record{Key: "k1", Username: "ip:10.0.0.1", Fingerprint: "f1", Status: idempotency.StatusProcessing}
+
+	t.Run("returns the stored record", func(t *testing.T) {
+		db, mock := dbtest.New(t)
+		mock.ExpectExec("DELETE FROM `idempotency_records`").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO `idempotency_records`").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT \\* FROM `idempotency_records`").
+			WillReturnRows(sqlmock.NewRows([]string{"key", "username", "fingerprint", "status"}).AddRow("k1", "ip:10.0.0.1", "f0", idempotency.StatusCompleted))
+
+		existing, err := NewIdempotencyStore(db).Reserve(record)
+		if err != nil || existing == nil || existing.Fingerprint != "f0" {
+			t.Fatalf("got %+v, %v; want the stored record", existing, err)
+		}
+	})
+
+	t.Run("reports a key released meanwhile as busy", func(t *testing.T) {
+		db, mock := dbtest.New(t)
+		mock.ExpectExec("DELETE FROM `idempotency_records`").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO `idempotency_records`").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT \\* FROM `idempotency_records`").
+			WillReturnRows(sqlmock.NewRows([]string{"key", "username", "fingerprint", "status"}))
+
+		existing, err := NewIdempotencyStore(db).Reserve(record)
+		if err != nil || existing == nil || existing.Fingerprint != "f1" || existing.Status != idempotency.StatusProcessing {
+			t.Fatalf("got %+v, %v; want a processing record", existing, err)
+		}
+	})
+}
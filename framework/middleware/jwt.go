@@ -4,10 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"gin-dbo/framework/config"
+	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/logger"
+	"gin-dbo/framework/tracing"
+	"gin-dbo/model/apikey"
 	"gin-dbo/model/login"
 
 	"github.com/dgrijalva/jwt-go"
@@ -22,25 +26,40 @@ const (
 	MethodGet         = "GET"
 	MethodPost        = "POST"
 	MethodDelete      = "DELETE"
-	JwtSecretKey      = "JWT_SECRET_KEY"
-	JwtIssuer         = "JWT_ISSUER"
 	JwtClaims         = "JWT_CLAIMS"
+	// SelfServicePath holds the endpoints every user may call on its own
+	// account, whatever its role.
+	SelfServicePath = "/api/user/me/"
+	// MePath is the account of the caller, resolved from its token.
+	MePath = "/api/me"
+	// TwoFactorPath is all an enrollment token may reach.
+	TwoFactorPath          = "/api/user/me/2fa/"
+	ErrorTwoFactorRequired = "two-factor authentication must be set up first"
 )
 
 type JWTService interface {
 	GenerateToken(p *login.User) string
+	// GenerateEnrollmentToken is a token only good to set up two-factor
+	// authentication, for users the policy requires it of.
+	GenerateEnrollmentToken(p *login.User) string
 	ValidateToken(token string) (*AuthCustomClaims, error)
 }
 type AuthCustomClaims struct {
 	Username   string `json:"username"`
 	Role       string `json:"role"`
 	CustomerId string `json:"customer_id"`
+	MustEnroll bool   `json:"must_enroll,omitempty"`
+	// APIKeyId and Scopes are set when the request came with an API key
+	// instead of a JWT.
+	APIKeyId string   `json:"-"`
+	Scopes   []string `json:"-"`
 	jwt.StandardClaims
 }
 
 type jwtServices struct {
 	secretKey string
 	issuer    string
+	expiry    time.Duration
 }
 
 type Response struct {
@@ -49,22 +68,32 @@ type Response struct {
 	Message string `json:"message" example:"unauthorized"`
 }
 
-func JWTAuthService() JWTService {
+func JWTAuthService(cfg config.JWT) JWTService {
 	return &jwtServices{
-		secretKey: os.Getenv(JwtSecretKey),
-		issuer:    os.Getenv(JwtIssuer),
+		secretKey: cfg.SecretKey.Value(),
+		issuer:    cfg.Issuer,
+		expiry:    cfg.Expiry.Duration(),
 	}
 }
 
 func (service *jwtServices) GenerateToken(p *login.User) string {
+	return service.generate(p, false)
+}
+
+func (service *jwtServices) GenerateEnrollmentToken(p *login.User) string {
+	return service.generate(p, true)
+}
+
+func (service *jwtServices) generate(p *login.User, mustEnroll bool) string {
 	claims := &AuthCustomClaims{
 		Username:   p.Username,
 		Role:       p.Role,
 		CustomerId: p.CustomerId,
+		MustEnroll: mustEnroll,
 		StandardClaims: jwt.StandardClaims{
 			IssuedAt:  time.Now().Unix(),
 			Subject:   p.Username,
-			ExpiresAt: time.Now().Add(time.Hour * 24).Unix(),
+			ExpiresAt: time.Now().Add(service.expiry).Unix(),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -77,7 +106,7 @@ func (service *jwtServices) GenerateToken(p *login.User) string {
 
 func (service *jwtServices) ValidateToken(encodedToken string) (*AuthCustomClaims, error) {
 	token, err := jwt.ParseWithClaims(encodedToken, &AuthCustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv(JwtSecretKey)), nil
+		return []byte(service.secretKey), nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error parsing token : %v", err)
@@ -90,20 +119,34 @@ func (service *jwtServices) ValidateToken(encodedToken string) (*AuthCustomClaim
 	}
 }
 
-func AuthorizeJWT() func(*gin.Context) {
+// AuthorizeJWT accepts a JWT, or an API key of keys, as the Bearer token.
+func AuthorizeJWT(service JWTService, keys APIKeyStore) func(*gin.Context) {
 	return func(c *gin.Context) {
 		authHeader := strings.Split(c.GetHeader(Authorization), " ")
-		if authHeader[0] != BEARER_SCHEMA {
+		if len(authHeader) != 2 || authHeader[0] != BEARER_SCHEMA {
 			err := errors.New(ErrorMissingAuth)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, &Response{Code: http.StatusUnauthorized, Success: false, Message: err.Error()})
 			return
+		} else if strings.HasPrefix(authHeader[1], apikey.Prefix) {
+			authorizeAPIKey(c, keys, authHeader[1])
 		} else {
 			jwtSegment := strings.Split(authHeader[1], ".")
 			if authHeader[1] != "" && len(jwtSegment) == 3 {
 				tokenString := authHeader[1]
-				claims, err := JWTAuthService().ValidateToken(tokenString)
+				span := tracing.Start(c, "middleware.AuthorizeJWT")
+				claims, err := service.ValidateToken(tokenString)
+				var failure *internal.Error
+				if err != nil {
+					failure = internal.NewError(http.StatusUnauthorized, err)
+				}
+				span.End(&failure)
 				if err == nil {
 					c.Set(JwtClaims, claims)
+					logger.WithField(c, "username", claims.Username)
+					if claims.MustEnroll && !strings.HasPrefix(c.Request.URL.Path, TwoFactorPath) {
+						c.AbortWithStatusJSON(http.StatusForbidden, &Response{Code: http.StatusForbidden, Success: false, Message: ErrorTwoFactorRequired})
+						return
+					}
 					claims.validatePath(c)
 				} else {
 					c.AbortWithStatusJSON(http.StatusUnauthorized, &Response{Code: http.StatusUnauthorized, Success: false, Message: err.Error()})
@@ -119,7 +162,7 @@ func AuthorizeJWT() func(*gin.Context) {
 }
 
 func (claims *AuthCustomClaims) validatePath(c *gin.Context) {
-	if claims.Role == "admin" {
+	if claims.Role == "admin" || strings.HasPrefix(c.Request.URL.Path, SelfServicePath) || isMePath(c.Request.URL.Path) {
 		c.Next()
 	} else {
 		if strings.Contains(c.Request.URL.Path, "order") {
@@ -132,3 +175,7 @@ func (claims *AuthCustomClaims) validatePath(c *gin.Context) {
 		}
 	}
 }
+
+func isMePath(path string) bool {
+	return path == MePath || strings.HasPrefix(path, MePath+"/")
+}
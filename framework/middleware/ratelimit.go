@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gin-dbo/framework/config"
+	"gin-dbo/framework/logger"
+	"gin-dbo/framework/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	RateLimitLimit     = "RateLimit-Limit"
+	RateLimitRemaining = "RateLimit-Remaining"
+	RateLimitReset     = "RateLimit-Reset"
+	RetryAfter         = "Retry-After"
+	ErrorRateLimited   = "too many requests, retry in %d seconds"
+
+	// rateLimitSweep is how often the memory store forgets full buckets.
+	rateLimitSweep = time.Minute
+)
+
+// RateLimitResult is the state of a bucket after a token was taken.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next token when none was left.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the token buckets. The memory store suits a single
+// instance, instances sharing their limits plug in a store backed by a
+// shared cache instead.
+type RateLimitStore interface {
+	// Take removes a token from the bucket of key, created full when it
+	// does not exist yet.
+	Take(key string, rate config.Rate) (RateLimitResult, error)
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	rate    config.Rate
+}
+
+// refill adds the tokens earned since the bucket was last updated.
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(float64(b.rate.Requests), b.tokens+now.Sub(b.updated).Seconds()*b.rate.PerSecond())
+	b.updated = now
+}
+
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	swept   time.Time
+	now     func() time.Time
+}
+
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{buckets: map[string]*tokenBucket{}, now: time.Now}
+}
+
+func (s *memoryRateLimitStore) Take(key string, rate config.Rate) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.swept) > rateLimitSweep {
+		s.sweep(now)
+	}
+
+	bucket, ok := s.buckets[key]
+	if !ok || bucket.rate != rate {
+		bucket = &tokenBucket{tokens: float64(rate.Requests), updated: now, rate: rate}
+		s.buckets[key] = bucket
+	}
+	bucket.refill(now)
+
+	var res RateLimitResult
+	perSecond := rate.PerSecond()
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - bucket.tokens) / perSecond * float64(time.Second))
+	}
+	res.Remaining = int(bucket.tokens)
+	res.Reset = time.Duration((float64(rate.Requests) - bucket.tokens) / perSecond * float64(time.Second))
+	return res, nil
+}
+
+// sweep forgets the buckets that refilled completely, they are the same as
+// a new one.
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	for key, bucket := range s.buckets {
+		bucket.refill(now)
+		if bucket.tokens >= float64(bucket.rate.Requests) {
+			delete(s.buckets, key)
+		}
+	}
+	s.swept = now
+}
+
+// RateLimitRule limits the routes starting with Route. The first matching
+// rule applies, so the more specific ones come first.
+type RateLimitRule struct {
+	Name  string
+	Route string
+	Rate  config.Rate
+}
+
+// RateLimit takes a token from the bucket of the caller for every request
+// and answers 429 with Retry-After once it is empty. Every limited response
+// carries the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// headers. It runs before AuthorizeJWT, so callers are keyed by the token
+// they send. When the store fails the request is let through.
+func RateLimit(store RateLimitStore, rules []RateLimitRule, jwt JWTService) func(*gin.Context) {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		var rule *RateLimitRule
+		for i := range rules {
+			if route != "" && strings.HasPrefix(route, rules[i].Route) {
+				rule = &rules[i]
+				break
+			}
+		}
+		if rule == nil || rule.Rate.Requests == 0 {
+			c.Next()
+			return
+		}
+
+		key := rule.Name + ":" + rateLimitKey(c, rule.Rate.Key, jwt)
+		res, err := store.Take(key, rule.Rate)
+		if err != nil {
+			logger.FromContext(c).Warnf("middleware.RateLimit : %v", err)
+			c.Next()
+			return
+		}
+
+		c.Header(RateLimitLimit, strconv.Itoa(rule.Rate.Requests))
+		c.Header(RateLimitRemaining, strconv.Itoa(res.Remaining))
+		c.Header(RateLimitReset, strconv.Itoa(seconds(res.Reset)))
+		if !res.Allowed {
+			retry := seconds(res.RetryAfter)
+			metrics.RateLimited.WithLabelValues(rule.Name).Inc()
+			logger.FromContext(c).Warnf("middleware.RateLimit : %s exceeded %s", key, rule.Rate)
+			c.Header(RetryAfter, strconv.Itoa(retry))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, &Response{Code: http.StatusTooManyRequests, Success: false, Message: fmt.Sprintf(ErrorRateLimited, retry)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitKey tells callers apart by their IP, their username or the
+// subject of their token, falling back to the IP when the request does not
+// name one.
+func rateLimitKey(c *gin.Context, key string, jwt JWTService) string {
+	switch key {
+	case config.RateKeySubject:
+		if subject := requestClaims(c, jwt).Subject; subject != "" {
+			return "sub:" + subject
+		}
+	case config.RateKeyUsername:
+		if username := requestClaims(c, jwt).Username; username != "" {
+			return "user:" + username
+		}
+		if username := bodyUsername(c); username != "" {
+			return "user:" + username
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// bodyUsername reads the username of a login or register payload and puts
+// the body back for the handler.
+func bodyUsername(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	var payload struct {
+		Username string `json:"username"`
+	}
+	json.Unmarshal(body, &payload)
+	return strings.ToLower(payload.Username)
+}
+
+// seconds rounds d up, so clients never retry too early.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
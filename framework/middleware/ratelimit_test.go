@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gin-dbo/framework/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clock is a time the tests move by hand.
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newTestRateLimitStore() (*memoryRateLimitStore, *clock) {
+	c := &clock{now: time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)}
+	store := NewMemoryRateLimitStore().(*memoryRateLimitStore)
+	store.now = c.Now
+	return store, c
+}
+
+func TestMemoryRateLimitStoreTake(t *testing.T) {
+	store, clock := newTestRateLimitStore()
+	rate := config.Rate{Requests: 2, Period: config.Duration(10 * time.Second), Key: config.RateKeyIP}
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		want    RateLimitResult
+	}{
+		{"full bucket", 0, RateLimitResult{Allowed: true, Remaining: 1, Reset: 5 * time.Second}},
+		{"last token", 0, RateLimitResult{Allowed: true, Remaining: 0, Reset: 10 * time.Second}},
+		{"empty bucket", 0, RateLimitResult{Remaining: 0, Reset: 10 * time.Second, RetryAfter: 5 * time.Second}},
+		{"half a token later", 2500 * time.Millisecond, RateLimitResult{Remaining: 0, Reset: 7500 * time.Millisecond, RetryAfter: 2500 * time.Millisecond}},
+		{"refilled a token", 2500 * time.Millisecond, RateLimitResult{Allowed: true, Remaining: 0, Reset: 10 * time.Second}},
+		{"refilled to the brim", time.Hour, RateLimitResult{Allowed: true, Remaining: 1, Reset: 5 * time.Second}},
+	}
+	for _, tt := range tests {
+		clock.now = clock.now.Add(tt.advance)
+		got, err := store.Take("login:ip:192.0.2.1", rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s : got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryRateLimitStoreKeepsKeysApart(t *testing.T) {
+	store, _ := newTestRateLimitStore()
+	rate := config.Rate{Requests: 1, Period: config.Duration(time.Minute), Key: config.RateKeyIP}
+	for _, key := range []string{"login:ip:192.0.2.1", "login:ip:192.0.2.2"} {
+		if got, _ := store.Take(key, rate); !got.Allowed {
+			t.Fatalf("%s : refused its first request", key)
+		}
+	}
+}
+
+func rateLimitedRouter(store RateLimitStore, rate config.Rate) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RateLimit(store, []RateLimitRule{{Name: "login", Route: "/api/login", Rate: rate}}, JWTAuthService(config.JWT{SecretKey: "secret"})))
+	router.POST("/api/login", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	router.GET("/api/order", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	return router
+}
+
+func sendLogin(router *gin.Engine, remoteAddr string, username string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(`{"username":"`+username+`"}`))
+	req.RemoteAddr = remoteAddr
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	return res
+}
+
+func TestRateLimitAnswers429(t *testing.T) {
+	store, clock := newTestRateLimitStore()
+	router := rateLimitedRouter(store, config.Rate{Requests: 1, Period: config.Duration(time.Minute), Key: config.RateKeyIP})
+
+	res := sendLogin(router, "192.0.2.1:1234", "jane")
+	if res.Code != http.StatusOK || res.Header().Get(RateLimitLimit) != "1" || res.Header().Get(RateLimitRemaining) != "0" || res.Header().Get(RateLimitReset) != "60" {
+		t.Fatalf("got %d with headers %v, want 200 with limit 1, remaining 0 and reset 60", res.Code, res.Header())
+	}
+
+	clock.now = clock.now.Add(20 * time.Second)
+	res = sendLogin(router, "192.0.2.1:1234", "jane")
+	if res.Code != http.StatusTooManyRequests || res.Header().Get(RetryAfter) != "40" || res.Header().Get(RateLimitReset) != "40" {
+		t.Fatalf("got %d with headers %v, want 429 retrying after 40 seconds", res.Code, res.Header())
+	}
+	if !strings.Contains(res.Body.String(), "retry in 40 seconds") {
+		t.Fatalf("got body %s", res.Body)
+	}
+
+	if res = sendLogin(router, "192.0.2.2:1234", "jane"); res.Code != http.StatusOK {
+		t.Fatalf("got %d for another address, want 200", res.Code)
+	}
+}
+
+func TestRateLimitKeysByUsername(t *testing.T) {
+	store, _ := newTestRateLimitStore()
+	router := rateLimitedRouter(store, config.Rate{Requests: 1, Period: config.Duration(time.Minute), Key: config.RateKeyUsername})
+
+	sendLogin(router, "192.0.2.1:1234", "jane")
+	if res := sendLogin(router, "192.0.2.2:1234", "Jane"); res.Code != http.StatusTooManyRequests {
+		t.Fatalf("got %d for jane from another address, want 429", res.Code)
+	}
+	if res := sendLogin(router, "192.0.2.1:1234", "joe"); res.Code != http.StatusOK {
+		t.Fatalf("got %d for joe, want 200", res.Code)
+	}
+}
+
+func TestRateLimitSkipsRoutesWithoutRule(t *testing.T) {
+	store, _ := newTestRateLimitStore()
+	router := rateLimitedRouter(store, config.Rate{Requests: 1, Period: config.Duration(time.Minute), Key: config.RateKeyIP})
+	for i := 0; i < 3; i++ {
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/api/order", nil))
+		if res.Code != http.StatusOK || res.Header().Get(RateLimitLimit) != "" {
+			t.Fatalf("got %d with headers %v, want an unlimited 200", res.Code, res.Header())
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gin-dbo/framework/config"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	httpTimeout = 10 * time.Second
+	// keysRefresh is the least time between two fetches of the signing keys,
+	// so tokens with unknown key ids cannot hammer the provider.
+	keysRefresh = time.Minute
+	maxBody     = 1 << 20
+)
+
+// Identity is the user the provider vouched for in an ID token.
+type Identity struct {
+	Subject       string
+	Username      string
+	Email         string
+	EmailVerified bool
+	Groups        []string
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+// Provider is an OpenID Connect relying party of one identity provider. Its
+// discovery document and signing keys are fetched on first use and cached.
+type Provider struct {
+	cfg    config.OIDC
+	client *http.Client
+
+	mu          sync.Mutex
+	discovery   *discovery
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+}
+
+func New(cfg config.OIDC) *Provider {
+	return &Provider{cfg: cfg, client: &http.Client{Timeout: httpTimeout}}
+}
+
+// NewVerifier generates a PKCE code verifier, also fit for states and
+// nonces.
+func NewVerifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Challenge is the S256 PKCE code challenge of verifier.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL is where to send the user to sign in, coming back to the
+// redirect URL with a code and state.
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {Challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return d.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems code for an ID token and returns the identity in it once
+// its signature, issuer, audience, expiry and nonce are checked.
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string, nonce string) (*Identity, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if secret := p.cfg.ClientSecret.Value(); secret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(secret))
+	}
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.do(req, &token)
+	if err != nil {
+		return nil, err
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("token endpoint answered %s : %s", token.Error, token.ErrorDescription)
+	}
+	if status != http.StatusOK || token.IDToken == "" {
+		return nil, fmt.Errorf("token endpoint answered %d without an id token", status)
+	}
+	return p.verify(ctx, d, token.IDToken, nonce)
+}
+
+func (p *Provider) verify(ctx context.Context, d *discovery, raw string, nonce string) (*Identity, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, d, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token : %v", err)
+	}
+
+	if iss, _ := claims["iss"].(string); iss != d.Issuer {
+		return nil, fmt.Errorf("id token issued by %q instead of %q", iss, d.Issuer)
+	}
+	if !contains(claimStrings(claims["aud"]), p.cfg.ClientID) {
+		return nil, fmt.Errorf("id token is not meant for client %s", p.cfg.ClientID)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.New("id token has no expiry")
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, errors.New("id token nonce does not match")
+	}
+
+	identity := &Identity{Groups: claimStrings(claims[p.cfg.GroupsClaim])}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Username, _ = claims[p.cfg.UsernameClaim].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	if identity.Subject == "" {
+		return nil, errors.New("id token has no subject")
+	}
+	return identity, nil
+}
+
+// discover reads the discovery document of the issuer, keeping it once it
+// was read.
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	issuer := strings.TrimSuffix(p.cfg.Issuer, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var d discovery
+	status, err := p.do(req, &d)
+	if err != nil {
+		return nil, fmt.Errorf("discovery : %v", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("discovery answered %d", status)
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery names issuer %q instead of %q", d.Issuer, p.cfg.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JwksURI == "" {
+		return nil, errors.New("discovery lacks an authorization, token or jwks endpoint")
+	}
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+// key finds the signing key kid, fetching the keys again when it is unknown
+// because the provider may have rotated them.
+func (p *Provider) key(ctx context.Context, d *discovery, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.lookup(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetched) < keysRefresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.JwksURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	status, err := p.do(req, &set)
+	if err != nil {
+		return nil, fmt.Errorf("signing keys : %v", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("signing keys answered %d", status)
+	}
+	p.keysFetched = time.Now()
+	p.keys = map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errn := base64.RawURLEncoding.DecodeString(k.N)
+		e, erre := base64.RawURLEncoding.DecodeString(k.E)
+		if errn != nil || erre != nil || len(e) > 4 {
+			continue
+		}
+		p.keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	if key, ok := p.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookup finds kid among the cached keys, any key matching a token without
+// a key id when there is a single one.
+func (p *Provider) lookup(kid string) (*rsa.PublicKey, bool) {
+	if key, ok := p.keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+func (p *Provider) do(req *http.Request, v interface{}) (int, error) {
+	res, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxBody))
+	if err != nil {
+		return res.StatusCode, err
+	}
+	if err = json.Unmarshal(body, v); err != nil && res.StatusCode == http.StatusOK {
+		return res.StatusCode, fmt.Errorf("invalid response : %v", err)
+	}
+	return res.StatusCode, nil
+}
+
+// claimStrings reads a claim holding a single string or an array of them.
+func claimStrings(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
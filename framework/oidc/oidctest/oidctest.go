@@ -0,0 +1,202 @@
+// Package oidctest is a local stand-in for an OpenID Connect provider. It
+// signs in a single configured user without asking anything, for the
+// mock-idp command and for tests of the OIDC sign in.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"gin-dbo/framework/oidc"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const keyId = "mock"
+
+// Options describe the provider and the user it signs in.
+type Options struct {
+	// Issuer is the URL the service reaches the provider at.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	User         string
+	// Email is reported verified unless empty.
+	Email  string
+	Groups []string
+	// Deny refuses every sign in with access_denied.
+	Deny bool
+	// Log tells of every sign in when set.
+	Log *log.Logger
+}
+
+type grant struct {
+	challenge   string
+	nonce       string
+	redirectURI string
+	expiresAt   time.Time
+}
+
+// IdP serves the discovery document, signing keys, authorization and token
+// endpoints of the provider.
+type IdP struct {
+	Options
+	key *rsa.PrivateKey
+	mux *http.ServeMux
+
+	mu     sync.Mutex
+	grants map[string]*grant
+}
+
+// New generates the signing key of a provider. Its Issuer may still be set
+// until it serves the first request.
+func New(opts Options) (*IdP, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	p := &IdP{Options: opts, key: key, mux: http.NewServeMux(), grants: map[string]*grant{}}
+	p.mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	p.mux.HandleFunc("/jwks", p.jwks)
+	p.mux.HandleFunc("/authorize", p.authorize)
+	p.mux.HandleFunc("/token", p.token)
+	return p, nil
+}
+
+func (p *IdP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mux.ServeHTTP(w, r)
+}
+
+func (p *IdP) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.Issuer,
+		"authorization_endpoint":                p.Issuer + "/authorize",
+		"token_endpoint":                        p.Issuer + "/token",
+		"jwks_uri":                              p.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *IdP) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": keyId,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+	}}})
+}
+
+func (p *IdP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirectURI.Host == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if query.Get("client_id") != p.ClientID || query.Get("response_type") != "code" {
+		http.Error(w, "unknown client or unsupported response_type", http.StatusBadRequest)
+		return
+	}
+	back := redirectURI.Query()
+	back.Set("state", query.Get("state"))
+	if p.Deny {
+		back.Set("error", "access_denied")
+		back.Set("error_description", "the mock provider was started with -deny")
+	} else if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		back.Set("error", "invalid_request")
+		back.Set("error_description", "PKCE with S256 is required")
+	} else {
+		code, _ := oidc.NewVerifier()
+		p.mu.Lock()
+		p.grants[code] = &grant{
+			challenge:   query.Get("code_challenge"),
+			nonce:       query.Get("nonce"),
+			redirectURI: query.Get("redirect_uri"),
+			expiresAt:   time.Now().Add(time.Minute),
+		}
+		p.mu.Unlock()
+		back.Set("code", code)
+	}
+	redirectURI.RawQuery = back.Encode()
+	if p.Log != nil {
+		p.Log.Printf("signing %s in, back to %s", p.User, redirectURI.Redacted())
+	}
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (p *IdP) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	id, secret, basic := r.BasicAuth()
+	if basic {
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id = r.PostForm.Get("client_id")
+	}
+	if id != p.ClientID || secret != p.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mu.Lock()
+	g := p.grants[r.PostForm.Get("code")]
+	delete(p.grants, r.PostForm.Get("code"))
+	p.mu.Unlock()
+	if g == nil || time.Now().After(g.expiresAt) || g.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	if oidc.Challenge(r.PostForm.Get("code_verifier")) != g.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "code_verifier does not match"})
+		return
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                p.Issuer,
+		"sub":                "mock|" + p.User,
+		"aud":                p.ClientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              g.nonce,
+		"preferred_username": p.User,
+		"email":              p.Email,
+		"email_verified":     p.Email != "",
+		"groups":             p.Groups,
+	})
+	token.Header["kid"] = keyId
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	accessToken, _ := oidc.NewVerifier()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
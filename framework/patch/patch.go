@@ -0,0 +1,80 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const ContentType = "application/merge-patch+json"
+
+// Merge applies an RFC 7396 JSON merge patch to the target document.
+func Merge(target []byte, patch []byte) ([]byte, error) {
+	t, err := decode(target)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeValue(t, p))
+}
+
+// CheckFields makes sure the patch is a JSON object that only touches the
+// allowed top level members.
+func CheckFields(patch []byte, allowed []string) error {
+	doc, err := decode(patch)
+	if err != nil {
+		return err
+	}
+	members, ok := doc.(map[string]interface{})
+	if !ok {
+		return errors.New("merge patch must be a json object")
+	}
+
+	for name := range members {
+		found := false
+		for _, field := range allowed {
+			if field == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("field %s can't be patched", name)
+		}
+	}
+	return nil
+}
+
+func decode(doc []byte) (interface{}, error) {
+	var res interface{}
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.UseNumber()
+	if err := decoder.Decode(&res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func mergeValue(target interface{}, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+
+	for name, value := range p {
+		if value == nil {
+			delete(t, name)
+		} else {
+			t[name] = mergeValue(t[name], value)
+		}
+	}
+	return t
+}
@@ -0,0 +1,66 @@
+package patch
+
+import (
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		patch  string
+		want   string
+	}{
+		{"replaces a member", `{"name":"pen","qty":1}`, `{"qty":2}`, `{"name":"pen","qty":2}`},
+		{"adds a member", `{"name":"pen"}`, `{"qty":2}`, `{"name":"pen","qty":2}`},
+		{"removes a member set to null", `{"name":"pen","qty":1}`, `{"qty":null}`, `{"name":"pen"}`},
+		{"merges nested objects", `{"a":{"b":1,"c":2}}`, `{"a":{"c":null,"d":3}}`, `{"a":{"b":1,"d":3}}`},
+		{"replaces arrays whole", `{"tags":["a","b"]}`, `{"tags":["c"]}`, `{"tags":["c"]}`},
+		{"replaces a non object target", `{"a":"b"}`, `{"a":{"c":1}}`, `{"a":{"c":1}}`},
+		{"replaces the document with a non object patch", `{"a":1}`, `["a"]`, `["a"]`},
+		{"keeps large numbers exact", `{"qty":1}`, `{"qty":9007199254740993}`, `{"qty":9007199254740993}`},
+		{"changes nothing with an empty patch", `{"name":"pen"}`, `{}`, `{"name":"pen"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Merge([]byte(tt.target), []byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeRefusesInvalidJSON(t *testing.T) {
+	if _, err := Merge([]byte(`{"name":"pen"}`), []byte(`{"qty":`)); err == nil {
+		t.Fatal("merged an invalid patch")
+	}
+	if _, err := Merge([]byte(`{"name"`), []byte(`{}`)); err == nil {
+		t.Fatal("merged into an invalid target")
+	}
+}
+
+func TestCheckFields(t *testing.T) {
+	allowed := []string{"name", "qty"}
+	tests := []struct {
+		name    string
+		patch   string
+		wantErr bool
+	}{
+		{"allowed members", `{"name":"pen","qty":null}`, false},
+		{"empty object", `{}`, false},
+		{"other member", `{"name":"pen","customerId":"c2"}`, true},
+		{"array", `["name"]`, true},
+		{"invalid json", `{"name"`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckFields([]byte(tt.patch), allowed); (err != nil) != tt.wantErr {
+				t.Fatalf("got %v, want an error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	"gin-dbo/framework/config"
+	"gin-dbo/framework/lifecycle"
+
+	"github.com/sirupsen/logrus"
+)
+
+// New builds the HTTP server of handler with the configured port and
+// timeouts.
+func New(handler http.Handler, cfg config.Server) *http.Server {
+	return &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.Port),
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout.Duration(),
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout.Duration(),
+		WriteTimeout:      cfg.WriteTimeout.Duration(),
+		IdleTimeout:       cfg.IdleTimeout.Duration(),
+	}
+}
+
+// Hook listens when the lifecycle starts, so a taken port fails the start,
+// and drains in-flight requests when it stops. The lifecycle is shut down
+// when the server stops serving on its own.
+func Hook(srv *http.Server, lc *lifecycle.Lifecycle, log *logrus.Logger) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name: "http server",
+		OnStart: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+			log.Infof("server.Hook : listening on %s", listener.Addr())
+			go func() {
+				if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					lc.Shutdown(err)
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return srv.Shutdown(ctx)
+		},
+	}
+}
@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"errors"
+	"regexp"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+// literals matches quoted strings and numbers a statement may inline, as raw
+// queries do.
+var literals = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.)*"|\b\d+(?:\.\d+)?\b`)
+
+// Sanitize replaces the literals of statement by placeholders so spans never
+// carry credentials or personal data.
+func Sanitize(statement string) string {
+	return literals.ReplaceAllString(statement, "?")
+}
+
+// Plugin opens a client span around every statement gorm runs, nested under
+// the span of the statement's context.
+type Plugin struct{}
+
+func (Plugin) Name() string {
+	return "tracing"
+}
+
+func (p Plugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	for _, err := range []error{
+		callback.Create().Before("gorm:create").Register("tracing:before_create", before("create")),
+		callback.Create().After("gorm:create").Register("tracing:after_create", after),
+		callback.Query().Before("gorm:query").Register("tracing:before_query", before("select")),
+		callback.Query().After("gorm:query").Register("tracing:after_query", after),
+		callback.Update().Before("gorm:update").Register("tracing:before_update", before("update")),
+		callback.Update().After("gorm:update").Register("tracing:after_update", after),
+		callback.Delete().Before("gorm:delete").Register("tracing:before_delete", before("delete")),
+		callback.Delete().After("gorm:delete").Register("tracing:after_delete", after),
+		callback.Row().Before("gorm:row").Register("tracing:before_row", before("row")),
+		callback.Row().After("gorm:row").Register("tracing:after_row", after),
+		callback.Raw().Before("gorm:raw").Register("tracing:before_raw", before("raw")),
+		callback.Raw().After("gorm:raw").Register("tracing:after_raw", after),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		_, span := Tracer().Start(Context(db.Statement.Context), "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemMySQL, semconv.DBOperation(operation)),
+		)
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func after(db *gorm.DB) {
+	v, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := v.(trace.Span)
+	defer span.End()
+
+	// the SQL keeps its placeholders, the bound values are never recorded
+	span.SetAttributes(
+		semconv.DBStatement(Sanitize(db.Statement.SQL.String())),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if db.Statement.Table != "" {
+		span.SetAttributes(semconv.DBSQLTable(db.Statement.Table))
+	}
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
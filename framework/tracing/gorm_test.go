@@ -0,0 +1,24 @@
+package tracing
+
+import (
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		statement string
+		want      string
+	}{
+		{"SELECT * FROM `users` WHERE username = 'jane' AND password = 'p4ss'", "SELECT * FROM `users` WHERE username = ? AND password = ?"},
+		{`SELECT * FROM users WHERE name = "o\"brien"`, "SELECT * FROM users WHERE name = ?"},
+		{"SELECT * FROM users WHERE name = 'o''brien' OR name = 'it\\'s'", "SELECT * FROM users WHERE name = ? OR name = ?"},
+		{"SELECT * FROM `orders` WHERE qty > 10 AND price < 9.99 LIMIT 100", "SELECT * FROM `orders` WHERE qty > ? AND price < ? LIMIT ?"},
+		{"SELECT * FROM `v2_orders` WHERE id = ?", "SELECT * FROM `v2_orders` WHERE id = ?"},
+		{"SELECT * FROM `customers`", "SELECT * FROM `customers`"},
+	}
+	for _, tt := range tests {
+		if got := Sanitize(tt.statement); got != tt.want {
+			t.Errorf("Sanitize(%q) = %q, want %q", tt.statement, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/semconv/v1.17.0/httpconv"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const TraceIDHeader = "X-Trace-ID"
+
+// Middleware continues the trace of the caller's traceparent header, or
+// starts one, with a server span per request. The trace id is returned in
+// the X-Trace-ID header and added to JSON error bodies so a failing call can
+// be looked up.
+func Middleware(service string) func(*gin.Context) {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		name := c.Request.Method + " " + route
+		if route == "" {
+			name = c.Request.Method
+		}
+		ctx, span := Tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(httpconv.ServerRequest(service, c.Request)...),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		traceID := ""
+		if span.SpanContext().HasTraceID() {
+			traceID = span.SpanContext().TraceID().String()
+			c.Header(TraceIDHeader, traceID)
+			c.Writer = &errorWriter{ResponseWriter: c.Writer, traceID: traceID}
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if route != "" {
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetAttributes(semconv.HTTPStatusCode(status))
+		span.SetStatus(httpconv.ServerStatus(status))
+		if len(c.Errors) > 0 {
+			span.SetAttributes(semconv.ExceptionMessage(c.Errors.String()))
+		}
+	}
+}
+
+// errorWriter adds the trace id to the JSON object of an error response,
+// which the handlers write in a single call.
+type errorWriter struct {
+	gin.ResponseWriter
+	traceID string
+}
+
+func (w *errorWriter) Write(data []byte) (int, error) {
+	if w.Status() < 400 || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") || !bytes.HasPrefix(data, []byte("{")) {
+		return w.ResponseWriter.Write(data)
+	}
+
+	field := `"traceId":` + strconv.Quote(w.traceID)
+	if !bytes.HasPrefix(bytes.TrimSpace(data[1:]), []byte("}")) {
+		field += ","
+	}
+	body := make([]byte, 0, len(data)+len(field))
+	body = append(body, '{')
+	body = append(body, field...)
+	body = append(body, data[1:]...)
+	if _, err := w.ResponseWriter.Write(body); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (w *errorWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
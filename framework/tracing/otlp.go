@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+)
+
+// NewOTLPExporter sends spans to an OpenTelemetry collector with OTLP over
+// HTTP, e.g. to http://localhost:4318/v1/traces; headers is a comma
+// separated list of key=value pairs, such as an authorization header.
+func NewOTLPExporter(endpoint string, headers string) (*otlptrace.Exporter, error) {
+	target, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	options := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(target.Host),
+		otlptracehttp.WithTimeout(10 * time.Second),
+	}
+	if target.Path != "" {
+		options = append(options, otlptracehttp.WithURLPath(target.Path))
+	}
+	if target.Scheme == "http" {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	if pairs := parseHeaders(headers); len(pairs) > 0 {
+		options = append(options, otlptracehttp.WithHeaders(pairs))
+	}
+	return otlptracehttp.New(context.Background(), options...)
+}
+
+func parseHeaders(headers string) map[string]string {
+	res := map[string]string{}
+	for _, pair := range strings.Split(headers, ",") {
+		if key, value, ok := strings.Cut(pair, "="); ok {
+			res[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return res
+}
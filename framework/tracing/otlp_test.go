@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	collector "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOTLPExporterSendsToTheCollector(t *testing.T) {
+	var (
+		request *http.Request
+		body    []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	exporter, err := NewOTLPExporter(server.URL+"/otlp/v1/traces", "Authorization=Bearer token, X-Tenant = dbo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	_, span := provider.Tracer(TracerName).Start(context.Background(), "customer.usecase.Get")
+	span.End()
+	if err = provider.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if request == nil {
+		t.Fatal("the collector got no request")
+	}
+	if request.URL.Path != "/otlp/v1/traces" || request.Header.Get("Authorization") != "Bearer token" || request.Header.Get("X-Tenant") != "dbo" {
+		t.Fatalf("got %s with headers %v", request.URL.Path, request.Header)
+	}
+	var export collector.ExportTraceServiceRequest
+	if err = proto.Unmarshal(body, &export); err != nil {
+		t.Fatal(err)
+	}
+	spans := export.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 1 || spans[0].Name != "customer.usecase.Get" {
+		t.Fatalf("got spans %v, want customer.usecase.Get", spans)
+	}
+}
+
+func TestOTLPExporterRefusesABadEndpoint(t *testing.T) {
+	if _, err := NewOTLPExporter("http://[::1", ""); err == nil {
+		t.Fatal("accepted a bad endpoint")
+	}
+}
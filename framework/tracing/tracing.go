@@ -0,0 +1,135 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gin-dbo/framework/config"
+	internal "gin-dbo/framework/error"
+	"gin-dbo/framework/health"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TracerName = "gin-dbo"
+
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// Setup installs the global tracer provider and W3C trace context
+// propagation. The returned shutdown flushes the spans still buffered.
+func Setup(cfg config.Tracing, environment string) (func(ctx context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch cfg.Exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		exporter, err = NewOTLPExporter(cfg.Endpoint, cfg.Headers.Value())
+	default:
+		err = fmt.Errorf("unknown exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(health.Version),
+		semconv.DeploymentEnvironment(environment),
+	)
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Context returns the context carrying the current span of ctx. The span of
+// a request lives in the context of its *http.Request, which *gin.Context
+// does not expose through Value.
+func Context(ctx context.Context) context.Context {
+	if c, ok := ctx.(*gin.Context); ok {
+		if c.Request == nil {
+			return context.Background()
+		}
+		return c.Request.Context()
+	}
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+// TraceID is the id of the trace ctx belongs to, or empty when it is not
+// sampled.
+func TraceID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(Context(ctx)); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
+
+// Span is a span made the current one of a request until it ends.
+type Span struct {
+	trace.Span
+	c      *gin.Context
+	parent context.Context
+}
+
+// Start opens a child span of the current span of c, so spans started
+// further down the call, including the SQL statements, nest under it.
+//
+//	defer tracing.Start(ctx, "customer.usecase.Get").End(&err)
+func Start(c *gin.Context, name string, attrs ...attribute.KeyValue) *Span {
+	parent := Context(c)
+	ctx, span := Tracer().Start(parent, name, trace.WithAttributes(attrs...))
+	if c != nil && c.Request != nil {
+		c.Request = c.Request.WithContext(ctx)
+	}
+	return &Span{Span: span, c: c, parent: parent}
+}
+
+// End records the error err points to, if any, and restores the parent span
+// as the current one. Client errors are recorded without failing the span.
+func (s *Span) End(err **internal.Error) {
+	if err != nil && *err != nil {
+		e := *err
+		s.SetAttributes(attribute.Int("error.code", e.Code))
+		if e.Message != nil {
+			s.RecordError(e.Message)
+			if e.Code >= 500 {
+				s.SetStatus(codes.Error, e.Message.Error())
+			}
+		}
+	}
+	s.Span.End()
+	if s.c != nil && s.c.Request != nil {
+		s.c.Request = s.c.Request.WithContext(s.parent)
+	}
+}
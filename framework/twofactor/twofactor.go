@@ -0,0 +1,109 @@
+package twofactor
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	// Period is how long a TOTP code is valid, the default of authenticator
+	// apps.
+	Period = 30
+	// Skew accepts the codes of the periods right before and after the
+	// current one, for clocks drifting a little.
+	Skew = 1
+
+	qrSize = 256
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Key is a new TOTP secret of an account, along with the otpauth URI and
+// the QR code authenticator apps scan to enroll it.
+type Key struct {
+	Secret string
+	URI    string
+	// QRCode is the URI as a PNG data URI.
+	QRCode string
+}
+
+func Generate(issuer string, account string) (*Key, error) {
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: issuer, AccountName: account, Period: Period})
+	if err != nil {
+		return nil, err
+	}
+	image, err := key.Image(qrSize, qrSize)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, image); err != nil {
+		return nil, err
+	}
+	return &Key{
+		Secret: key.Secret(),
+		URI:    key.URL(),
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// Validate checks code against secret at now and returns the time step it
+// belongs to. Steps up to lastStep were already used, their codes are
+// refused so a code cannot be replayed.
+func Validate(secret string, code string, now time.Time, lastStep int64) (int64, bool) {
+	code = strings.TrimSpace(code)
+	current := now.Unix() / Period
+	for offset := int64(-Skew); offset <= Skew; offset++ {
+		step := current + offset
+		if step <= lastStep {
+			continue
+		}
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*Period, 0), totp.ValidateOpts{
+			Period:    Period,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// RecoveryCodes generates n single use codes such as ABCDE-FGHIJ, to log in
+// when the authenticator is lost.
+func RecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := recoveryEncoding.EncodeToString(b)[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode lets users type a recovery code in any case, with
+// or without its dash and spaces.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.Join(strings.Fields(code), ""))
+	code = strings.ReplaceAll(code, "-", "")
+	if len(code) == 10 {
+		code = code[:5] + "-" + code[5:]
+	}
+	return code
+}
@@ -0,0 +1,80 @@
+package twofactor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const secret = "JBSWY3DPEHPK3PXP"
+
+func codeAt(t *testing.T, step int64) string {
+	code, err := totp.GenerateCodeCustom(secret, time.Unix(step*Period, 0), totp.ValidateOpts{
+		Period:    Period,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2026, 10, 19, 10, 0, 10, 0, time.UTC)
+	current := now.Unix() / Period
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOk   bool
+	}{
+		{"current step", codeAt(t, current), 0, current, true},
+		{"with spaces", " " + codeAt(t, current) + " ", 0, current, true},
+		{"previous step", codeAt(t, current-1), 0, current - 1, true},
+		{"next step", codeAt(t, current+1), 0, current + 1, true},
+		{"beyond skew behind", codeAt(t, current-2), 0, 0, false},
+		{"beyond skew ahead", codeAt(t, current+2), 0, 0, false},
+		{"replayed", codeAt(t, current), current, 0, false},
+		{"older than the last step", codeAt(t, current-1), current, 0, false},
+		{"after the last step", codeAt(t, current+1), current, current + 1, true},
+		{"wrong code", "000000", 0, 0, false},
+		{"too short", codeAt(t, current)[:5], 0, 0, false},
+		{"empty", "", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(secret, tt.code, now, tt.lastStep)
+			if ok != tt.wantOk || step != tt.wantStep {
+				t.Fatalf("got step %d and %t, want step %d and %t", step, ok, tt.wantStep, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestValidateRefusesABadSecret(t *testing.T) {
+	if _, ok := Validate("not base32!", "123456", time.Now(), 0); ok {
+		t.Fatal("accepted a code for a bad secret")
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	codes, err := RecoveryCodes(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Fatalf("got recovery code %q", code)
+		}
+		for _, typed := range []string{code, " " + code[:5] + " " + code[6:] + " ", code[:5] + code[6:]} {
+			if got := NormalizeRecoveryCode(typed); got != code {
+				t.Fatalf("%q normalized to %q, want %q", typed, got, code)
+			}
+		}
+	}
+}
@@ -11,9 +11,12 @@ import (
 )
 
 const (
-	Limit   = "limit"
-	Page    = "page"
-	Keyword = "keyword"
+	Limit      = "limit"
+	Page       = "page"
+	Keyword    = "keyword"
+	Tag        = "tag"
+	Segment    = "segment"
+	TimeLayout = "2006-01-02 15:04:05"
 )
 
 func Encrypt(s string) string {
@@ -24,7 +27,16 @@ func Encrypt(s string) string {
 }
 
 func FormatTime() string {
-	return time.Now().Local().Format("2006-01-02 15:04:05")
+	return time.Now().Local().Format(TimeLayout)
+}
+
+func FormatTimeAfter(d time.Duration) string {
+	return time.Now().Add(d).Local().Format(TimeLayout)
+}
+
+// ParseTime reads a time written by FormatTime.
+func ParseTime(v string) (time.Time, error) {
+	return time.ParseInLocation(TimeLayout, v, time.Local)
 }
 
 func GetLimit(v string) (int, *internal.Error) {
@@ -67,3 +79,16 @@ func GetTotalPage(limit int, total int) int {
 		return 1
 	}
 }
+
+// Backoff is the delay before the given attempt is retried: it doubles from
+// min on every attempt and is capped at max.
+func Backoff(attempts int, min time.Duration, max time.Duration) time.Duration {
+	delay := min
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
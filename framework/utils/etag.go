@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	internal "gin-dbo/framework/error"
+)
+
+const (
+	ETag        = "ETag"
+	IfMatch     = "If-Match"
+	IfNoneMatch = "If-None-Match"
+)
+
+// IfMatchRequired rejects writes sent without an If-Match header; it is set
+// from the configuration at startup.
+var IfMatchRequired bool
+
+func FormatETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// GetIfMatch returns the version a write is conditioned on, or 0 when the
+// client sent no precondition. A missing header is rejected when
+// IfMatchRequired is set.
+func GetIfMatch(v string) (int64, *internal.Error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		if IfMatchRequired {
+			return 0, internal.NewError(428, fmt.Errorf("%s header is required", IfMatch))
+		}
+		return 0, nil
+	}
+	if v == "*" {
+		return 0, nil
+	}
+
+	res, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(v, "W/"), `"`), 10, 64)
+	if err != nil || res <= 0 {
+		return 0, internal.NewError(400, fmt.Errorf("%s header %s is not a valid etag", IfMatch, v))
+	}
+	return res, nil
+}
+
+// MatchETag reports whether an If-None-Match header matches etag.
+func MatchETag(header string, etag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.TrimPrefix(v, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,19 @@
 package utils
 
 import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"gin-dbo/framework/patch"
+	apikeyModel "gin-dbo/model/apikey"
+	segmentModel "gin-dbo/model/segment"
+	apiKeyView "gin-dbo/view/apikey"
 	customerModel "gin-dbo/view/customer"
 	loginModel "gin-dbo/view/login"
 	orderModel "gin-dbo/view/order"
+	segmentView "gin-dbo/view/segment"
+	webhookModel "gin-dbo/view/webhook"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -15,27 +25,79 @@ var (
 		"Password": "required",
 	}
 	createLoginRule = map[string]string{
-		"Username": "required",
-		"Password": "required",
-		"Role":     "required",
+		"Username": "required,username",
+		"Password": "required,password",
+		"Role":     "required,oneof=admin customer",
+		"Email":    "omitempty,email,max=191",
 	}
 	updateLoginRule = map[string]string{
 		"Username": "required",
-		"Password": "required",
-		"Role":     "required",
+		"Password": "required,password",
+		"Role":     "required,oneof=admin customer",
+	}
+	registerLoginRule = map[string]string{
+		"Username":        "required,username",
+		"Password":        "required,password",
+		"Email":           "required,email,max=191",
+		"InvitationToken": "omitempty,max=128",
+	}
+	loginTwoFactorRule = map[string]string{
+		"ChallengeToken": "required,max=128",
+		"Code":           "required,max=16",
+	}
+	oidcCallbackRule = map[string]string{
+		"Code":  "required_without=Error,max=2048",
+		"State": "required,max=128",
+	}
+	twoFactorCodeRule = map[string]string{
+		"Code": "required,max=16",
+	}
+	verifyEmailRule = map[string]string{
+		"Token": "required,max=128",
+	}
+	forgotPasswordRule = map[string]string{
+		"Email": "required,email,max=191",
+	}
+	resetPasswordRule = map[string]string{
+		"Token":    "required,max=128",
+		"Password": "required,password",
+	}
+	updateMeRule = map[string]string{
+		"Email": "required,email,max=191",
+	}
+	changePasswordRule = map[string]string{
+		"CurrentPassword": "required,max=72",
+		"Password":        "required,password",
+	}
+	createInvitationRule = map[string]string{
+		"Role":     "required,oneof=admin customer",
+		"Username": "omitempty,username",
+	}
+	deleteInvitationRule = map[string]string{
+		"Id": "required",
 	}
 
 	// customer
 	createCustomerRule = map[string]string{
-		"Name": "required",
+		"Name":  "required",
+		"Email": "omitempty,email,max=191",
+		"Phone": "omitempty,phone",
+		"Tags":  "omitempty,max=20,unique,dive,tag",
 	}
 	updateCustomerRule = map[string]string{
-		"Id":   "required",
-		"Name": "required",
+		"Id":    "required",
+		"Name":  "required",
+		"Email": "omitempty,email,max=191",
+		"Phone": "omitempty,phone",
+		"Tags":  "omitempty,max=20,unique,dive,tag",
 	}
 	deleteCustomerRule = map[string]string{
 		"Id": "required",
 	}
+	mergeCustomerRule = map[string]string{
+		"TargetId":  "required",
+		"SourceIds": "required,min=1,max=50,unique,dive,required,max=191,nefield=TargetId",
+	}
 
 	// Order
 	createOrderRule = map[string]string{
@@ -52,19 +114,188 @@ var (
 	deleteOrderRule = map[string]string{
 		"Id": "required",
 	}
+	batchOrderRule = map[string]string{
+		"Operations": "required,min=1,max=100,dive,required",
+	}
+	batchOrderOperationRule = map[string]string{
+		"Op": "required,oneof=create update delete",
+	}
+
+	// webhook
+	createWebhookRule = map[string]string{
+		"Url":        "required,http_url,max=2048",
+		"Secret":     "omitempty,min=16,max=128",
+		"EventTypes": "required,min=1,max=50,dive,required,max=64",
+	}
+	updateWebhookRule = map[string]string{
+		"Id":         "required",
+		"Url":        "required,http_url,max=2048",
+		"Secret":     "omitempty,min=16,max=128",
+		"EventTypes": "required,min=1,max=50,dive,required,max=64",
+		"Active":     "required",
+	}
+	deleteWebhookRule = map[string]string{
+		"Id": "required",
+	}
+
+	// api key
+	createAPIKeyRule = map[string]string{
+		"Name":       "required,max=100",
+		"Scopes":     "required,min=1,dive,oneof=" + strings.Join(apikeyModel.Scopes, " "),
+		"AllowedIps": "omitempty,max=50,dive,ip|cidr",
+		"ExpiresAt":  "omitempty,datetime=" + TimeLayout,
+	}
+	revokeAPIKeyRule = map[string]string{
+		"Id": "required",
+	}
+
+	// segment
+	createSegmentRule = map[string]string{
+		"Name":        "required,max=100",
+		"Description": "max=500",
+		"Match":       "omitempty,oneof=all any",
+		"Rules":       "required,min=1,max=20,dive,required",
+	}
+	updateSegmentRule = map[string]string{
+		"Id":          "required",
+		"Name":        "required,max=100",
+		"Description": "max=500",
+		"Match":       "omitempty,oneof=all any",
+		"Rules":       "required,min=1,max=20,dive,required",
+	}
+	deleteSegmentRule = map[string]string{
+		"Id": "required",
+	}
+	segmentRuleRule = map[string]string{
+		"Field":    "required,oneof=orders items tag",
+		"Operator": "omitempty,oneof=gt gte lt lte eq",
+		"Value":    "min=0",
+		"Days":     "min=0,max=3650",
+		"Tag":      "omitempty,tag",
+	}
+
+	// fields each role may change through a merge patch
+	patchLoginFields = map[string][]string{
+		"admin": {"password", "role", "customerId"},
+	}
+	patchCustomerFields = map[string][]string{
+		"admin":    {"name", "email", "phone", "tags"},
+		"customer": {"name", "email", "phone"},
+	}
+	patchOrderFields = map[string][]string{
+		"admin":    {"customerId", "name", "qty"},
+		"customer": {"name", "qty"},
+	}
+	// fields users may change of their own account
+	patchMeFields = []string{"email"}
 )
 
+// usernameFormat is 3 to 32 letters, digits, dots, dashes or underscores,
+// starting with a letter or digit.
+var usernameFormat = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{2,31}$`)
+
+// phoneFormat is a number of 6 to 32 digits, spaces, dots, dashes or
+// parentheses, optionally starting with +.
+var phoneFormat = regexp.MustCompile(`^\+?[0-9 ().-]{6,32}$`)
+
+// tagFormat is 1 to 32 lower case letters, digits, dots, colons, dashes or
+// underscores, starting with a letter or digit.
+var tagFormat = regexp.MustCompile(`^[a-z0-9][a-z0-9.:_-]{0,31}$`)
+
+func validUsername(fl validator.FieldLevel) bool {
+	return usernameFormat.MatchString(fl.Field().String())
+}
+
+func validPhone(fl validator.FieldLevel) bool {
+	return phoneFormat.MatchString(fl.Field().String())
+}
+
+func validTag(fl validator.FieldLevel) bool {
+	return tagFormat.MatchString(fl.Field().String())
+}
+
+// validPassword wants 8 to 72 characters mixing upper and lower case letters
+// and digits.
+func validPassword(fl validator.FieldLevel) bool {
+	password := fl.Field().String()
+	if len(password) < 8 || len(password) > 72 {
+		return false
+	}
+	var upper, lower, digit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return upper && lower && digit
+}
+
+// passwordWithoutUsername rejects passwords containing the username.
+func passwordWithoutUsername(sl validator.StructLevel) {
+	username := strings.ToLower(sl.Current().FieldByName("Username").String())
+	password := strings.ToLower(sl.Current().FieldByName("Password").String())
+	if username != "" && strings.Contains(password, username) {
+		sl.ReportError(password, "Password", "Password", "nousername", "")
+	}
+}
+
+// completeSegmentRule wants a tag for tag rules and an operator for the
+// others.
+func completeSegmentRule(sl validator.StructLevel) {
+	rule := sl.Current().Interface().(segmentModel.Rule)
+	if rule.Field == segmentModel.FieldTag && rule.Tag == "" {
+		sl.ReportError(rule.Tag, "Tag", "Tag", "required_if", "Field tag")
+	}
+	if rule.Field != segmentModel.FieldTag && rule.Operator == "" {
+		sl.ReportError(rule.Operator, "Operator", "Operator", "required_unless", "Field tag")
+	}
+}
+
 func NewValidate() *validator.Validate {
 	validate := validator.New()
+	validate.RegisterValidation("username", validUsername)
+	validate.RegisterValidation("password", validPassword)
+	validate.RegisterValidation("phone", validPhone)
+	validate.RegisterValidation("tag", validTag)
+	validate.RegisterStructValidation(passwordWithoutUsername, loginModel.CreateRequest{}, loginModel.UpdateRequest{}, loginModel.RegisterRequest{})
+	validate.RegisterStructValidation(completeSegmentRule, segmentModel.Rule{})
 	validate.RegisterStructValidationMapRules(loginRule, loginModel.LoginRequest{})
 	validate.RegisterStructValidationMapRules(createLoginRule, loginModel.CreateRequest{})
 	validate.RegisterStructValidationMapRules(updateLoginRule, loginModel.UpdateRequest{})
+	validate.RegisterStructValidationMapRules(registerLoginRule, loginModel.RegisterRequest{})
+	validate.RegisterStructValidationMapRules(loginTwoFactorRule, loginModel.LoginTwoFactorRequest{})
+	validate.RegisterStructValidationMapRules(oidcCallbackRule, loginModel.OIDCCallbackRequest{})
+	validate.RegisterStructValidationMapRules(twoFactorCodeRule, loginModel.TwoFactorCodeRequest{})
+	validate.RegisterStructValidationMapRules(verifyEmailRule, loginModel.VerifyEmailRequest{})
+	validate.RegisterStructValidationMapRules(forgotPasswordRule, loginModel.ForgotPasswordRequest{})
+	validate.RegisterStructValidationMapRules(resetPasswordRule, loginModel.ResetPasswordRequest{})
+	validate.RegisterStructValidationMapRules(updateMeRule, loginModel.UpdateMeRequest{})
+	validate.RegisterStructValidationMapRules(changePasswordRule, loginModel.ChangePasswordRequest{})
+	validate.RegisterStructValidationMapRules(createInvitationRule, loginModel.CreateInvitationRequest{})
+	validate.RegisterStructValidationMapRules(deleteInvitationRule, loginModel.DeleteInvitationRequest{})
 	validate.RegisterStructValidationMapRules(createCustomerRule, customerModel.CreateRequest{})
 	validate.RegisterStructValidationMapRules(updateCustomerRule, customerModel.UpdateRequest{})
 	validate.RegisterStructValidationMapRules(deleteCustomerRule, customerModel.DeleteRequest{})
+	validate.RegisterStructValidationMapRules(mergeCustomerRule, customerModel.MergeRequest{})
 	validate.RegisterStructValidationMapRules(createOrderRule, orderModel.CreateRequest{})
 	validate.RegisterStructValidationMapRules(updateOrderRule, orderModel.UpdateRequest{})
 	validate.RegisterStructValidationMapRules(deleteOrderRule, orderModel.DeleteRequest{})
+	validate.RegisterStructValidationMapRules(batchOrderRule, orderModel.BatchRequest{})
+	validate.RegisterStructValidationMapRules(batchOrderOperationRule, orderModel.BatchOperation{})
+	validate.RegisterStructValidationMapRules(createWebhookRule, webhookModel.CreateRequest{})
+	validate.RegisterStructValidationMapRules(updateWebhookRule, webhookModel.UpdateRequest{})
+	validate.RegisterStructValidationMapRules(deleteWebhookRule, webhookModel.DeleteRequest{})
+	validate.RegisterStructValidationMapRules(createAPIKeyRule, apiKeyView.CreateRequest{})
+	validate.RegisterStructValidationMapRules(revokeAPIKeyRule, apiKeyView.RevokeRequest{})
+	validate.RegisterStructValidationMapRules(createSegmentRule, segmentView.CreateRequest{})
+	validate.RegisterStructValidationMapRules(updateSegmentRule, segmentView.UpdateRequest{})
+	validate.RegisterStructValidationMapRules(deleteSegmentRule, segmentView.DeleteRequest{})
+	validate.RegisterStructValidationMapRules(segmentRuleRule, segmentModel.Rule{})
 	return validate
 }
 
@@ -78,10 +309,71 @@ func ValidateCreateRequest(request *loginModel.CreateRequest) error {
 	return Validate.Struct(request)
 }
 
+func ValidateRegisterRequest(request *loginModel.RegisterRequest) error {
+	return Validate.Struct(request)
+}
+
+func ValidateLoginTwoFactorRequest(request *loginModel.LoginTwoFactorRequest) error {
+	return Validate.Struct(request)
+}
+
+func ValidateOIDCCallbackRequest(request *loginModel.OIDCCallbackRequest) error {
+	return Validate.Struct(request)
+}
+
+func ValidateTwoFactorCodeRequest(request *loginModel.TwoFactorCodeRequest) error {
+	return Validate.Struct(request)
+}
+
+func ValidateVerifyEmailRequest(request *loginModel.VerifyEmailRequest) error {
+	return Validate.Struct(request)
+}
+
+func ValidateForgotPasswordRequest(request *loginModel.ForgotPasswordRequest) error {
+	return Validate.Struct(request)
+}
+
+func ValidateResetPasswordRequest(request *loginModel.ResetPasswordRequest) error {
+	return Validate.Struct(request)
+}
+
+func ValidateUpdateMeRequest(request *loginModel.UpdateMeRequest) error {
+	return Validate.Struct(request)
+}
+
+func ValidatePatchMeFields(document []byte) error {
+	return patch.CheckFields(document, patchMeFields)
+}
+
+func ValidateChangePasswordRequest(request *loginModel.ChangePasswordRequest) error {
+	return Validate.Struct(request)
+}
+
+func ValidateCreateInvitationRequest(request *loginModel.CreateInvitationRequest) error {
+	return Validate.Struct(request)
+}
+
+func ValidateDeleteInvitationRequest(request *loginModel.DeleteInvitationRequest) error {
+	return Validate.Struct(request)
+}
+
 func ValidateUpdateRequest(request *loginModel.UpdateRequest) error {
 	return Validate.Struct(request)
 }
 
+// ValidatePatchedRequest validates a user after a merge patch was applied;
+// the password is only checked when the patch sets a new one.
+func ValidatePatchedRequest(request *loginModel.UpdateRequest) error {
+	if request.Password == "" {
+		return Validate.StructExcept(request, "Password")
+	}
+	return Validate.Struct(request)
+}
+
+func ValidatePatchFields(role string, document []byte) error {
+	return patch.CheckFields(document, patchLoginFields[role])
+}
+
 func ValidateDeleteRequest(request *loginModel.DeleteRequest) error {
 	return Validate.Struct(request)
 }
@@ -94,10 +386,18 @@ func ValidateUpdateCustomerRequest(request *customerModel.UpdateRequest) error {
 	return Validate.Struct(request)
 }
 
+func ValidatePatchCustomerFields(role string, document []byte) error {
+	return patch.CheckFields(document, patchCustomerFields[role])
+}
+
 func ValidateDeleteCustomerRequest(request *customerModel.DeleteRequest) error {
 	return Validate.Struct(request)
 }
 
+func ValidateMergeCustomerRequest(request *customerModel.MergeRequest) error {
+	return Validate.Struct(request)
+}
+
 func ValidateCreateOrderRequest(request *orderModel.CreateRequest) error {
 	return Validate.Struct(request)
 }
@@ -106,6 +406,62 @@ func ValidateUpdateOrderRequest(request *orderModel.UpdateRequest) error {
 	return Validate.Struct(request)
 }
 
+func ValidatePatchOrderFields(role string, document []byte) error {
+	return patch.CheckFields(document, patchOrderFields[role])
+}
+
 func ValidateDeleteOrderRequest(request *orderModel.DeleteRequest) error {
 	return Validate.Struct(request)
 }
+
+func ValidateBatchOrderRequest(request *orderModel.BatchRequest) error {
+	return Validate.Struct(request)
+}
+
+// ValidateBatchOrderOperation checks the operation kind and then validates
+// its payload with the same rules as the single order endpoints.
+func ValidateBatchOrderOperation(request *orderModel.BatchOperation) error {
+	if err := Validate.Struct(request); err != nil {
+		return err
+	}
+	switch request.Op {
+	case orderModel.OpCreate:
+		return ValidateCreateOrderRequest(request.CreateRequest())
+	case orderModel.OpUpdate:
+		return ValidateUpdateOrderRequest(request.UpdateRequest())
+	default:
+		return ValidateDeleteOrderRequest(request.DeleteRequest())
+	}
+}
+
+func ValidateCreateWebhookRequest(request *webhookModel.CreateRequest) error {
+	return Validate.Struct(request)
+}
+
+func ValidateUpdateWebhookRequest(request *webhookModel.UpdateRequest) error {
+	return Validate.Struct(request)
+}
+
+func ValidateDeleteWebhookRequest(request *webhookModel.DeleteRequest) error {
+	return Validate.Struct(request)
+}
+
+func ValidateCreateAPIKeyRequest(request *apiKeyView.CreateRequest) error {
+	return Validate.Struct(request)
+}
+
+func ValidateRevokeAPIKeyRequest(request *apiKeyView.RevokeRequest) error {
+	return Validate.Struct(request)
+}
+
+func ValidateCreateSegmentRequest(request *segmentView.CreateRequest) error {
+	return Validate.Struct(request)
+}
+
+func ValidateUpdateSegmentRequest(request *segmentView.UpdateRequest) error {
+	return Validate.Struct(request)
+}
+
+func ValidateDeleteSegmentRequest(request *segmentView.DeleteRequest) error {
+	return Validate.Struct(request)
+}
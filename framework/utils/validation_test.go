@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestValidatePatchFieldsPerRole(t *testing.T) {
+	tests := []struct {
+		name     string
+		validate func(role string, document []byte) error
+		role     string
+		patch    string
+		wantErr  bool
+	}{
+		{"admin sets a password", ValidatePatchFields, "admin", `{"password":"Correct-Horse-1","role":"customer"}`, false},
+		{"admin sets a username", ValidatePatchFields, "admin", `{"username":"joe"}`, true},
+		{"customer patches a user", ValidatePatchFields, "customer", `{"password":"Correct-Horse-1"}`, true},
+		{"admin renames a customer", ValidatePatchCustomerFields, "admin", `{"name":"Jane"}`, false},
+		{"customer renames itself", ValidatePatchCustomerFields, "customer", `{"name":"Jane"}`, false},
+		{"customer sets its id", ValidatePatchCustomerFields, "customer", `{"id":"c2"}`, true},
+		{"admin moves an order", ValidatePatchOrderFields, "admin", `{"customerId":"c2","qty":2}`, false},
+		{"customer changes a quantity", ValidatePatchOrderFields, "customer", `{"qty":2}`, false},
+		{"customer moves an order", ValidatePatchOrderFields, "customer", `{"customerId":"c2"}`, true},
+		{"unknown role", ValidatePatchOrderFields, "guest", `{"qty":2}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.validate(tt.role, []byte(tt.patch)); (err != nil) != tt.wantErr {
+				t.Fatalf("got %v, want an error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
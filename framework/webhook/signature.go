@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+
+	// DefaultTolerance is how old a signature Verify accepts, which bounds
+	// replays of a captured request.
+	DefaultTolerance = 5 * time.Minute
+)
+
+// Sign returns the signature header of body sent at timestamp, in the form
+// t=<unix seconds>,v1=<hex hmac-sha256 of "<t>.<body>" keyed by secret>.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", t, digest(secret, t, body))
+}
+
+// Verify checks a signature header made by Sign, rejecting it when it is
+// older than tolerance. Receivers can use it as is.
+func Verify(secret string, header string, body []byte, tolerance time.Duration) error {
+	var t, signature string
+	for _, part := range strings.Split(header, ",") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(part), "="); ok {
+			switch k {
+			case "t":
+				t = v
+			case "v1":
+				signature = v
+			}
+		}
+	}
+	if t == "" || signature == "" {
+		return errors.New("malformed signature header")
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return errors.New("malformed signature timestamp")
+	}
+	if tolerance > 0 && math.Abs(time.Since(time.Unix(unix, 0)).Seconds()) > tolerance.Seconds() {
+		return errors.New("signature timestamp is outside the tolerance")
+	}
+	if !hmac.Equal([]byte(signature), []byte(digest(secret, t, body))) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+func digest(secret string, t string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Matches tells whether eventType is selected by one of patterns: an exact
+// type, a prefix ending in ".*" or "*".
+func Matches(patterns []string, eventType string) bool {
+	for _, pattern := range patterns {
+		if pattern == "*" || pattern == eventType {
+			return true
+		}
+		if strings.HasSuffix(pattern, ".*") && strings.HasPrefix(eventType, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,279 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"gin-dbo/framework/utils"
+	eventModel "gin-dbo/model/event"
+	models "gin-dbo/model/webhook"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultInterval     = 2 * time.Second
+	defaultBatchSize    = 50
+	defaultMaxAttempts  = 12
+	defaultDisableAfter = 20
+	defaultLease        = 2 * time.Minute
+	defaultTimeout      = 10 * time.Second
+	defaultMinBackoff   = 10 * time.Second
+	defaultMaxBackoff   = time.Hour
+	maxResponseBody     = 1024
+	userAgent           = "gin-dbo-webhook/1.0"
+)
+
+// Worker posts webhook deliveries to their subscriptions. It subscribes to
+// the event bus through Enqueue and delivers from its own table, so a slow
+// partner never holds back the outbox.
+type Worker struct {
+	Dbconn       *gorm.DB
+	Client       *http.Client
+	Log          *logrus.Logger
+	Interval     time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	DisableAfter int
+	Lease        time.Duration
+	MinBackoff   time.Duration
+	MaxBackoff   time.Duration
+}
+
+func NewWorker(dbconn *gorm.DB, log *logrus.Logger) *Worker {
+	return &Worker{
+		Dbconn:       dbconn,
+		Client:       &http.Client{Timeout: defaultTimeout},
+		Log:          log,
+		Interval:     defaultInterval,
+		BatchSize:    defaultBatchSize,
+		MaxAttempts:  defaultMaxAttempts,
+		DisableAfter: defaultDisableAfter,
+		Lease:        defaultLease,
+		MinBackoff:   defaultMinBackoff,
+		MaxBackoff:   defaultMaxBackoff,
+	}
+}
+
+// Enqueue creates a delivery of event for every active subscription
+// selecting its type. Seeing the same event twice creates nothing new.
+func (w *Worker) Enqueue(ctx context.Context, event *eventModel.Event) error {
+	var subscriptions []*models.Subscription
+	if err := w.Dbconn.WithContext(ctx).Where("active = ?", true).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	now := utils.FormatTime()
+	var deliveries []*models.Delivery
+	for _, subscription := range subscriptions {
+		if Matches(subscription.EventTypes, event.Type) {
+			deliveries = append(deliveries, &models.Delivery{
+				Id:             uuid.New().String(),
+				SubscriptionId: subscription.Id,
+				EventId:        event.Id,
+				EventType:      event.Type,
+				Payload:        body,
+				Status:         models.StatusPending,
+				NextAttemptAt:  now,
+				CreatedAt:      now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return w.Dbconn.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error
+}
+
+// Run delivers until ctx is cancelled, retrying failed deliveries with
+// exponential backoff up to MaxAttempts. A subscription is disabled after
+// DisableAfter failed attempts in a row.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := w.Dispatch(ctx)
+		if err != nil {
+			w.Log.Errorf("webhook.Run : %v", err)
+		}
+		if n < w.BatchSize || err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		} else if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Dispatch sends one batch of due deliveries and returns how many were
+// claimed.
+func (w *Worker) Dispatch(ctx context.Context) (int, error) {
+	deliveries, err := w.claim()
+	if err != nil || len(deliveries) == 0 {
+		return 0, err
+	}
+
+	ids := make([]string, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		ids = append(ids, delivery.SubscriptionId)
+	}
+	var subscriptions []*models.Subscription
+	if err = w.Dbconn.Where("id in ?", ids).Find(&subscriptions).Error; err != nil {
+		return 0, err
+	}
+	byId := make(map[string]*models.Subscription, len(subscriptions))
+	for _, subscription := range subscriptions {
+		byId[subscription.Id] = subscription
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			// the lease runs out and another run picks the rest up
+			break
+		}
+		if subscription, ok := byId[delivery.SubscriptionId]; ok && subscription.Active {
+			w.attempt(ctx, subscription, delivery)
+		}
+	}
+	return len(deliveries), nil
+}
+
+func (w *Worker) claim() ([]*models.Delivery, error) {
+	var deliveries []*models.Delivery
+	err := w.Dbconn.Transaction(func(tx *gorm.DB) error {
+		active := tx.Model(&models.Subscription{}).Select("id").Where("active = ?", true)
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? and next_attempt_at <= ? and subscription_id in (?)", models.StatusPending, utils.FormatTime(), active).
+			Order("created_at, id").Limit(w.BatchSize).Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]string, len(deliveries))
+		for i, delivery := range deliveries {
+			ids[i] = delivery.Id
+		}
+		return tx.Model(&models.Delivery{}).Where("id in ?", ids).Update("next_attempt_at", utils.FormatTimeAfter(w.Lease)).Error
+	})
+	return deliveries, err
+}
+
+// attempt posts delivery once and logs the outcome.
+func (w *Worker) attempt(ctx context.Context, subscription *models.Subscription, delivery *models.Delivery) {
+	record := &models.Attempt{
+		Id:         uuid.New().String(),
+		DeliveryId: delivery.Id,
+		Attempt:    delivery.Attempts + 1,
+		CreatedAt:  utils.FormatTime(),
+	}
+
+	start := time.Now()
+	failure := w.post(ctx, subscription, delivery, record)
+	record.DurationMs = time.Since(start).Milliseconds()
+	if failure != nil && ctx.Err() != nil {
+		// shutting down, the delivery is retried once its lease runs out
+		return
+	}
+	if failure != nil {
+		record.Error = failure.Error()
+	}
+
+	err := w.Dbconn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		if failure == nil {
+			return w.succeed(tx, subscription, delivery, record)
+		}
+		return w.fail(tx, subscription, delivery, record)
+	})
+	if err != nil {
+		w.Log.Errorf("webhook.attempt : %v", err)
+	}
+}
+
+func (w *Worker) post(ctx context.Context, subscription *models.Subscription, delivery *models.Delivery, record *models.Attempt) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", userAgent)
+	request.Header.Set(HeaderEvent, delivery.EventType)
+	request.Header.Set(HeaderDelivery, delivery.Id)
+	request.Header.Set(HeaderSignature, Sign(subscription.Secret, time.Now(), delivery.Payload))
+
+	response, err := w.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(response.Body, maxResponseBody))
+	record.StatusCode = response.StatusCode
+	record.ResponseBody = string(body)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	return nil
+}
+
+func (w *Worker) succeed(tx *gorm.DB, subscription *models.Subscription, delivery *models.Delivery, record *models.Attempt) error {
+	err := tx.Model(&models.Delivery{}).Where("id = ?", delivery.Id).Updates(map[string]interface{}{
+		"status":           models.StatusDelivered,
+		"attempts":         record.Attempt,
+		"last_status_code": record.StatusCode,
+		"last_error":       "",
+		"delivered_at":     utils.FormatTime(),
+	}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(&models.Subscription{}).Where("id = ?", subscription.Id).Update("consecutive_failures", 0).Error
+}
+
+func (w *Worker) fail(tx *gorm.DB, subscription *models.Subscription, delivery *models.Delivery, record *models.Attempt) error {
+	data := map[string]interface{}{
+		"attempts":         record.Attempt,
+		"last_status_code": record.StatusCode,
+		"last_error":       record.Error,
+		"next_attempt_at":  utils.FormatTimeAfter(utils.Backoff(record.Attempt, w.MinBackoff, w.MaxBackoff)),
+	}
+	if record.Attempt >= w.MaxAttempts {
+		data["status"] = models.StatusFailed
+	}
+	if err := tx.Model(&models.Delivery{}).Where("id = ?", delivery.Id).Updates(data).Error; err != nil {
+		return err
+	}
+	w.Log.Warnf("webhook.fail : delivery %s to %s attempt %d : %s", delivery.Id, subscription.Url, record.Attempt, record.Error)
+
+	err := tx.Model(&models.Subscription{}).Where("id = ?", subscription.Id).Update("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error
+	if err != nil {
+		return err
+	}
+	query := tx.Model(&models.Subscription{}).Where("id = ? and active = ? and consecutive_failures >= ?", subscription.Id, true, w.DisableAfter).Updates(map[string]interface{}{
+		"active":          false,
+		"disabled_at":     utils.FormatTime(),
+		"disabled_reason": fmt.Sprintf("disabled after %d consecutive failed deliveries", w.DisableAfter),
+	})
+	if query.Error == nil && query.RowsAffected > 0 {
+		w.Log.Warnf("webhook.fail : subscription %s to %s disabled", subscription.Id, subscription.Url)
+	}
+	return query.Error
+}
@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gin-dbo/framework/database/dbtest"
+	models "gin-dbo/model/webhook"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+)
+
+// standIn is a local receiver checking the signature of what it is sent
+// and answering with status.
+func standIn(t *testing.T, secret string, status int) (*httptest.Server, chan *http.Request) {
+	t.Helper()
+	received := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify(secret, r.Header.Get(HeaderSignature), body, DefaultTolerance); err != nil {
+			t.Errorf("stand-in rejected the signature : %v", err)
+		}
+		received <- r
+		w.WriteHeader(status)
+		io.WriteString(w, "thanks")
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func TestPostSignsTheDelivery(t *testing.T) {
+	server, received := standIn(t, "s3cret", http.StatusNoContent)
+	subscription := &models.Subscription{Id: "s1", Url: server.URL, Secret: "s3cret"}
+	delivery := &models.Delivery{Id: "d1", EventType: "order.created", Payload: []byte(`{"id":"o1"}`)}
+	record := &models.Attempt{}
+
+	worker := NewWorker(nil, nil)
+	if err := worker.post(context.Background(), subscription, delivery, record); err != nil {
+		t.Fatal(err)
+	}
+	request := <-received
+	if request.Header.Get(HeaderEvent) != "order.created" || request.Header.Get(HeaderDelivery) != "d1" {
+		t.Fatalf("got headers %v", request.Header)
+	}
+	if record.StatusCode != http.StatusNoContent {
+		t.Fatalf("got status %d logged, want %d", record.StatusCode, http.StatusNoContent)
+	}
+}
+
+func TestPostFailsOnErrorStatus(t *testing.T) {
+	server, _ := standIn(t, "s3cret", http.StatusServiceUnavailable)
+	subscription := &models.Subscription{Id: "s1", Url: server.URL, Secret: "s3cret"}
+	delivery := &models.Delivery{Id: "d1", EventType: "order.created", Payload: []byte(`{"id":"o1"}`)}
+	record := &models.Attempt{}
+
+	worker := NewWorker(nil, nil)
+	if err := worker.post(context.Background(), subscription, delivery, record); err == nil {
+		t.Fatal("got no error, want the status to fail the attempt")
+	}
+	if record.StatusCode != http.StatusServiceUnavailable || record.ResponseBody != "thanks" {
+		t.Fatalf("got attempt %+v, want the response logged", record)
+	}
+}
+
+func TestVerifyRejectsAnotherSecret(t *testing.T) {
+	body := []byte(`{"id":"o1"}`)
+	header := Sign("s3cret", time.Now(), body)
+	if err := Verify("other", header, body, DefaultTolerance); err == nil {
+		t.Fatal("got no error, want the signature rejected")
+	}
+	if err := Verify("s3cret", Sign("s3cret", time.Now().Add(-time.Hour), body), body, DefaultTolerance); err == nil {
+		t.Fatal("got no error, want an old signature rejected")
+	}
+}
+
+func TestDispatchLogsTheAttempt(t *testing.T) {
+	server, received := standIn(t, "s3cret", http.StatusOK)
+	db, mock := dbtest.New(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `webhook_deliveries` WHERE .* FOR UPDATE SKIP LOCKED").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "subscription_id", "event_type", "payload", "status"}).AddRow("d1", "s1", "order.created", []byte(`{"id":"o1"}`), models.StatusPending))
+	mock.ExpectExec("UPDATE `webhook_deliveries` SET `next_attempt_at`=\\? WHERE id in \\(\\?\\)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT \\* FROM `webhook_subscriptions` WHERE id in \\(\\?\\)").WithArgs("s1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "secret", "active"}).AddRow("s1", server.URL, "s3cret", true))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `webhook_attempts`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `webhook_deliveries` SET .*`status`=\\?").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `webhook_subscriptions` SET `consecutive_failures`=\\?").WithArgs(0, "s1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	n, err := NewWorker(db, log).Dispatch(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("got %d, %v; want one delivery", n, err)
+	}
+	if request := <-received; request.Header.Get(HeaderDelivery) != "d1" {
+		t.Fatalf("got delivery %q, want d1", request.Header.Get(HeaderDelivery))
+	}
+}
@@ -0,0 +1,36 @@
+package apikey
+
+// Prefix starts every API key, telling them apart from JWTs in the
+// Authorization header.
+const Prefix = "gdk_"
+
+// Scopes an API key may be granted, "<resource>:read" for GET requests and
+// "<resource>:write" for the others.
+var Scopes = []string{
+	"customer:read", "customer:write",
+	"order:read", "order:write",
+	"user:read", "user:write",
+	"audit:read",
+	"webhook:read", "webhook:write",
+}
+
+// Key is an API key of a machine integration. Only the hash of the key is
+// stored, Hint keeps its first characters to recognize it in listings.
+type Key struct {
+	Id         string   `json:"id" gorm:"id;primaryKey;size:36"`
+	Name       string   `json:"name" gorm:"name;size:100"`
+	Hint       string   `json:"hint" gorm:"hint;size:16" example:"gdk_Xy3f"`
+	KeyHash    string   `json:"-" gorm:"key_hash;uniqueIndex;size:64"`
+	Scopes     []string `json:"scopes" gorm:"scopes;serializer:json;type:text"`
+	AllowedIps []string `json:"allowedIps,omitempty" gorm:"allowed_ips;serializer:json;type:text"`
+	ExpiresAt  string   `json:"expiresAt,omitempty" gorm:"expires_at;size:19"`
+	LastUsedAt string   `json:"lastUsedAt,omitempty" gorm:"last_used_at;size:19"`
+	LastUsedIp string   `json:"lastUsedIp,omitempty" gorm:"last_used_ip;size:45"`
+	CreatedBy  string   `json:"createdBy" gorm:"created_by;size:191"`
+	CreatedAt  string   `json:"createdAt" gorm:"createdAt;size:19"`
+	RevokedAt  string   `json:"revokedAt,omitempty" gorm:"revoked_at;size:19"`
+}
+
+func (Key) TableName() string {
+	return "api_keys"
+}
@@ -0,0 +1,23 @@
+package audit
+
+import "encoding/json"
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	// ActionMerge folds a customer into another, its id redirecting there.
+	ActionMerge = "merge"
+)
+
+type Audit struct {
+	Id         string          `json:"id" gorm:"id;primaryKey;uniqueIndex"`
+	Actor      string          `json:"actor" gorm:"actor;index;size:191"`
+	Action     string          `json:"action" gorm:"action;size:32"`
+	Resource   string          `json:"resource" gorm:"resource;index:idx_audit_resource;size:64"`
+	ResourceId string          `json:"resourceId" gorm:"resource_id;index:idx_audit_resource;size:191"`
+	Changes    json.RawMessage `json:"changes" gorm:"changes;type:text" swaggertype:"object"`
+	ClientIp   string          `json:"clientIp" gorm:"client_ip"`
+	RequestId  string          `json:"requestId" gorm:"request_id"`
+	CreatedAt  string          `json:"createdAt" gorm:"createdAt;index;size:19"`
+}
@@ -1,8 +1,15 @@
 package customer
 
 type Customer struct {
-	Id        string `json:"id" gorm:"id;primaryKey;uniqueIndex"`
-	Name      string `json:"name,omitempty" gorm:"name"`
-	CreatedAt string `json:"createdAt" gorm:"createdAt"`
-	UpdatedAt string `json:"updatedAt" gorm:"updatedAt"`
+	Id   string `json:"id" gorm:"id;primaryKey;uniqueIndex"`
+	Name string `json:"name,omitempty" gorm:"name"`
+	// Email and Phone are how to reach the customer, also used to tell
+	// duplicates apart.
+	Email string `json:"email,omitempty" gorm:"email;size:191"`
+	Phone string `json:"phone,omitempty" gorm:"phone;size:32"`
+	// Tags are free-form labels marketing groups customers by.
+	Tags      []string `json:"tags,omitempty" gorm:"tags;serializer:json;type:text" example:"vip,newsletter"`
+	Version   int64    `json:"version" gorm:"version;default:1"`
+	CreatedAt string   `json:"createdAt" gorm:"createdAt"`
+	UpdatedAt string   `json:"updatedAt" gorm:"updatedAt"`
 }
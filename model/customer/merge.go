@@ -0,0 +1,32 @@
+package customer
+
+// Merge records source customers folded into a target one, with what moved
+// along.
+type Merge struct {
+	Id        string   `json:"id" gorm:"id;primaryKey;size:36"`
+	TargetId  string   `json:"targetId" gorm:"target_id;index;size:191"`
+	SourceIds []string `json:"sourceIds" gorm:"source_ids;serializer:json;type:text"`
+	// Sources are the source customers as they were before the merge.
+	Sources   []*Customer `json:"sources" gorm:"sources;serializer:json;type:text"`
+	Orders    int64       `json:"orders" gorm:"orders"`
+	Users     int64       `json:"users" gorm:"users"`
+	MergedBy  string      `json:"mergedBy" gorm:"merged_by;size:191"`
+	CreatedAt string      `json:"createdAt" gorm:"createdAt;size:19"`
+}
+
+func (Merge) TableName() string {
+	return "customer_merges"
+}
+
+// Redirect keeps the id of a merged customer resolving to the customer it
+// was merged into.
+type Redirect struct {
+	FromId    string `json:"fromId" gorm:"from_id;primaryKey;size:191"`
+	ToId      string `json:"toId" gorm:"to_id;index;size:191"`
+	MergeId   string `json:"mergeId" gorm:"merge_id;size:36"`
+	CreatedAt string `json:"createdAt" gorm:"createdAt;size:19"`
+}
+
+func (Redirect) TableName() string {
+	return "customer_redirects"
+}
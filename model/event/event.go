@@ -0,0 +1,41 @@
+package event
+
+import "encoding/json"
+
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+
+	CustomerCreated = "customer.created"
+	CustomerUpdated = "customer.updated"
+	CustomerDeleted = "customer.deleted"
+	CustomerMerged  = "customer.merged"
+	OrderCreated    = "order.created"
+	OrderUpdated    = "order.updated"
+	OrderDeleted    = "order.deleted"
+	UserCreated     = "user.created"
+	UserUpdated     = "user.updated"
+	UserDeleted     = "user.deleted"
+)
+
+// Event is a domain event kept in the outbox until every subscriber and
+// transport has received it.
+type Event struct {
+	Id            string          `json:"id" gorm:"id;primaryKey;size:36"`
+	Type          string          `json:"type" gorm:"index;size:64"`
+	Resource      string          `json:"resource" gorm:"resource;size:64"`
+	ResourceId    string          `json:"resourceId" gorm:"resource_id;size:191"`
+	Actor         string          `json:"actor" gorm:"actor;size:191"`
+	Payload       json.RawMessage `json:"payload" gorm:"payload;type:text" swaggertype:"object"`
+	Status        string          `json:"-" gorm:"status;index:idx_outbox_pending;size:16"`
+	Attempts      int             `json:"-" gorm:"attempts"`
+	NextAttemptAt string          `json:"-" gorm:"next_attempt_at;index:idx_outbox_pending;size:19"`
+	LastError     string          `json:"-" gorm:"last_error;type:text"`
+	CreatedAt     string          `json:"createdAt" gorm:"createdAt;size:19"`
+	DeliveredAt   string          `json:"-" gorm:"delivered_at;size:19"`
+}
+
+func (Event) TableName() string {
+	return "outbox_events"
+}
@@ -0,0 +1,22 @@
+package idempotency
+
+const (
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+)
+
+type Record struct {
+	Key         string `json:"key" gorm:"key;primaryKey;size:191"`
+	Username    string `json:"username" gorm:"username;primaryKey;size:191"`
+	Fingerprint string `json:"fingerprint" gorm:"fingerprint"`
+	Status      string `json:"status" gorm:"status"`
+	StatusCode  int    `json:"statusCode" gorm:"status_code"`
+	ContentType string `json:"contentType" gorm:"content_type"`
+	Body        []byte `json:"body" gorm:"body"`
+	CreatedAt   string `json:"createdAt" gorm:"createdAt"`
+	ExpiresAt   string `json:"expiresAt" gorm:"expiresAt;index;size:19"`
+}
+
+func (Record) TableName() string {
+	return "idempotency_records"
+}
@@ -0,0 +1,17 @@
+package invoice
+
+type Invoice struct {
+	Id        string `json:"id" gorm:"id;primaryKey;uniqueIndex"`
+	Number    int64  `json:"number" gorm:"number;uniqueIndex"`
+	OrderId   string `json:"orderId" gorm:"order_id;uniqueIndex;size:191"`
+	CreatedAt string `json:"createdAt" gorm:"createdAt"`
+}
+
+type Sequence struct {
+	Name  string `json:"name" gorm:"name;primaryKey"`
+	Value int64  `json:"value" gorm:"value"`
+}
+
+func (Sequence) TableName() string {
+	return "invoice_sequences"
+}
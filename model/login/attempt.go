@@ -0,0 +1,14 @@
+package login
+
+// Attempt counts the failed logins of a username in a row, whether or not
+// the user exists, and until when it is locked out.
+type Attempt struct {
+	Username      string `json:"username" gorm:"username;primaryKey;size:191"`
+	Failures      int    `json:"failures" gorm:"failures"`
+	LastFailureAt string `json:"lastFailureAt" gorm:"lastFailureAt;size:19"`
+	LockedUntil   string `json:"lockedUntil" gorm:"lockedUntil;size:19"`
+}
+
+func (Attempt) TableName() string {
+	return "login_attempts"
+}
@@ -0,0 +1,20 @@
+package login
+
+// Invitation lets whoever holds its token register once with Role, e.g. to
+// onboard staff. Only the hash of the token is stored; Username, when set,
+// is the only username it can be used for.
+type Invitation struct {
+	Id        string `json:"id" gorm:"id;primaryKey;size:36"`
+	TokenHash string `json:"-" gorm:"token_hash;uniqueIndex;size:64"`
+	Role      string `json:"role" gorm:"role"`
+	Username  string `json:"username,omitempty" gorm:"username;size:191"`
+	CreatedBy string `json:"createdBy" gorm:"created_by"`
+	ExpiresAt string `json:"expiresAt" gorm:"expiresAt;size:19"`
+	UsedAt    string `json:"usedAt,omitempty" gorm:"usedAt;size:19"`
+	UsedBy    string `json:"usedBy,omitempty" gorm:"usedBy"`
+	CreatedAt string `json:"createdAt" gorm:"createdAt"`
+}
+
+func (Invitation) TableName() string {
+	return "invitations"
+}
@@ -1,10 +1,27 @@
 package login
 
+const (
+	RoleAdmin    = "admin"
+	RoleCustomer = "customer"
+)
+
 type User struct {
 	Username   string `json:"username" gorm:"username;primaryKey;uniqueIndex"`
 	Password   string `json:"password,omitempty" gorm:"password" swaggerignore:"true"`
 	Role       string `json:"role" gorm:"role"`
 	CustomerId string `json:"customerId,omitempty" gorm:"customer_id"`
-	CreatedAt  string `json:"createdAt" gorm:"createdAt"`
-	UpdatedAt  string `json:"updatedAt" gorm:"updatedAt"`
+	Email      string `json:"email,omitempty" gorm:"email;index;size:191"`
+	// EmailVerifiedAt is set once the owner of Email followed the link sent
+	// to it.
+	EmailVerifiedAt string `json:"emailVerifiedAt,omitempty" gorm:"emailVerifiedAt;size:19"`
+	// TotpSecret is pending until TwoFactorEnabledAt is set by a first
+	// valid code. TotpLastStep is the time step of the last code accepted.
+	TotpSecret         string `json:"-" gorm:"totp_secret" swaggerignore:"true"`
+	TotpLastStep       int64  `json:"-" gorm:"totp_last_step" swaggerignore:"true"`
+	TwoFactorEnabledAt string `json:"twoFactorEnabledAt,omitempty" gorm:"twoFactorEnabledAt;size:19"`
+	// OidcSubject links the user to its identity at the OIDC provider.
+	OidcSubject string `json:"oidcSubject,omitempty" gorm:"oidc_subject;index;size:191"`
+	Version     int64  `json:"version" gorm:"version;default:1"`
+	CreatedAt   string `json:"createdAt" gorm:"createdAt"`
+	UpdatedAt   string `json:"updatedAt" gorm:"updatedAt"`
 }
@@ -0,0 +1,15 @@
+package login
+
+// OIDCState is a sign in started at the OIDC provider, found again by the
+// hash of the state sent along. Verifier is the PKCE code verifier.
+type OIDCState struct {
+	StateHash string `json:"-" gorm:"state_hash;primaryKey;size:64"`
+	Verifier  string `json:"-" gorm:"verifier;size:64"`
+	Nonce     string `json:"-" gorm:"nonce;size:64"`
+	ExpiresAt string `json:"expiresAt" gorm:"expires_at;index;size:19"`
+	CreatedAt string `json:"createdAt" gorm:"createdAt"`
+}
+
+func (OIDCState) TableName() string {
+	return "oidc_states"
+}
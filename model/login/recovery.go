@@ -0,0 +1,14 @@
+package login
+
+// RecoveryCode is a single use code to pass the two-factor step without
+// the authenticator. Only its hash is stored.
+type RecoveryCode struct {
+	CodeHash  string `json:"-" gorm:"code_hash;primaryKey;size:64"`
+	Username  string `json:"username" gorm:"username;index;size:191"`
+	UsedAt    string `json:"usedAt,omitempty" gorm:"usedAt;size:19"`
+	CreatedAt string `json:"createdAt" gorm:"createdAt"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
@@ -0,0 +1,25 @@
+package login
+
+const (
+	TokenVerifyEmail   = "verify_email"
+	TokenResetPassword = "reset_password"
+	// TokenLoginChallenge is the first login step passed, waiting for the
+	// two-factor code.
+	TokenLoginChallenge = "login_challenge"
+)
+
+// Token is a single use secret mailed to a user to prove they own Email.
+// Only its hash is stored.
+type Token struct {
+	TokenHash string `json:"-" gorm:"token_hash;primaryKey;size:64"`
+	Purpose   string `json:"purpose" gorm:"purpose;index:idx_user_token_owner;size:32"`
+	Username  string `json:"username" gorm:"username;index:idx_user_token_owner;size:191"`
+	Email     string `json:"email" gorm:"email"`
+	ExpiresAt string `json:"expiresAt" gorm:"expiresAt;size:19"`
+	UsedAt    string `json:"usedAt,omitempty" gorm:"usedAt;size:19"`
+	CreatedAt string `json:"createdAt" gorm:"createdAt"`
+}
+
+func (Token) TableName() string {
+	return "user_tokens"
+}
@@ -5,6 +5,7 @@ type Order struct {
 	CustomerId string `json:"customer_id" gorm:"customer_id"`
 	Name       string `json:"name,omitempty" gorm:"name"`
 	Qty        int64  `json:"qty,omitempty" gorm:"qty"`
+	Version    int64  `json:"version" gorm:"version;default:1"`
 	CreatedAt  string `json:"createdAt" gorm:"createdAt"`
 	UpdatedAt  string `json:"updatedAt" gorm:"updatedAt"`
 }
@@ -0,0 +1,51 @@
+package segment
+
+const (
+	// MatchAll keeps the customers passing every rule, MatchAny those
+	// passing at least one.
+	MatchAll = "all"
+	MatchAny = "any"
+
+	// FieldOrders counts the orders of a customer and FieldItems sums their
+	// quantities, both over the last Days days or ever when Days is 0.
+	// FieldTag keeps the customers tagged Tag.
+	FieldOrders = "orders"
+	FieldItems  = "items"
+	FieldTag    = "tag"
+)
+
+// Operators maps the operators a rule may compare with to SQL.
+var Operators = map[string]string{
+	"gt":  ">",
+	"gte": ">=",
+	"lt":  "<",
+	"lte": "<=",
+	"eq":  "=",
+}
+
+// Segment is a group of customers defined by rules rather than by a list,
+// its members being worked out whenever it is read.
+type Segment struct {
+	Id          string  `json:"id" gorm:"id;primaryKey;size:36"`
+	Name        string  `json:"name" gorm:"name;uniqueIndex;size:100"`
+	Description string  `json:"description,omitempty" gorm:"description;size:500"`
+	Match       string  `json:"match" gorm:"match;size:8" example:"all"`
+	Rules       []*Rule `json:"rules" gorm:"rules;serializer:json;type:text"`
+	CreatedBy   string  `json:"createdBy" gorm:"created_by;size:191"`
+	CreatedAt   string  `json:"createdAt" gorm:"createdAt"`
+	UpdatedAt   string  `json:"updatedAt" gorm:"updatedAt"`
+}
+
+func (Segment) TableName() string {
+	return "segments"
+}
+
+// Rule is a condition on a customer, such as "ordered more than 10 items
+// in the last 30 days" or "is tagged vip".
+type Rule struct {
+	Field    string `json:"field" example:"items"`
+	Operator string `json:"operator,omitempty" example:"gt"`
+	Value    int64  `json:"value,omitempty" example:"10"`
+	Days     int    `json:"days,omitempty" example:"30"`
+	Tag      string `json:"tag,omitempty"`
+}
@@ -0,0 +1,70 @@
+package webhook
+
+import "encoding/json"
+
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+
+	// EventPing is sent by the ping endpoint to check a subscription.
+	EventPing = "webhook.ping"
+)
+
+// Subscription is an endpoint receiving the events whose type matches one of
+// EventTypes; an entry may be an exact type, a prefix such as "order.*" or
+// "*" for every event.
+type Subscription struct {
+	Id                  string   `json:"id" gorm:"id;primaryKey;size:36"`
+	Url                 string   `json:"url" gorm:"url;size:2048"`
+	Secret              string   `json:"-" gorm:"secret"`
+	EventTypes          []string `json:"eventTypes" gorm:"event_types;serializer:json;type:text"`
+	Active              bool     `json:"active" gorm:"active;index"`
+	ConsecutiveFailures int      `json:"consecutiveFailures" gorm:"consecutive_failures"`
+	DisabledAt          string   `json:"disabledAt,omitempty" gorm:"disabled_at;size:19"`
+	DisabledReason      string   `json:"disabledReason,omitempty" gorm:"disabled_reason"`
+	CreatedAt           string   `json:"createdAt" gorm:"createdAt"`
+	UpdatedAt           string   `json:"updatedAt" gorm:"updatedAt"`
+}
+
+func (Subscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// Delivery is one event to be posted to one subscription. Payload is the
+// exact body sent, so a redelivery is byte for byte the same.
+type Delivery struct {
+	Id             string          `json:"id" gorm:"id;primaryKey;size:36"`
+	SubscriptionId string          `json:"subscriptionId" gorm:"subscription_id;uniqueIndex:idx_webhook_delivery_event;size:36"`
+	EventId        string          `json:"eventId" gorm:"event_id;uniqueIndex:idx_webhook_delivery_event;size:36"`
+	EventType      string          `json:"eventType" gorm:"event_type;size:64"`
+	Payload        json.RawMessage `json:"payload" gorm:"payload;type:text" swaggertype:"object"`
+	Status         string          `json:"status" gorm:"status;index:idx_webhook_delivery_pending;size:16"`
+	Attempts       int             `json:"attempts" gorm:"attempts"`
+	NextAttemptAt  string          `json:"nextAttemptAt" gorm:"next_attempt_at;index:idx_webhook_delivery_pending;size:19"`
+	LastStatusCode int             `json:"lastStatusCode" gorm:"last_status_code"`
+	LastError      string          `json:"lastError,omitempty" gorm:"last_error;type:text"`
+	CreatedAt      string          `json:"createdAt" gorm:"createdAt;size:19"`
+	DeliveredAt    string          `json:"deliveredAt,omitempty" gorm:"delivered_at;size:19"`
+	AttemptLog     []*Attempt      `json:"attemptLog,omitempty" gorm:"-"`
+}
+
+func (Delivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// Attempt logs a single request made for a delivery.
+type Attempt struct {
+	Id           string `json:"id" gorm:"id;primaryKey;size:36"`
+	DeliveryId   string `json:"deliveryId" gorm:"delivery_id;index;size:36"`
+	Attempt      int    `json:"attempt" gorm:"attempt"`
+	StatusCode   int    `json:"statusCode" gorm:"status_code"`
+	ResponseBody string `json:"responseBody,omitempty" gorm:"response_body;type:text"`
+	Error        string `json:"error,omitempty" gorm:"error;type:text"`
+	DurationMs   int64  `json:"durationMs" gorm:"duration_ms"`
+	CreatedAt    string `json:"createdAt" gorm:"createdAt;size:19"`
+}
+
+func (Attempt) TableName() string {
+	return "webhook_attempts"
+}
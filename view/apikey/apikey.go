@@ -0,0 +1,55 @@
+package apikey
+
+import "gin-dbo/model/apikey"
+
+type GetRequest struct {
+	Page  int `json:"page,omitempty"`
+	Limit int `json:"limit,omitempty"`
+}
+
+// CreateRequest issues a key granted Scopes, usable from AllowedIps
+// (addresses or CIDR ranges, any address when empty) until ExpiresAt, or
+// forever when empty.
+type CreateRequest struct {
+	Name       string   `json:"name" example:"erp"`
+	Scopes     []string `json:"scopes" example:"order:read,order:write,customer:read"`
+	AllowedIps []string `json:"allowedIps,omitempty" example:"203.0.113.0/24"`
+	ExpiresAt  string   `json:"expiresAt,omitempty" example:"2030-01-01 00:00:00"`
+}
+
+type RevokeRequest struct {
+	Id string `json:"id"`
+}
+
+type GeneralResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Id      string `json:"id,omitempty"`
+	// Key is only returned on create, it cannot be read back later.
+	Key string `json:"key,omitempty"`
+}
+
+type Response400 struct {
+	Success bool   `json:"success" example:"false"`
+	Message string `json:"message" example:"invalid request"`
+}
+
+type Response500 struct {
+	Success bool   `json:"success" example:"false"`
+	Message string `json:"message" example:"something went wrong"`
+}
+
+type ResponseDetail struct {
+	Success bool        `json:"success"`
+	Message string      `json:"message"`
+	Data    *apikey.Key `json:"data"`
+}
+
+type ResponseData struct {
+	Success   bool          `json:"success"`
+	Message   string        `json:"message"`
+	Data      []*apikey.Key `json:"data"`
+	Limit     int           `json:"limit"`
+	Page      int           `json:"page"`
+	TotalPage int           `json:"totalPage"`
+}
@@ -0,0 +1,37 @@
+package audit
+
+import "gin-dbo/model/audit"
+
+type GetRequest struct {
+	Actor      string `json:"actor"`
+	Resource   string `json:"resource"`
+	ResourceId string `json:"resourceId"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+}
+
+type GeneralResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+type Response400 struct {
+	Success bool   `json:"success" example:"false"`
+	Message string `json:"message" example:"invalid request"`
+}
+
+type Response500 struct {
+	Success bool   `json:"success" example:"false"`
+	Message string `json:"message" example:"something went wrong"`
+}
+
+type ResponseData struct {
+	Success   bool           `json:"success"`
+	Message   string         `json:"message"`
+	Data      []*audit.Audit `json:"data"`
+	Limit     int            `json:"limit"`
+	Page      int            `json:"page"`
+	TotalPage int            `json:"totalPage"`
+}
@@ -1,23 +1,64 @@
 package customer
 
-import "gin-dbo/model/customer"
+import (
+	"gin-dbo/model/customer"
+	"gin-dbo/model/segment"
+)
 
+// GetRequest filters customers by name, tag and segment, Segment being the
+// segment SegmentId names once it is read.
 type GetRequest struct {
-	Keyword string `json:"keyword"`
-	Page    int    `json:"page,omitempty"`
-	Limit   int    `json:"limit,omitempty"`
+	Keyword   string           `json:"keyword"`
+	Tag       string           `json:"tag"`
+	SegmentId string           `json:"segment"`
+	Segment   *segment.Segment `json:"-"`
+	Page      int              `json:"page,omitempty"`
+	Limit     int              `json:"limit,omitempty"`
 }
 type CreateRequest struct {
-	Name string `json:"name"`
+	Name  string   `json:"name"`
+	Email string   `json:"email,omitempty" example:"jane.doe@example.com"`
+	Phone string   `json:"phone,omitempty" example:"+62 812 3456 7890"`
+	Tags  []string `json:"tags,omitempty" example:"vip,newsletter"`
 }
 
+// UpdateRequest replaces the customer, keeping its tags when Tags is left
+// out.
 type UpdateRequest struct {
-	Id   string `json:"id" swaggerignore:"true"`
-	Name string `json:"name"`
+	Id      string    `json:"id" swaggerignore:"true"`
+	Name    string    `json:"name"`
+	Email   string    `json:"email,omitempty" example:"jane.doe@example.com"`
+	Phone   string    `json:"phone,omitempty" example:"+62 812 3456 7890"`
+	Tags    *[]string `json:"tags,omitempty" example:"vip,newsletter"`
+	Version int64     `json:"-"`
+}
+
+// PatchRequest carries a merge patch document that is applied on top of the
+// stored customer.
+type PatchRequest struct {
+	Id      string
+	Patch   []byte
+	Version int64
 }
 
 type DeleteRequest struct {
-	Id string `json:"id"`
+	Id      string `json:"id"`
+	Version int64  `json:"-"`
+}
+
+// MergeRequest folds the source customers into the target one, Version
+// being the version of the target the client read.
+type MergeRequest struct {
+	TargetId  string   `json:"-"`
+	SourceIds []string `json:"sourceIds"`
+	Version   int64    `json:"-"`
+}
+
+// DuplicateGroup is a set of customers likely to be the same, with what
+// they have in common.
+type DuplicateGroup struct {
+	Reasons   []string             `json:"reasons" example:"name,email"`
+	Customers []*customer.Customer `json:"customers"`
 }
 
 type GeneralResponse struct {
@@ -50,3 +91,24 @@ type ResponseData struct {
 	Page      int                  `json:"page"`
 	TotalPage int                  `json:"totalPage"`
 }
+
+type ResponseDuplicates struct {
+	Success   bool              `json:"success"`
+	Message   string            `json:"message"`
+	Data      []*DuplicateGroup `json:"data"`
+	Limit     int               `json:"limit"`
+	Page      int               `json:"page"`
+	TotalPage int               `json:"totalPage"`
+}
+
+type ResponseMerge struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    *customer.Merge `json:"data"`
+}
+
+type ResponseMerges struct {
+	Success bool              `json:"success"`
+	Message string            `json:"message"`
+	Data    []*customer.Merge `json:"data"`
+}
@@ -0,0 +1,18 @@
+package customer
+
+import (
+	"strings"
+
+	"gin-dbo/framework/export"
+	"gin-dbo/model/customer"
+)
+
+var ExportColumns = []export.Column[*customer.Customer]{
+	{Name: "id", Value: func(v *customer.Customer) string { return v.Id }},
+	{Name: "name", Value: func(v *customer.Customer) string { return v.Name }},
+	{Name: "email", Value: func(v *customer.Customer) string { return v.Email }},
+	{Name: "phone", Value: func(v *customer.Customer) string { return v.Phone }},
+	{Name: "tags", Value: func(v *customer.Customer) string { return strings.Join(v.Tags, ",") }},
+	{Name: "createdAt", Value: func(v *customer.Customer) string { return v.CreatedAt }},
+	{Name: "updatedAt", Value: func(v *customer.Customer) string { return v.UpdatedAt }},
+}
@@ -0,0 +1,43 @@
+package customer
+
+import (
+	"strings"
+
+	"gin-dbo/framework/importer"
+)
+
+type ImportRow struct {
+	Row     int
+	Request *CreateRequest
+	Error   error
+}
+
+type ImportRequest struct {
+	DryRun bool
+	Rows   []*ImportRow
+}
+
+type ImportResponse struct {
+	Success bool               `json:"success"`
+	Message string             `json:"message"`
+	DryRun  bool               `json:"dryRun"`
+	Created int                `json:"created"`
+	Skipped int                `json:"skipped"`
+	Failed  int                `json:"failed"`
+	Data    []*importer.Result `json:"data"`
+}
+
+// ParseImportRecord maps a CSV record to a create request, returning nil for
+// blank rows.
+func ParseImportRecord(record map[string]string) (*CreateRequest, error) {
+	if importer.IsBlank(record) {
+		return nil, nil
+	}
+	request := &CreateRequest{Name: record["name"], Email: record["email"], Phone: record["phone"]}
+	for _, tag := range strings.Split(record["tags"], ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			request.Tags = append(request.Tags, tag)
+		}
+	}
+	return request, nil
+}
@@ -0,0 +1,18 @@
+package health
+
+import "gin-dbo/framework/health"
+
+type ResponseHealth struct {
+	Status string `json:"status" example:"up"`
+}
+
+type ResponseReady struct {
+	Status string           `json:"status" example:"up"`
+	Checks []*health.Result `json:"checks"`
+}
+
+type ResponseVersion struct {
+	Success bool         `json:"success"`
+	Message string       `json:"message"`
+	Data    health.Build `json:"data"`
+}
@@ -0,0 +1,53 @@
+package invoice
+
+import (
+	"bytes"
+	"embed"
+	htmlTemplate "html/template"
+	textTemplate "text/template"
+
+	"gin-dbo/model/customer"
+	"gin-dbo/model/invoice"
+	"gin-dbo/model/order"
+)
+
+//go:embed template
+var templates embed.FS
+
+var (
+	htmlInvoice = htmlTemplate.Must(htmlTemplate.ParseFS(templates, "template/invoice.html"))
+	textInvoice = textTemplate.Must(textTemplate.ParseFS(templates, "template/invoice.txt"))
+)
+
+type Invoice struct {
+	Number   string             `json:"number"`
+	Invoice  *invoice.Invoice   `json:"invoice"`
+	Customer *customer.Customer `json:"customer"`
+	Order    *order.Order       `json:"order"`
+}
+
+type GeneralResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+type Response500 struct {
+	Success bool   `json:"success" example:"false"`
+	Message string `json:"message" example:"something went wrong"`
+}
+
+func RenderHTML(data *Invoice) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlInvoice.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func RenderText(data *Invoice) (string, error) {
+	var buf bytes.Buffer
+	if err := textInvoice.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
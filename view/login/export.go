@@ -0,0 +1,16 @@
+package login
+
+import (
+	"gin-dbo/framework/export"
+	"gin-dbo/model/login"
+)
+
+var ExportColumns = []export.Column[*login.User]{
+	{Name: "username", Value: func(v *login.User) string { return v.Username }},
+	{Name: "role", Value: func(v *login.User) string { return v.Role }},
+	{Name: "customerId", Value: func(v *login.User) string { return v.CustomerId }},
+	{Name: "email", Value: func(v *login.User) string { return v.Email }},
+	{Name: "emailVerifiedAt", Value: func(v *login.User) string { return v.EmailVerifiedAt }},
+	{Name: "createdAt", Value: func(v *login.User) string { return v.CreatedAt }},
+	{Name: "updatedAt", Value: func(v *login.User) string { return v.UpdatedAt }},
+}
@@ -11,18 +11,64 @@ type CreateRequest struct {
 	Username   string `json:"username"`
 	Password   string `json:"password"`
 	Role       string `json:"role"`
+	Email      string `json:"email,omitempty"`
 	CustomerId string `json:"customerId" swaggerignore:"true"`
 }
 
+// RegisterRequest is the public sign up. The user gets the role of the
+// invitation when a token is given and the customer role otherwise.
+type RegisterRequest struct {
+	Username        string `json:"username" example:"jane.doe"`
+	Password        string `json:"password" example:"Correct-Horse-1"`
+	Email           string `json:"email" example:"jane.doe@example.com"`
+	InvitationToken string `json:"invitationToken,omitempty"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" example:"jane.doe@example.com"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password" example:"Correct-Horse-2"`
+}
+
 type UpdateRequest struct {
 	Username   string `json:"username" swaggerignore:"true"`
 	Password   string `json:"password"`
 	CustomerId string `json:"customerId,omitempty"`
 	Role       string `json:"role"`
+	Version    int64  `json:"-"`
+}
+
+// PatchRequest carries a merge patch document that is applied on top of the
+// stored user.
+type PatchRequest struct {
+	Username string
+	Patch    []byte
+	Version  int64
+}
+
+// UpdateMeRequest is what users may change of their own account, merged
+// from a patch on PATCH api/me.
+type UpdateMeRequest struct {
+	Email string `json:"email" example:"jane.doe@example.com"`
+}
+
+// ChangePasswordRequest replaces the password of the caller, who proves
+// knowing the current one.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	Password        string `json:"password" example:"Correct-Horse-2"`
 }
 
 type DeleteRequest struct {
 	Username string `json:"username"`
+	Version  int64  `json:"-"`
 }
 
 type LoginRequest struct {
@@ -44,14 +90,65 @@ type Response500 struct {
 	Success bool   `json:"success" example:"false"`
 	Message string `json:"message" example:"something went wrong"`
 }
+
+// ResponseLogin carries the token, or the challenge token to send with the
+// two-factor code when TwoFactorRequired is set. TwoFactorSetupRequired
+// means the token is only good to set up two-factor authentication.
 type ResponseLogin struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
 	Data    struct {
-		Token string `json:"token,omitempty"`
+		Token                  string `json:"token,omitempty"`
+		TwoFactorRequired      bool   `json:"twoFactorRequired,omitempty"`
+		ChallengeToken         string `json:"challengeToken,omitempty"`
+		TwoFactorSetupRequired bool   `json:"twoFactorSetupRequired,omitempty"`
 	} `json:"data,omitempty"`
 }
 
+// LoginTwoFactorRequest is the second login step, Code being a TOTP code or
+// a recovery code.
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code" example:"123456"`
+}
+
+// OIDCCallbackRequest is where the OIDC provider sends the user back, with
+// a code or an error.
+type OIDCCallbackRequest struct {
+	Code             string `json:"code" form:"code"`
+	State            string `json:"state" form:"state"`
+	Error            string `json:"error" form:"error"`
+	ErrorDescription string `json:"errorDescription" form:"error_description"`
+}
+
+// TwoFactorCodeRequest proves the caller holds the authenticator, with a
+// TOTP code or, except to confirm a setup, a recovery code.
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" example:"123456"`
+}
+
+type ResponseTwoFactorSetup struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Data    struct {
+		Secret string `json:"secret"`
+		Uri    string `json:"uri" example:"otpauth://totp/gin-dbo:jane.doe?issuer=gin-dbo&secret=..."`
+		// QrCode is the uri as a PNG data URI.
+		QrCode string `json:"qrCode" example:"data:image/png;base64,..."`
+	} `json:"data"`
+}
+
+// ResponseRecoveryCodes is the only time the recovery codes are shown. Token
+// replaces an enrollment token once two-factor authentication is on.
+type ResponseRecoveryCodes struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Data    struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+		Token         string   `json:"token,omitempty"`
+	} `json:"data"`
+}
+
 type ResponseDetail struct {
 	Success bool        `json:"success"`
 	Message string      `json:"message"`
@@ -66,3 +163,28 @@ type ResponseData struct {
 	Page      int           `json:"page"`
 	TotalPage int           `json:"totalPage"`
 }
+
+type CreateInvitationRequest struct {
+	Role string `json:"role" example:"admin"`
+	// Username, when set, is the only username the invitation registers.
+	Username string `json:"username,omitempty"`
+}
+
+type DeleteInvitationRequest struct {
+	Id string `json:"id"`
+}
+
+type ResponseInvitation struct {
+	Success bool              `json:"success"`
+	Message string            `json:"message"`
+	Data    *login.Invitation `json:"data,omitempty"`
+	// Token is only returned when the invitation is created, it cannot be
+	// read back later.
+	Token string `json:"token,omitempty"`
+}
+
+type ResponseInvitations struct {
+	Success bool                `json:"success"`
+	Message string              `json:"message"`
+	Data    []*login.Invitation `json:"data"`
+}
@@ -0,0 +1,52 @@
+package order
+
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+type BatchOperation struct {
+	Op         string `json:"op" enums:"create,update,delete"`
+	Id         string `json:"id,omitempty"`
+	CustomerId string `json:"customerId,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Qty        *int64 `json:"qty,omitempty"`
+	Version    int64  `json:"version,omitempty"`
+}
+
+type BatchRequest struct {
+	Atomic     bool              `json:"atomic"`
+	Operations []*BatchOperation `json:"operations"`
+}
+
+type BatchResult struct {
+	Index   int    `json:"index"`
+	Op      string `json:"op"`
+	Id      string `json:"id,omitempty"`
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+type BatchResponse struct {
+	Success bool           `json:"success"`
+	Message string         `json:"message"`
+	Atomic  bool           `json:"atomic"`
+	Data    []*BatchResult `json:"data"`
+}
+
+func (o *BatchOperation) CreateRequest() *CreateRequest {
+	res := &CreateRequest{CustomerId: o.CustomerId, Name: o.Name}
+	if o.Qty != nil {
+		res.Qty = *o.Qty
+	}
+	return res
+}
+
+func (o *BatchOperation) UpdateRequest() *UpdateRequest {
+	return &UpdateRequest{Id: o.Id, CustomerId: o.CustomerId, Name: o.Name, Qty: o.Qty, Version: o.Version}
+}
+
+func (o *BatchOperation) DeleteRequest() *DeleteRequest {
+	return &DeleteRequest{Id: o.Id, Version: o.Version}
+}
@@ -0,0 +1,17 @@
+package order
+
+import (
+	"strconv"
+
+	"gin-dbo/framework/export"
+	"gin-dbo/model/order"
+)
+
+var ExportColumns = []export.Column[*order.Order]{
+	{Name: "id", Value: func(v *order.Order) string { return v.Id }},
+	{Name: "customer_id", Value: func(v *order.Order) string { return v.CustomerId }},
+	{Name: "name", Value: func(v *order.Order) string { return v.Name }},
+	{Name: "qty", Value: func(v *order.Order) string { return strconv.FormatInt(v.Qty, 10) }},
+	{Name: "createdAt", Value: func(v *order.Order) string { return v.CreatedAt }},
+	{Name: "updatedAt", Value: func(v *order.Order) string { return v.UpdatedAt }},
+}
@@ -0,0 +1,52 @@
+package order
+
+import (
+	"fmt"
+	"strconv"
+
+	"gin-dbo/framework/importer"
+)
+
+type ImportRow struct {
+	Row     int
+	Request *CreateRequest
+	Error   error
+}
+
+type ImportRequest struct {
+	DryRun bool
+	Rows   []*ImportRow
+}
+
+type ImportResponse struct {
+	Success bool               `json:"success"`
+	Message string             `json:"message"`
+	DryRun  bool               `json:"dryRun"`
+	Created int                `json:"created"`
+	Skipped int                `json:"skipped"`
+	Failed  int                `json:"failed"`
+	Data    []*importer.Result `json:"data"`
+}
+
+// ParseImportRecord maps a CSV record to a create request, returning nil for
+// blank rows. The customer is read from the customer_id column of exports,
+// or from customerId as in the JSON requests.
+func ParseImportRecord(record map[string]string) (*CreateRequest, error) {
+	if importer.IsBlank(record) {
+		return nil, nil
+	}
+
+	customerId, ok := record["customer_id"]
+	if !ok {
+		customerId = record["customerId"]
+	}
+	res := &CreateRequest{CustomerId: customerId, Name: record["name"]}
+	if record["qty"] != "" {
+		qty, err := strconv.ParseInt(record["qty"], 10, 64)
+		if err != nil {
+			return res, fmt.Errorf("qty %s is not a number", record["qty"])
+		}
+		res.Qty = qty
+	}
+	return res, nil
+}
@@ -0,0 +1,58 @@
+package order
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"gin-dbo/framework/export"
+	"gin-dbo/framework/importer"
+	"gin-dbo/model/order"
+)
+
+func TestParseImportRecord(t *testing.T) {
+	tests := []struct {
+		name    string
+		record  map[string]string
+		want    *CreateRequest
+		wantErr bool
+	}{
+		{"exported column", map[string]string{"customer_id": "c1", "name": "book", "qty": "2"}, &CreateRequest{CustomerId: "c1", Name: "book", Qty: 2}, false},
+		{"json spelling", map[string]string{"customerId": "c1", "name": "book"}, &CreateRequest{CustomerId: "c1", Name: "book"}, false},
+		{"exported column first", map[string]string{"customer_id": "c1", "customerId": "c2", "name": "book"}, &CreateRequest{CustomerId: "c1", Name: "book"}, false},
+		{"blank", map[string]string{"customer_id": "", "name": ""}, nil, false},
+		{"bad quantity", map[string]string{"customer_id": "c1", "name": "book", "qty": "two"}, &CreateRequest{CustomerId: "c1", Name: "book"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseImportRecord(tt.record)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want an error %t", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportReadsAnExport(t *testing.T) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(export.Header(ExportColumns))
+	writer.Write(export.Record(ExportColumns, &order.Order{Id: "o1", CustomerId: "c1", Name: "book", Qty: 2}))
+	writer.Flush()
+
+	var got []*CreateRequest
+	err := importer.ReadCSV(&buf, func(row int, record map[string]string) error {
+		request, err := ParseImportRecord(record)
+		got = append(got, request)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || *got[0] != (CreateRequest{CustomerId: "c1", Name: "book", Qty: 2}) {
+		t.Fatalf("got %+v, want the exported order", got)
+	}
+}
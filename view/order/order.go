@@ -4,8 +4,11 @@ import "gin-dbo/model/order"
 
 type GetRequest struct {
 	Keyword string `json:"keyword"`
-	Page    int    `json:"page,omitempty"`
-	Limit   int    `json:"limit,omitempty"`
+	// CustomerId narrows the orders to one customer, set from the token of
+	// the caller and never from the request.
+	CustomerId string `json:"-"`
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
 }
 type CreateRequest struct {
 	CustomerId string `json:"customerId"`
@@ -17,11 +20,21 @@ type UpdateRequest struct {
 	Id         string `json:"id" swaggerignore:"true"`
 	CustomerId string `json:"customerId"`
 	Name       string `json:"name"`
-	Qty        int64  `json:"qty"`
+	Qty        *int64 `json:"qty"`
+	Version    int64  `json:"-"`
+}
+
+// PatchRequest carries a merge patch document that is applied on top of the
+// stored order.
+type PatchRequest struct {
+	Id      string
+	Patch   []byte
+	Version int64
 }
 
 type DeleteRequest struct {
-	Id string `json:"id"`
+	Id      string `json:"id"`
+	Version int64  `json:"-"`
 }
 
 type GeneralResponse struct {
@@ -0,0 +1,79 @@
+package segment
+
+import (
+	"gin-dbo/model/customer"
+	"gin-dbo/model/segment"
+)
+
+type GetRequest struct {
+	Page  int `json:"page,omitempty"`
+	Limit int `json:"limit,omitempty"`
+}
+
+// CreateRequest defines a segment keeping the customers passing every rule,
+// or at least one when Match is "any".
+type CreateRequest struct {
+	Name        string          `json:"name" example:"frequent buyers"`
+	Description string          `json:"description,omitempty" example:"ordered more than 10 items in the last 30 days"`
+	Match       string          `json:"match,omitempty" example:"all"`
+	Rules       []*segment.Rule `json:"rules"`
+}
+
+type UpdateRequest struct {
+	Id          string          `json:"id" swaggerignore:"true"`
+	Name        string          `json:"name" example:"frequent buyers"`
+	Description string          `json:"description,omitempty" example:"ordered more than 10 items in the last 30 days"`
+	Match       string          `json:"match,omitempty" example:"all"`
+	Rules       []*segment.Rule `json:"rules"`
+}
+
+type DeleteRequest struct {
+	Id string `json:"id"`
+}
+
+type GetMembersRequest struct {
+	SegmentId string `json:"segmentId"`
+	Keyword   string `json:"keyword"`
+	Page      int    `json:"page,omitempty"`
+	Limit     int    `json:"limit,omitempty"`
+}
+
+type GeneralResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Id      string `json:"id,omitempty"`
+}
+
+type Response400 struct {
+	Success bool   `json:"success" example:"false"`
+	Message string `json:"message" example:"invalid request"`
+}
+
+type Response500 struct {
+	Success bool   `json:"success" example:"false"`
+	Message string `json:"message" example:"something went wrong"`
+}
+
+type ResponseDetail struct {
+	Success bool             `json:"success"`
+	Message string           `json:"message"`
+	Data    *segment.Segment `json:"data"`
+}
+
+type ResponseData struct {
+	Success   bool               `json:"success"`
+	Message   string             `json:"message"`
+	Data      []*segment.Segment `json:"data"`
+	Limit     int                `json:"limit"`
+	Page      int                `json:"page"`
+	TotalPage int                `json:"totalPage"`
+}
+
+type ResponseMembers struct {
+	Success   bool                 `json:"success"`
+	Message   string               `json:"message"`
+	Data      []*customer.Customer `json:"data"`
+	Limit     int                  `json:"limit"`
+	Page      int                  `json:"page"`
+	TotalPage int                  `json:"totalPage"`
+}
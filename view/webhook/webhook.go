@@ -0,0 +1,88 @@
+package webhook
+
+import "gin-dbo/model/webhook"
+
+type GetRequest struct {
+	Page  int `json:"page,omitempty"`
+	Limit int `json:"limit,omitempty"`
+}
+
+type CreateRequest struct {
+	Url        string   `json:"url" example:"https://partner.example.com/hooks"`
+	Secret     string   `json:"secret,omitempty"`
+	EventTypes []string `json:"eventTypes" example:"order.*,customer.created"`
+}
+
+// UpdateRequest replaces the subscription; an empty secret keeps the current
+// one and setting active again clears the failure count.
+type UpdateRequest struct {
+	Id         string   `json:"id" swaggerignore:"true"`
+	Url        string   `json:"url"`
+	Secret     string   `json:"secret,omitempty"`
+	EventTypes []string `json:"eventTypes"`
+	Active     *bool    `json:"active"`
+}
+
+type DeleteRequest struct {
+	Id string `json:"id"`
+}
+
+type GetDeliveriesRequest struct {
+	SubscriptionId string `json:"subscriptionId"`
+	Status         string `json:"status"`
+	Page           int    `json:"page,omitempty"`
+	Limit          int    `json:"limit,omitempty"`
+}
+
+type DeliveryRequest struct {
+	SubscriptionId string `json:"subscriptionId"`
+	DeliveryId     string `json:"deliveryId"`
+}
+
+type GeneralResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Id      string `json:"id,omitempty"`
+	// Secret is only returned when it is set, it cannot be read back later.
+	Secret string `json:"secret,omitempty"`
+}
+
+type Response400 struct {
+	Success bool   `json:"success" example:"false"`
+	Message string `json:"message" example:"invalid request"`
+}
+
+type Response500 struct {
+	Success bool   `json:"success" example:"false"`
+	Message string `json:"message" example:"something went wrong"`
+}
+
+type ResponseDetail struct {
+	Success bool                  `json:"success"`
+	Message string                `json:"message"`
+	Data    *webhook.Subscription `json:"data"`
+}
+
+type ResponseData struct {
+	Success   bool                    `json:"success"`
+	Message   string                  `json:"message"`
+	Data      []*webhook.Subscription `json:"data"`
+	Limit     int                     `json:"limit"`
+	Page      int                     `json:"page"`
+	TotalPage int                     `json:"totalPage"`
+}
+
+type ResponseDelivery struct {
+	Success bool              `json:"success"`
+	Message string            `json:"message"`
+	Data    *webhook.Delivery `json:"data"`
+}
+
+type ResponseDeliveries struct {
+	Success   bool                `json:"success"`
+	Message   string              `json:"message"`
+	Data      []*webhook.Delivery `json:"data"`
+	Limit     int                 `json:"limit"`
+	Page      int                 `json:"page"`
+	TotalPage int                 `json:"totalPage"`
+}